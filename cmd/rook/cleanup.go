@@ -0,0 +1,119 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/rook/rook/pkg/ceph/mon"
+	"github.com/rook/rook/pkg/ceph/osd"
+	"github.com/rook/rook/pkg/util/exec"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var cleanupCmd = &cobra.Command{
+	Use:    "cleanup",
+	Short:  "Removes the data and osd partitions of a deleted cluster from a node",
+	Hidden: true,
+}
+
+func init() {
+	cleanupCmd.Flags().StringVar(&cfg.dataDir, "config-dir", "/var/lib/rook", "directory where the cluster configuration and data was stored")
+	cleanupCmd.Flags().StringVar(&clusterInfo.Name, "cluster-name", "", "name of the deleted cluster")
+	cleanupCmd.Flags().StringVar(&clusterInfo.FSID, "fsid", "", "uuid of the deleted cluster")
+	flags.SetFlagsFromEnv(cleanupCmd.Flags(), "ROOKD")
+
+	cleanupCmd.RunE = startCleanup
+}
+
+func startCleanup(cmd *cobra.Command, args []string) error {
+	setLogLevel()
+
+	if clusterInfo.Name == "" || clusterInfo.FSID == "" {
+		fmt.Fprintln(os.Stderr, "the cluster name and fsid are required")
+		os.Exit(1)
+	}
+
+	if err := cleanupNode(cfg.dataDir, clusterInfo.Name, clusterInfo.FSID, &exec.CommandExecutor{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// cleanupNode removes the data of the cluster from the node. The data dir may be shared with other clusters, so only
+// the dir of the cluster and the dirs of the mons and osds whose config records the fsid of the cluster are removed,
+// and only the devices of those osds are zapped.
+func cleanupNode(dataDir, clusterName, fsid string, executor exec.Executor) error {
+	entries, err := ioutil.ReadDir(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to read data dir %s. %+v", dataDir, err)
+	}
+
+	dirs := []string{}
+	osdIDs := []int{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if entry.Name() == clusterName {
+			dirs = append(dirs, entry.Name())
+			continue
+		}
+		if !daemonOfCluster(path.Join(dataDir, entry.Name()), clusterName, fsid) {
+			continue
+		}
+		dirs = append(dirs, entry.Name())
+		if id, err := strconv.Atoi(strings.TrimPrefix(entry.Name(), "osd")); err == nil {
+			osdIDs = append(osdIDs, id)
+		}
+	}
+
+	// the devices are found from the partition scheme before the osd dirs are removed
+	if len(osdIDs) > 0 {
+		logger.Infof("removing the partitions of osds %+v", osdIDs)
+		if err := osd.RemoveOSDPartitions(executor, dataDir, osdIDs); err != nil {
+			return fmt.Errorf("failed to remove rook partitions. %+v", err)
+		}
+	}
+
+	for _, dir := range dirs {
+		logger.Infof("removing %s", path.Join(dataDir, dir))
+		if err := os.RemoveAll(path.Join(dataDir, dir)); err != nil {
+			return fmt.Errorf("failed to remove %s. %+v", dir, err)
+		}
+	}
+
+	logger.Infof("done cleaning up the node")
+	return nil
+}
+
+// daemonOfCluster returns whether the dir contains the config of a daemon of the cluster with the fsid
+func daemonOfCluster(dir, clusterName, fsid string) bool {
+	config, err := ini.Load(mon.GetConfFilePath(dir, clusterName))
+	if err != nil {
+		return false
+	}
+	return config.Section("global").Key("fsid").String() == fsid
+}
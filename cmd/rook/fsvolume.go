@@ -0,0 +1,82 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rook/rook/pkg/ceph/mds"
+	"github.com/rook/rook/pkg/ceph/mon"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var fsVolumeCmd = &cobra.Command{
+	Use:    "filesystem-volume",
+	Short:  "Creates or removes the directory of a file system volume",
+	Hidden: true,
+}
+
+var (
+	fsVolumeFilesystem string
+	fsVolumeName       string
+	fsVolumeSize       uint64
+	fsVolumeRemove     bool
+)
+
+func init() {
+	fsVolumeCmd.Flags().StringVar(&fsVolumeFilesystem, "filesystem", "", "the file system of the volume")
+	fsVolumeCmd.Flags().StringVar(&fsVolumeName, "volume", "", "the name of the volume")
+	fsVolumeCmd.Flags().Uint64Var(&fsVolumeSize, "size", 0, "the quota of the volume in bytes")
+	fsVolumeCmd.Flags().BoolVar(&fsVolumeRemove, "remove", false, "remove the directory of the volume with all its content")
+	addCephFlags(fsVolumeCmd)
+
+	flags.SetFlagsFromEnv(fsVolumeCmd.Flags(), "ROOKD")
+
+	fsVolumeCmd.RunE = runFilesystemVolume
+}
+
+func runFilesystemVolume(cmd *cobra.Command, args []string) error {
+	if err := flags.VerifyRequiredFlags(fsVolumeCmd, []string{"mon-endpoints", "cluster-name", "mon-secret", "admin-secret", "filesystem", "volume"}); err != nil {
+		return err
+	}
+	if !fsVolumeRemove && fsVolumeSize == 0 {
+		return fmt.Errorf("size is required")
+	}
+
+	setLogLevel()
+
+	clusterInfo.Monitors = mon.ParseMonEndpoints(cfg.monEndpoints)
+	context := createContext()
+	if err := mon.GenerateAdminConnectionConfig(context, &clusterInfo); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write connection config. %+v\n", err)
+		os.Exit(1)
+	}
+
+	var err error
+	if fsVolumeRemove {
+		err = mds.DeleteVolumeDir(context, clusterInfo.Name, fsVolumeFilesystem, fsVolumeName)
+	} else {
+		err = mds.CreateVolumeDir(context, clusterInfo.Name, fsVolumeFilesystem, fsVolumeName, fsVolumeSize)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -66,6 +66,7 @@ type config struct {
 	location           string
 	logLevel           capnslog.LogLevel
 	cephConfigOverride string
+	cephConfig         string
 	storeConfig        osd.StoreConfig
 	networkInfo        clusterd.NetworkInfo
 	monEndpoints       string
@@ -104,13 +105,13 @@ func addCommands() {
 	rootCmd.AddCommand(mdsCmd)
 	rootCmd.AddCommand(apiCmd)
 	rootCmd.AddCommand(operatorCmd)
+	rootCmd.AddCommand(cleanupCmd)
+	rootCmd.AddCommand(fsVolumeCmd)
 }
 
 func addStandaloneRookFlags(command *cobra.Command) {
 	command.Flags().StringVar(&cfg.discoveryURL, "discovery-url", "", "etcd discovery URL. Example: http://discovery.rook.com/26bd83c92e7145e6b103f623263f61df")
 	command.Flags().StringVar(&cfg.etcdMembers, "etcd-members", "", "etcd members to connect to. Overrides the discovery URL. Example: http://10.23.45.56:2379")
-	command.Flags().StringVar(&cfg.networkInfo.PublicNetwork, "public-network", "", "public (front-side) network and subnet mask for the cluster, using CIDR notation (e.g., 192.168.0.0/24)")
-	command.Flags().StringVar(&cfg.networkInfo.ClusterNetwork, "private-network", "", "private (back-side) network and subnet mask for the cluster, using CIDR notation (e.g., 10.0.0.0/24)")
 	addOSDFlags(command)
 	addCephFlags(command)
 }
@@ -234,11 +235,22 @@ func setLogLevel() {
 
 func createContext() *clusterd.Context {
 	executor := &exec.CommandExecutor{}
+	cephConfig, err := mon.LoadCephConfigSettings(cfg.cephConfig)
+	if err != nil {
+		// proceed without the settings like a config file override that cannot be read
+		logger.Warningf("failed to load the ceph config settings. %+v", err)
+	}
 	return &clusterd.Context{
 		Executor:           executor,
 		ProcMan:            proc.New(executor),
 		ConfigDir:          cfg.dataDir,
 		ConfigFileOverride: cfg.cephConfigOverride,
+		CephConfig:         cephConfig,
 		LogLevel:           cfg.logLevel,
+		// the networks are rendered in the ceph config of the daemons
+		NetworkInfo: clusterd.NetworkInfo{
+			PublicNetwork:  cfg.networkInfo.PublicNetwork,
+			ClusterNetwork: cfg.networkInfo.ClusterNetwork,
+		},
 	}
 }
@@ -26,8 +26,11 @@ import (
 )
 
 var (
-	mdsID      string
-	mdsKeyring string
+	mdsID              string
+	mdsKeyring         string
+	mdsStandbyReplay   bool
+	mdsStandbyForRank  int
+	mdsStandbyForFscid int
 )
 
 var mdsCmd = &cobra.Command{
@@ -39,6 +42,9 @@ var mdsCmd = &cobra.Command{
 func init() {
 	mdsCmd.Flags().StringVar(&mdsID, "mds-id", "", "the mds ID")
 	mdsCmd.Flags().StringVar(&mdsKeyring, "mds-keyring", "", "the mds keyring")
+	mdsCmd.Flags().BoolVar(&mdsStandbyReplay, "mds-standby-replay", false, "whether the mds follows the journal of an active rank")
+	mdsCmd.Flags().IntVar(&mdsStandbyForRank, "mds-standby-for-rank", 0, "the rank followed by a standby-replay mds")
+	mdsCmd.Flags().IntVar(&mdsStandbyForFscid, "mds-standby-for-fscid", 0, "the file system followed by a standby-replay mds")
 	addCephFlags(mdsCmd)
 
 	flags.SetFlagsFromEnv(mdsCmd.Flags(), "ROOKD")
@@ -55,10 +61,13 @@ func startMDS(cmd *cobra.Command, args []string) error {
 
 	clusterInfo.Monitors = mon.ParseMonEndpoints(cfg.monEndpoints)
 	config := &mds.Config{
-		ID:          mdsID,
-		Keyring:     mdsKeyring,
-		ClusterInfo: &clusterInfo,
-		InProc:      true,
+		ID:              mdsID,
+		Keyring:         mdsKeyring,
+		ClusterInfo:     &clusterInfo,
+		InProc:          true,
+		StandbyReplay:   mdsStandbyReplay,
+		StandbyForRank:  mdsStandbyForRank,
+		StandbyForFscid: mdsStandbyForFscid,
 	}
 
 	err := mds.Run(createContext(), config)
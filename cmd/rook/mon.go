@@ -18,8 +18,10 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/rook/rook/pkg/ceph/mon"
+	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/util/flags"
 	"github.com/spf13/cobra"
 )
@@ -31,13 +33,19 @@ var monCmd = &cobra.Command{
 }
 
 var (
-	monName string
-	monPort int
+	monName           string
+	monPort           int
+	monPodAnnotations string
 )
 
+// the interval to check whether the operator requested a restart of the mon
+const monRestartCheckInterval = 10 * time.Second
+
 func addCephFlags(command *cobra.Command) {
 	command.Flags().StringVar(&cfg.networkInfo.PublicAddrIPv4, "public-ipv4", "127.0.0.1", "public IPv4 address for this machine")
 	command.Flags().StringVar(&cfg.networkInfo.ClusterAddrIPv4, "private-ipv4", "127.0.0.1", "private IPv4 address for this machine")
+	command.Flags().StringVar(&cfg.networkInfo.PublicNetwork, "public-network", "", "public (front-side) network and subnet mask for the cluster, using CIDR notation (e.g., 192.168.0.0/24)")
+	command.Flags().StringVar(&cfg.networkInfo.ClusterNetwork, "private-network", "", "private (back-side) network and subnet mask for the cluster, using CIDR notation (e.g., 10.0.0.0/24)")
 	command.Flags().StringVar(&clusterInfo.Name, "cluster-name", "rookcluster", "ceph cluster name")
 	command.Flags().StringVar(&clusterInfo.FSID, "fsid", "", "the cluster uuid")
 	command.Flags().StringVar(&clusterInfo.MonitorSecret, "mon-secret", "", "the cephx keyring for monitors")
@@ -45,11 +53,13 @@ func addCephFlags(command *cobra.Command) {
 	command.Flags().StringVar(&cfg.monEndpoints, "mon-endpoints", "", "ceph mon endpoints")
 	command.Flags().StringVar(&cfg.dataDir, "config-dir", "/var/lib/rook", "directory for storing configuration")
 	command.Flags().StringVar(&cfg.cephConfigOverride, "ceph-config-override", "", "optional path to a ceph config file that will be appended to the config files that rook generates")
+	command.Flags().StringVar(&cfg.cephConfig, "ceph-config", "", "optional path to a ceph config file with the settings from the cluster spec that will be merged into the config files that rook generates")
 }
 
 func init() {
 	monCmd.Flags().StringVar(&monName, "name", "", "name of the monitor")
 	monCmd.Flags().IntVar(&monPort, "port", 0, "port of the monitor")
+	monCmd.Flags().StringVar(&monPodAnnotations, "pod-annotations", "", "optional path to the annotations of the pod. the mon exits to be restarted in place when the config override hash annotation changes")
 	addCephFlags(monCmd)
 
 	flags.SetFlagsFromEnv(monCmd.Flags(), "ROOKD")
@@ -72,6 +82,17 @@ func startMon(cmd *cobra.Command, args []string) error {
 	clusterInfo.Monitors = mon.ParseMonEndpoints(cfg.monEndpoints)
 	clusterInfo.Monitors[monName] = mon.ToCephMon(monName, cfg.networkInfo.ClusterAddrIPv4)
 
+	if monPodAnnotations != "" {
+		// the operator changes the annotation to restart the mon in the same pod, so it keeps its address
+		err := k8sutil.WatchPodAnnotation(monPodAnnotations, k8sutil.ConfigOverrideHashAttr, monRestartCheckInterval, func(hash string) {
+			logger.Infof("config override hash changed to %s. exiting to restart the mon", hash)
+			os.Exit(1)
+		})
+		if err != nil {
+			logger.Warningf("cannot restart the mon when the config override changes. %+v", err)
+		}
+	}
+
 	monCfg := &mon.Config{Name: monName, Cluster: &clusterInfo}
 	err := mon.Run(createContext(), monCfg)
 	if err != nil {
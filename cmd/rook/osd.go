@@ -33,6 +33,7 @@ var osdCmd = &cobra.Command{
 var (
 	osdCluster          mon.ClusterInfo
 	osdDataDeviceFilter string
+	osdMemoryLimitMB    int
 )
 
 func addOSDFlags(command *cobra.Command) {
@@ -50,6 +51,7 @@ func addOSDFlags(command *cobra.Command) {
 	command.Flags().IntVar(&cfg.storeConfig.DatabaseSizeMB, "osd-database-size", osd.DBDefaultSizeMB, "default size (MB) for OSD database (bluestore)")
 	command.Flags().IntVar(&cfg.storeConfig.JournalSizeMB, "osd-journal-size", osd.JournalDefaultSizeMB, "default size (MB) for OSD journal (filestore)")
 	command.Flags().StringVar(&cfg.storeConfig.StoreType, "osd-store", osd.Filestore, "type of backing OSD store to use (bluestore or filestore)")
+	command.Flags().IntVar(&osdMemoryLimitMB, "osd-memory-limit", 0, "memory limit (MB) shared by the OSDs on the node, used to size the bluestore cache")
 }
 
 func init() {
@@ -83,7 +85,7 @@ func startOSD(cmd *cobra.Command, args []string) error {
 	forceFormat := false
 	clusterInfo.Monitors = mon.ParseMonEndpoints(cfg.monEndpoints)
 	agent := osd.NewAgent(dataDevices, usingDeviceFilter, cfg.metadataDevice, cfg.directories, forceFormat,
-		cfg.location, cfg.storeConfig, &clusterInfo, cfg.nodeName)
+		cfg.location, cfg.storeConfig, osdMemoryLimitMB, &clusterInfo, cfg.nodeName)
 
 	err := osd.Run(createContext(), agent)
 	if err != nil {
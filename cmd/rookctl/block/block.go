@@ -30,6 +30,9 @@ func init() {
 	Cmd.AddCommand(listCmd)
 	Cmd.AddCommand(createCmd)
 	Cmd.AddCommand(deleteCmd)
+	Cmd.AddCommand(resizeCmd)
+	Cmd.AddCommand(snapshotCmd)
+	Cmd.AddCommand(flattenCmd)
 
 	if runtime.GOOS == "linux" {
 		Cmd.AddCommand(mapCmd)
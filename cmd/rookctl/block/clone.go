@@ -0,0 +1,135 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package block
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/rook/rook/cmd/rookctl/rook"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cloneImageName     string
+	cloneImagePoolName string
+)
+
+var snapshotCloneCmd = &cobra.Command{
+	Use:   "clone [SnapshotName]",
+	Short: "Creates a copy-on-write clone of a protected snapshot of a block image",
+}
+
+var snapshotChildrenCmd = &cobra.Command{
+	Use:   "children [SnapshotName]",
+	Short: "Gets a listing of the block images cloned from a snapshot",
+}
+
+func init() {
+	snapshotCloneCmd.Flags().StringVar(&cloneImageName, "clone-name", "", "Name of the new block image (required)")
+	snapshotCloneCmd.Flags().StringVar(&cloneImagePoolName, "clone-pool-name", "", "Name of storage pool of the new block image. Default is the pool of the snapshot.")
+	snapshotCloneCmd.MarkFlagRequired("clone-name")
+
+	snapshotCmd.AddCommand(snapshotCloneCmd)
+	snapshotCloneCmd.RunE = cloneSnapshotEntry
+	snapshotCmd.AddCommand(snapshotChildrenCmd)
+	snapshotChildrenCmd.RunE = listSnapshotChildrenEntry
+}
+
+func cloneSnapshotEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := flags.VerifyRequiredFlags(cmd, []string{"name", "clone-name"}); err != nil {
+		return err
+	}
+
+	snapshotName, err := snapshotNameArg(args)
+	if err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := cloneSnapshot(snapshotName, snapshotImageName, snapshotImagePoolName, cloneImageName, cloneImagePoolName, c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(out)
+	return nil
+}
+
+func cloneSnapshot(snapshotName, imageName, poolName, cloneName, clonePoolName string, c client.RookRestClient) (string, error) {
+	snapshot := model.BlockSnapshot{Name: snapshotName, ImageName: imageName, PoolName: poolName}
+	clone := model.BlockImage{Name: cloneName, PoolName: clonePoolName}
+	resp, err := c.CloneBlockSnapshot(snapshot, clone)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone snapshot '%+v' to block image %s: %+v", snapshot, cloneName, err)
+	}
+
+	return resp, nil
+}
+
+func listSnapshotChildrenEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := flags.VerifyRequiredFlags(cmd, []string{"name"}); err != nil {
+		return err
+	}
+
+	snapshotName, err := snapshotNameArg(args)
+	if err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := listSnapshotChildren(snapshotName, snapshotImageName, snapshotImagePoolName, c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+func listSnapshotChildren(snapshotName, imageName, poolName string, c client.RookRestClient) (string, error) {
+	snapshot := model.BlockSnapshot{Name: snapshotName, ImageName: imageName, PoolName: poolName}
+	children, err := c.GetBlockSnapshotChildren(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the children of snapshot '%+v': %+v", snapshot, err)
+	}
+
+	if len(children) == 0 {
+		return "", nil
+	}
+
+	var buffer bytes.Buffer
+	w := rook.NewTableWriter(&buffer)
+
+	fmt.Fprintln(w, "NAME\tPOOL")
+
+	for _, i := range children {
+		fmt.Fprintf(w, "%s\t%s\n", i.Name, i.PoolName)
+	}
+
+	w.Flush()
+	return buffer.String(), nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package block
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneSnapshot(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockCloneBlockSnapshot: func(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error) {
+			assert.Equal(t, model.BlockSnapshot{Name: "snap1", ImageName: "myimage1", PoolName: "mypool1"}, snapshot)
+			assert.Equal(t, model.BlockImage{Name: "myclone1", PoolName: "mypool2"}, clone)
+			return fmt.Sprintf("succeeded cloning snapshot %s to image %s", snapshot.Name, clone.Name), nil
+		},
+	}
+
+	out, err := cloneSnapshot("snap1", "myimage1", "mypool1", "myclone1", "mypool2", c)
+	assert.Nil(t, err)
+	assert.Equal(t, "succeeded cloning snapshot snap1 to image myclone1", out)
+
+	c.MockCloneBlockSnapshot = func(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error) {
+		return "", fmt.Errorf("snapshot is not protected")
+	}
+	out, err = cloneSnapshot("snap1", "myimage1", "mypool1", "myclone1", "", c)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", out)
+}
+
+func TestListSnapshotChildren(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockGetBlockSnapshotChildren: func(snapshot model.BlockSnapshot) ([]model.BlockImage, error) {
+			return []model.BlockImage{{Name: "myclone1", PoolName: "mypool1"}}, nil
+		},
+	}
+
+	out, err := listSnapshotChildren("snap1", "myimage1", "mypool1", c)
+	assert.Nil(t, err)
+	assert.Equal(t, "NAME       POOL\nmyclone1   mypool1\n", out)
+
+	// no children
+	c.MockGetBlockSnapshotChildren = nil
+	out, err = listSnapshotChildren("snap1", "myimage1", "mypool1", c)
+	assert.Nil(t, err)
+	assert.Equal(t, "", out)
+}
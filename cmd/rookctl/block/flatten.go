@@ -0,0 +1,73 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package block
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rook/rook/cmd/rookctl/rook"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flattenImageName     string
+	flattenImagePoolName string
+)
+
+var flattenCmd = &cobra.Command{
+	Use:   "flatten",
+	Short: "Copies the data of the parent snapshot into a cloned block image so the clone no longer depends on it",
+}
+
+func init() {
+	flattenCmd.Flags().StringVar(&flattenImageName, "name", "", "Name of the cloned block image to flatten (required)")
+	flattenCmd.Flags().StringVar(&flattenImagePoolName, "pool-name", "rbd", "Name of storage pool of the block image")
+
+	flattenCmd.MarkFlagRequired("name")
+	flattenCmd.RunE = flattenBlockImageEntry
+}
+
+func flattenBlockImageEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := flags.VerifyRequiredFlags(cmd, []string{"name"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := flattenBlockImage(flattenImageName, flattenImagePoolName, c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(out)
+	return nil
+}
+
+func flattenBlockImage(imageName, poolName string, c client.RookRestClient) (string, error) {
+	image := model.BlockImage{Name: imageName, PoolName: poolName}
+	resp, err := c.FlattenBlockImage(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to flatten block image '%+v': %+v", image, err)
+	}
+
+	return resp, nil
+}
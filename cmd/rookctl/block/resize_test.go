@@ -0,0 +1,51 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package block
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResizeBlockImage(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockResizeBlockImage: func(image model.BlockImage, force bool) (string, error) {
+			assert.Equal(t, uint64(2048), image.Size)
+			assert.True(t, force)
+			return fmt.Sprintf("succeeded resizing image %s", image.Name), nil
+		},
+	}
+
+	out, err := resizeBlockImage("myimage1", "mypool1", 2048, true, c)
+	assert.Nil(t, err)
+	assert.Equal(t, "succeeded resizing image myimage1", out)
+}
+
+func TestResizeBlockImageFailure(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockResizeBlockImage: func(image model.BlockImage, force bool) (string, error) {
+			return "", fmt.Errorf("failed to resize image %s", image.Name)
+		},
+	}
+
+	out, err := resizeBlockImage("myimage1", "mypool1", 1024, false, c)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", out)
+}
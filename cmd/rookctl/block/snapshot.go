@@ -0,0 +1,185 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package block
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/rook/rook/cmd/rookctl/rook"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/util/display"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotImageName     string
+	snapshotImagePoolName string
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Performs commands and operations on the snapshots of a block image in the cluster",
+}
+
+// snapshotAction is a rest client call that acts on a single snapshot
+type snapshotAction func(c client.RookRestClient, snapshot model.BlockSnapshot) (string, error)
+
+var snapshotActions = map[string]snapshotAction{
+	"create":    client.RookRestClient.CreateBlockSnapshot,
+	"delete":    client.RookRestClient.DeleteBlockSnapshot,
+	"rollback":  client.RookRestClient.RollbackBlockSnapshot,
+	"protect":   client.RookRestClient.ProtectBlockSnapshot,
+	"unprotect": client.RookRestClient.UnprotectBlockSnapshot,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "Gets a listing of the snapshots of a block image with their size and creation time",
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create [SnapshotName]",
+	Short: "Creates a snapshot of a block image",
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete [SnapshotName]",
+	Short: "Deletes a snapshot of a block image. A protected snapshot must be unprotected first.",
+}
+
+var snapshotRollbackCmd = &cobra.Command{
+	Use:   "rollback [SnapshotName]",
+	Short: "Rolls back a block image to a snapshot. The image must be unmapped from all clients.",
+}
+
+var snapshotProtectCmd = &cobra.Command{
+	Use:   "protect [SnapshotName]",
+	Short: "Protects a snapshot of a block image from deletion",
+}
+
+var snapshotUnprotectCmd = &cobra.Command{
+	Use:   "unprotect [SnapshotName]",
+	Short: "Removes the deletion protection of a snapshot of a block image",
+}
+
+func init() {
+	snapshotCmd.PersistentFlags().StringVar(&snapshotImageName, "name", "", "Name of the block image (required)")
+	snapshotCmd.PersistentFlags().StringVar(&snapshotImagePoolName, "pool-name", "rbd", "Name of storage pool of the block image")
+
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotListCmd.RunE = listSnapshotsEntry
+
+	for _, cmd := range []*cobra.Command{snapshotCreateCmd, snapshotDeleteCmd, snapshotRollbackCmd, snapshotProtectCmd, snapshotUnprotectCmd} {
+		snapshotCmd.AddCommand(cmd)
+		cmd.RunE = snapshotActionEntry
+	}
+}
+
+func listSnapshotsEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := flags.VerifyRequiredFlags(cmd, []string{"name"}); err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := listSnapshots(snapshotImageName, snapshotImagePoolName, c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(out)
+	return nil
+}
+
+func listSnapshots(imageName, poolName string, c client.RookRestClient) (string, error) {
+	snapshots, err := c.GetBlockSnapshots(poolName, imageName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get snapshots of block image %s: %+v", imageName, err)
+	}
+
+	if len(snapshots) == 0 {
+		return "", nil
+	}
+
+	var buffer bytes.Buffer
+	w := rook.NewTableWriter(&buffer)
+
+	fmt.Fprintln(w, "NAME\tSIZE\tTIMESTAMP")
+
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, display.BytesToString(s.Size), s.Timestamp)
+	}
+
+	w.Flush()
+	return buffer.String(), nil
+}
+
+func snapshotActionEntry(cmd *cobra.Command, args []string) error {
+	rook.SetupLogging()
+
+	if err := flags.VerifyRequiredFlags(cmd, []string{"name"}); err != nil {
+		return err
+	}
+
+	snapshotName, err := snapshotNameArg(args)
+	if err != nil {
+		return err
+	}
+
+	c := rook.NewRookNetworkRestClient()
+	out, err := runSnapshotAction(cmd.Name(), snapshotName, snapshotImageName, snapshotImagePoolName, c)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(out)
+	return nil
+}
+
+// snapshotNameArg returns the snapshot name, which is the only argument of the snapshot commands
+func snapshotNameArg(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("Missing required argument SnapshotName")
+	}
+
+	if len(args) > 1 {
+		return "", fmt.Errorf("Too many arguments")
+	}
+
+	return args[0], nil
+}
+
+func runSnapshotAction(action, snapshotName, imageName, poolName string, c client.RookRestClient) (string, error) {
+	run, ok := snapshotActions[action]
+	if !ok {
+		return "", fmt.Errorf("unknown snapshot command %s", action)
+	}
+
+	snapshot := model.BlockSnapshot{Name: snapshotName, ImageName: imageName, PoolName: poolName}
+	resp, err := run(c, snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to %s snapshot '%+v': %+v", action, snapshot, err)
+	}
+
+	return resp, nil
+}
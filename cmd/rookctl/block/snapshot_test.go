@@ -0,0 +1,100 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package block
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/rook/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListSnapshots(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockGetBlockSnapshots: func(poolName, imageName string) ([]model.BlockSnapshot, error) {
+			assert.Equal(t, "mypool1", poolName)
+			assert.Equal(t, "myimage1", imageName)
+			return []model.BlockSnapshot{
+				{Name: "snap1", ImageName: "myimage1", PoolName: "mypool1", Size: 1024 * 1024 * 1024, Timestamp: "Tue Oct 17 10:00:00 2017"},
+				{Name: "snap2", ImageName: "myimage1", PoolName: "mypool1", Size: 2048, Timestamp: "Tue Oct 17 11:30:00 2017"},
+			}, nil
+		},
+	}
+
+	out, err := listSnapshots("myimage1", "mypool1", c)
+	assert.Nil(t, err)
+	expectedOut := "NAME      SIZE       TIMESTAMP\n" +
+		"snap1     1.00 GiB   Tue Oct 17 10:00:00 2017\n" +
+		"snap2     2.00 KiB   Tue Oct 17 11:30:00 2017\n"
+	assert.Equal(t, expectedOut, out)
+}
+
+func TestListSnapshotsFailure(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockGetBlockSnapshots: func(poolName, imageName string) ([]model.BlockSnapshot, error) {
+			return nil, fmt.Errorf("mock failure")
+		},
+	}
+
+	out, err := listSnapshots("myimage1", "mypool1", c)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", out)
+}
+
+func TestSnapshotActions(t *testing.T) {
+	var called []string
+	mockAction := func(action string) func(snapshot model.BlockSnapshot) (string, error) {
+		return func(snapshot model.BlockSnapshot) (string, error) {
+			assert.Equal(t, model.BlockSnapshot{Name: "snap1", ImageName: "myimage1", PoolName: "mypool1"}, snapshot)
+			called = append(called, action)
+			return fmt.Sprintf("succeeded %s snapshot %s", action, snapshot.Name), nil
+		}
+	}
+	c := &test.MockRookRestClient{
+		MockCreateBlockSnapshot:    mockAction("creating"),
+		MockDeleteBlockSnapshot:    mockAction("deleting"),
+		MockRollbackBlockSnapshot:  mockAction("rolling back to"),
+		MockProtectBlockSnapshot:   mockAction("protecting"),
+		MockUnprotectBlockSnapshot: mockAction("unprotecting"),
+	}
+
+	out, err := runSnapshotAction("create", "snap1", "myimage1", "mypool1", c)
+	assert.Nil(t, err)
+	assert.Equal(t, "succeeded creating snapshot snap1", out)
+
+	for _, action := range []string{"rollback", "protect", "unprotect", "delete"} {
+		_, err = runSnapshotAction(action, "snap1", "myimage1", "mypool1", c)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{"creating", "rolling back to", "protecting", "unprotecting", "deleting"}, called)
+
+	_, err = runSnapshotAction("clone", "snap1", "myimage1", "mypool1", c)
+	assert.NotNil(t, err)
+}
+
+func TestSnapshotActionFailure(t *testing.T) {
+	c := &test.MockRookRestClient{
+		MockProtectBlockSnapshot: func(snapshot model.BlockSnapshot) (string, error) {
+			return "", fmt.Errorf("mock failure")
+		},
+	}
+
+	out, err := runSnapshotAction("protect", "snap1", "myimage1", "mypool1", c)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", out)
+}
@@ -16,8 +16,12 @@ limitations under the License.
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/gorilla/mux"
 	ceph "github.com/rook/rook/pkg/ceph/client"
 	"github.com/rook/rook/pkg/model"
 )
@@ -58,3 +62,67 @@ func (h *Handler) GetClientAccessInfo(w http.ResponseWriter, r *http.Request) {
 
 	FormatJsonResponse(w, clientAccessInfo)
 }
+
+// Creates a user that can only access the block images of a pool. The key of the user is returned. If the user
+// already exists with the same pool, the existing key is returned.
+// POST
+// /client/users
+func (h *Handler) CreateClientUser(w http.ResponseWriter, r *http.Request) {
+	body, ok := handleReadBody(w, r, "create client user")
+	if !ok {
+		return
+	}
+
+	var user model.ClientUser
+	if err := json.Unmarshal(body, &user); err != nil {
+		logger.Errorf("failed to unmarshal create client user request body '%s': %+v", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if user.Name == "" || user.Pool == "" {
+		logger.Errorf("client user missing required fields: %+v", user)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !strings.HasPrefix(user.Name, model.ClientUserPrefix) {
+		logger.Errorf("client user %s does not have the prefix %s", user.Name, model.ClientUserPrefix)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	caps := []string{"mon", "profile rbd", "osd", fmt.Sprintf("profile rbd pool=%s", user.Pool)}
+	key, err := ceph.AuthGetOrCreateKey(h.context, h.config.ClusterInfo.Name, clientEntity(user.Name), caps)
+	if err != nil {
+		logger.Errorf("failed to create client user %s: %+v", user.Name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	user.Key = key
+	FormatJsonResponse(w, user)
+}
+
+// Deletes a client user. Only the users with the client user prefix can be deleted.
+// DELETE
+// /client/users/{name}
+func (h *Handler) DeleteClientUser(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !strings.HasPrefix(name, model.ClientUserPrefix) {
+		logger.Errorf("client user %s does not have the prefix %s", name, model.ClientUserPrefix)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := ceph.AuthDelete(h.context, h.config.ClusterInfo.Name, clientEntity(name)); err != nil {
+		logger.Errorf("failed to delete client user %s: %+v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func clientEntity(name string) string {
+	return fmt.Sprintf("client.%s", name)
+}
@@ -36,6 +36,7 @@ type ClusterHandler interface {
 	RemoveFileSystem(fs *model.FilesystemRequest) error
 	GetMonitors() (map[string]*mon.CephMonitorConfig, error)
 	GetNodes() ([]model.Node, error)
+	GetVolumes() ([]model.ProvisionedVolume, error)
 }
 
 type etcdHandler struct {
@@ -140,3 +141,8 @@ func (e *etcdHandler) GetNodes() ([]model.Node, error) {
 
 	return nodes, nil
 }
+
+func (e *etcdHandler) GetVolumes() ([]model.ProvisionedVolume, error) {
+	// volumes are only provisioned by the operator in kubernetes
+	return []model.ProvisionedVolume{}, nil
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	ceph "github.com/rook/rook/pkg/ceph/client"
 	"github.com/rook/rook/pkg/ceph/mds"
 	"github.com/rook/rook/pkg/model"
@@ -104,6 +105,66 @@ func (h *Handler) RemoveFileSystem(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// Creates the user of a volume in a file system. The user can only access the directory of the volume, which is
+// created by the operator. The body is the volume with its name and size.
+// POST
+// /filesystem/{name}/volumes
+func (h *Handler) CreateFilesystemVolume(w http.ResponseWriter, r *http.Request) {
+	fsName := mux.Vars(r)["name"]
+	body, ok := handleReadBody(w, r, "create filesystem volume")
+	if !ok {
+		return
+	}
+
+	var volume model.FilesystemVolume
+	if err := json.Unmarshal(body, &volume); err != nil {
+		logger.Errorf("failed to unmarshal create filesystem volume request body '%s': %+v", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if volume.Name == "" || volume.Size == 0 {
+		logger.Errorf("filesystem volume missing required fields: %+v", volume)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := mds.ValidateVolumeName(volume.Name); err != nil {
+		logger.Errorf("failed to create filesystem volume. %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	created, err := mds.CreateVolume(h.context, h.config.ClusterInfo.Name, fsName, volume.Name, volume.Size)
+	if err != nil {
+		logger.Errorf("failed to create volume %s in file system %s: %+v", volume.Name, fsName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	FormatJsonResponse(w, created)
+}
+
+// Deletes the user of a volume from a file system. The directory of the volume is removed by the operator.
+// DELETE
+// /filesystem/{name}/volumes/{volume}
+func (h *Handler) DeleteFilesystemVolume(w http.ResponseWriter, r *http.Request) {
+	fsName := mux.Vars(r)["name"]
+	volume := mux.Vars(r)["volume"]
+	if err := mds.ValidateVolumeName(volume); err != nil {
+		logger.Errorf("failed to delete filesystem volume. %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := mds.DeleteVolume(h.context, h.config.ClusterInfo.Name, fsName, volume); err != nil {
+		logger.Errorf("failed to delete volume %s from file system %s: %+v", volume, fsName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 func handleReadFilesystemRequest(w http.ResponseWriter, r *http.Request, handlerName string) (*model.FilesystemRequest, bool) {
 	body, ok := handleReadBody(w, r, handlerName)
 	if !ok {
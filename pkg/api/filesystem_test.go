@@ -147,3 +147,59 @@ func TestRemoveFileSystemHandler(t *testing.T) {
 	assert.Equal(t, http.StatusAccepted, w.Code)
 	assert.Equal(t, "", etcdClient.GetValue("/rook/services/ceph/fs/desired/myfs1/pool"))
 }
+
+func TestFilesystemVolumeHandlers(t *testing.T) {
+	context, _, executor := testContext()
+	defer os.RemoveAll(context.ConfigDir)
+
+	executor.MockExecuteCommandWithOutputFile = func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "fs" && args[1] == "ls":
+			return cephFilesystemListResponseRaw, nil
+		case args[0] == "mon_status":
+			return `{"quorum":[0],"monmap":{"mons":[{"name":"mon0","rank":0,"addr":"10.0.0.1:6790/0"}]}}`, nil
+		case args[0] == "auth" && args[1] == "get-key":
+			return `{"key":"adminkey"}`, nil
+		case args[0] == "auth" && args[1] == "get-or-create-key":
+			return `{"key":"volumekey"}`, nil
+		case args[0] == "auth" && args[1] == "del":
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	runTest := func(method, url, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h := newTestHandler(context)
+		r := newRouter(h.GetRoutes())
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the size is required
+	w := runTest("POST", "http://10.0.0.100/filesystem/myfs1/volumes", `{"name":"vol1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// the volume cannot refer to other directories of the file system
+	w = runTest("POST", "http://10.0.0.100/filesystem/myfs1/volumes", `{"name":"../vol1","size":1048576}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = runTest("DELETE", "http://10.0.0.100/filesystem/myfs1/volumes/vol..1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = runTest("POST", "http://10.0.0.100/filesystem/myfs1/volumes", `{"name":"vol1","size":1048576}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var volume model.FilesystemVolume
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &volume))
+	assert.Equal(t, model.FilesystemVolume{Filesystem: "myfs1", Name: "vol1", Path: "/volumes/vol1", Size: 1048576,
+		UserName: "myfs1-vol1", SecretKey: "volumekey"}, volume)
+
+	// the file system does not exist
+	w = runTest("POST", "http://10.0.0.100/filesystem/myfs2/volumes", `{"name":"vol1","size":1048576}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	w = runTest("DELETE", "http://10.0.0.100/filesystem/myfs1/volumes/vol1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
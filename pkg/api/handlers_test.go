@@ -268,6 +268,53 @@ func TestCreatePoolHandler(t *testing.T) {
 	assert.Equal(t, "pool 'ecPool1' created", w.Body.String())
 }
 
+func TestCreatePoolHandlerCrushRoot(t *testing.T) {
+	context, _, executor := testContext()
+	defer os.RemoveAll(context.ConfigDir)
+
+	commands := []string{}
+	executor.MockExecuteCommandWithOutputFile = func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[1] == "crush" && args[2] == "rule" && args[3] == "create-simple":
+			commands = append(commands, strings.Join(args[0:7], " "))
+			return "", nil
+		case args[1] == "pool" && args[2] == "create":
+			commands = append(commands, strings.Join(args[0:6], " "))
+			return "pool 'pool1' created", nil
+		case args[1] == "pool" && args[2] == "set":
+			commands = append(commands, strings.Join(args[0:6], " "))
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected mon_command '%v'", args)
+	}
+	runTest := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "http://10.0.0.100/pool", strings.NewReader(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h := newTestHandler(context)
+		h.CreatePool(w, req)
+		return w
+	}
+
+	// the replicas are placed on different hosts under the crush root
+	w := runTest(`{"poolName":"pool1","type":0,"replicationConfig":{"size":2},"crushRoot":"zone1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{
+		"osd crush rule create-simple rook-zone1 zone1 host",
+		"osd pool create pool1 0 replicated",
+		"osd pool set pool1 size 2",
+		"osd pool set pool1 crush_rule rook-zone1",
+	}, commands)
+
+	// erasure coded pools cannot be placed under a crush root
+	commands = []string{}
+	w = runTest(`{"poolName":"ecPool1","type":1,"erasureCodedConfig":{"dataChunkCount":2,"codingChunkCount":1},"crushRoot":"zone1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0, len(commands))
+}
+
 func TestCreatePoolHandlerFailure(t *testing.T) {
 	context, _, _ := testContext()
 	defer os.RemoveAll(context.ConfigDir)
@@ -291,6 +338,154 @@ func TestCreatePoolHandlerFailure(t *testing.T) {
 	assert.Equal(t, "", w.Body.String())
 }
 
+func TestDeletePoolHandler(t *testing.T) {
+	context, _, executor := testContext()
+	defer os.RemoveAll(context.ConfigDir)
+
+	images := map[string]string{"rbd": `[{"image":"image1","size":100,"format":2}]`}
+	dataPool := ""
+	filesystems := `[{"name":"myfs","metadata_pool":"myfs-metadata","data_pools":["myfs-data"]}]`
+	deleted := []string{}
+	executor.MockExecuteCommandWithOutput = func(actionName string, command string, args ...string) (string, error) {
+		if command == "rbd" && args[0] == "ls" {
+			if list, ok := images[args[2]]; ok {
+				return list, nil
+			}
+			return `[]`, nil
+		}
+		if command == "rbd" && args[0] == "info" {
+			assert.Equal(t, "rbd/image1", args[1])
+			return fmt.Sprintf(`{"name":"image1","size":100,"data_pool":"%s"}`, dataPool), nil
+		}
+		return "", fmt.Errorf("unexpected rbd command '%v'", args)
+	}
+	executor.MockExecuteCommandWithOutputFile = func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "osd" && args[1] == "lspools":
+			return `[{"poolnum":0,"poolname":"rbd"},{"poolnum":1,"poolname":"ecPool1"},{"poolnum":2,"poolname":"myfs-data"},` +
+				`{"poolnum":3,"poolname":"default.rgw.buckets.data"}]`, nil
+		case args[0] == "osd" && args[1] == "pool" && args[2] == "get":
+			if args[3] == "ecPool1" {
+				return SuccessGetPoolECPool1Response, nil
+			}
+			return SuccessGetPoolRBDResponse, nil
+		case args[0] == "fs" && args[1] == "ls":
+			return filesystems, nil
+		case args[0] == "osd" && args[1] == "pool" && args[2] == "delete":
+			assert.Equal(t, args[3], args[4])
+			deleted = append(deleted, args[3])
+			return "", nil
+		case args[0] == "osd" && args[1] == "erasure-code-profile" && args[2] == "rm":
+			deleted = append(deleted, args[3])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+
+	deletePool := func(name string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("DELETE", "http://10.0.0.100/pool/"+name, nil)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// a pool that does not exist is not found
+	w := deletePool("otherpool")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// a pool with images is not deleted
+	w = deletePool("rbd")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "pool rbd contains 1 images", w.Body.String())
+
+	// a pool that stores the data of an image in another pool is not deleted
+	dataPool = "ecPool1"
+	w = deletePool("ecPool1")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "pool ecPool1 stores the data of image rbd/image1", w.Body.String())
+	dataPool = ""
+
+	// a pool of an object store is not deleted
+	w = deletePool("default.rgw.buckets.data")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "pool default.rgw.buckets.data is used by an object store", w.Body.String())
+
+	// a pool used by a file system is not deleted
+	w = deletePool("myfs-data")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "pool myfs-data is used by file system myfs", w.Body.String())
+	assert.Equal(t, 0, len(deleted))
+
+	// an unused pool is deleted along with its erasure code profile
+	w = deletePool("ecPool1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "succeeded deleting pool ecPool1", w.Body.String())
+	assert.Equal(t, []string{"ecPool1", "ecPool1_ecprofile"}, deleted)
+}
+
+func TestUpdatePoolHandler(t *testing.T) {
+	context, _, executor := testContext()
+	defer os.RemoveAll(context.ConfigDir)
+
+	var sizeArgs []string
+	executor.MockExecuteCommandWithOutputFile = func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "osd" && args[1] == "lspools":
+			return `[{"poolnum":0,"poolname":"rbd"},{"poolnum":1,"poolname":"ecPool1"}]`, nil
+		case args[0] == "osd" && args[1] == "pool" && args[2] == "get":
+			if args[3] == "ecPool1" {
+				return SuccessGetPoolECPool1Response, nil
+			}
+			return SuccessGetPoolRBDResponse, nil
+		case args[0] == "osd" && args[1] == "pool" && args[2] == "set":
+			sizeArgs = args[3:6]
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+
+	updatePool := func(name, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("PUT", "http://10.0.0.100/pool/"+name, strings.NewReader(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the replication size is changed
+	w := updatePool("rbd", `{"poolName":"rbd","type":0,"replicationConfig":{"size":3}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"rbd", "size", "3"}, sizeArgs)
+
+	// the size is not set if it did not change
+	sizeArgs = nil
+	w = updatePool("rbd", `{"poolName":"rbd","type":0,"replicationConfig":{"size":1}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, sizeArgs)
+
+	// erasure coded pools cannot be changed
+	w = updatePool("ecPool1", `{"poolName":"ecPool1","type":1,"erasureCodedConfig":{"dataChunkCount":3,"codingChunkCount":1}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// a replicated pool cannot be changed to erasure coded
+	w = updatePool("rbd", `{"poolName":"rbd","type":1,"erasureCodedConfig":{"dataChunkCount":2,"codingChunkCount":1}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, sizeArgs)
+
+	// the pool must exist
+	w = updatePool("otherpool", `{"poolName":"otherpool","type":0,"replicationConfig":{"size":3}}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
 func TestGetClientAccessInfo(t *testing.T) {
 	context, _, executor := testContext()
 	defer os.RemoveAll(context.ConfigDir)
@@ -339,6 +534,57 @@ func TestGetClientAccessInfoHandlerFailure(t *testing.T) {
 	assert.Equal(t, "", w.Body.String())
 }
 
+func TestClientUserHandlers(t *testing.T) {
+	context, _, executor := testContext()
+	defer os.RemoveAll(context.ConfigDir)
+
+	var authArgs []string
+	executor.MockExecuteCommandWithOutputFile = func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "auth" && args[1] == "get-or-create-key":
+			authArgs = args[0:7]
+			return `{"key":"userkey"}`, nil
+		case args[0] == "auth" && args[1] == "del":
+			authArgs = args[0:3]
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	runTest := func(method, url, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h := newTestHandler(context)
+		r := newRouter(h.GetRoutes())
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the pool is required
+	w := runTest("POST", "http://10.0.0.100/client/users", `{"name":"rook-user1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// the user can only access the pool
+	w = runTest("POST", "http://10.0.0.100/client/users", `{"name":"rook-user1","pool":"pool1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"name":"rook-user1","pool":"pool1","key":"userkey"}`, w.Body.String())
+	assert.Equal(t, []string{"auth", "get-or-create-key", "client.rook-user1", "mon", "profile rbd", "osd", "profile rbd pool=pool1"}, authArgs)
+
+	w = runTest("DELETE", "http://10.0.0.100/client/users/rook-user1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"auth", "del", "client.rook-user1"}, authArgs)
+
+	// only the users with the rook prefix are managed
+	authArgs = nil
+	w = runTest("POST", "http://10.0.0.100/client/users", `{"name":"user1","pool":"pool1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = runTest("DELETE", "http://10.0.0.100/client/users/admin", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, authArgs)
+}
+
 func testContext() (*clusterd.Context, *util.MockEtcdClient, *exectest.MockExecutor) {
 	return testContextWithMons([]string{"mon0"})
 }
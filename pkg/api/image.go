@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	ceph "github.com/rook/rook/pkg/ceph/client"
 	"github.com/rook/rook/pkg/model"
 )
@@ -94,8 +95,15 @@ func (h *Handler) CreateImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	options := ceph.ImageOptions{
+		Format:      newImage.Format,
+		Features:    newImage.Features,
+		StripeUnit:  newImage.StripeUnit,
+		StripeCount: newImage.StripeCount,
+		DataPool:    newImage.DataPoolName,
+	}
 	createdImage, err := ceph.CreateImage(h.context, h.config.ClusterInfo.Name, newImage.Name,
-		newImage.PoolName, newImage.Size)
+		newImage.PoolName, newImage.Size, options)
 	if err != nil {
 		logger.Errorf("failed to create image %+v: %+v", newImage, err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -132,3 +140,76 @@ func (h *Handler) DeleteImage(w http.ResponseWriter, r *http.Request) {
 
 	w.Write([]byte(fmt.Sprintf("succeeded deleting image %s", deleteImageReq.Name)))
 }
+
+// Resizes a block image in this cluster. An image is only shrunk if forced, since the data beyond the new size is lost.
+// PUT
+// /image?force=<true|false>
+func (h *Handler) ResizeImage(w http.ResponseWriter, r *http.Request) {
+	var image model.BlockImage
+	body, ok := handleReadBody(w, r, "resize image")
+	if !ok {
+		return
+	}
+
+	if err := json.Unmarshal(body, &image); err != nil {
+		logger.Errorf("failed to unmarshal resize image request body '%s': %+v", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if image.Name == "" || image.PoolName == "" || image.Size == 0 {
+		logger.Errorf("image missing required fields: %+v", image)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	force := r.URL.Query().Get("force") == "true"
+
+	images, err := ceph.ListImages(h.context, h.config.ClusterInfo.Name, image.PoolName)
+	if err != nil {
+		logger.Errorf("failed to get images in pool %s: %+v", image.PoolName, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var existing *ceph.CephBlockImage
+	for i := range images {
+		if images[i].Name == image.Name {
+			existing = &images[i]
+		}
+	}
+	if existing == nil {
+		logger.Errorf("image %s not found in pool %s", image.Name, image.PoolName)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if image.Size < existing.Size && !force {
+		msg := fmt.Sprintf("cannot shrink image %s from %d to %d bytes without force", image.Name, existing.Size, image.Size)
+		logger.Errorf(msg)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(msg))
+		return
+	}
+
+	if err := ceph.ResizeImage(h.context, h.config.ClusterInfo.Name, image.Name, image.PoolName, image.Size, force); err != nil {
+		logger.Errorf("failed to resize image %+v: %+v", image, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded resizing image %s", image.Name)))
+}
+
+// Flattens a cloned block image so that it no longer depends on the snapshot it was cloned from.
+// POST
+// /image/{pool}/{name}/flatten
+func (h *Handler) FlattenImage(w http.ResponseWriter, r *http.Request) {
+	pool := mux.Vars(r)["pool"]
+	image := mux.Vars(r)["name"]
+
+	if err := ceph.FlattenImage(h.context, h.config.ClusterInfo.Name, image, pool); err != nil {
+		logger.Errorf("failed to flatten image %s in pool %s: %+v", image, pool, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded flattening image %s", image)))
+}
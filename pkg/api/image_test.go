@@ -260,3 +260,54 @@ func TestDeleteImageHandlerFailure(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Equal(t, ``, w.Body.String())
 }
+
+func TestResizeImageHandler(t *testing.T) {
+	context, _, executor := testContext()
+	defer os.RemoveAll(context.ConfigDir)
+
+	var resizeArgs []string
+	executor.MockExecuteCommandWithOutput = func(actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "resize":
+			resizeArgs = args
+			return "", nil
+		case command == "rbd" && args[0] == "ls" && args[1] == "-l":
+			return `[{"image":"myImage1","size":2097152,"format":2}]`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	runTest := func(url, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("PUT", url, strings.NewReader(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h := newTestHandler(context)
+		h.ResizeImage(w, req)
+		return w
+	}
+
+	// missing fields for the image, should be bad request
+	w := runTest("http://10.0.0.100/image", `{"imageName":"myImage1"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// the image does not exist
+	w = runTest("http://10.0.0.100/image", `{"imageName":"myImage2","poolName":"myPool1","size":4194304}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// grow the image
+	w = runTest("http://10.0.0.100/image", `{"imageName":"myImage1","poolName":"myPool1","size":4194304}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `succeeded resizing image myImage1`, w.Body.String())
+	assert.Equal(t, []string{"resize", "myPool1/myImage1", "--size", "4"}, resizeArgs[0:4])
+
+	// shrinking the image is rejected unless forced
+	resizeArgs = nil
+	w = runTest("http://10.0.0.100/image", `{"imageName":"myImage1","poolName":"myPool1","size":1048576}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Nil(t, resizeArgs)
+
+	w = runTest("http://10.0.0.100/image?force=true", `{"imageName":"myImage1","poolName":"myPool1","size":1048576}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"resize", "myPool1/myImage1", "--size", "1", "--allow-shrink"}, resizeArgs[0:5])
+}
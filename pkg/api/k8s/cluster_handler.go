@@ -25,6 +25,7 @@ import (
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	k8smds "github.com/rook/rook/pkg/operator/mds"
 	k8srgw "github.com/rook/rook/pkg/operator/rgw"
+	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -47,7 +48,7 @@ func (s *clusterHandler) EnableObjectStore() error {
 	logger.Infof("Starting the Object store")
 	// Passing an empty Placement{} as the api doesn't know about placement
 	// information. This should be resolved with the transition to CRD (TPR).
-	r := k8srgw.New(s.context, s.namespace, s.versionTag, k8sutil.Placement{})
+	r := k8srgw.New(s.context, s.namespace, s.versionTag, k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 	err := r.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start rgw. %+v", err)
@@ -56,7 +57,11 @@ func (s *clusterHandler) EnableObjectStore() error {
 }
 
 func (s *clusterHandler) RemoveObjectStore() error {
-	logger.Infof("TODO: Remove the object store")
+	logger.Infof("Removing the object store")
+	r := k8srgw.New(s.context, s.namespace, s.versionTag, k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	if err := r.Delete(); err != nil {
+		return fmt.Errorf("failed to remove rgw. %+v", err)
+	}
 	return nil
 }
 
@@ -67,9 +72,14 @@ func (s *clusterHandler) GetObjectStoreConnectionInfo() (*model.ObjectStoreConne
 		return nil, false, fmt.Errorf("failed to get rgw service. %+v", err)
 	}
 
+	// the port of the service is configured in the object store spec
+	port := int32(rgw.RGWPort)
+	if len(service.Spec.Ports) > 0 {
+		port = service.Spec.Ports[0].Port
+	}
 	info := &model.ObjectStoreConnectInfo{
 		Host:       "rook-ceph-rgw",
-		IPEndpoint: rgw.GetRGWEndpoint(service.Spec.ClusterIP),
+		IPEndpoint: fmt.Sprintf("%s:%d", service.Spec.ClusterIP, port),
 	}
 	logger.Infof("Object store connection: %+v", info)
 	return info, true, nil
@@ -79,13 +89,16 @@ func (s *clusterHandler) StartFileSystem(fs *model.FilesystemRequest) error {
 	logger.Infof("Starting the MDS")
 	// Passing an empty Placement{} as the api doesn't know about placement
 	// information. This should be resolved with the transition to CRD (TPR).
-	c := k8smds.New(s.context, s.namespace, s.versionTag, k8sutil.Placement{})
+	c := k8smds.New(s.context, s.namespace, s.versionTag, k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.Filesystem = fs.Name
 	return c.Start()
 }
 
 func (s *clusterHandler) RemoveFileSystem(fs *model.FilesystemRequest) error {
-	logger.Infof("TODO: Remove file system")
-	return nil
+	logger.Infof("Removing file system %s", fs.Name)
+	c := k8smds.New(s.context, s.namespace, s.versionTag, k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.Filesystem = fs.Name
+	return c.RemoveFilesystem()
 }
 
 func (s *clusterHandler) GetMonitors() (map[string]*mon.CephMonitorConfig, error) {
@@ -99,3 +112,7 @@ func (s *clusterHandler) GetNodes() ([]model.Node, error) {
 	logger.Infof("Getting nodes")
 	return getNodes(s.context.Clientset)
 }
+
+func (s *clusterHandler) GetVolumes() ([]model.ProvisionedVolume, error) {
+	return getVolumes(s.context.Clientset, s.namespace)
+}
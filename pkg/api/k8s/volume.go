@@ -0,0 +1,74 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package k8s
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// the annotations recorded on the PVs by the block provisioner of the operator
+	provisionedByAnnotation    = "pv.kubernetes.io/provisioned-by"
+	provisionerName            = "rook.io/block"
+	poolAnnotation             = "rook.io/pool"
+	clusterNamespaceAnnotation = "rook.io/clusterNamespace"
+)
+
+// getVolumes returns the PVs that were provisioned by the operator in the cluster of the namespace. Volumes that were
+// provisioned before the cluster was recorded on the PV belong to the default cluster.
+func getVolumes(clientset kubernetes.Interface, namespace string) ([]model.ProvisionedVolume, error) {
+	pvs, err := clientset.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volumes. %+v", err)
+	}
+
+	volumes := []model.ProvisionedVolume{}
+	for _, pv := range pvs.Items {
+		rbd := pv.Spec.PersistentVolumeSource.RBD
+		if pv.Annotations[provisionedByAnnotation] != provisionerName || rbd == nil {
+			continue
+		}
+		clusterNamespace := pv.Annotations[clusterNamespaceAnnotation]
+		if clusterNamespace == "" {
+			clusterNamespace = k8sutil.Namespace
+		}
+		if clusterNamespace != namespace {
+			continue
+		}
+
+		volume := model.ProvisionedVolume{
+			Name:         pv.Name,
+			Phase:        string(pv.Status.Phase),
+			ImageName:    rbd.RBDImage,
+			PoolName:     pv.Annotations[poolAnnotation],
+			StorageClass: pv.Spec.StorageClassName,
+		}
+		if volume.PoolName == "" {
+			volume.PoolName = rbd.RBDPool
+		}
+		if pv.Spec.ClaimRef != nil {
+			volume.ClaimName = pv.Spec.ClaimRef.Name
+			volume.ClaimNamespace = pv.Spec.ClaimRef.Namespace
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
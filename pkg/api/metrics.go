@@ -24,7 +24,6 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/rook/rook/pkg/ceph/collectors"
-	"github.com/rook/rook/pkg/clusterd"
 )
 
 // CephExporter wraps all the ceph collectors and provides a single global
@@ -47,7 +46,7 @@ var _ prometheus.Collector = &CephExporter{}
 func NewCephExporter(handler *Handler) *CephExporter {
 	return &CephExporter{
 		handler:    handler, // not implemented
-		collectors: createCollectors(handler.context, handler.config.ClusterInfo.Name),
+		collectors: createCollectors(handler),
 	}
 }
 
@@ -72,12 +71,15 @@ func (c *CephExporter) Collect(ch chan<- prometheus.Metric) {
 	}
 }
 
-func createCollectors(context *clusterd.Context, clusterName string) []prometheus.Collector {
+func createCollectors(handler *Handler) []prometheus.Collector {
+	context := handler.context
+	clusterName := handler.config.ClusterInfo.Name
 	return []prometheus.Collector{
 		collectors.NewClusterUsageCollector(context, clusterName),
 		collectors.NewClusterHealthCollector(context, clusterName),
 		collectors.NewMonitorCollector(context, clusterName),
 		collectors.NewOSDCollector(context, clusterName),
 		collectors.NewPoolUsageCollector(context, clusterName),
+		collectors.NewVolumeUsageCollector(handler.getVolumeUsage),
 	}
 }
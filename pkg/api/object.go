@@ -241,6 +241,44 @@ func (h *Handler) GetBucket(w http.ResponseWriter, r *http.Request) {
 	FormatJsonResponse(w, user)
 }
 
+// CreateBucket creates a bucket owned by the passed user in the object store in this cluster.
+// POST
+// /objectstore/buckets
+func (h *Handler) CreateBucket(w http.ResponseWriter, r *http.Request) {
+	var bucket model.ObjectBucketRequest
+	if err := json.NewDecoder(r.Body).Decode(&bucket); err != nil {
+		logger.Errorf("Error parsing bucket: %+v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s3Info, found, err := h.config.ClusterHandler.GetObjectStoreConnectionInfo()
+	if err != nil {
+		logger.Errorf("failed get object store info. %+v", err)
+		if found {
+			w.WriteHeader(http.StatusInternalServerError)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+
+	rgwError, err := rgw.CreateBucket(h.context, bucket, s3Info.IPEndpoint, h.config.ClusterHandler.GetClusterInfo)
+	if err != nil {
+		logger.Errorf("Error creating bucket: %+v", err)
+
+		if rgwError == rgw.RGWErrorBadData {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(err.Error()))
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
 // DeleteBucket deletes the bucket in the object store in this cluster.
 // DELETE
 // /objectstore/buckets/{BUCKET_NAME}
@@ -751,6 +751,78 @@ func TestGetBucket(t *testing.T) {
 	assert.Equal(t, "{\"name\":\"test\",\"owner\":\"bill\",\"createdAt\":\"2016-08-05T18:31:22.445343Z\",\"size\":5,\"numberOfObjects\":4}", w.Body.String())
 }
 
+func TestCreateBucket(t *testing.T) {
+	etcdClient := util.NewMockEtcdClient()
+	inventory.SetIPAddress(etcdClient, "123", "1.2.3.4", "2.3.4.5")
+
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+
+	cephtest.CreateClusterInfo(etcdClient, configDir, []string{"mymon"})
+
+	runTest := func(body string, runner func(args ...string) (string, error)) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "http://10.0.0.100/objectstore/buckets", bytes.NewBufferString(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		executor := &testexec.MockExecutor{MockExecuteCommandWithCombinedOutput: func(command string, subcommand string, args ...string) (string, error) { return runner(args...) }}
+		context := &clusterd.Context{
+			DirectContext: clusterd.DirectContext{EtcdClient: etcdClient},
+			ConfigDir:     configDir,
+			ProcMan:       proc.New(executor),
+			Executor:      executor,
+		}
+		w := httptest.NewRecorder()
+		h := newTestHandler(context)
+		r := newRouter(h.GetRoutes())
+
+		r.ServeHTTP(w, req)
+
+		return w
+	}
+	runner := func(owner string) func(args ...string) (string, error) {
+		return func(args ...string) (string, error) {
+			if args[0] == "user" {
+				if args[len(args)-1] != "bill" {
+					return "could not fetch user info: no user info saved", nil
+				}
+				return `{"user_id":"bill","display_name":"bill","keys":[{"access_key":"key","secret_key":"secret"}]}`, nil
+			}
+			return fmt.Sprintf(`{"data":{"owner":"%s","creation_time":"2016-08-05 18:31:22.445343Z"}}`, owner), nil
+		}
+	}
+
+	// the object store is not running
+	w := runTest(`{"name":"test","owner":"bill"}`, runner("bill"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// simulate RGW being installed
+	etcdClient.SetValue("/rook/services/ceph/rgw/applied/node/123", "")
+
+	// bad body
+	w = runTest("{", runner("bill"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// missing owner
+	w = runTest(`{"name":"test"}`, runner("bill"))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "bucket owner cannot be empty", w.Body.String())
+
+	// owner not found
+	w = runTest(`{"name":"test","owner":"ted"}`, runner("bill"))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "owner ted not found", w.Body.String())
+
+	// the bucket is owned by another user
+	w = runTest(`{"name":"test","owner":"bill"}`, runner("ted"))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "bucket already exists", w.Body.String())
+
+	// the bucket already exists with the same owner
+	w = runTest(`{"name":"test","owner":"bill"}`, runner("bill"))
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
 func TestBucketDelete(t *testing.T) {
 	etcdClient := util.NewMockEtcdClient()
 	req, err := http.NewRequest("DELETE", "http://10.0.0.100/objectstore/buckets/test", nil)
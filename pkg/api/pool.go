@@ -19,9 +19,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/gorilla/mux"
 	ceph "github.com/rook/rook/pkg/ceph/client"
 	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/util"
+)
+
+const (
+	// the rules of the pools that are placed under a crush root are named after the root
+	crushRulePrefix = "rook-"
+
+	// the replicas of the pools under a crush root are placed on different hosts
+	crushRuleFailureDomain = "host"
 )
 
 // Gets the storage pools that have been created in this cluster.
@@ -114,6 +126,21 @@ func (h *Handler) CreatePool(w http.ResponseWriter, r *http.Request) {
 
 	newPool := modelPoolToCephPool(newPoolReq)
 
+	if newPoolReq.CrushRoot != "" {
+		if newPoolReq.Type != model.Replicated {
+			logger.Errorf("cannot create pool %s. only the replicas of a replicated pool can be placed under crush root %s", newPoolReq.Name, newPoolReq.CrushRoot)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// the rule is shared by all the pools under the same root
+		if err := ceph.CreateCrushRule(h.context, h.config.ClusterInfo.Name, newPool.CrushRule, newPoolReq.CrushRoot, crushRuleFailureDomain); err != nil {
+			logger.Errorf("failed to create crush rule for pool '%s': %+v", newPoolReq.Name, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
 	if newPoolReq.Type == model.ErasureCoded {
 		// create a new erasure code profile for the new pool
 		if err := ceph.CreateErasureCodeProfile(h.context, h.config.ClusterInfo.Name, newPoolReq.ErasureCodedConfig, newPool.ErasureCodeProfile); err != nil {
@@ -133,6 +160,193 @@ func (h *Handler) CreatePool(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(info))
 }
 
+// Updates the settings of a storage pool. Only the replication size of a replicated pool can be changed.
+// PUT
+// /pool/{name}
+func (h *Handler) UpdatePool(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var updateReq model.Pool
+	body, ok := handleReadBody(w, r, "update pool")
+	if !ok {
+		return
+	}
+	if err := json.Unmarshal(body, &updateReq); err != nil {
+		logger.Errorf("failed to unmarshal update pool request body '%s': %+v", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	pool, found := h.getPoolDetails(w, name)
+	if !found {
+		return
+	}
+
+	if pool.ErasureCodeProfile != "" || updateReq.Type != model.Replicated {
+		// the coding settings of an erasure coded pool are fixed when the pool is created
+		logger.Errorf("cannot update pool %s. only the replication size of a replicated pool can be changed", name)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("only the replication size of a replicated pool can be changed"))
+		return
+	}
+
+	size := updateReq.ReplicationConfig.Size
+	if size == 0 {
+		logger.Errorf("invalid replication size for pool %s", name)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if size != pool.Size {
+		if err := ceph.SetPoolProperty(h.context, h.config.ClusterInfo.Name, name, "size", strconv.FormatUint(uint64(size), 10)); err != nil {
+			logger.Errorf("failed to set the size of pool %s to %d: %+v", name, size, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded updating pool %s", name)))
+}
+
+// Deletes a storage pool. Pools that still contain block images, that store the data of the images in other pools, or
+// that are used by a file system or an object store are not deleted.
+// DELETE
+// /pool/{name}
+func (h *Handler) DeletePool(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	pool, found := h.getPoolDetails(w, name)
+	if !found {
+		return
+	}
+
+	images, err := ceph.ListImages(h.context, h.config.ClusterInfo.Name, name)
+	if err != nil {
+		logger.Errorf("failed to list images in pool %s: %+v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(images) > 0 {
+		logger.Errorf("cannot delete pool %s. it contains %d images", name, len(images))
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(fmt.Sprintf("pool %s contains %d images", name, len(images))))
+		return
+	}
+
+	image, err := h.findImageWithDataPool(name)
+	if err != nil {
+		logger.Errorf("failed to find the images with data in pool %s: %+v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if image != "" {
+		logger.Errorf("cannot delete pool %s. it stores the data of image %s", name, image)
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(fmt.Sprintf("pool %s stores the data of image %s", name, image)))
+		return
+	}
+
+	if objectStorePool(name) {
+		logger.Errorf("cannot delete pool %s. it is used by an object store", name)
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(fmt.Sprintf("pool %s is used by an object store", name)))
+		return
+	}
+
+	filesystems, err := ceph.ListFilesystems(h.context, h.config.ClusterInfo.Name)
+	if err != nil {
+		logger.Errorf("failed to list file systems: %+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for _, fs := range filesystems {
+		if fs.MetadataPool == name || util.CreateSet(fs.DataPools).Contains(name) {
+			logger.Errorf("cannot delete pool %s. it is used by file system %s", name, fs.Name)
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(fmt.Sprintf("pool %s is used by file system %s", name, fs.Name)))
+			return
+		}
+	}
+
+	if err := ceph.DeletePool(h.context, h.config.ClusterInfo.Name, name); err != nil {
+		logger.Errorf("failed to delete pool %s: %+v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if pool.ErasureCodeProfile != "" {
+		// the profile was created for the pool, so it is not needed anymore
+		if err := ceph.DeleteErasureCodeProfile(h.context, h.config.ClusterInfo.Name, pool.ErasureCodeProfile); err != nil {
+			logger.Warningf("failed to delete erasure code profile %s of pool %s: %+v", pool.ErasureCodeProfile, name, err)
+		}
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded deleting pool %s", name)))
+}
+
+// findImageWithDataPool returns the image in another pool whose data is stored in the pool, such as an erasure coded
+// data pool, or an empty string if there is none
+func (h *Handler) findImageWithDataPool(dataPool string) (string, error) {
+	summaries, err := ceph.ListPoolSummaries(h.context, h.config.ClusterInfo.Name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, summary := range summaries {
+		if summary.Name == dataPool {
+			continue
+		}
+		images, err := ceph.ListImages(h.context, h.config.ClusterInfo.Name, summary.Name)
+		if err != nil {
+			return "", err
+		}
+		for _, image := range images {
+			info, err := ceph.GetImageInfo(h.context, h.config.ClusterInfo.Name, image.Name, summary.Name)
+			if err != nil {
+				return "", err
+			}
+			if info.DataPool == dataPool {
+				return fmt.Sprintf("%s/%s", summary.Name, image.Name), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// objectStorePool returns whether the pool is one of the pools that rgw creates for an object store, which are named
+// after the zone such as default.rgw.buckets.data, or the realm pool .rgw.root
+func objectStorePool(name string) bool {
+	return name == ".rgw.root" || strings.Contains(name, ".rgw.")
+}
+
+// getPoolDetails returns the details of the pool. If the pool is not found or the lookup fails, the error status is
+// written to the response.
+func (h *Handler) getPoolDetails(w http.ResponseWriter, name string) (ceph.CephStoragePoolDetails, bool) {
+	summaries, err := ceph.ListPoolSummaries(h.context, h.config.ClusterInfo.Name)
+	if err != nil {
+		logger.Errorf("failed to list pools: %+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return ceph.CephStoragePoolDetails{}, false
+	}
+
+	for _, summary := range summaries {
+		if summary.Name != name {
+			continue
+		}
+		pool, err := ceph.GetPoolDetails(h.context, h.config.ClusterInfo.Name, name)
+		if err != nil {
+			logger.Errorf("%+v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return ceph.CephStoragePoolDetails{}, false
+		}
+		return pool, true
+	}
+
+	logger.Errorf("pool %s not found", name)
+	w.WriteHeader(http.StatusNotFound)
+	return ceph.CephStoragePoolDetails{}, false
+}
+
 func modelPoolToCephPool(modelPool model.Pool) ceph.CephStoragePoolDetails {
 	pool := ceph.CephStoragePoolDetails{
 		Name:   modelPool.Name,
@@ -141,6 +355,9 @@ func modelPoolToCephPool(modelPool model.Pool) ceph.CephStoragePoolDetails {
 
 	if modelPool.Type == model.Replicated {
 		pool.Size = modelPool.ReplicationConfig.Size
+		if modelPool.CrushRoot != "" {
+			pool.CrushRule = crushRulePrefix + modelPool.CrushRoot
+		}
 	} else if modelPool.Type == model.ErasureCoded {
 		pool.ErasureCodeProfile = fmt.Sprintf("%s_ecprofile", pool.Name)
 	}
@@ -167,6 +384,9 @@ func cephPoolToModelPool(cephPool ceph.CephStoragePoolDetails, ecpDetails map[st
 	} else if cephPool.Size > 0 {
 		pool.Type = model.Replicated
 		pool.ReplicationConfig.Size = cephPool.Size
+		if strings.HasPrefix(cephPool.CrushRule, crushRulePrefix) {
+			pool.CrushRoot = strings.TrimPrefix(cephPool.CrushRule, crushRulePrefix)
+		}
 	} else {
 		pool.Type = model.PoolTypeUnknown
 	}
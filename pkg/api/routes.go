@@ -47,6 +47,18 @@ func (h *Handler) GetRoutes() []Route {
 			"/pool",
 			h.CreatePool,
 		},
+		{
+			"UpdatePool",
+			"PUT",
+			"/pool/{name}",
+			h.UpdatePool,
+		},
+		{
+			"DeletePool",
+			"DELETE",
+			"/pool/{name}",
+			h.DeletePool,
+		},
 		{
 			"GetImages",
 			"GET",
@@ -59,18 +71,96 @@ func (h *Handler) GetRoutes() []Route {
 			"/image",
 			h.CreateImage,
 		},
+		{
+			"ResizeImage",
+			"PUT",
+			"/image",
+			h.ResizeImage,
+		},
 		{
 			"DeleteImage",
 			"DELETE",
 			"/image",
 			h.DeleteImage,
 		},
+		{
+			"GetSnapshots",
+			"GET",
+			"/image/{pool}/{name}/snapshots",
+			h.GetSnapshots,
+		},
+		{
+			"CreateSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots",
+			h.CreateSnapshot,
+		},
+		{
+			"DeleteSnapshot",
+			"DELETE",
+			"/image/{pool}/{name}/snapshots/{snapshot}",
+			h.DeleteSnapshot,
+		},
+		{
+			"RollbackSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots/{snapshot}/rollback",
+			h.RollbackSnapshot,
+		},
+		{
+			"ProtectSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots/{snapshot}/protect",
+			h.ProtectSnapshot,
+		},
+		{
+			"UnprotectSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots/{snapshot}/unprotect",
+			h.UnprotectSnapshot,
+		},
+		{
+			"CloneSnapshot",
+			"POST",
+			"/image/{pool}/{name}/snapshots/{snapshot}/clone",
+			h.CloneSnapshot,
+		},
+		{
+			"GetSnapshotChildren",
+			"GET",
+			"/image/{pool}/{name}/snapshots/{snapshot}/children",
+			h.GetSnapshotChildren,
+		},
+		{
+			"FlattenImage",
+			"POST",
+			"/image/{pool}/{name}/flatten",
+			h.FlattenImage,
+		},
+		{
+			"GetVolumeUsage",
+			"GET",
+			"/image/usage",
+			h.GetVolumeUsage,
+		},
 		{
 			"GetClientAccessInfo",
 			"GET",
 			"/client",
 			h.GetClientAccessInfo,
 		},
+		{
+			"CreateClientUser",
+			"POST",
+			"/client/users",
+			h.CreateClientUser,
+		},
+		{
+			"DeleteClientUser",
+			"DELETE",
+			"/client/users/{name}",
+			h.DeleteClientUser,
+		},
 		{
 			"GetMonitors",
 			"GET",
@@ -143,6 +233,12 @@ func (h *Handler) GetRoutes() []Route {
 			"/objectstore/buckets/{bucketName}",
 			h.GetBucket,
 		},
+		{
+			"CreateBucket",
+			"POST",
+			"/objectstore/buckets",
+			h.CreateBucket,
+		},
 		{
 			"DeleteBucket",
 			"DELETE",
@@ -167,6 +263,18 @@ func (h *Handler) GetRoutes() []Route {
 			"/filesystem",
 			h.RemoveFileSystem,
 		},
+		{
+			"CreateFilesystemVolume",
+			"POST",
+			"/filesystem/{name}/volumes",
+			h.CreateFilesystemVolume,
+		},
+		{
+			"DeleteFilesystemVolume",
+			"DELETE",
+			"/filesystem/{name}/volumes/{volume}",
+			h.DeleteFilesystemVolume,
+		},
 		{
 			"SetLogLevel",
 			"POST",
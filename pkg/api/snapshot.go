@@ -0,0 +1,191 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	ceph "github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+)
+
+// Gets the snapshots of a block image.
+// GET
+// /image/{pool}/{name}/snapshots
+func (h *Handler) GetSnapshots(w http.ResponseWriter, r *http.Request) {
+	pool := mux.Vars(r)["pool"]
+	image := mux.Vars(r)["name"]
+
+	cephSnapshots, err := ceph.ListSnapshots(h.context, h.config.ClusterInfo.Name, image, pool)
+	if err != nil {
+		logger.Errorf("failed to get snapshots of image %s in pool %s: %+v", image, pool, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	snapshots := make([]model.BlockSnapshot, len(cephSnapshots))
+	for i, s := range cephSnapshots {
+		snapshots[i] = model.BlockSnapshot{
+			Name:      s.Name,
+			ImageName: image,
+			PoolName:  pool,
+			Size:      s.Size,
+			Timestamp: s.Timestamp,
+		}
+	}
+
+	FormatJsonResponse(w, snapshots)
+}
+
+// Creates a snapshot of a block image. The body is the snapshot with its name.
+// POST
+// /image/{pool}/{name}/snapshots
+func (h *Handler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	var snapshot model.BlockSnapshot
+	body, ok := handleReadBody(w, r, "create snapshot")
+	if !ok {
+		return
+	}
+
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		logger.Errorf("failed to unmarshal create snapshot request body '%s': %+v", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	h.runSnapshotAction(w, r, snapshot.Name, "creating", ceph.CreateSnapshot)
+}
+
+// Deletes a snapshot of a block image. A protected snapshot must be unprotected first.
+// DELETE
+// /image/{pool}/{name}/snapshots/{snapshot}
+func (h *Handler) DeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.runSnapshotAction(w, r, mux.Vars(r)["snapshot"], "deleting", ceph.DeleteSnapshot)
+}
+
+// Rolls back a block image to a snapshot.
+// POST
+// /image/{pool}/{name}/snapshots/{snapshot}/rollback
+func (h *Handler) RollbackSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.runSnapshotAction(w, r, mux.Vars(r)["snapshot"], "rolling back to", ceph.RollbackSnapshot)
+}
+
+// Protects a snapshot of a block image from deletion.
+// POST
+// /image/{pool}/{name}/snapshots/{snapshot}/protect
+func (h *Handler) ProtectSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.runSnapshotAction(w, r, mux.Vars(r)["snapshot"], "protecting", ceph.ProtectSnapshot)
+}
+
+// Unprotects a snapshot of a block image.
+// POST
+// /image/{pool}/{name}/snapshots/{snapshot}/unprotect
+func (h *Handler) UnprotectSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.runSnapshotAction(w, r, mux.Vars(r)["snapshot"], "unprotecting", ceph.UnprotectSnapshot)
+}
+
+// Clones a snapshot of a block image to a new image. The body is the new image. The clone is created in the pool of
+// the snapshot if the pool is not specified. The snapshot must be protected.
+// POST
+// /image/{pool}/{name}/snapshots/{snapshot}/clone
+func (h *Handler) CloneSnapshot(w http.ResponseWriter, r *http.Request) {
+	pool := mux.Vars(r)["pool"]
+	image := mux.Vars(r)["name"]
+	snapshot := mux.Vars(r)["snapshot"]
+
+	var clone model.BlockImage
+	body, ok := handleReadBody(w, r, "clone snapshot")
+	if !ok {
+		return
+	}
+
+	if err := json.Unmarshal(body, &clone); err != nil {
+		logger.Errorf("failed to unmarshal clone snapshot request body '%s': %+v", string(body), err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if clone.Name == "" {
+		logger.Errorf("clone missing required fields: %+v", clone)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if clone.PoolName == "" {
+		clone.PoolName = pool
+	}
+
+	options := ceph.ImageOptions{
+		Features:    clone.Features,
+		StripeUnit:  clone.StripeUnit,
+		StripeCount: clone.StripeCount,
+		DataPool:    clone.DataPoolName,
+	}
+	err := ceph.CloneSnapshot(h.context, h.config.ClusterInfo.Name, snapshot, image, pool, clone.Name, clone.PoolName, options)
+	if err != nil {
+		logger.Errorf("failed to clone snapshot %s of image %s in pool %s to %+v: %+v", snapshot, image, pool, clone, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded cloning snapshot %s to image %s", snapshot, clone.Name)))
+}
+
+// Gets the images that were cloned from a snapshot of a block image.
+// GET
+// /image/{pool}/{name}/snapshots/{snapshot}/children
+func (h *Handler) GetSnapshotChildren(w http.ResponseWriter, r *http.Request) {
+	pool := mux.Vars(r)["pool"]
+	image := mux.Vars(r)["name"]
+	snapshot := mux.Vars(r)["snapshot"]
+
+	cephChildren, err := ceph.ListChildren(h.context, h.config.ClusterInfo.Name, snapshot, image, pool)
+	if err != nil {
+		logger.Errorf("failed to get children of snapshot %s of image %s in pool %s: %+v", snapshot, image, pool, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	children := make([]model.BlockImage, len(cephChildren))
+	for i, child := range cephChildren {
+		children[i] = model.BlockImage{Name: child.Name, PoolName: child.PoolName}
+	}
+
+	FormatJsonResponse(w, children)
+}
+
+type snapshotAction func(context *clusterd.Context, clusterName, name, imageName, poolName string) error
+
+func (h *Handler) runSnapshotAction(w http.ResponseWriter, r *http.Request, snapshot, verb string, action snapshotAction) {
+	pool := mux.Vars(r)["pool"]
+	image := mux.Vars(r)["name"]
+	if snapshot == "" || image == "" || pool == "" {
+		logger.Errorf("snapshot missing required fields. snapshot=%s, image=%s, pool=%s", snapshot, image, pool)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := action(h.context, h.config.ClusterInfo.Name, snapshot, image, pool); err != nil {
+		logger.Errorf("failed %s snapshot %s of image %s in pool %s: %+v", verb, snapshot, image, pool, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write([]byte(fmt.Sprintf("succeeded %s snapshot %s", verb, snapshot)))
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotHandlers(t *testing.T) {
+	context, _, executor := testContext()
+	defer os.RemoveAll(context.ConfigDir)
+
+	var snapArgs []string
+	executor.MockExecuteCommandWithOutput = func(actionName string, command string, args ...string) (string, error) {
+		if command != "rbd" || args[0] != "snap" {
+			return "", fmt.Errorf("unexpected ceph command '%v'", args)
+		}
+		snapArgs = args[0:3]
+		switch {
+		case args[1] == "ls":
+			return `[{"id":3,"name":"snap1","size":1048576,"timestamp":"Tue Oct 17 10:00:00 2017"}]`, nil
+		case args[2] == "myPool1/myImage1@busy":
+			return "", fmt.Errorf("mock failure")
+		}
+		return "", nil
+	}
+	runTest := func(method, url, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h := newTestHandler(context)
+		r := newRouter(h.GetRoutes())
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// list the snapshots
+	w := runTest("GET", "http://10.0.0.100/image/myPool1/myImage1/snapshots", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"name":"snap1","imageName":"myImage1","poolName":"myPool1","size":1048576,"timestamp":"Tue Oct 17 10:00:00 2017"}]`,
+		w.Body.String())
+	assert.Equal(t, []string{"snap", "ls", "myPool1/myImage1"}, snapArgs)
+
+	// create a snapshot
+	w = runTest("POST", "http://10.0.0.100/image/myPool1/myImage1/snapshots", `{"name":"snap2"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "succeeded creating snapshot snap2", w.Body.String())
+	assert.Equal(t, []string{"snap", "create", "myPool1/myImage1@snap2"}, snapArgs)
+
+	// the snapshot name is required
+	snapArgs = nil
+	w = runTest("POST", "http://10.0.0.100/image/myPool1/myImage1/snapshots", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, snapArgs)
+
+	// rollback, protect, unprotect and delete the snapshot
+	w = runTest("POST", "http://10.0.0.100/image/myPool1/myImage1/snapshots/snap2/rollback", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"snap", "rollback", "myPool1/myImage1@snap2"}, snapArgs)
+	w = runTest("POST", "http://10.0.0.100/image/myPool1/myImage1/snapshots/snap2/protect", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"snap", "protect", "myPool1/myImage1@snap2"}, snapArgs)
+	w = runTest("POST", "http://10.0.0.100/image/myPool1/myImage1/snapshots/snap2/unprotect", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"snap", "unprotect", "myPool1/myImage1@snap2"}, snapArgs)
+	w = runTest("DELETE", "http://10.0.0.100/image/myPool1/myImage1/snapshots/snap2", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "succeeded deleting snapshot snap2", w.Body.String())
+	assert.Equal(t, []string{"snap", "rm", "myPool1/myImage1@snap2"}, snapArgs)
+
+	// failure from rbd
+	w = runTest("DELETE", "http://10.0.0.100/image/myPool1/myImage1/snapshots/busy", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestCloneHandlers(t *testing.T) {
+	context, _, executor := testContext()
+	defer os.RemoveAll(context.ConfigDir)
+
+	var rbdArgs []string
+	executor.MockExecuteCommandWithOutput = func(actionName string, command string, args ...string) (string, error) {
+		rbdArgs = args
+		switch {
+		case command == "rbd" && args[0] == "clone":
+			return "", nil
+		case command == "rbd" && args[0] == "children":
+			return `["myPool1/myClone1"]`, nil
+		case command == "rbd" && args[0] == "flatten":
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	runTest := func(method, url, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			logger.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h := newTestHandler(context)
+		r := newRouter(h.GetRoutes())
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// the clone name is required
+	w := runTest("POST", "http://10.0.0.100/image/myPool1/myImage1/snapshots/snap1/clone", `{"poolName":"myPool2"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// the clone is created in the pool of the snapshot by default
+	w = runTest("POST", "http://10.0.0.100/image/myPool1/myImage1/snapshots/snap1/clone", `{"imageName":"myClone1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "succeeded cloning snapshot snap1 to image myClone1", w.Body.String())
+	assert.Equal(t, []string{"clone", "myPool1/myImage1@snap1", "myPool1/myClone1"}, rbdArgs[0:3])
+
+	w = runTest("POST", "http://10.0.0.100/image/myPool1/myImage1/snapshots/snap1/clone", `{"imageName":"myClone2","poolName":"myPool2"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"clone", "myPool1/myImage1@snap1", "myPool2/myClone2"}, rbdArgs[0:3])
+
+	w = runTest("GET", "http://10.0.0.100/image/myPool1/myImage1/snapshots/snap1/children", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"imageName":"myClone1","poolName":"myPool1","size":0,"device":"","mountPoint":""}]`, w.Body.String())
+
+	w = runTest("POST", "http://10.0.0.100/image/myPool1/myClone1/flatten", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "succeeded flattening image myClone1", w.Body.String())
+	assert.Equal(t, []string{"flatten", "myPool1/myClone1"}, rbdArgs[0:2])
+}
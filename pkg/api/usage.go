@@ -0,0 +1,125 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	ceph "github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+)
+
+// the prefix of the images that are dynamically provisioned for persistent volumes
+const dynamicImagePrefix = "k8s-dynamic-"
+
+// Gets the space provisioned and used by the dynamically provisioned volumes, by claim, namespace and storage class.
+// GET
+// /image/usage
+func (h *Handler) GetVolumeUsage(w http.ResponseWriter, r *http.Request) {
+	report, err := h.getVolumeUsage()
+	if err != nil {
+		logger.Errorf("failed to get volume usage: %+v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	FormatJsonResponse(w, report)
+}
+
+func (h *Handler) getVolumeUsage() (*model.VolumeUsageReport, error) {
+	volumes, err := h.config.ClusterHandler.GetVolumes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volumes: %+v", err)
+	}
+	return getVolumeUsage(h.context, h.config.ClusterInfo.Name, volumes)
+}
+
+// getVolumeUsage joins the usage of the images in all pools with the volumes they were provisioned for. Dynamically
+// provisioned images without a volume are reported without a claim so that abandoned images can be found.
+func getVolumeUsage(context *clusterd.Context, clusterName string, volumes []model.ProvisionedVolume) (*model.VolumeUsageReport, error) {
+	pools, err := ceph.ListPoolSummaries(context, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pools: %+v", err)
+	}
+
+	provisioned := map[string]model.ProvisionedVolume{}
+	for _, v := range volumes {
+		provisioned[v.PoolName+"/"+v.ImageName] = v
+	}
+
+	report := &model.VolumeUsageReport{Volumes: []model.VolumeUsage{}}
+	for _, p := range pools {
+		images, err := ceph.GetImageUsage(context, clusterName, p.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, image := range images {
+			volume, ok := provisioned[p.Name+"/"+image.Name]
+			if !ok {
+				if !strings.HasPrefix(image.Name, dynamicImagePrefix) {
+					// the image was not provisioned for a volume
+					continue
+				}
+				volume = model.ProvisionedVolume{ImageName: image.Name, PoolName: p.Name}
+			}
+			report.Volumes = append(report.Volumes, model.VolumeUsage{
+				ProvisionedVolume: volume,
+				ProvisionedBytes:  image.ProvisionedSize,
+				UsedBytes:         image.UsedSize,
+			})
+		}
+	}
+
+	report.Namespaces = summarizeUsage(report.Volumes, func(v model.VolumeUsage) string { return v.ClaimNamespace })
+	report.StorageClasses = summarizeUsage(report.Volumes, func(v model.VolumeUsage) string { return v.StorageClass })
+	return report, nil
+}
+
+// summarizeUsage adds up the usage of the volumes by the key, sorted by the key. Volumes without a key are skipped.
+func summarizeUsage(volumes []model.VolumeUsage, key func(model.VolumeUsage) string) []model.VolumeUsageSummary {
+	summaries := map[string]*model.VolumeUsageSummary{}
+	for _, v := range volumes {
+		name := key(v)
+		if name == "" {
+			continue
+		}
+		s, ok := summaries[name]
+		if !ok {
+			s = &model.VolumeUsageSummary{Name: name}
+			summaries[name] = s
+		}
+		s.Volumes++
+		s.ProvisionedBytes += v.ProvisionedBytes
+		s.UsedBytes += v.UsedBytes
+	}
+
+	names := []string{}
+	for name := range summaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := []model.VolumeUsageSummary{}
+	for _, name := range names {
+		result = append(result, *summaries[name])
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/rook/rook/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetVolumeUsage(t *testing.T) {
+	context, _, executor := testContext()
+	defer os.RemoveAll(context.ConfigDir)
+
+	executor.MockExecuteCommandWithOutputFile = func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+		if args[0] == "osd" && args[1] == "lspools" {
+			return `[{"poolnum":0,"poolname":"pool1"},{"poolnum":1,"poolname":"pool2"}]`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	executor.MockExecuteCommandWithOutput = func(actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "du" && args[2] == "pool1":
+			return `{"images":[{"name":"k8s-dynamic-pv1-1","provisioned_size":100,"used_size":10},` +
+				`{"name":"k8s-dynamic-pv2-2","provisioned_size":200,"used_size":20},` +
+				`{"name":"image1","provisioned_size":300,"used_size":30}]}`, nil
+		case command == "rbd" && args[0] == "du" && args[2] == "pool2":
+			return `{"images":[{"name":"k8s-dynamic-pv3-3","provisioned_size":400,"used_size":40},` +
+				`{"name":"k8s-dynamic-pv4-4","provisioned_size":500,"used_size":50}]}`, nil
+		}
+		return "", fmt.Errorf("unexpected rbd command '%v'", args)
+	}
+
+	volumes := []model.ProvisionedVolume{
+		{Name: "pv1", Phase: "Bound", ImageName: "k8s-dynamic-pv1-1", PoolName: "pool1", ClaimName: "claim1", ClaimNamespace: "app1", StorageClass: "block"},
+		{Name: "pv2", Phase: "Bound", ImageName: "k8s-dynamic-pv2-2", PoolName: "pool1", ClaimName: "claim2", ClaimNamespace: "app2", StorageClass: "block"},
+		{Name: "pv3", Phase: "Released", ImageName: "k8s-dynamic-pv3-3", PoolName: "pool2", ClaimName: "claim3", ClaimNamespace: "app1", StorageClass: "fast"},
+	}
+	report, err := getVolumeUsage(context, "mycluster", volumes)
+	assert.Nil(t, err)
+
+	// the images that were not provisioned for a volume are skipped, and the images without a volume are abandoned
+	assert.Equal(t, 4, len(report.Volumes))
+	assert.Equal(t, model.VolumeUsage{ProvisionedVolume: volumes[0], ProvisionedBytes: 100, UsedBytes: 10}, report.Volumes[0])
+	assert.Equal(t, model.VolumeUsage{ProvisionedVolume: volumes[2], ProvisionedBytes: 400, UsedBytes: 40}, report.Volumes[2])
+	assert.Equal(t, model.VolumeUsage{ProvisionedVolume: model.ProvisionedVolume{ImageName: "k8s-dynamic-pv4-4", PoolName: "pool2"},
+		ProvisionedBytes: 500, UsedBytes: 50}, report.Volumes[3])
+
+	assert.Equal(t, []model.VolumeUsageSummary{
+		{Name: "app1", Volumes: 2, ProvisionedBytes: 500, UsedBytes: 50},
+		{Name: "app2", Volumes: 1, ProvisionedBytes: 200, UsedBytes: 20},
+	}, report.Namespaces)
+	assert.Equal(t, []model.VolumeUsageSummary{
+		{Name: "block", Volumes: 2, ProvisionedBytes: 300, UsedBytes: 30},
+		{Name: "fast", Volumes: 1, ProvisionedBytes: 400, UsedBytes: 40},
+	}, report.StorageClasses)
+
+	// there are no volumes outside of kubernetes so all dynamic images are abandoned
+	req, err := http.NewRequest("GET", "http://10.0.0.100/image/usage", nil)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h := newTestHandler(context)
+	r := newRouter(h.GetRoutes())
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"namespaces":[],"storageClasses":[]`)
+	assert.Contains(t, w.Body.String(), `{"name":"","phase":"","imageName":"k8s-dynamic-pv1-1","poolName":"pool1",`)
+
+	// the usage cannot be reported if an image pool cannot be read
+	executor.MockExecuteCommandWithOutput = func(actionName string, command string, args ...string) (string, error) {
+		return "", fmt.Errorf("mock failure")
+	}
+	_, err = getVolumeUsage(context, "mycluster", volumes)
+	assert.NotNil(t, err)
+}
@@ -38,7 +38,7 @@ func NewCephService(devices, metadataDevice, directories string, forceFormat boo
 		Agents: []clusterd.ServiceAgent{
 			mon.NewAgent(),
 			mgr.NewAgent(),
-			osd.NewAgent(devices, false, metadataDevice, directories, forceFormat, location, storeConfig, nil, nodeName),
+			osd.NewAgent(devices, false, metadataDevice, directories, forceFormat, location, storeConfig, 0, nil, nodeName),
 			mds.NewAgent(),
 			rgw.NewAgent(),
 		},
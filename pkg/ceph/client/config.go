@@ -0,0 +1,83 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+// the daemons report the settings that they do not apply at runtime with one of these messages
+var injectArgsRestartMessages = []string{"not observed", "may require restart", "unchangeable"}
+
+// ListConfigOptions returns the names of the settings known to the running mons. The settings are listed with
+// ceph config ls, which requires ceph mimic or newer. Returns false if the mons cannot list the settings.
+func ListConfigOptions(context *clusterd.Context, clusterName string) (map[string]bool, bool, error) {
+	args := []string{"config", "ls"}
+	buf, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		if strings.Contains(string(buf), "EINVAL") {
+			// the mons before mimic do not know the command
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to list the config settings. %+v", err)
+	}
+
+	var names []string
+	if err := json.Unmarshal(buf, &names); err != nil {
+		return nil, false, fmt.Errorf("unmarshal failed: %+v.  raw buffer response: %s", err, string(buf))
+	}
+	options := map[string]bool{}
+	for _, name := range names {
+		options[name] = true
+	}
+	return options, true, nil
+}
+
+// InjectArgs applies the settings to the running daemons that match the target, such as osd.* or mon.a. The keys
+// are the names of the settings in the ceph config. Returns false if any of the daemons reported that a setting only
+// takes effect after the daemon is restarted.
+func InjectArgs(context *clusterd.Context, clusterName, target string, settings map[string]string) (bool, error) {
+	keys := []string{}
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	injected := []string{}
+	for _, key := range keys {
+		injected = append(injected, fmt.Sprintf("--%s=%s", strings.Replace(key, " ", "_", -1), settings[key]))
+	}
+
+	args := []string{"tell", target, "injectargs", strings.Join(injected, " ")}
+	buf, err := ExecuteCephCommandPlain(context, clusterName, args)
+	if err != nil {
+		return false, fmt.Errorf("failed to inject args into %s. %+v", target, err)
+	}
+
+	output := string(buf)
+	for _, message := range injectArgsRestartMessages {
+		if strings.Contains(output, message) {
+			logger.Infof("settings of %s require a restart. %s", target, output)
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInjectArgs(t *testing.T) {
+	output := ""
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			assert.Equal(t, []string{"tell", "osd.*", "injectargs", "--debug_osd=0 --osd_max_backfills=2"}, args[0:4])
+			return output, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+	settings := map[string]string{"osd max backfills": "2", "debug_osd": "0"}
+
+	// the settings were applied at runtime
+	output = "osd.0: osd_max_backfills = '2' debug_osd = '0/0'"
+	live, err := InjectArgs(context, "mycluster", "osd.*", settings)
+	assert.Nil(t, err)
+	assert.True(t, live)
+
+	// a setting only takes effect after a restart
+	output = "osd.0: osd_max_backfills = '2' (not observed, change may require restart) debug_osd = '0/0'"
+	live, err = InjectArgs(context, "mycluster", "osd.*", settings)
+	assert.Nil(t, err)
+	assert.False(t, live)
+
+	executor.MockExecuteCommandWithOutputFile = func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+		return "", fmt.Errorf("mock failure")
+	}
+	_, err = InjectArgs(context, "mycluster", "osd.*", settings)
+	assert.NotNil(t, err)
+}
+
+func TestListConfigOptions(t *testing.T) {
+	output := ""
+	var outputErr error
+	calls := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			calls++
+			assert.Equal(t, []string{"config", "ls"}, args[0:2])
+			return output, outputErr
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	output = `["osd_max_backfills","osd_objectstore"]`
+	options, listed, err := ListConfigOptions(context, "mycluster")
+	assert.Nil(t, err)
+	assert.True(t, listed)
+	assert.Equal(t, map[string]bool{"osd_max_backfills": true, "osd_objectstore": true}, options)
+	assert.Equal(t, 1, calls)
+
+	// the mons before mimic cannot list the settings
+	output = "Error EINVAL: invalid command"
+	outputErr = fmt.Errorf("exit status 22")
+	options, listed, err = ListConfigOptions(context, "mycluster")
+	assert.Nil(t, err)
+	assert.False(t, listed)
+	assert.Nil(t, options)
+
+	// the settings cannot be listed while ceph is not available
+	output = ""
+	outputErr = fmt.Errorf("mock failure")
+	_, _, err = ListConfigOptions(context, "mycluster")
+	assert.NotNil(t, err)
+}
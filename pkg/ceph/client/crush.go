@@ -45,8 +45,9 @@ type 5 pdu
 type 6 pod
 type 7 room
 type 8 datacenter
-type 9 region
-type 10 root
+type 9 zone
+type 10 region
+type 11 root
 
 # default bucket
 root default {
@@ -89,6 +90,18 @@ func SetCrushMap(context *clusterd.Context, clusterName, compiledMap string) (st
 	return string(buf), nil
 }
 
+// CreateCrushRule creates a replicated rule that places the replicas in different buckets of the failure domain type
+// under the root bucket. The rule is not changed if it already exists.
+func CreateCrushRule(context *clusterd.Context, clusterName, name, root, failureDomain string) error {
+	args := []string{"osd", "crush", "rule", "create-simple", name, root, failureDomain}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to create crush rule %s under %s. %+v", name, root, err)
+	}
+
+	return nil
+}
+
 func SetCrushTunables(context *clusterd.Context, clusterName, profile string) (string, error) {
 	args := []string{"osd", "crush", "tunables", profile}
 	buf, err := ExecuteCephCommandPlain(context, clusterName, args)
@@ -59,3 +59,17 @@ func TestCrushLocation(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "is not in a valid format")
 }
+
+func TestCreateCrushRule(t *testing.T) {
+	var ruleArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			ruleArgs = args[0:7]
+			return "", nil
+		},
+	}
+
+	err := CreateCrushRule(&clusterd.Context{Executor: executor}, "rook", "rook-zone1", "zone1", "host")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"osd", "crush", "rule", "create-simple", "rook-zone1", "zone1", "host"}, ruleArgs)
+}
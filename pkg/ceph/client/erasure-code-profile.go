@@ -87,3 +87,13 @@ func CreateErasureCodeProfile(context *clusterd.Context, clusterName string, con
 
 	return nil
 }
+
+func DeleteErasureCodeProfile(context *clusterd.Context, clusterName, name string) error {
+	args := []string{"osd", "erasure-code-profile", "rm", name}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to delete ec-profile %s. %+v", name, err)
+	}
+
+	return nil
+}
@@ -125,3 +125,33 @@ func RemoveFilesystem(context *clusterd.Context, clusterName string, fsName stri
 	}
 	return nil
 }
+
+// AddDataPool adds an existing pool to the data pools of the file system
+func AddDataPool(context *clusterd.Context, clusterName, fsName, poolName string) error {
+	args := []string{"fs", "add_data_pool", fsName, poolName}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to add pool %s to file system %s. %+v", poolName, fsName, err)
+	}
+	return nil
+}
+
+// SetMaxMDS sets the number of active mds ranks of the file system
+func SetMaxMDS(context *clusterd.Context, clusterName, fsName string, maxMDS int) error {
+	args := []string{"fs", "set", fsName, "max_mds", strconv.Itoa(maxMDS)}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to set max_mds of file system %s to %d. %+v", fsName, maxMDS, err)
+	}
+	return nil
+}
+
+// DeactivateMDS stops an active rank of the file system. The rank must be above max_mds.
+func DeactivateMDS(context *clusterd.Context, clusterName, fsName string, rank int) error {
+	args := []string{"mds", "deactivate", fmt.Sprintf("%s:%d", fsName, rank)}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate rank %d of file system %s. %+v", rank, fsName, err)
+	}
+	return nil
+}
@@ -28,10 +28,25 @@ const (
 	ImageMinSize = uint64(1048576) // 1 MB
 )
 
+// ImageOptions are the optional settings of a new image. The rbd defaults are used for the settings that are not set.
+type ImageOptions struct {
+	// The image format, 1 or 2
+	Format int
+	// The image features such as layering, exclusive-lock, object-map and fast-diff
+	Features []string
+	// The size in bytes of the stripe unit and the number of objects in a stripe
+	StripeUnit  uint64
+	StripeCount uint64
+	// The pool that stores the image data, for example an erasure coded pool. The metadata is stored in the image pool.
+	DataPool string
+}
+
 type CephBlockImage struct {
 	Name   string `json:"image"`
 	Size   uint64 `json:"size"`
 	Format int    `json:"format"`
+	// The snapshot name is only set on the entries of the image snapshots in the long listing
+	Snapshot string `json:"snapshot"`
 }
 
 func ListImages(context *clusterd.Context, clusterName, poolName string) ([]CephBlockImage, error) {
@@ -41,16 +56,95 @@ func ListImages(context *clusterd.Context, clusterName, poolName string) ([]Ceph
 		return nil, fmt.Errorf("failed to list images for pool %s: %+v", poolName, err)
 	}
 
-	var images []CephBlockImage
-	err = json.Unmarshal(buf, &images)
+	var entries []CephBlockImage
+	err = json.Unmarshal(buf, &entries)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal failed: %+v.  raw buffer response: %s", err, string(buf))
 	}
 
+	// the snapshots of the images are listed after each image and are not returned
+	images := []CephBlockImage{}
+	for _, entry := range entries {
+		if entry.Snapshot == "" {
+			images = append(images, entry)
+		}
+	}
+
+	return images, nil
+}
+
+// CephBlockImageInfo is the detail of an image as reported by rbd info
+type CephBlockImageInfo struct {
+	Name string `json:"name"`
+	Size uint64 `json:"size"`
+	// The pool that stores the image data if it is not stored in the image pool
+	DataPool string `json:"data_pool"`
+}
+
+// GetImageInfo returns the detail of an image
+func GetImageInfo(context *clusterd.Context, clusterName, name, poolName string) (*CephBlockImageInfo, error) {
+	args := []string{"info", getImageSpec(name, poolName)}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of image %s in pool %s: %+v", name, poolName, err)
+	}
+
+	var info CephBlockImageInfo
+	if err := json.Unmarshal(buf, &info); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v.  raw buffer response: %s", err, string(buf))
+	}
+	return &info, nil
+}
+
+// CephBlockImageUsage is the space of an image as reported by rbd du
+type CephBlockImageUsage struct {
+	Name            string `json:"name"`
+	ProvisionedSize uint64 `json:"provisioned_size"`
+	UsedSize        uint64 `json:"used_size"`
+}
+
+type cephBlockImageUsageEntry struct {
+	CephBlockImageUsage
+	// The snapshot name is only set on the entries of the image snapshots
+	Snapshot string `json:"snapshot"`
+}
+
+// GetImageUsage returns the provisioned and used space of the images in a pool. The space used by the snapshots of an
+// image is added to the space used by the image. The usage is only accurate and fast when the fast-diff feature is
+// enabled on the images, otherwise rbd scans the objects of each image.
+func GetImageUsage(context *clusterd.Context, clusterName, poolName string) ([]CephBlockImageUsage, error) {
+	args := []string{"du", "-p", poolName}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the usage of the images in pool %s: %+v", poolName, err)
+	}
+
+	var result struct {
+		Images []cephBlockImageUsageEntry `json:"images"`
+	}
+	if err := json.Unmarshal(buf, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v.  raw buffer response: %s", err, string(buf))
+	}
+
+	images := []CephBlockImageUsage{}
+	index := map[string]int{}
+	for _, entry := range result.Images {
+		i, ok := index[entry.Name]
+		if !ok {
+			index[entry.Name] = len(images)
+			images = append(images, CephBlockImageUsage{Name: entry.Name})
+			i = len(images) - 1
+		}
+		if entry.Snapshot == "" {
+			images[i].ProvisionedSize = entry.ProvisionedSize
+		}
+		images[i].UsedSize += entry.UsedSize
+	}
+
 	return images, nil
 }
 
-func CreateImage(context *clusterd.Context, clusterName, name, poolName string, size uint64) (*CephBlockImage, error) {
+func CreateImage(context *clusterd.Context, clusterName, name, poolName string, size uint64, options ImageOptions) (*CephBlockImage, error) {
 	if size > 0 && size < ImageMinSize {
 		// rbd tool uses MB as the smallest unit for size input.  0 is OK but anything else smaller
 		// than 1 MB should just be rounded up to 1 MB.
@@ -62,6 +156,7 @@ func CreateImage(context *clusterd.Context, clusterName, name, poolName string,
 	imageSpec := getImageSpec(name, poolName)
 
 	args := []string{"create", imageSpec, "--size", strconv.Itoa(sizeMB)}
+	args = append(args, options.args()...)
 	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create image %s in pool %s of size %d: %+v. output: %s",
@@ -82,6 +177,50 @@ func CreateImage(context *clusterd.Context, clusterName, name, poolName string,
 	return nil, fmt.Errorf("failed to find image %s after creating it", name)
 }
 
+// args returns the rbd create args for the options
+func (o ImageOptions) args() []string {
+	var args []string
+	if o.Format != 0 {
+		args = append(args, "--image-format", strconv.Itoa(o.Format))
+	}
+	for _, feature := range o.Features {
+		args = append(args, "--image-feature", feature)
+	}
+	if o.StripeUnit != 0 {
+		args = append(args, "--stripe-unit", strconv.FormatUint(o.StripeUnit, 10))
+	}
+	if o.StripeCount != 0 {
+		args = append(args, "--stripe-count", strconv.FormatUint(o.StripeCount, 10))
+	}
+	if o.DataPool != "" {
+		args = append(args, "--data-pool", o.DataPool)
+	}
+	return args
+}
+
+// ResizeImage sets the size of the image. rbd rejects a size that is smaller than the image unless allowShrink is set.
+func ResizeImage(context *clusterd.Context, clusterName, name, poolName string, size uint64, allowShrink bool) error {
+	if size < ImageMinSize {
+		logger.Warningf("requested image size %d is less than the minimum size of %d, using the minimum.", size, ImageMinSize)
+		size = ImageMinSize
+	}
+
+	sizeMB := int(size / 1024 / 1024)
+	imageSpec := getImageSpec(name, poolName)
+
+	args := []string{"resize", imageSpec, "--size", strconv.Itoa(sizeMB)}
+	if allowShrink {
+		args = append(args, "--allow-shrink")
+	}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to resize image %s in pool %s to size %d: %+v. output: %s",
+			name, poolName, size, err, string(buf))
+	}
+
+	return nil
+}
+
 func DeleteImage(context *clusterd.Context, clusterName, name, poolName string) error {
 	imageSpec := getImageSpec(name, poolName)
 	args := []string{"rm", imageSpec}
@@ -94,6 +233,20 @@ func DeleteImage(context *clusterd.Context, clusterName, name, poolName string)
 	return nil
 }
 
+// FlattenImage copies the data of the parent snapshot into a cloned image so that the clone no longer depends on
+// its parent
+func FlattenImage(context *clusterd.Context, clusterName, name, poolName string) error {
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"flatten", imageSpec}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to flatten image %s in pool %s: %+v. output: %s",
+			name, poolName, err, string(buf))
+	}
+
+	return nil
+}
+
 func getImageSpec(name, poolName string) string {
 	return fmt.Sprintf("%s/%s", poolName, name)
 }
@@ -39,7 +39,7 @@ func TestCreateImage(t *testing.T) {
 		}
 		return "", fmt.Errorf("unexpected ceph command '%v'", args)
 	}
-	image, err := CreateImage(context, "foocluster", "image1", "pool1", uint64(1048576)) // 1MB
+	image, err := CreateImage(context, "foocluster", "image1", "pool1", uint64(1048576), ImageOptions{}) // 1MB
 	assert.NotNil(t, err)
 	assert.True(t, strings.Contains(err.Error(), "mocked detailed ceph error output stream"))
 
@@ -61,7 +61,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 0 byte --> 0 MB
 	expectedSizeArg = "0"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", uint64(0))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", uint64(0), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -69,7 +69,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 1 byte --> 1 MB
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", uint64(1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", uint64(1), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -77,7 +77,7 @@ func TestCreateImage(t *testing.T) {
 
 	// (1 MB - 1 byte) --> 1 MB
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", uint64(1048575))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", uint64(1048575), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -85,9 +85,73 @@ func TestCreateImage(t *testing.T) {
 
 	// 1 MB
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", uint64(1048576))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", uint64(1048576), ImageOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
 	createCalled = false
 }
+
+func TestCreateImageOptions(t *testing.T) {
+	var createArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(actionName string, command string, args ...string) (string, error) {
+			switch {
+			case command == "rbd" && args[0] == "create":
+				createArgs = args
+				return "", nil
+			case command == "rbd" && args[0] == "ls" && args[1] == "-l":
+				return `[{"image":"image1","size":1048576,"format":2}]`, nil
+			}
+			return "", fmt.Errorf("unexpected ceph command '%v'", args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	options := ImageOptions{
+		Format:      2,
+		Features:    []string{"layering", "exclusive-lock"},
+		StripeUnit:  65536,
+		StripeCount: 16,
+		DataPool:    "ecpool",
+	}
+	_, err := CreateImage(context, "foocluster", "image1", "pool1", uint64(1048576), options)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"create", "pool1/image1", "--size", "1", "--image-format", "2", "--image-feature", "layering",
+		"--image-feature", "exclusive-lock", "--stripe-unit", "65536", "--stripe-count", "16", "--data-pool", "ecpool"}, createArgs[0:16])
+}
+
+func TestListImagesSkipsSnapshots(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(actionName string, command string, args ...string) (string, error) {
+			return `[{"image":"image1","size":1048576,"format":2},` +
+				`{"image":"image1","snapshot":"snap1","size":1048576,"format":2,"protected":"true"}]`, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	images, err := ListImages(context, "foocluster", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []CephBlockImage{{Name: "image1", Size: 1048576, Format: 2}}, images)
+}
+
+func TestGetImageUsage(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, []string{"du", "-p", "pool1"}, args[0:3])
+			return `{"images":[{"name":"image1","snapshot":"snap1","provisioned_size":2097152,"used_size":1048576},` +
+				`{"name":"image1","provisioned_size":2097152,"used_size":524288},` +
+				`{"name":"image2","provisioned_size":1048576,"used_size":0}],` +
+				`"total_provisioned_size":3145728,"total_used_size":1572864}`, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	// the space used by the snapshots is added to the space used by the image
+	images, err := GetImageUsage(context, "foocluster", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []CephBlockImageUsage{
+		{Name: "image1", ProvisionedSize: 2097152, UsedSize: 1572864},
+		{Name: "image2", ProvisionedSize: 1048576, UsedSize: 0},
+	}, images)
+}
@@ -109,3 +109,25 @@ func GetOSDDump(context *clusterd.Context, clusterName string) (*OSDDump, error)
 
 	return &osdDump, nil
 }
+
+// SetOSDFlag sets a cluster-wide osd flag such as noout
+func SetOSDFlag(context *clusterd.Context, clusterName, flag string) error {
+	args := []string{"osd", "set", flag}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to set osd flag %s: %+v", flag, err)
+	}
+
+	return nil
+}
+
+// UnsetOSDFlag clears a cluster-wide osd flag such as noout
+func UnsetOSDFlag(context *clusterd.Context, clusterName, flag string) error {
+	args := []string{"osd", "unset", flag}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to unset osd flag %s: %+v", flag, err)
+	}
+
+	return nil
+}
@@ -37,6 +37,7 @@ type CephStoragePoolDetails struct {
 	Number             int    `json:"pool_id"`
 	Size               uint   `json:"size"`
 	ErasureCodeProfile string `json:"erasure_code_profile"`
+	CrushRule          string `json:"crush_rule"`
 }
 
 type CephStoragePoolStats struct {
@@ -127,10 +128,29 @@ func CreatePool(context *clusterd.Context, clusterName string, newPool CephStora
 		}
 	}
 
+	if newPool.CrushRule != "" {
+		// place the data of the pool with the rule instead of the default rule
+		if err = SetPoolProperty(context, clusterName, newPool.Name, "crush_rule", newPool.CrushRule); err != nil {
+			return "", err
+		}
+	}
+
 	logger.Infof("creating pool %s succeeded, buf: %s", newPool.Name, string(buf))
 	return string(buf), nil
 }
 
+// DeletePool removes the pool and all the data it contains. The name of the pool is passed twice to confirm the deletion.
+func DeletePool(context *clusterd.Context, clusterName, name string) error {
+	args := []string{"osd", "pool", "delete", name, name, "--yes-i-really-really-mean-it"}
+	buf, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to delete pool %s. %+v", name, err)
+	}
+
+	logger.Infof("deleting pool %s succeeded, buf: %s", name, string(buf))
+	return nil
+}
+
 func SetPoolProperty(context *clusterd.Context, clusterName, name, propName string, propVal string) error {
 	args := []string{"osd", "pool", "set", name, propName, propVal}
 	_, err := ExecuteCephCommand(context, clusterName, args)
@@ -0,0 +1,135 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+type CephBlockSnapshot struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Size      uint64 `json:"size"`
+	Timestamp string `json:"timestamp"`
+}
+
+func ListSnapshots(context *clusterd.Context, clusterName, imageName, poolName string) ([]CephBlockSnapshot, error) {
+	args := []string{"snap", "ls", getImageSpec(imageName, poolName)}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots of image %s in pool %s: %+v", imageName, poolName, err)
+	}
+
+	var snapshots []CephBlockSnapshot
+	err = json.Unmarshal(buf, &snapshots)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v.  raw buffer response: %s", err, string(buf))
+	}
+
+	return snapshots, nil
+}
+
+func CreateSnapshot(context *clusterd.Context, clusterName, name, imageName, poolName string) error {
+	return runSnapshotCommand(context, clusterName, "create", name, imageName, poolName)
+}
+
+// RollbackSnapshot reverts the image to the content of the snapshot. The image must not be in use.
+func RollbackSnapshot(context *clusterd.Context, clusterName, name, imageName, poolName string) error {
+	return runSnapshotCommand(context, clusterName, "rollback", name, imageName, poolName)
+}
+
+// ProtectSnapshot prevents the snapshot from being deleted. Snapshots must be protected before they are cloned.
+func ProtectSnapshot(context *clusterd.Context, clusterName, name, imageName, poolName string) error {
+	return runSnapshotCommand(context, clusterName, "protect", name, imageName, poolName)
+}
+
+func UnprotectSnapshot(context *clusterd.Context, clusterName, name, imageName, poolName string) error {
+	return runSnapshotCommand(context, clusterName, "unprotect", name, imageName, poolName)
+}
+
+func DeleteSnapshot(context *clusterd.Context, clusterName, name, imageName, poolName string) error {
+	return runSnapshotCommand(context, clusterName, "rm", name, imageName, poolName)
+}
+
+// CloneSnapshot creates a copy-on-write clone of the snapshot. The snapshot must be protected.
+// The clone has the size of the snapshot.
+func CloneSnapshot(context *clusterd.Context, clusterName, name, imageName, poolName, cloneName, clonePoolName string,
+	options ImageOptions) error {
+
+	// clones are always created with the image format 2
+	options.Format = 0
+	args := []string{"clone", getSnapshotSpec(name, imageName, poolName), getImageSpec(cloneName, clonePoolName)}
+	args = append(args, options.args()...)
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to clone snapshot %s of image %s in pool %s to image %s in pool %s: %+v. output: %s",
+			name, imageName, poolName, cloneName, clonePoolName, err, string(buf))
+	}
+
+	return nil
+}
+
+// CephBlockImageChild is an image cloned from a snapshot
+type CephBlockImageChild struct {
+	Name     string
+	PoolName string
+}
+
+// ListChildren returns the images that were cloned from the snapshot and are not flattened yet
+func ListChildren(context *clusterd.Context, clusterName, name, imageName, poolName string) ([]CephBlockImageChild, error) {
+	args := []string{"children", getSnapshotSpec(name, imageName, poolName)}
+	buf, err := ExecuteRBDCommand(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list children of snapshot %s of image %s in pool %s: %+v", name, imageName, poolName, err)
+	}
+
+	// the children are listed as <pool>/<image>
+	var specs []string
+	err = json.Unmarshal(buf, &specs)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v.  raw buffer response: %s", err, string(buf))
+	}
+
+	children := []CephBlockImageChild{}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid child image %s", spec)
+		}
+		children = append(children, CephBlockImageChild{Name: parts[1], PoolName: parts[0]})
+	}
+
+	return children, nil
+}
+
+func runSnapshotCommand(context *clusterd.Context, clusterName, command, name, imageName, poolName string) error {
+	args := []string{"snap", command, getSnapshotSpec(name, imageName, poolName)}
+	buf, err := ExecuteRBDCommandNoFormat(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to %s snapshot %s of image %s in pool %s: %+v. output: %s",
+			command, name, imageName, poolName, err, string(buf))
+	}
+
+	return nil
+}
+
+func getSnapshotSpec(name, imageName, poolName string) string {
+	return fmt.Sprintf("%s@%s", getImageSpec(imageName, poolName), name)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListSnapshots(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, []string{"snap", "ls", "pool1/image1"}, args[0:3])
+			return `[{"id":4,"name":"snap1","size":1048576,"timestamp":"Tue Oct 17 10:00:00 2017"}]`, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	snapshots, err := ListSnapshots(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []CephBlockSnapshot{{ID: 4, Name: "snap1", Size: 1048576, Timestamp: "Tue Oct 17 10:00:00 2017"}}, snapshots)
+}
+
+func TestSnapshotCommands(t *testing.T) {
+	var commandArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(actionName string, command string, args ...string) (string, error) {
+			commandArgs = args[0:3]
+			if args[1] == "unprotect" {
+				return "mocked detailed rbd error", fmt.Errorf("some mocked error")
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	assert.Nil(t, CreateSnapshot(context, "foocluster", "snap1", "image1", "pool1"))
+	assert.Equal(t, []string{"snap", "create", "pool1/image1@snap1"}, commandArgs)
+	assert.Nil(t, RollbackSnapshot(context, "foocluster", "snap1", "image1", "pool1"))
+	assert.Equal(t, []string{"snap", "rollback", "pool1/image1@snap1"}, commandArgs)
+	assert.Nil(t, ProtectSnapshot(context, "foocluster", "snap1", "image1", "pool1"))
+	assert.Equal(t, []string{"snap", "protect", "pool1/image1@snap1"}, commandArgs)
+	assert.Nil(t, DeleteSnapshot(context, "foocluster", "snap1", "image1", "pool1"))
+	assert.Equal(t, []string{"snap", "rm", "pool1/image1@snap1"}, commandArgs)
+
+	// the rbd output is returned with the error
+	err := UnprotectSnapshot(context, "foocluster", "snap1", "image1", "pool1")
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "mocked detailed rbd error"))
+}
+
+func TestCloneSnapshot(t *testing.T) {
+	var cloneArgs []string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(actionName string, command string, args ...string) (string, error) {
+			switch {
+			case args[0] == "clone":
+				cloneArgs = args
+				return "", nil
+			case args[0] == "children":
+				assert.Equal(t, "pool1/image1@snap1", args[1])
+				return `["pool1/clone1","pool2/clone2"]`, nil
+			case args[0] == "flatten":
+				assert.Equal(t, "pool2/clone2", args[1])
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected rbd command '%v'", args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	// the image format is never passed to the clone
+	options := ImageOptions{Format: 2, Features: []string{"layering"}}
+	err := CloneSnapshot(context, "foocluster", "snap1", "image1", "pool1", "clone2", "pool2", options)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"clone", "pool1/image1@snap1", "pool2/clone2", "--image-feature", "layering"}, cloneArgs[0:5])
+
+	children, err := ListChildren(context, "foocluster", "snap1", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []CephBlockImageChild{{Name: "clone1", PoolName: "pool1"}, {Name: "clone2", PoolName: "pool2"}}, children)
+
+	err = FlattenImage(context, "foocluster", "clone2", "pool2")
+	assert.Nil(t, err)
+}
@@ -36,6 +36,8 @@ const (
 	// CephHealthErr denotes the status of ceph cluster when unhealthy but usually needs
 	// manual intervention.
 	CephHealthErr = "HEALTH_ERR"
+
+	activeCleanPGState = "active+clean"
 )
 
 type CephStatus struct {
@@ -150,6 +152,21 @@ func Status(context *clusterd.Context, clusterName string) (CephStatus, error) {
 	return status, nil
 }
 
+// IsClusterClean returns true if all the placement groups in the cluster are active+clean
+func IsClusterClean(status CephStatus) bool {
+	if status.PgMap.NumPgs == 0 {
+		// a cluster without any placement groups has nothing to recover
+		return true
+	}
+
+	for _, state := range status.PgMap.PgsByState {
+		if state.StateName != activeCleanPGState {
+			return false
+		}
+	}
+	return len(status.PgMap.PgsByState) > 0
+}
+
 func StatusPlain(context *clusterd.Context, clusterName string) ([]byte, error) {
 	args := []string{"status"}
 	buf, err := ExecuteCephCommandPlain(context, clusterName, args)
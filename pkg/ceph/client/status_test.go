@@ -59,3 +59,18 @@ func TestStatusMarshal(t *testing.T) {
 	assert.Equal(t, 2048, status.PgMap.PgsByState[0].Count)
 	assert.Equal(t, "active+clean", status.PgMap.PgsByState[0].StateName)
 }
+
+func TestIsClusterClean(t *testing.T) {
+	status := CephStatus{PgMap: PgMap{NumPgs: 100}}
+
+	// all pgs are active+clean
+	status.PgMap.PgsByState = []PgStateEntry{{StateName: "active+clean", Count: 100}}
+	assert.True(t, IsClusterClean(status))
+
+	// some of the pgs are still recovering
+	status.PgMap.PgsByState = []PgStateEntry{{StateName: "active+clean", Count: 90}, {StateName: "active+degraded", Count: 10}}
+	assert.False(t, IsClusterClean(status))
+
+	// there are no pgs to wait for
+	assert.True(t, IsClusterClean(CephStatus{}))
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rook/rook/pkg/model"
+)
+
+// VolumeUsageSource returns the usage of the provisioned volumes of the cluster
+type VolumeUsageSource func() (*model.VolumeUsageReport, error)
+
+// VolumeUsageCollector displays the space provisioned and used by each
+// dynamically provisioned volume, and the totals per namespace and storage
+// class. Images that are no longer bound to a volume are reported with empty
+// claim labels so that abandoned volumes can be found.
+type VolumeUsageCollector struct {
+	// Source of the usage of the volumes
+	source VolumeUsageSource
+
+	// ProvisionedBytes tracks the size of the image of each volume.
+	ProvisionedBytes *prometheus.GaugeVec
+
+	// UsedBytes tracks the space used by the image of each volume and its snapshots.
+	UsedBytes *prometheus.GaugeVec
+
+	// NamespaceProvisionedBytes tracks the size of the volumes of the claims in each namespace.
+	NamespaceProvisionedBytes *prometheus.GaugeVec
+
+	// NamespaceUsedBytes tracks the space used by the volumes of the claims in each namespace.
+	NamespaceUsedBytes *prometheus.GaugeVec
+
+	// StorageClassProvisionedBytes tracks the size of the volumes of each storage class.
+	StorageClassProvisionedBytes *prometheus.GaugeVec
+
+	// StorageClassUsedBytes tracks the space used by the volumes of each storage class.
+	StorageClassUsedBytes *prometheus.GaugeVec
+}
+
+// NewVolumeUsageCollector creates a new instance of VolumeUsageCollector and
+// returns its reference.
+func NewVolumeUsageCollector(source VolumeUsageSource) *VolumeUsageCollector {
+	var (
+		volumeLabels       = []string{"namespace", "claim", "volume", "phase", "storage_class", "pool", "image"}
+		namespaceLabel     = []string{"namespace"}
+		storageClassLabel  = []string{"storage_class"}
+		volumeSubsystem    = "volume"
+		namespaceSubsystem = "namespace"
+		classSubsystem     = "storage_class"
+	)
+	return &VolumeUsageCollector{
+		source: source,
+
+		ProvisionedBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: cephNamespace,
+				Subsystem: volumeSubsystem,
+				Name:      "provisioned_bytes",
+				Help:      "Provisioned size of the volume",
+			},
+			volumeLabels,
+		),
+		UsedBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: cephNamespace,
+				Subsystem: volumeSubsystem,
+				Name:      "used_bytes",
+				Help:      "Capacity of the volume and its snapshots that is currently under use",
+			},
+			volumeLabels,
+		),
+		NamespaceProvisionedBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: cephNamespace,
+				Subsystem: namespaceSubsystem,
+				Name:      "provisioned_bytes",
+				Help:      "Provisioned size of the volumes of the claims in the namespace",
+			},
+			namespaceLabel,
+		),
+		NamespaceUsedBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: cephNamespace,
+				Subsystem: namespaceSubsystem,
+				Name:      "used_bytes",
+				Help:      "Capacity of the volumes of the claims in the namespace that is currently under use",
+			},
+			namespaceLabel,
+		),
+		StorageClassProvisionedBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: cephNamespace,
+				Subsystem: classSubsystem,
+				Name:      "provisioned_bytes",
+				Help:      "Provisioned size of the volumes of the storage class",
+			},
+			storageClassLabel,
+		),
+		StorageClassUsedBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: cephNamespace,
+				Subsystem: classSubsystem,
+				Name:      "used_bytes",
+				Help:      "Capacity of the volumes of the storage class that is currently under use",
+			},
+			storageClassLabel,
+		),
+	}
+}
+
+func (v *VolumeUsageCollector) collectorList() []prometheus.Collector {
+	return []prometheus.Collector{
+		v.ProvisionedBytes,
+		v.UsedBytes,
+		v.NamespaceProvisionedBytes,
+		v.NamespaceUsedBytes,
+		v.StorageClassProvisionedBytes,
+		v.StorageClassUsedBytes,
+	}
+}
+
+func (v *VolumeUsageCollector) collect() error {
+	report, err := v.source()
+	if err != nil {
+		return err
+	}
+
+	// volumes come and go so the metrics of deleted volumes must not be reported
+	for _, metric := range v.collectorList() {
+		metric.(*prometheus.GaugeVec).Reset()
+	}
+
+	for _, volume := range report.Volumes {
+		labels := []string{volume.ClaimNamespace, volume.ClaimName, volume.Name, volume.Phase, volume.StorageClass,
+			volume.PoolName, volume.ImageName}
+		v.ProvisionedBytes.WithLabelValues(labels...).Set(float64(volume.ProvisionedBytes))
+		v.UsedBytes.WithLabelValues(labels...).Set(float64(volume.UsedBytes))
+	}
+	for _, namespace := range report.Namespaces {
+		v.NamespaceProvisionedBytes.WithLabelValues(namespace.Name).Set(float64(namespace.ProvisionedBytes))
+		v.NamespaceUsedBytes.WithLabelValues(namespace.Name).Set(float64(namespace.UsedBytes))
+	}
+	for _, class := range report.StorageClasses {
+		v.StorageClassProvisionedBytes.WithLabelValues(class.Name).Set(float64(class.ProvisionedBytes))
+		v.StorageClassUsedBytes.WithLabelValues(class.Name).Set(float64(class.UsedBytes))
+	}
+
+	return nil
+}
+
+// Describe fulfills the prometheus.Collector's interface and sends the descriptors
+// of volume's metrics to the given channel.
+func (v *VolumeUsageCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range v.collectorList() {
+		metric.Describe(ch)
+	}
+}
+
+// Collect extracts the current values of all the metrics and sends them to the
+// prometheus channel.
+func (v *VolumeUsageCollector) Collect(ch chan<- prometheus.Metric) {
+	if err := v.collect(); err != nil {
+		logger.Errorf("failed collecting volume usage metrics: %+v", err)
+		return
+	}
+
+	for _, metric := range v.collectorList() {
+		metric.Collect(ch)
+	}
+}
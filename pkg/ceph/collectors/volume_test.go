@@ -0,0 +1,90 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collectors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rook/rook/pkg/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVolumeUsageCollector(t *testing.T) {
+	report := &model.VolumeUsageReport{
+		Volumes: []model.VolumeUsage{
+			{
+				ProvisionedVolume: model.ProvisionedVolume{Name: "pv1", Phase: "Bound", ImageName: "k8s-dynamic-pv1-1", PoolName: "pool1",
+					ClaimName: "claim1", ClaimNamespace: "app1", StorageClass: "block"},
+				ProvisionedBytes: 100,
+				UsedBytes:        10,
+			},
+			{
+				ProvisionedVolume: model.ProvisionedVolume{ImageName: "k8s-dynamic-pv2-2", PoolName: "pool1"},
+				ProvisionedBytes:  200,
+				UsedBytes:         20,
+			},
+		},
+		Namespaces:     []model.VolumeUsageSummary{{Name: "app1", Volumes: 1, ProvisionedBytes: 100, UsedBytes: 10}},
+		StorageClasses: []model.VolumeUsageSummary{{Name: "block", Volumes: 1, ProvisionedBytes: 100, UsedBytes: 10}},
+	}
+	collector := NewVolumeUsageCollector(func() (*model.VolumeUsageReport, error) { return report, nil })
+	if err := prometheus.Register(collector); err != nil {
+		t.Fatalf("collector failed to register: %s", err)
+	}
+	defer prometheus.Unregister(collector)
+
+	server := httptest.NewServer(prometheus.Handler())
+	defer server.Close()
+
+	scrape := func() []byte {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected failed response from prometheus: %s", err)
+		}
+		defer resp.Body.Close()
+
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed reading server response: %s", err)
+		}
+		return buf
+	}
+
+	buf := scrape()
+	for _, re := range []*regexp.Regexp{
+		regexp.MustCompile(`ceph_volume_provisioned_bytes{claim="claim1",image="k8s-dynamic-pv1-1",namespace="app1",phase="Bound",pool="pool1",storage_class="block",volume="pv1"} 100`),
+		regexp.MustCompile(`ceph_volume_used_bytes{claim="claim1",image="k8s-dynamic-pv1-1",namespace="app1",phase="Bound",pool="pool1",storage_class="block",volume="pv1"} 10`),
+		regexp.MustCompile(`ceph_volume_used_bytes{claim="",image="k8s-dynamic-pv2-2",namespace="",phase="",pool="pool1",storage_class="",volume=""} 20`),
+		regexp.MustCompile(`ceph_namespace_provisioned_bytes{namespace="app1"} 100`),
+		regexp.MustCompile(`ceph_namespace_used_bytes{namespace="app1"} 10`),
+		regexp.MustCompile(`ceph_storage_class_provisioned_bytes{storage_class="block"} 100`),
+		regexp.MustCompile(`ceph_storage_class_used_bytes{storage_class="block"} 10`),
+	} {
+		assert.True(t, re.Match(buf), fmt.Sprintf("failed matching: %q", re))
+	}
+
+	// the metrics of deleted volumes are no longer reported
+	report.Volumes = report.Volumes[0:1]
+	buf = scrape()
+	assert.False(t, regexp.MustCompile(`k8s-dynamic-pv2-2`).Match(buf))
+}
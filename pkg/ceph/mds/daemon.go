@@ -31,6 +31,11 @@ type Config struct {
 	Keyring     string
 	InProc      bool
 	ClusterInfo *mon.ClusterInfo
+	// Whether the mds follows the journal of an active rank as a standby-replay daemon
+	StandbyReplay bool
+	// The rank and the file system followed by the standby-replay daemon
+	StandbyForRank  int
+	StandbyForFscid int
 }
 
 func Run(context *clusterd.Context, config *Config) error {
@@ -89,6 +94,12 @@ func startMDS(context *clusterd.Context, config *Config) (mdsProc *proc.Monitore
 		fmt.Sprintf("--keyring=%s", getMDSKeyringPath(context.ConfigDir, config.ID)),
 		"-i", config.ID,
 	}
+	if config.StandbyReplay {
+		args = append(args,
+			"--mds-standby-replay=true",
+			fmt.Sprintf("--mds-standby-for-rank=%d", config.StandbyForRank),
+			fmt.Sprintf("--mds-standby-for-fscid=%d", config.StandbyForFscid))
+	}
 
 	name := fmt.Sprintf("mds%s", config.ID)
 	if config.InProc {
@@ -0,0 +1,243 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package mds
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+const (
+	// the directory of the file system where the volumes are created
+	volumesDir = "volumes"
+
+	// the prefix of the rados namespace where the data of a volume is stored, as in the ceph volume client
+	volumeNamespacePrefix = "fsvolumens_"
+
+	quotaMaxBytesAttr       = "ceph.quota.max_bytes"
+	layoutPoolNamespaceAttr = "ceph.dir.layout.pool_namespace"
+)
+
+// CreateVolume creates a cephx user that can only access the directory of the volume in the file system, and the
+// objects of the volume in the rados namespace of the volume in the data pools. The volume is returned with the key of
+// the user. The directory is created with CreateVolumeDir.
+func CreateVolume(context *clusterd.Context, clusterName, fsName, name string, size uint64) (*model.FilesystemVolume, error) {
+	if err := ValidateVolumeName(name); err != nil {
+		return nil, err
+	}
+	dataPools, err := getDataPools(context, clusterName, fsName)
+	if err != nil {
+		return nil, err
+	}
+
+	volumePath := volumePath(name)
+	userName := volumeUserName(fsName, name)
+	osdCaps := make([]string, len(dataPools))
+	for i, pool := range dataPools {
+		osdCaps[i] = fmt.Sprintf("allow rw pool=%s namespace=%s", pool, volumeNamespace(name))
+	}
+	caps := []string{
+		"mon", "allow r",
+		"mds", fmt.Sprintf("allow rw path=%s", volumePath),
+		"osd", strings.Join(osdCaps, ", "),
+	}
+	key, err := client.AuthGetOrCreateKey(context, clusterName, "client."+userName, caps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the user of volume %s. %+v", name, err)
+	}
+
+	logger.Infof("created the user of volume %s in file system %s", volumePath, fsName)
+	return &model.FilesystemVolume{
+		Filesystem: fsName,
+		Name:       name,
+		Path:       volumePath,
+		Size:       size,
+		UserName:   userName,
+		SecretKey:  key,
+	}, nil
+}
+
+// DeleteVolume deletes the user of the volume. The directory is removed with DeleteVolumeDir.
+func DeleteVolume(context *clusterd.Context, clusterName, fsName, name string) error {
+	if err := ValidateVolumeName(name); err != nil {
+		return err
+	}
+	if err := client.AuthDelete(context, clusterName, "client."+volumeUserName(fsName, name)); err != nil {
+		return fmt.Errorf("failed to delete the user of volume %s. %+v", name, err)
+	}
+
+	logger.Infof("deleted the user of volume %s from file system %s", volumePath(name), fsName)
+	return nil
+}
+
+// CreateVolumeDir creates the directory of the volume in the file system with a quota of the given size. The files of
+// the directory are stored in the rados namespace of the volume, so the user of the volume cannot access the objects of
+// the other volumes in the data pools. The file system is mounted, so the caller must be privileged.
+func CreateVolumeDir(context *clusterd.Context, clusterName, fsName, name string, size uint64) error {
+	if err := ValidateVolumeName(name); err != nil {
+		return err
+	}
+
+	volumePath := volumePath(name)
+	err := withFilesystemRoot(context, clusterName, fsName, func(root string) error {
+		dir := path.Join(root, volumePath)
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return fmt.Errorf("failed to create directory %s. %+v", volumePath, err)
+		}
+		// the mode is not applied by mkdir with the default umask
+		if err := os.Chmod(dir, 0777); err != nil {
+			return fmt.Errorf("failed to set the mode of directory %s. %+v", volumePath, err)
+		}
+
+		// the layout only applies to the files created after it is set, so it is set before the volume is used
+		err := context.Executor.ExecuteCommand(fmt.Sprintf("set namespace %s", volumePath), "setfattr",
+			"-n", layoutPoolNamespaceAttr, "-v", volumeNamespace(name), dir)
+		if err != nil {
+			return fmt.Errorf("failed to set the pool namespace of directory %s. %+v", volumePath, err)
+		}
+
+		// the quota is enforced by the ceph clients when the directory grows beyond the size. the kernel clients
+		// before 4.17 ignore the quota.
+		err = context.Executor.ExecuteCommand(fmt.Sprintf("set quota %s", volumePath), "setfattr",
+			"-n", quotaMaxBytesAttr, "-v", strconv.FormatUint(size, 10), dir)
+		if err != nil {
+			return fmt.Errorf("failed to set the quota of directory %s. %+v", volumePath, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("created directory %s in file system %s with size %d", volumePath, fsName, size)
+	return nil
+}
+
+// DeleteVolumeDir removes the directory of the volume with all its content. The file system is mounted, so the caller
+// must be privileged.
+func DeleteVolumeDir(context *clusterd.Context, clusterName, fsName, name string) error {
+	if err := ValidateVolumeName(name); err != nil {
+		return err
+	}
+
+	volumePath := volumePath(name)
+	err := withFilesystemRoot(context, clusterName, fsName, func(root string) error {
+		if err := os.RemoveAll(path.Join(root, volumePath)); err != nil {
+			return fmt.Errorf("failed to remove directory %s. %+v", volumePath, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("deleted directory %s from file system %s", volumePath, fsName)
+	return nil
+}
+
+// ValidateVolumeName checks that the volume is a single directory under the volumes directory, so that the volume
+// cannot refer to other directories of the file system
+func ValidateVolumeName(name string) error {
+	if name == "" || name == "." || strings.Contains(name, "/") || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid volume name %q", name)
+	}
+	return nil
+}
+
+func volumePath(name string) string {
+	return "/" + path.Join(volumesDir, name)
+}
+
+func volumeNamespace(name string) string {
+	return volumeNamespacePrefix + name
+}
+
+func volumeUserName(fsName, name string) string {
+	return fmt.Sprintf("%s-%s", fsName, name)
+}
+
+func getDataPools(context *clusterd.Context, clusterName, fsName string) ([]string, error) {
+	filesystems, err := client.ListFilesystems(context, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	for _, fs := range filesystems {
+		if fs.Name == fsName {
+			return fs.DataPools, nil
+		}
+	}
+	return nil, fmt.Errorf("file system %s not found", fsName)
+}
+
+// withFilesystemRoot mounts the root of the file system with the admin credentials in a temporary directory and calls
+// the function with the mount point. The file system is unmounted when the function returns. The admin key is passed
+// to the mount in a file, since the mount command line is logged.
+func withFilesystemRoot(context *clusterd.Context, clusterName, fsName string, f func(root string) error) error {
+	monStatus, err := client.GetMonStatus(context, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to get the mon status. %+v", err)
+	}
+	monAddrs := make([]string, len(monStatus.MonMap.Mons))
+	for i, m := range monStatus.MonMap.Mons {
+		// the mon address has the form <ip>:<port>/<nonce>
+		monAddrs[i] = strings.Split(m.Address, "/")[0]
+	}
+	secret, err := client.AuthGetKey(context, clusterName, client.AdminUsername)
+	if err != nil {
+		return err
+	}
+
+	root, err := ioutil.TempDir(context.ConfigDir, "fs-"+fsName)
+	if err != nil {
+		return fmt.Errorf("failed to create the mount point of file system %s. %+v", fsName, err)
+	}
+	defer os.Remove(root)
+
+	secretFile, err := ioutil.TempFile(context.ConfigDir, "fs-"+fsName+"-secret")
+	if err != nil {
+		return fmt.Errorf("failed to create the secret file of file system %s. %+v", fsName, err)
+	}
+	defer os.Remove(secretFile.Name())
+	_, err = secretFile.WriteString(secret)
+	if closeErr := secretFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the secret file of file system %s. %+v", fsName, err)
+	}
+
+	devicePath := strings.Join(monAddrs, ",") + ":/"
+	options := fmt.Sprintf("name=admin,secretfile=%s,mds_namespace=%s", secretFile.Name(), fsName)
+	if err := sys.MountDeviceWithOptions(devicePath, root, "ceph", options, context.Executor); err != nil {
+		return fmt.Errorf("failed to mount file system %s. %+v", fsName, err)
+	}
+	defer func() {
+		if err := sys.UnmountDevice(root, context.Executor); err != nil {
+			logger.Warningf("failed to unmount file system %s from %s. %+v", fsName, root, err)
+		}
+	}()
+
+	return f(root)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package mds
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilesystemVolumes(t *testing.T) {
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+
+	var mountArgs []string
+	var mountSecret string
+	var xattrArgs [][]string
+	var authArgs []string
+	var mountPoint string
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			switch {
+			case args[0] == "fs" && args[1] == "ls":
+				return `[{"name":"myfs","metadata_pool":"myfs-metadata","data_pools":["myfs-data0","myfs-data1"]}]`, nil
+			case args[0] == "mon_status":
+				return `{"quorum":[0,1],"monmap":{"mons":[{"name":"mon0","rank":0,"addr":"10.0.0.1:6790/0"},{"name":"mon1","rank":1,"addr":"10.0.0.2:6790/0"}]}}`, nil
+			case args[0] == "auth" && args[1] == "get-key":
+				return `{"key":"adminkey"}`, nil
+			case args[0] == "auth" && args[1] == "get-or-create-key":
+				authArgs = args[2:9]
+				return `{"key":"volumekey"}`, nil
+			case args[0] == "auth" && args[1] == "del":
+				authArgs = args[0:3]
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected ceph command '%v'", args)
+		},
+		MockExecuteCommand: func(actionName string, command string, args ...string) error {
+			switch command {
+			case "mount":
+				mountArgs = args
+				mountPoint = args[len(args)-1]
+				options := strings.Split(args[3], ",")
+				if strings.HasPrefix(options[1], "secretfile=") {
+					secret, _ := ioutil.ReadFile(strings.TrimPrefix(options[1], "secretfile="))
+					mountSecret = string(secret)
+				}
+			case "setfattr":
+				xattrArgs = append(xattrArgs, args)
+			}
+			return nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, ConfigDir: configDir}
+
+	err := CreateVolumeDir(context, "mycluster", "myfs", "vol1", 1024*1024)
+	assert.Nil(t, err)
+
+	// the root of the file system is mounted with the admin key in a file that is removed after the unmount
+	assert.Equal(t, "adminkey", mountSecret)
+	secretFile := strings.TrimPrefix(strings.Split(mountArgs[3], ",")[1], "secretfile=")
+	assert.Equal(t, []string{"-t", "ceph", "-o", fmt.Sprintf("name=admin,secretfile=%s,mds_namespace=myfs", secretFile),
+		"10.0.0.1:6790,10.0.0.2:6790:/"}, mountArgs[0:5])
+	assert.NotContains(t, strings.Join(mountArgs, " "), "adminkey")
+	_, err = os.Stat(secretFile)
+	assert.True(t, os.IsNotExist(err))
+
+	// the files of the volume are stored in the namespace of the volume with the quota of the volume
+	dir := path.Join(mountPoint, "volumes/vol1")
+	assert.Equal(t, [][]string{
+		{"-n", "ceph.dir.layout.pool_namespace", "-v", "fsvolumens_vol1", dir},
+		{"-n", "ceph.quota.max_bytes", "-v", "1048576", dir},
+	}, xattrArgs)
+	info, err := os.Stat(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0777), info.Mode().Perm())
+
+	// the user can only access the directory and the namespace of the volume
+	volume, err := CreateVolume(context, "mycluster", "myfs", "vol1", 1024*1024)
+	assert.Nil(t, err)
+	assert.Equal(t, model.FilesystemVolume{Filesystem: "myfs", Name: "vol1", Path: "/volumes/vol1", Size: 1024 * 1024,
+		UserName: "myfs-vol1", SecretKey: "volumekey"}, *volume)
+	assert.Equal(t, []string{"client.myfs-vol1", "mon", "allow r", "mds", "allow rw path=/volumes/vol1",
+		"osd", "allow rw pool=myfs-data0 namespace=fsvolumens_vol1, allow rw pool=myfs-data1 namespace=fsvolumens_vol1"}, authArgs)
+
+	// the directory and the user are removed
+	err = DeleteVolumeDir(context, "mycluster", "myfs", "vol1")
+	assert.Nil(t, err)
+	_, err = os.Stat(path.Join(mountPoint, "volumes/vol1"))
+	assert.True(t, os.IsNotExist(err))
+	err = DeleteVolume(context, "mycluster", "myfs", "vol1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"auth", "del", "client.myfs-vol1"}, authArgs)
+
+	// the volume cannot refer to other directories of the file system
+	for _, invalid := range []string{"", ".", "..", "../vol1", "vol1/..", "a/b", "/"} {
+		_, err = CreateVolume(context, "mycluster", "myfs", invalid, 1024*1024)
+		assert.NotNil(t, err, invalid)
+		assert.NotNil(t, CreateVolumeDir(context, "mycluster", "myfs", invalid, 1024*1024), invalid)
+		assert.NotNil(t, DeleteVolumeDir(context, "mycluster", "myfs", invalid), invalid)
+		assert.NotNil(t, DeleteVolume(context, "mycluster", "myfs", invalid), invalid)
+	}
+
+	// the file system must exist
+	_, err = CreateVolume(context, "mycluster", "otherfs", "vol1", 1024*1024)
+	assert.NotNil(t, err)
+}
@@ -16,11 +16,13 @@ limitations under the License.
 package mon
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/coreos/pkg/capnslog"
@@ -158,6 +160,10 @@ func GenerateConfigFile(context *clusterd.Context, cluster *ClusterInfo, pathRoo
 		return "", fmt.Errorf("failed to add initial monitor config sections, %+v", err)
 	}
 
+	if err := addCephConfigSettings(configFile, context.CephConfig); err != nil {
+		return "", fmt.Errorf("failed to add ceph config settings, %+v", err)
+	}
+
 	// if there's a config file override path given, process the given config file
 	if context.ConfigFileOverride != "" {
 		err := configFile.Append(context.ConfigFileOverride)
@@ -289,6 +295,10 @@ func CreateDefaultCephConfig(context *clusterd.Context, cluster *ClusterInfo, ru
 func createGlobalConfigFileSection(context *clusterd.Context, cluster *ClusterInfo, runDir string,
 	bluestore bool, userConfig *cephConfig) (*ini.File, error) {
 
+	if err := clusterd.VerifyNetworkInfo(context.NetworkInfo); err != nil {
+		return nil, fmt.Errorf("invalid network settings. %+v", err)
+	}
+
 	var ceph *cephConfig
 
 	if userConfig != nil {
@@ -343,6 +353,91 @@ func addInitialMonitorsConfigFileSections(configFile *ini.File, cluster *Cluster
 	return nil
 }
 
+// adds the settings to the config file. A setting replaces the generated setting with the same name in the section,
+// whether the words of the name are separated by spaces or underscores.
+func addCephConfigSettings(configFile *ini.File, settings map[string]map[string]string) error {
+	sections := []string{}
+	for section := range settings {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	for _, name := range sections {
+		s, err := configFile.NewSection(name)
+		if err != nil {
+			return err
+		}
+
+		keys := []string{}
+		for key := range settings[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			for _, existing := range s.KeyStrings() {
+				if NormalizeConfigKey(existing) == NormalizeConfigKey(key) {
+					s.DeleteKey(existing)
+				}
+			}
+			if _, err := s.NewKey(key, settings[name][key]); err != nil {
+				return fmt.Errorf("failed to add key %s to section %s. %v", key, name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// NormalizeConfigKey returns the name of a setting with the words separated by underscores, the way ceph compares
+// the names of the settings
+func NormalizeConfigKey(key string) string {
+	return strings.Replace(strings.TrimSpace(key), " ", "_", -1)
+}
+
+// SerializeCephConfigSettings returns the settings in the ini format of the ceph config file
+func SerializeCephConfigSettings(settings map[string]map[string]string) (string, error) {
+	configFile := ini.Empty()
+	if err := addCephConfigSettings(configFile, settings); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if _, err := configFile.WriteTo(&buf); err != nil {
+		return "", fmt.Errorf("failed to serialize ceph config settings. %+v", err)
+	}
+	return buf.String(), nil
+}
+
+// LoadCephConfigSettings reads the settings from a file in the ini format of the ceph config file. No settings are
+// returned if the path is empty.
+func LoadCephConfigSettings(path string) (map[string]map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	configFile, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ceph config settings from %s. %+v", path, err)
+	}
+
+	settings := map[string]map[string]string{}
+	for _, s := range configFile.Sections() {
+		name := s.Name()
+		if name == ini.DEFAULT_SECTION {
+			// the settings without a section are global settings
+			name = "global"
+		}
+		for _, key := range s.Keys() {
+			if settings[name] == nil {
+				settings[name] = map[string]string{}
+			}
+			settings[name][key.Name()] = key.Value()
+		}
+	}
+	return settings, nil
+}
+
 func logLevelToCephLogLevel(logLevel capnslog.LogLevel) int {
 	switch logLevel {
 	case capnslog.CRITICAL:
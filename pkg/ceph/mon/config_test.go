@@ -120,6 +120,64 @@ debug bluestore = 1234`
 
 	// verify the content of the config file override successfully overwrote the default generated config
 	verifyConfigValue(t, actualConf, "global", "debug bluestore", "1234")
+
+	// the networks are rendered in the global section
+	context.NetworkInfo = clusterd.NetworkInfo{PublicNetwork: "192.168.0.0/24", ClusterNetwork: "10.0.0.0/24"}
+	configFilePath, err = GenerateConfigFile(context, clusterInfo, configDir, "myuser", filepath.Join(configDir, "mykeyring"), false, nil, nil)
+	assert.Nil(t, err)
+	actualConf, err = ini.Load(configFilePath)
+	assert.Nil(t, err)
+	verifyConfigValue(t, actualConf, "global", "public network", "192.168.0.0/24")
+	verifyConfigValue(t, actualConf, "global", "cluster network", "10.0.0.0/24")
+
+	// invalid networks are rejected
+	context.NetworkInfo = clusterd.NetworkInfo{PublicNetwork: "192.168.0.1"}
+	_, err = GenerateConfigFile(context, clusterInfo, configDir, "myuser", filepath.Join(configDir, "mykeyring"), false, nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestCephConfigSettings(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "TestCephConfigSettings")
+	if err != nil {
+		t.Fatalf("failed to create temp config dir: %+v", err)
+	}
+	defer os.RemoveAll(configDir)
+
+	settings := map[string]map[string]string{
+		"global": {"osd_pool_default_size": "3"},
+		"osd":    {"osd max backfills": "2"},
+	}
+	context := &clusterd.Context{ConfigDir: configDir, CephConfig: settings}
+	clusterInfo := &ClusterInfo{
+		FSID: "myfsid",
+		Name: "foo-cluster",
+		Monitors: map[string]*CephMonitorConfig{
+			"node0": {Name: "mon0", Endpoint: "10.0.0.1:6790"},
+		},
+	}
+
+	// the settings replace the generated settings with the same name
+	configFilePath, err := GenerateConfigFile(context, clusterInfo, configDir, "myuser", filepath.Join(configDir, "mykeyring"), false, nil, nil)
+	assert.Nil(t, err)
+	actualConf, err := ini.Load(configFilePath)
+	assert.Nil(t, err)
+	verifyConfigValue(t, actualConf, "global", "osd_pool_default_size", "3")
+	assert.False(t, actualConf.Section("global").HasKey("osd pool default size"))
+	verifyConfigValue(t, actualConf, "global", "fsid", "myfsid")
+	verifyConfigValue(t, actualConf, "osd", "osd max backfills", "2")
+
+	// the settings are passed to the daemons in a file
+	serialized, err := SerializeCephConfigSettings(settings)
+	assert.Nil(t, err)
+	settingsPath := filepath.Join(configDir, "settings.conf")
+	assert.Nil(t, ioutil.WriteFile(settingsPath, []byte(serialized), 0644))
+	loaded, err := LoadCephConfigSettings(settingsPath)
+	assert.Nil(t, err)
+	assert.Equal(t, settings, loaded)
+
+	loaded, err = LoadCephConfigSettings("")
+	assert.Nil(t, err)
+	assert.Nil(t, loaded)
 }
 
 func verifyConfig(t *testing.T, cephConfig *cephConfig, expectedMonMembers, experimental, objectStore string, loggingLevel int) {
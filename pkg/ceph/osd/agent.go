@@ -63,16 +63,18 @@ type OsdAgent struct {
 	metadataDevice     string
 	directories        string
 	storeConfig        StoreConfig
+	memoryLimitMB      int
+	cacheSizeMB        int
 	configCounter      int32
 	osdsCompleted      chan struct{}
 }
 
 func NewAgent(devices string, usingDeviceFilter bool, metadataDevice, directories string, forceFormat bool,
-	location string, storeConfig StoreConfig, cluster *mon.ClusterInfo, nodeName string) *OsdAgent {
+	location string, storeConfig StoreConfig, memoryLimitMB int, cluster *mon.ClusterInfo, nodeName string) *OsdAgent {
 
 	return &OsdAgent{devices: devices, usingDeviceFilter: usingDeviceFilter, metadataDevice: metadataDevice,
-		directories: directories, forceFormat: forceFormat, location: location, storeConfig: storeConfig, cluster: cluster,
-		nodeName: nodeName,
+		directories: directories, forceFormat: forceFormat, location: location, storeConfig: storeConfig,
+		memoryLimitMB: memoryLimitMB, cluster: cluster, nodeName: nodeName,
 	}
 }
 
@@ -304,6 +306,9 @@ func (a *OsdAgent) createDesiredOSDs(context *clusterd.Context) error {
 		return err
 	}
 
+	// the osds on the node share the memory limit
+	a.setCacheSize(devices, dirs)
+
 	// initialize the desired OSD directories
 	err = a.configureDirs(context, dirs)
 	if err != nil {
@@ -313,6 +318,23 @@ func (a *OsdAgent) createDesiredOSDs(context *clusterd.Context) error {
 	return a.configureDevices(context, devices)
 }
 
+// setCacheSize divides the memory limit of the node among the osds on the devices and in the dirs
+func (a *OsdAgent) setCacheSize(devices *DeviceOsdMapping, dirs map[string]int) {
+	count := len(dirs)
+	if devices != nil {
+		for name := range devices.Entries {
+			if name != a.metadataDevice {
+				count++
+			}
+		}
+	}
+
+	a.cacheSizeMB = getCacheSizeMB(a.memoryLimitMB, count)
+	if a.cacheSizeMB > 0 {
+		logger.Infof("%d osds share the memory limit of %dMB. bluestore cache size is %dMB", count, a.memoryLimitMB, a.cacheSizeMB)
+	}
+}
+
 func (a *OsdAgent) configureDirs(context *clusterd.Context, dirs map[string]int) error {
 	if len(dirs) == 0 {
 		return nil
@@ -321,7 +343,7 @@ func (a *OsdAgent) configureDirs(context *clusterd.Context, dirs map[string]int)
 	succeeded := 0
 	var lastErr error
 	for dirPath, osdID := range dirs {
-		config := &osdConfig{id: osdID, configRoot: dirPath, dir: true}
+		config := &osdConfig{id: osdID, configRoot: dirPath, dir: true, cacheSizeMB: a.cacheSizeMB}
 
 		if config.id == unassignedOSDID {
 			// the osd hasn't been registered with ceph yet, do so now to give it a cluster wide ID
@@ -392,7 +414,8 @@ func (a *OsdAgent) configureDevices(context *clusterd.Context, devices *DeviceOs
 		// initialize and start all the desired OSDs using the computed scheme
 		succeeded := 0
 		for _, entry := range scheme.Entries {
-			config := &osdConfig{id: entry.ID, uuid: entry.OsdUUID, configRoot: context.ConfigDir, partitionScheme: entry}
+			config := &osdConfig{id: entry.ID, uuid: entry.OsdUUID, configRoot: context.ConfigDir, partitionScheme: entry,
+				cacheSizeMB: a.cacheSizeMB}
 			err := a.startOSD(context, config)
 			if err != nil {
 				logger.Errorf("failed to config osd %d. %+v", entry.ID, err)
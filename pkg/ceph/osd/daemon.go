@@ -63,18 +63,22 @@ func Run(context *clusterd.Context, agent *OsdAgent) error {
 		return fmt.Errorf("failed to get available devices. %+v", err)
 	}
 
-	logger.Infof("configuring osd devices: %+v", devices)
-	err = agent.configureDevices(context, devices)
-	if err != nil {
-		return fmt.Errorf("failed to configure devices. %+v", err)
-	}
-
 	// initialize the data directories, with the default dir if no devices were specified
 	devicesSpecified := len(devices.Entries) > 0
 	dirs, err := getDataDirs(context, agent.directories, devicesSpecified)
 	if err != nil {
 		return fmt.Errorf("failed to get data dirs. %+v", err)
 	}
+
+	// the osds on the node share the memory limit of the pod
+	agent.setCacheSize(devices, dirs)
+
+	logger.Infof("configuring osd devices: %+v", devices)
+	err = agent.configureDevices(context, devices)
+	if err != nil {
+		return fmt.Errorf("failed to configure devices. %+v", err)
+	}
+
 	logger.Infof("configuring osd dirs: %+v", dirs)
 	err = agent.configureDirs(context, dirs)
 	if err != nil {
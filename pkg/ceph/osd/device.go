@@ -53,6 +53,13 @@ const (
 `
 )
 
+const (
+	// the minimum size of the bluestore cache when the osds have a memory limit
+	minCacheSizeMB = 128
+	// the percentage of the memory share of each osd that is used for the bluestore cache
+	osdCachePercent = 50
+)
+
 type osdConfig struct {
 	configRoot      string
 	rootPath        string
@@ -60,6 +67,8 @@ type osdConfig struct {
 	uuid            uuid.UUID
 	dir             bool
 	partitionScheme *PerfSchemeEntry
+	// the size of the bluestore cache, or 0 to use the ceph default
+	cacheSizeMB int
 }
 
 type Device struct {
@@ -158,6 +167,95 @@ func rookOwnsPartitions(partitions []*sys.Partition) bool {
 	return true
 }
 
+// RemoveOSDPartitions zaps the devices of the given osds and removes the osds from the partition scheme in the
+// config dir. The devices are found by the disk uuids recorded in the scheme, so the devices of other clusters on the
+// node are left untouched. A dedicated metadata device is only zapped when all of its partitions belong to the given
+// osds, and a device is never zapped if any of its partitions was not created by a rook osd.
+func RemoveOSDPartitions(executor exec.Executor, configDir string, ids []int) error {
+	scheme, err := LoadScheme(configDir)
+	if err != nil {
+		return fmt.Errorf("failed to load partition scheme. %+v", err)
+	}
+
+	removed := func(id int) bool {
+		for _, i := range ids {
+			if i == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	diskUUIDs := map[string]bool{}
+	entries := []*PerfSchemeEntry{}
+	for _, entry := range scheme.Entries {
+		if !removed(entry.ID) {
+			entries = append(entries, entry)
+			continue
+		}
+		for _, p := range entry.Partitions {
+			if scheme.Metadata != nil && p.DiskUUID == scheme.Metadata.DiskUUID {
+				continue
+			}
+			diskUUIDs[p.DiskUUID] = true
+		}
+	}
+	scheme.Entries = entries
+
+	if scheme.Metadata != nil {
+		partitions := []*MetadataDevicePartition{}
+		for _, p := range scheme.Metadata.Partitions {
+			if !removed(p.ID) {
+				partitions = append(partitions, p)
+			}
+		}
+		scheme.Metadata.Partitions = partitions
+		if len(partitions) == 0 {
+			diskUUIDs[scheme.Metadata.DiskUUID] = true
+			scheme.Metadata = nil
+		}
+	}
+
+	if len(diskUUIDs) > 0 {
+		devices, err := sys.ListDevices(executor)
+		if err != nil {
+			return err
+		}
+		for _, device := range devices {
+			if device == "" {
+				continue
+			}
+			diskUUID, err := sys.GetDiskUUID(device, executor)
+			if err != nil || !diskUUIDs[diskUUID] {
+				logger.Debugf("skipping device %s that does not belong to the osds", device)
+				continue
+			}
+
+			partitions, _, err := sys.GetDevicePartitions(device, executor)
+			if err != nil {
+				return fmt.Errorf("failed to get %s partitions. %+v", device, err)
+			}
+			if !rookOwnsPartitions(partitions) {
+				logger.Warningf("skipping device %s with partitions that were not created by rook", device)
+				continue
+			}
+
+			logger.Infof("removing rook partitions from device %s", device)
+			if err := sys.RemovePartitions(device, executor); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(scheme.Entries) == 0 && scheme.Metadata == nil {
+		if err := os.Remove(path.Join(configDir, schemeFilename)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove partition scheme. %+v", err)
+		}
+		return nil
+	}
+	return scheme.Save(configDir)
+}
+
 // partitions a given device exclusively for metadata usage
 func partitionMetadata(context *clusterd.Context, info *MetadataDeviceInfo, configRoot string) error {
 	if len(info.Partitions) == 0 {
@@ -468,9 +566,30 @@ func getStoreSettings(config *osdConfig, storeConfig StoreConfig) (map[string]st
 	settings["bluestore block db path"] = path.Join(prefix, dbPartition.PartitionUUID)
 	settings["bluestore block path"] = path.Join(prefix, blockPartition.PartitionUUID)
 
+	if config.cacheSizeMB > 0 {
+		settings["bluestore cache size"] = strconv.Itoa(config.cacheSizeMB * 1024 * 1024)
+	}
+
 	return settings, nil
 }
 
+// getCacheSizeMB returns the size of the bluestore cache of each osd when the given number of osds share the memory
+// limit. Only part of the share of each osd is used for the cache since the memory used by the osd for the placement
+// groups and the client requests is not bounded by the cache size. No size is returned if there is no limit.
+func getCacheSizeMB(memoryLimitMB, osdCount int) int {
+	if memoryLimitMB <= 0 || osdCount <= 0 {
+		return 0
+	}
+
+	cacheSizeMB := memoryLimitMB / osdCount * osdCachePercent / 100
+	if cacheSizeMB < minCacheSizeMB {
+		logger.Warningf("the memory limit of %dMB for %d osds is too low. using the minimum cache size of %dMB.",
+			memoryLimitMB, osdCount, minCacheSizeMB)
+		cacheSizeMB = minCacheSizeMB
+	}
+	return cacheSizeMB
+}
+
 func writeConfigFile(config *osdConfig, context *clusterd.Context, cluster *mon.ClusterInfo, storeConfig StoreConfig) error {
 	cephConfig := mon.CreateDefaultCephConfig(context, cluster, config.rootPath, isBluestore(config))
 
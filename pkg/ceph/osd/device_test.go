@@ -368,3 +368,109 @@ func testPartitionOSDHelper(t *testing.T, storeConfig StoreConfig) {
 		fmt.Sprintf("/rook/services/ceph/osd/desired/node123/device/%s/osd-id-metadata", dataDetails.DiskUUID))
 	assert.Equal(t, "1", metadataID)
 }
+
+func TestRemoveOSDPartitions(t *testing.T) {
+	configDir, err := ioutil.TempDir("", "TestRemoveOSDPartitions")
+	if err != nil {
+		t.Fatalf("failed to create temp config dir: %+v", err)
+	}
+	defer os.RemoveAll(configDir)
+
+	diskUUIDs := map[string]string{}
+	for _, device := range []string{"sda", "sdb", "sdc", "sdd"} {
+		diskUUIDs[device] = uuid.Must(uuid.NewRandom()).String()
+	}
+
+	// osd 0 and 1 have their wal and db on the metadata device sdc. sdd belongs to another cluster.
+	scheme := NewPerfScheme()
+	scheme.Metadata = NewMetadataDeviceInfo("sdc")
+	scheme.Metadata.DiskUUID = diskUUIDs["sdc"]
+	for i, device := range []string{"sda", "sdb"} {
+		entry := NewPerfSchemeEntry(Bluestore)
+		entry.ID = i
+		entry.Partitions[BlockPartitionType] = &PerfSchemePartitionDetails{Device: device, DiskUUID: diskUUIDs[device]}
+		entry.Partitions[WalPartitionType] = &PerfSchemePartitionDetails{Device: "sdc", DiskUUID: diskUUIDs["sdc"]}
+		scheme.Entries = append(scheme.Entries, entry)
+		scheme.Metadata.Partitions = append(scheme.Metadata.Partitions, &MetadataDevicePartition{ID: i, Type: WalPartitionType})
+	}
+	assert.Nil(t, scheme.Save(configDir))
+
+	zapped := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(actionName string, command string, args ...string) (string, error) {
+			logger.Infof("OUTPUT: %s %+v", command, args)
+			if actionName == "lsblk all" {
+				return "sda\nsda1\nsdb\nsdb1\nsdc\nsdc1\nsdd\nsdd1\n", nil
+			}
+			for device, diskUUID := range diskUUIDs {
+				switch actionName {
+				case fmt.Sprintf("get disk %s uuid", device):
+					return fmt.Sprintf("Disk identifier (GUID): %s", diskUUID), nil
+				case fmt.Sprintf("lsblk /dev/%s", device):
+					return fmt.Sprintf("NAME=\"%s\" SIZE=\"100\" TYPE=\"disk\" PKNAME=\"\"\nNAME=\"%s1\" SIZE=\"50\" TYPE=\"part\" PKNAME=\"%s\"",
+						device, device, device), nil
+				case fmt.Sprintf("blkid /dev/%s1", device):
+					return "ROOK-OSD0-BLOCK", nil
+				}
+			}
+			return "", nil
+		},
+		MockExecuteCommand: func(actionName string, command string, args ...string) error {
+			logger.Infof("RUN: %s %+v", command, args)
+			assert.Equal(t, "sgdisk", command)
+			if args[0] == "--zap-all" {
+				zapped = append(zapped, args[1])
+			}
+			return nil
+		},
+	}
+
+	// the metadata device is kept while another osd has partitions on it
+	err = RemoveOSDPartitions(executor, configDir, []int{0})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/dev/sda"}, zapped)
+	scheme, err = LoadScheme(configDir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(scheme.Entries))
+	assert.Equal(t, 1, scheme.Entries[0].ID)
+	assert.Equal(t, 1, len(scheme.Metadata.Partitions))
+
+	// the metadata device is zapped with the last osd and the scheme is removed
+	zapped = []string{}
+	err = RemoveOSDPartitions(executor, configDir, []int{1})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/dev/sdb", "/dev/sdc"}, zapped)
+	_, err = os.Stat(path.Join(configDir, schemeFilename))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBluestoreCacheSize(t *testing.T) {
+	// the cache size is only set when there is a memory limit
+	assert.Equal(t, 0, getCacheSizeMB(0, 4))
+	assert.Equal(t, 512, getCacheSizeMB(4096, 4))
+	assert.Equal(t, minCacheSizeMB, getCacheSizeMB(1024, 16))
+
+	// the metadata device does not run an osd
+	agent := &OsdAgent{metadataDevice: "sdc", memoryLimitMB: 3072}
+	devices := &DeviceOsdMapping{Entries: map[string]*DeviceOsdIDEntry{
+		"sda": {Data: 1}, "sdb": {Data: unassignedOSDID}, "sdc": {Data: unassignedOSDID, Metadata: []int{1}},
+	}}
+	agent.setCacheSize(devices, map[string]int{"/var/lib/rook/osd": 2})
+	assert.Equal(t, 512, agent.cacheSizeMB)
+
+	storeConfig := StoreConfig{StoreType: Bluestore}
+	entry := NewPerfSchemeEntry(storeConfig.StoreType)
+	entry.ID = 1
+	PopulateCollocatedPerfSchemeEntry(entry, "sda", storeConfig)
+	config := &osdConfig{id: entry.ID, partitionScheme: entry, cacheSizeMB: agent.cacheSizeMB}
+	settings, err := getStoreSettings(config, storeConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, "536870912", settings["bluestore cache size"])
+
+	// filestore osds do not have a bluestore cache
+	config = &osdConfig{id: 2, dir: true, cacheSizeMB: agent.cacheSizeMB}
+	settings, err = getStoreSettings(config, storeConfig)
+	assert.Nil(t, err)
+	_, ok := settings["bluestore cache size"]
+	assert.False(t, ok)
+}
@@ -21,6 +21,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/rook/rook/pkg/ceph/mon"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/model"
@@ -163,6 +167,64 @@ func GetBucket(context *clusterd.Context, bucket string, getClusterInfo func() (
 	return &model.ObjectBucket{Name: bucket, ObjectBucketMetadata: model.ObjectBucketMetadata{Owner: metadata.Owner, CreatedAt: metadata.CreatedAt}, ObjectBucketStats: *stat}, RGWErrorNone, nil
 }
 
+// createS3Bucket creates the bucket through the s3 api of the object store. radosgw-admin cannot create buckets,
+// so the bucket is created with the credentials of the owner.
+var createS3Bucket = func(endpoint, accessKey, secretKey, bucketName string) error {
+	creds := credentials.NewStaticCredentials(accessKey, secretKey, "")
+
+	// ceph requires the default aws region
+	config := aws.NewConfig().
+		WithRegion("us-east-1").
+		WithCredentials(creds).
+		WithEndpoint(endpoint).
+		WithS3ForcePathStyle(true).
+		WithDisableSSL(true)
+
+	client := s3.New(session.New(), config)
+	_, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucketName)})
+	return err
+}
+
+// CreateBucket creates the bucket owned by the given user through the object store endpoint. Creating a bucket
+// that already exists with the same owner succeeds.
+func CreateBucket(context *clusterd.Context, bucket model.ObjectBucketRequest, endpoint string, getClusterInfo func() (*mon.ClusterInfo, error)) (int, error) {
+	logger.Infof("Creating bucket %s for owner %s", bucket.Name, bucket.Owner)
+	if bucket.Name == "" {
+		return RGWErrorBadData, fmt.Errorf("bucket name cannot be empty")
+	}
+	if bucket.Owner == "" {
+		return RGWErrorBadData, fmt.Errorf("bucket owner cannot be empty")
+	}
+
+	owner, rgwError, err := GetUser(context, bucket.Owner, getClusterInfo)
+	if err != nil {
+		if rgwError == RGWErrorNotFound {
+			return RGWErrorBadData, fmt.Errorf("owner %s not found", bucket.Owner)
+		}
+		return rgwError, err
+	}
+	if owner.AccessKey == nil || owner.SecretKey == nil {
+		return RGWErrorBadData, fmt.Errorf("owner %s does not have keys", bucket.Owner)
+	}
+
+	metadata, notFound, err := getBucketMetadata(context, bucket.Name, getClusterInfo)
+	if err == nil {
+		if metadata.Owner == bucket.Owner {
+			return RGWErrorNone, nil
+		}
+		return RGWErrorBadData, fmt.Errorf("bucket already exists")
+	}
+	if !notFound {
+		return RGWErrorUnknown, err
+	}
+
+	if err := createS3Bucket(endpoint, *owner.AccessKey, *owner.SecretKey, bucket.Name); err != nil {
+		return RGWErrorUnknown, fmt.Errorf("failed to create bucket: %+v", err)
+	}
+
+	return RGWErrorNone, nil
+}
+
 func DeleteBucket(context *clusterd.Context, bucketName string, purge bool, getClusterInfo func() (*mon.ClusterInfo, error)) (int, error) {
 	options := []string{"--bucket", bucketName}
 	if purge {
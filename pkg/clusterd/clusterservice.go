@@ -81,6 +81,9 @@ type Context struct {
 	// The full path to a config file that can be used to override generated settings
 	ConfigFileOverride string
 
+	// Ceph settings indexed by the section and the name of the setting that are merged into the generated settings
+	CephConfig map[string]map[string]string
+
 	// Information about the network for this machine and its cluster
 	NetworkInfo NetworkInfo
 }
@@ -247,5 +247,6 @@ func copyContext(c *Context) *Context {
 		ConfigDir:          c.ConfigDir,
 		LogLevel:           c.LogLevel,
 		ConfigFileOverride: c.ConfigFileOverride,
+		CephConfig:         c.CephConfig,
 	}
 }
@@ -21,4 +21,11 @@ type BlockImage struct {
 	Size       uint64 `json:"size"`
 	Device     string `json:"device"`
 	MountPoint string `json:"mountPoint"`
+
+	// Optional settings of a new image
+	Format       int      `json:"format,omitempty"`
+	Features     []string `json:"features,omitempty"`
+	StripeUnit   uint64   `json:"stripeUnit,omitempty"`
+	StripeCount  uint64   `json:"stripeCount,omitempty"`
+	DataPoolName string   `json:"dataPoolName,omitempty"`
 }
@@ -21,6 +21,17 @@ type ClientAccessInfo struct {
 	SecretKey    string   `json:"secretKey"`
 }
 
+// ClientUserPrefix is the prefix of the names of the client users. Only the users with the prefix are managed by the api
+// so that the admin and the daemon users cannot be changed or deleted.
+const ClientUserPrefix = "rook-"
+
+// ClientUser is a cephx user that can only access the block images of a pool
+type ClientUser struct {
+	Name string `json:"name"`
+	Pool string `json:"pool"`
+	Key  string `json:"key,omitempty"`
+}
+
 const (
 	Port = 8124
 )
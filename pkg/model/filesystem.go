@@ -25,3 +25,14 @@ type Filesystem struct {
 	MetadataPool string   `json:"metadataPool"`
 	DataPools    []string `json:"dataPools"`
 }
+
+// FilesystemVolume is a directory of a shared file system with a quota and a cephx user that can only access the
+// directory
+type FilesystemVolume struct {
+	Filesystem string `json:"filesystem"`
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Size       uint64 `json:"size"`
+	UserName   string `json:"userName,omitempty"`
+	SecretKey  string `json:"secretKey,omitempty"`
+}
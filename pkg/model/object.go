@@ -46,6 +46,11 @@ type ObjectBucket struct {
 	ObjectBucketStats
 }
 
+type ObjectBucketRequest struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+}
+
 type ObjectBuckets []ObjectBucket
 
 func (slice ObjectBuckets) Len() int {
@@ -39,6 +39,10 @@ type Pool struct {
 	Type               PoolType               `json:"type"`
 	ReplicationConfig  ReplicatedPoolConfig   `json:"replicationConfig"`
 	ErasureCodedConfig ErasureCodedPoolConfig `json:"erasureCodedConfig"`
+
+	// The CRUSH bucket, for example a zone, that all the replicas of a replicated pool are placed under.
+	// The replicas are placed on different hosts. The bucket can only be set when the pool is created.
+	CrushRoot string `json:"crushRoot,omitempty"`
 }
 
 func PoolTypeToString(poolType PoolType) string {
@@ -0,0 +1,50 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package model
+
+// ProvisionedVolume is a persistent volume whose block image was provisioned in the cluster
+type ProvisionedVolume struct {
+	Name           string `json:"name"`
+	Phase          string `json:"phase"`
+	ImageName      string `json:"imageName"`
+	PoolName       string `json:"poolName"`
+	ClaimName      string `json:"claimName"`
+	ClaimNamespace string `json:"claimNamespace"`
+	StorageClass   string `json:"storageClass"`
+}
+
+// VolumeUsage is the space provisioned and used by the image of a volume. The volume is empty for images that were
+// dynamically provisioned but are no longer referenced by any persistent volume.
+type VolumeUsage struct {
+	ProvisionedVolume
+	ProvisionedBytes uint64 `json:"provisionedBytes"`
+	UsedBytes        uint64 `json:"usedBytes"`
+}
+
+// VolumeUsageSummary is the space provisioned and used by the volumes of a namespace or storage class
+type VolumeUsageSummary struct {
+	Name             string `json:"name"`
+	Volumes          int    `json:"volumes"`
+	ProvisionedBytes uint64 `json:"provisionedBytes"`
+	UsedBytes        uint64 `json:"usedBytes"`
+}
+
+// VolumeUsageReport is the usage of the dynamically provisioned volumes, summarized by namespace and storage class
+type VolumeUsageReport struct {
+	Volumes        []VolumeUsage        `json:"volumes"`
+	Namespaces     []VolumeUsageSummary `json:"namespaces"`
+	StorageClasses []VolumeUsageSummary `json:"storageClasses"`
+}
@@ -63,16 +63,18 @@ type Cluster struct {
 	context   *clusterd.Context
 	Namespace string
 	placement k8sutil.Placement
+	resources v1.ResourceRequirements
 	Version   string
 	Replicas  int32
 }
 
 // New creates an instance
-func New(context *clusterd.Context, namespace, version string, placement k8sutil.Placement) *Cluster {
+func New(context *clusterd.Context, namespace, version string, placement k8sutil.Placement, resources v1.ResourceRequirements) *Cluster {
 	return &Cluster{
 		context:   context,
 		Namespace: namespace,
 		placement: placement,
+		resources: resources,
 		Version:   version,
 		Replicas:  1,
 	}
@@ -109,6 +111,35 @@ func (c *Cluster) Start() error {
 	return nil
 }
 
+// Update the api deployment with the current settings
+func (c *Cluster) Update() error {
+	logger.Infof("updating the Rook api")
+	deployment := c.makeDeployment()
+	_, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Update(deployment)
+	if err != nil {
+		return fmt.Errorf("failed to update api deployment. %+v", err)
+	}
+
+	logger.Infof("api deployment updated")
+	return nil
+}
+
+// Delete the api deployment, service and service account. The cluster role is shared by all clusters and is not removed.
+func (c *Cluster) Delete() error {
+	logger.Infof("deleting the Rook api in namespace %s", c.Namespace)
+	if err := k8sutil.DeleteResource("api deployment", DeploymentName, c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Delete); err != nil {
+		return err
+	}
+	if err := k8sutil.DeleteResource("api service", DeploymentName, c.context.Clientset.CoreV1().Services(c.Namespace).Delete); err != nil {
+		return err
+	}
+	if err := k8sutil.DeleteResource("api service account", DeploymentName, c.context.Clientset.CoreV1().ServiceAccounts(c.Namespace).Delete); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // make a cluster role
 func (c *Cluster) makeClusterRole() error {
 	account := &v1.ServiceAccount{}
@@ -177,15 +208,15 @@ func (c *Cluster) makeDeployment() *extensions.Deployment {
 }
 
 func (c *Cluster) apiContainer() v1.Container {
-
 	return v1.Container{
 		Args: []string{
 			"api",
 			fmt.Sprintf("--config-dir=%s", k8sutil.DataDir),
 			fmt.Sprintf("--port=%d", model.Port),
 		},
-		Name:  DeploymentName,
-		Image: k8sutil.MakeRookImage(c.Version),
+		Name:      DeploymentName,
+		Image:     k8sutil.MakeRookImage(c.Version),
+		Resources: c.resources,
 		VolumeMounts: []v1.VolumeMount{
 			{Name: k8sutil.DataDirVolume, MountPath: k8sutil.DataDir},
 		},
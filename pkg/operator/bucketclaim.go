@@ -0,0 +1,206 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operator to manage Kubernetes storage.
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/kit"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	kwatch "k8s.io/apimachinery/pkg/watch"
+)
+
+type bucketClaimInitiator struct {
+	context *clusterd.Context
+}
+
+// bucketClaimManager handles the claims for the buckets of a cluster. The claims are created in the namespaces of
+// the applications, so the claims in all namespaces are watched and the claims for other clusters are ignored.
+type bucketClaimManager struct {
+	namespace  string
+	context    *clusterd.Context
+	rclient    rookclient.RookRestClient
+	clusterMgr *clusterManager
+	deleted    pausedDeletes
+}
+
+type bucketClaimEvent struct {
+	Type   kwatch.EventType
+	Object *cluster.BucketClaim
+}
+
+func newBucketClaimInitiator(context *clusterd.Context) *bucketClaimInitiator {
+	return &bucketClaimInitiator{context: context}
+}
+
+func (b *bucketClaimInitiator) Create(clusterMgr *clusterManager, namespace string) (resourceManager, error) {
+	rclient, err := clusterMgr.getRookClient(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api client for bucket claim tpr for cluster in namespace %s. %+v", namespace, err)
+	}
+	return &bucketClaimManager{context: b.context, namespace: namespace, rclient: rclient, clusterMgr: clusterMgr}, nil
+}
+
+func (b *bucketClaimInitiator) Resource() kit.CustomResource {
+	return cluster.BucketClaimResource
+}
+
+// Run the tpr manager until the caller signals with an EndWatch()
+func (b *bucketClaimManager) Manage() {
+	for {
+
+		// load and initialize the bucket claims
+		watchVersion, err := b.Load()
+		if err != nil {
+			logger.Errorf("cannot load bucket claim tpr for cluster %s. %+v. retrying...", b.namespace, err)
+		} else {
+			// watch for added/updated/deleted bucket claims in all namespaces
+			watcher := kit.NewWatcher(b.context.KubeContext, cluster.BucketClaimResource, "", watchVersion, b.handleBucketClaimEvent, nil)
+			if err := watcher.Watch(); err != nil {
+				logger.Errorf("failed to watch bucket claim tpr for cluster %s. %+v. retrying...", b.namespace, err)
+			}
+		}
+
+		<-time.After(time.Second * time.Duration(b.context.RetryDelay))
+	}
+}
+
+func (b *bucketClaimManager) handleBucketClaimEvent(event *kit.RawEvent) error {
+	claim := &bucketClaimEvent{
+		Type:   event.Type,
+		Object: &cluster.BucketClaim{},
+	}
+	err := json.Unmarshal(event.Object, claim.Object)
+	if err != nil {
+		return fmt.Errorf("fail to unmarshal BucketClaim from data (%s): %v", claim.Object, err)
+	}
+
+	if !claim.Object.InCluster(b.namespace) {
+		return nil
+	}
+
+	if b.clusterMgr.isPaused(b.namespace) {
+		// the bucket claims will be reconciled when the cluster is resumed
+		if event.Type == kwatch.Deleted {
+			// the deleted bucket claims are not listed when the cluster is resumed, so the event is replayed then
+			b.deleted.add(event)
+		}
+		logger.Infof("deferring %s event for bucket claim %s while the cluster in namespace %s is paused", event.Type, claim.Object.Name, b.namespace)
+		return nil
+	}
+
+	switch event.Type {
+	case kwatch.Added:
+		saved := claim.Object.Status
+		err := claim.Object.Create(b.context, b.rclient)
+		if err != nil {
+			logger.Errorf("failed to create bucket for claim %s in namespace %s. %+v", claim.Object.Name, claim.Object.Namespace, err)
+		}
+		b.updateStatus(claim.Object, saved, err)
+
+	case kwatch.Modified:
+		// if the claim is modified, allow the bucket to be created if it wasn't already
+		saved := claim.Object.Status
+		err := claim.Object.Update(b.context, b.rclient)
+		if err != nil {
+			logger.Errorf("failed to update bucket for claim %s in namespace %s. %+v", claim.Object.Name, claim.Object.Namespace, err)
+		}
+		b.updateStatus(claim.Object, saved, err)
+
+	case kwatch.Deleted:
+		if err := claim.Object.Delete(b.context, b.rclient); err != nil {
+			logger.Errorf("failed to delete bucket claim %s in namespace %s. %+v", claim.Object.Name, claim.Object.Namespace, err)
+		}
+	}
+	return nil
+}
+
+func (b *bucketClaimManager) Load() (string, error) {
+	// Check if the buckets have all been created
+	logger.Info("finding existing bucket claims...")
+	claimList, err := b.getBucketClaimList()
+	if err != nil {
+		return "", err
+	}
+
+	claims := []cluster.BucketClaim{}
+	for _, claim := range claimList.Items {
+		if claim.InCluster(b.namespace) {
+			claims = append(claims, claim)
+		}
+	}
+
+	if b.clusterMgr.isPaused(b.namespace) {
+		logger.Infof("found %d bucket claims. not checking them while the cluster in namespace %s is paused.", len(claims), b.namespace)
+		return claimList.Metadata.ResourceVersion, nil
+	}
+
+	// delete the bucket claims that were deleted while the cluster was paused
+	b.deleted.replay(b.handleBucketClaimEvent)
+
+	logger.Infof("found %d bucket claims for cluster %s. ensuring they are bound.", len(claims), b.namespace)
+	for i := range claims {
+		item := claims[i]
+		logger.Infof("checking bucket claim %s in namespace %s", item.Name, item.Namespace)
+		saved := item.Status
+		err := item.Update(b.context, b.rclient)
+		if err != nil {
+			logger.Warningf("failed to check that the bucket for claim %s in namespace %s exists. %+v", item.Name, item.Namespace, err)
+		}
+		b.updateStatus(&item, saved, err)
+	}
+
+	return claimList.Metadata.ResourceVersion, nil
+}
+
+// updateStatus records in the claim resource whether the claim is bound. A claim that failed to update remains bound
+// to its bucket and owner, so they are removed when the claim is deleted. Saving the status generates a modified event
+// for the claim, so the status is only saved when it changes.
+func (b *bucketClaimManager) updateStatus(claim *cluster.BucketClaim, saved cluster.BucketClaimStatus, err error) {
+	status := claim.Status
+	status.State = cluster.BucketClaimStateBound
+	status.Message = ""
+	if err != nil {
+		status.State = cluster.BucketClaimStateFailed
+		status.Message = err.Error()
+	}
+	if saved == status {
+		return
+	}
+
+	if err := kit.UpdateRawResourceStatus(b.context.Clientset, cluster.BucketClaimResource, claim.Namespace, claim.Name, status); err != nil {
+		logger.Warningf("failed to save status of bucket claim %s in namespace %s. %+v", claim.Name, claim.Namespace, err)
+	}
+}
+
+func (b *bucketClaimManager) getBucketClaimList() (*cluster.BucketClaimList, error) {
+	raw, err := kit.GetRawList(b.context.Clientset, cluster.BucketClaimResource)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &cluster.BucketClaimList{}
+	if err := json.Unmarshal(raw, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -43,7 +43,7 @@ type clusterManager struct {
 	// The initiators that create TPRs specific to specific Rook clusters.
 	// For example, pools, object services, and file services, only make sense in the context of a Rook cluster
 	inclusterInitiators []inclusterInitiator
-	inclusterMgrs       []resourceManager
+	inclusterMgrs       map[string][]resourceManager
 }
 
 type clusterEvent struct {
@@ -57,6 +57,7 @@ func newClusterManager(context *clusterd.Context, inclusterInitiators []incluste
 		clusters:            make(map[string]*cluster.Cluster),
 		tracker:             newTPRTracker(),
 		inclusterInitiators: inclusterInitiators,
+		inclusterMgrs:       make(map[string][]resourceManager),
 	}
 }
 
@@ -86,9 +87,9 @@ func (m *clusterManager) Load() (string, error) {
 	}
 	logger.Infof("found %d clusters", len(clusterList.Items))
 	for i := range clusterList.Items {
-		c := clusterList.Items[i]
+		c := &clusterList.Items[i]
 		logger.Infof("checking if cluster %s is running in namespace %s", c.Name, c.Namespace)
-		m.startCluster(&c)
+		m.startCluster(c)
 	}
 
 	return clusterList.Metadata.ResourceVersion, nil
@@ -114,10 +115,14 @@ func (m *clusterManager) startTrack(c *cluster.Cluster) error {
 	return nil
 }
 
+// stopTrack stops tracking the cluster. A cluster created later in the same namespace is not affected.
 func (m *clusterManager) stopTrack(c *cluster.Cluster) {
 	m.Lock()
 	defer m.Unlock()
 
+	if existing, ok := m.clusters[c.Namespace]; !ok || existing != c {
+		return
+	}
 	m.tracker.remove(c.Namespace)
 	delete(m.clusters, c.Namespace)
 }
@@ -140,18 +145,29 @@ func (m *clusterManager) startCluster(c *cluster.Cluster) {
 
 	go func() {
 		defer m.stopTrack(c)
+		if !m.waitForResume(c) {
+			return
+		}
 		logger.Infof("starting cluster %s in namespace %s", c.Name, c.Namespace)
 
-		// Start the Rook cluster components. Retry several times in case of failure.
+		// Start the Rook cluster components. Retry several times in case of failure. The retries stop when the
+		// cluster is deleted.
 		err := kit.Retry(m.context.KubeContext, func() (bool, error) {
 			err := c.CreateInstance()
 			if err != nil {
+				if c.IsStopped() {
+					return false, err
+				}
 				logger.Errorf("failed to create cluster %s in namespace %s. %+v", c.Name, c.Namespace, err)
 				return false, nil
 			}
 			return true, nil
 		})
 		if err != nil {
+			if c.IsStopped() {
+				logger.Infof("stopped creating deleted cluster %s in namespace %s", c.Name, c.Namespace)
+				return
+			}
 			logger.Errorf("giving up to create cluster %s in namespace %s", c.Name, c.Namespace)
 			return
 		}
@@ -159,33 +175,122 @@ func (m *clusterManager) startCluster(c *cluster.Cluster) {
 		// Start all the TPRs for this cluster
 		for _, initiator := range m.inclusterInitiators {
 			kit.Retry(m.context.KubeContext, func() (bool, error) {
+				if c.IsStopped() {
+					return false, fmt.Errorf("cluster %s in namespace %s was deleted", c.Name, c.Namespace)
+				}
 				tprMgr, err := initiator.Create(m, c.Namespace)
 				if err != nil {
 					logger.Warningf("cannot create in-cluster tpr %s. %+v. retrying...", initiator.Resource().Name, err)
 					return false, nil
 				}
 
-				// Start the tpr-manager asynchronously
-				go tprMgr.Manage()
-
 				m.Lock()
 				defer m.Unlock()
-				m.inclusterMgrs = append(m.inclusterMgrs, tprMgr)
+				if c.IsStopped() {
+					return false, fmt.Errorf("cluster %s in namespace %s was deleted", c.Name, c.Namespace)
+				}
+
+				// Start the tpr-manager asynchronously
+				go tprMgr.Manage()
+				m.inclusterMgrs[c.Namespace] = append(m.inclusterMgrs[c.Namespace], tprMgr)
 
 				return true, nil
 			})
 		}
-		c.Monitor(m.tracker.stopChMap[c.Namespace])
+		c.Monitor(m.tracker.stopCh(c.Namespace))
 	}()
 }
 
+// waitForResume blocks while the cluster is paused. Returns false if the cluster stopped being tracked while waiting.
+func (m *clusterManager) waitForResume(c *cluster.Cluster) bool {
+	for c.IsPaused() {
+		logger.Infof("cluster %s in namespace %s is paused. waiting for it to be resumed", c.Name, c.Namespace)
+		select {
+		case <-m.tracker.stopCh(c.Namespace):
+			return false
+		case <-time.After(time.Second * time.Duration(m.context.RetryDelay)):
+		}
+	}
+	return true
+}
+
+func (m *clusterManager) updateCluster(newCluster *cluster.Cluster) {
+	c, err := m.getCluster(newCluster.Namespace)
+	if err != nil {
+		logger.Errorf("cannot update unknown cluster %s. %+v", newCluster.Name, err)
+		return
+	}
+	if c.Name != newCluster.Name {
+		logger.Errorf("cannot update cluster %s. cluster %s is running in namespace %s", newCluster.Name, c.Name, c.Namespace)
+		return
+	}
+
+	if m.devicesInUse && !c.Spec.Storage.AnyUseAllDevices() && newCluster.Spec.Storage.AnyUseAllDevices() {
+		logger.Warningf("using all devices in more than one namespace not supported. ignoring devices in namespace %s", c.Namespace)
+		newCluster.Spec.Storage.ClearUseAllDevices()
+	}
+
+	wasPaused := c.IsPaused()
+	if err := c.Update(newCluster.Spec); err != nil {
+		// the version is not refreshed so the stale cluster is detected and the update is retried
+		logger.Errorf("failed to update cluster %s in namespace %s. %+v", c.Name, c.Namespace, err)
+		return
+	}
+	if wasPaused && !c.IsPaused() {
+		// reconcile the in-cluster resources that were ignored while the cluster was paused
+		m.reloadInclusterMgrs(c.Namespace)
+	}
+
+	// refresh the version of the cluster we're tracking
+	m.Lock()
+	defer m.Unlock()
+	m.tracker.add(c.Namespace, newCluster.ResourceVersion)
+}
+
+func (m *clusterManager) deleteCluster(deleted *cluster.Cluster) {
+	c, err := m.getCluster(deleted.Namespace)
+	if err == nil {
+		if c.Name != deleted.Name {
+			// the deleted cluster was rejected when it was added, so there are no resources to remove
+			logger.Infof("cluster %s was not running in namespace %s", deleted.Name, deleted.Namespace)
+			return
+		}
+
+		// stop creating and restarting the daemons and monitoring the cluster before its resources are removed
+		c.Stop()
+		m.stopTrack(c)
+		if c.Spec.Storage.AnyUseAllDevices() {
+			m.devicesInUse = false
+		}
+	}
+
+	// the deleted object has the latest spec, including the cleanup policy
+	deleted.Init(m.context)
+	if err := deleted.Delete(); err != nil {
+		logger.Errorf("failed to delete cluster %s in namespace %s. %+v", deleted.Name, deleted.Namespace, err)
+	}
+}
+
+func (m *clusterManager) reloadInclusterMgrs(namespace string) {
+	m.Lock()
+	mgrs := m.inclusterMgrs[namespace]
+	m.Unlock()
+
+	for _, mgr := range mgrs {
+		if _, err := mgr.Load(); err != nil {
+			logger.Warningf("failed to reload in-cluster resources for namespace %s. %+v", namespace, err)
+		}
+	}
+}
+
 func (m *clusterManager) isClustersCacheStale(currentClusters []cluster.Cluster) bool {
-	if len(m.tracker.clusterRVs) != len(currentClusters) {
+	if m.tracker.count() != len(currentClusters) {
 		return true
 	}
 
-	for _, cc := range currentClusters {
-		rv, ok := m.tracker.clusterRVs[cc.Name]
+	for i := range currentClusters {
+		cc := &currentClusters[i]
+		rv, ok := m.tracker.version(cc.Name)
 		if !ok || rv != cc.ResourceVersion {
 			return true
 		}
@@ -232,10 +337,12 @@ func (m *clusterManager) handleClusterEvent(e *kit.RawEvent) error {
 		m.startCluster(event.Object)
 
 	case kwatch.Modified:
-		logger.Infof("modifying a cluster not implemented")
+		logger.Infof("modifying cluster %s in namespace %s", event.Object.Name, event.Object.Namespace)
+		m.updateCluster(event.Object)
 
 	case kwatch.Deleted:
-		logger.Infof("deleting a cluster not implemented")
+		logger.Infof("deleting cluster %s in namespace %s", event.Object.Name, event.Object.Namespace)
+		m.deleteCluster(event.Object)
 	}
 	return nil
 }
@@ -259,6 +366,12 @@ func (m *clusterManager) getRookClient(namespace string) (rookclient.RookRestCli
 	return nil, fmt.Errorf("namespace %s not found", namespace)
 }
 
+// isPaused returns whether the operator control of the cluster in the namespace is paused
+func (m *clusterManager) isPaused(namespace string) bool {
+	c, err := m.getCluster(namespace)
+	return err == nil && c.IsPaused()
+}
+
 func (m *clusterManager) getCluster(namespace string) (*cluster.Cluster, error) {
 	m.Lock()
 	defer m.Unlock()
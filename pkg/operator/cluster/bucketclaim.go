@@ -0,0 +1,303 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BucketClaimResource is the definition of the bucket claim TPR
+var BucketClaimResource = kit.CustomResource{
+	Name:        "bucketclaim",
+	Group:       k8sutil.CustomResourceGroup,
+	Version:     kit.V1Alpha1,
+	Description: "Managed Rook object store bucket claims",
+}
+
+// The keys of the bucket settings in the claim config map. The credentials are stored in the claim secret with the
+// same keys as the object user secret.
+const (
+	BucketNameKey = "BUCKET_NAME"
+	BucketHostKey = "BUCKET_HOST"
+	BucketPortKey = "BUCKET_PORT"
+
+	bucketOwnerPrefix = "rook-bucket-"
+)
+
+// the s3 bucket naming rules that are compatible with dns names
+var bucketNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// BucketReclaimPolicy is what happens to the bucket when the claim is deleted
+type BucketReclaimPolicy string
+
+const (
+	// BucketReclaimDelete deletes the bucket and its owner when the claim is deleted
+	BucketReclaimDelete BucketReclaimPolicy = "Delete"
+	// BucketReclaimRetain keeps the bucket and its owner when the claim is deleted
+	BucketReclaimRetain BucketReclaimPolicy = "Retain"
+)
+
+// BucketClaimState is the result of the last attempt to bind the claim to a bucket
+type BucketClaimState string
+
+const (
+	// BucketClaimStateBound means the bucket and its owner exist and the config map and secret are published
+	BucketClaimStateBound BucketClaimState = "Bound"
+	// BucketClaimStateFailed means the bucket could not be provisioned for the claim
+	BucketClaimStateFailed BucketClaimState = "Failed"
+)
+
+// BucketClaim is the spec for the bucket claim TPR. The claim is created in the namespace of the application, which
+// receives a config map and a secret with the same name as the claim.
+type BucketClaim struct {
+	v1.ObjectMeta   `json:"metadata,omitempty"`
+	BucketClaimSpec `json:"spec"`
+	Status          BucketClaimStatus `json:"status,omitempty"`
+}
+
+// BucketClaimSpec represents the spec of a bucket claim
+type BucketClaimSpec struct {
+	// The namespace of the rook cluster where the object store is running. Default is `rook`.
+	ClusterNamespace string `json:"clusterNamespace"`
+
+	// The name of the bucket. Default is `<namespace>-<name>` of the claim.
+	BucketName string `json:"bucketName"`
+
+	// Whether the bucket is deleted or retained when the claim is deleted. Default is Delete.
+	ReclaimPolicy BucketReclaimPolicy `json:"reclaimPolicy"`
+
+	// Whether the objects in the bucket are purged when the bucket is deleted. A bucket that is not empty
+	// cannot be deleted without purging.
+	Purge bool `json:"purge"`
+}
+
+// BucketClaimStatus is the status of the claim that the operator records in the claim resource
+type BucketClaimStatus struct {
+	// Whether the claim is bound to the bucket
+	State BucketClaimState `json:"state,omitempty"`
+
+	// The name of the bucket bound to the claim. Only the bucket bound to the claim is deleted with the claim.
+	BucketName string `json:"bucketName,omitempty"`
+
+	// Whether the dedicated owner of the bucket was created for the claim
+	OwnerCreated bool `json:"ownerCreated,omitempty"`
+
+	// The reason the bucket could not be provisioned
+	Message string `json:"message,omitempty"`
+}
+
+// Create the bucket and its owner and publish the bucket settings and credentials
+func (b *BucketClaim) Create(context *clusterd.Context, rclient rookclient.RookRestClient) error {
+	return b.Update(context, rclient)
+}
+
+// Update ensures the bucket and its dedicated owner exist in the object store and that the config map and the secret
+// of the claim are up to date. The bucket of a bound claim cannot be changed, and a bucket owned by anyone else than
+// the owner of the claim is not bound. The bucket and the owner are recorded in the status of the claim, which the
+// caller must save.
+func (b *BucketClaim) Update(context *clusterd.Context, rclient rookclient.RookRestClient) error {
+	if err := b.validate(); err != nil {
+		return fmt.Errorf("invalid bucket claim %s arguments. %+v", b.Name, err)
+	}
+
+	bucketName := b.Bucket()
+	displayName := fmt.Sprintf("bucket claim %s/%s", b.Namespace, b.Name)
+	user, err := applyObjectUser(rclient, model.ObjectUser{UserID: b.ownerID(), DisplayName: &displayName}, b.Status.OwnerCreated)
+	if err != nil {
+		return err
+	}
+	b.Status.OwnerCreated = true
+
+	info, err := rclient.GetObjectStoreConnectionInfo()
+	if err != nil {
+		return fmt.Errorf("failed to get the object store connection info. %+v", err)
+	}
+	if info == nil {
+		return fmt.Errorf("the object store connection info is not available")
+	}
+	_, port, err := net.SplitHostPort(info.IPEndpoint)
+	if err != nil {
+		return fmt.Errorf("invalid object store endpoint %s. %+v", info.IPEndpoint, err)
+	}
+
+	bucket, err := rclient.GetBucket(bucketName)
+	if err != nil && !rookclient.IsHttpNotFound(err) {
+		return fmt.Errorf("failed to get bucket %s. %+v", bucketName, err)
+	}
+	if err == nil && bucket != nil {
+		if bucket.Owner != user.UserID {
+			return fmt.Errorf("bucket %s is owned by %s and cannot be bound to the claim", bucketName, bucket.Owner)
+		}
+	} else {
+		logger.Infof("creating bucket %s for claim %s in namespace %s", bucketName, b.Name, b.Namespace)
+		if err := rclient.CreateBucket(model.ObjectBucketRequest{Name: bucketName, Owner: user.UserID}); err != nil {
+			return fmt.Errorf("failed to create bucket %s. %+v", bucketName, err)
+		}
+	}
+	b.Status.BucketName = bucketName
+
+	// the service of the object store is in the namespace of the cluster
+	err = saveConfigMap(context, b.Namespace, b.Name, map[string]string{
+		BucketNameKey: bucketName,
+		BucketHostKey: fmt.Sprintf("%s.%s", info.Host, b.clusterNamespace()),
+		BucketPortKey: port,
+	})
+	if err != nil {
+		return err
+	}
+
+	return saveSecret(context, b.Namespace, b.Name, string(b.UID), map[string]string{
+		ObjectUserAccessKey: *user.AccessKey,
+		ObjectUserSecretKey: *user.SecretKey,
+	})
+}
+
+// Delete the config map and the secret of the claim. The bucket bound to the claim and the owner created for the claim
+// are also deleted unless the reclaim policy retains them.
+func (b *BucketClaim) Delete(context *clusterd.Context, rclient rookclient.RookRestClient) error {
+	if b.ReclaimPolicy == BucketReclaimRetain {
+		logger.Infof("retaining bucket %s of deleted claim %s in namespace %s", b.Status.BucketName, b.Name, b.Namespace)
+	} else {
+		if b.Status.BucketName != "" {
+			logger.Infof("deleting bucket %s of claim %s in namespace %s (purge=%t)", b.Status.BucketName, b.Name, b.Namespace, b.Purge)
+			if err := rclient.DeleteBucket(b.Status.BucketName, b.Purge); err != nil && !rookclient.IsHttpNotFound(err) {
+				return fmt.Errorf("failed to delete bucket %s. %+v", b.Status.BucketName, err)
+			}
+		}
+		if b.Status.OwnerCreated {
+			if err := rclient.DeleteObjectUser(b.ownerID()); err != nil && !rookclient.IsHttpNotFound(err) {
+				return fmt.Errorf("failed to delete the owner %s of the claim. %+v", b.ownerID(), err)
+			}
+		}
+	}
+
+	if err := k8sutil.DeleteResource("bucket claim config map", b.Name, context.Clientset.CoreV1().ConfigMaps(b.Namespace).Delete); err != nil {
+		return err
+	}
+	return deleteSecret(context, b.Namespace, b.Name, string(b.UID))
+}
+
+// InCluster returns whether the claim requests a bucket from the cluster in the namespace
+func (b *BucketClaim) InCluster(namespace string) bool {
+	return b.clusterNamespace() == namespace
+}
+
+// Bucket returns the name of the bucket of the claim. Once the claim is bound the bucket in the status is used.
+func (b *BucketClaim) Bucket() string {
+	if b.Status.BucketName != "" {
+		return b.Status.BucketName
+	}
+	return b.requestedBucketName()
+}
+
+func (b *BucketClaim) requestedBucketName() string {
+	if b.BucketName != "" {
+		return b.BucketName
+	}
+	return fmt.Sprintf("%s-%s", b.Namespace, b.Name)
+}
+
+// ownerID returns the id of the dedicated object store user that owns the bucket. The id is unique to the claim, so a
+// claim recreated with the same name does not take over a retained bucket.
+func (b *BucketClaim) ownerID() string {
+	return bucketOwnerPrefix + string(b.UID)
+}
+
+func (b *BucketClaim) clusterNamespace() string {
+	if b.ClusterNamespace != "" {
+		return b.ClusterNamespace
+	}
+	return k8sutil.Namespace
+}
+
+// Validate the claim arguments
+func (b *BucketClaim) validate() error {
+	if b.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if b.Namespace == "" {
+		return fmt.Errorf("missing namespace")
+	}
+	if b.UID == "" {
+		return fmt.Errorf("missing uid")
+	}
+	if !bucketNameRegex.MatchString(b.requestedBucketName()) {
+		return fmt.Errorf("invalid bucket name %s", b.requestedBucketName())
+	}
+	if b.Status.BucketName != "" && b.Status.BucketName != b.requestedBucketName() {
+		return fmt.Errorf("the claim is bound to bucket %s. the bucket cannot be changed", b.Status.BucketName)
+	}
+	if b.ReclaimPolicy != "" && b.ReclaimPolicy != BucketReclaimDelete && b.ReclaimPolicy != BucketReclaimRetain {
+		return fmt.Errorf("invalid reclaim policy %s", b.ReclaimPolicy)
+	}
+	return nil
+}
+
+// saveConfigMap creates the config map with the given data or updates it if the data changed
+func saveConfigMap(context *clusterd.Context, namespace, name string, data map[string]string) error {
+	configMaps := context.Clientset.CoreV1().ConfigMaps(namespace)
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       data,
+	}
+
+	existing, err := configMaps.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get config map %s. %+v", name, err)
+		}
+		if _, err := configMaps.Create(configMap); err != nil {
+			return fmt.Errorf("failed to create config map %s. %+v", name, err)
+		}
+		logger.Infof("created config map %s in namespace %s", name, namespace)
+		return nil
+	}
+
+	if mapsEqual(existing.Data, data) {
+		return nil
+	}
+	configMap.ResourceVersion = existing.ResourceVersion
+	if _, err := configMaps.Update(configMap); err != nil {
+		return fmt.Errorf("failed to update config map %s. %+v", name, err)
+	}
+	logger.Infof("updated config map %s in namespace %s", name, namespace)
+	return nil
+}
+
+func mapsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/rook/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateBucketClaim(t *testing.T) {
+	b := BucketClaim{ObjectMeta: v1.ObjectMeta{Name: "photos", Namespace: "app", UID: "uid1"}}
+	assert.Nil(t, b.validate())
+	assert.Equal(t, "app-photos", b.Bucket())
+	assert.True(t, b.InCluster("rook"))
+
+	// the bucket name must follow the s3 naming rules
+	b.BucketName = "My_Bucket"
+	assert.NotNil(t, b.validate())
+	b.BucketName = "my-bucket"
+	assert.Nil(t, b.validate())
+
+	// the bucket of a bound claim cannot be changed
+	b.Status.BucketName = "app-photos"
+	assert.NotNil(t, b.validate())
+	assert.Equal(t, "app-photos", b.Bucket())
+
+	b.Status.BucketName = ""
+	b.ReclaimPolicy = "Recycle"
+	assert.NotNil(t, b.validate())
+
+	b.ReclaimPolicy = ""
+	b.UID = ""
+	assert.NotNil(t, b.validate())
+}
+
+func TestBucketClaim(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}
+
+	accessKey := "key1"
+	secretKey := "secret1"
+	users := map[string]bool{}
+	buckets := map[string]string{}
+	deletedBuckets := map[string]bool{}
+	rclient := &test.MockRookRestClient{
+		MockGetObjectUser: func(id string) (*model.ObjectUser, error) {
+			return nil, rookclient.RookRestError{Status: http.StatusNotFound}
+		},
+		MockCreateObjectUser: func(user model.ObjectUser) (*model.ObjectUser, error) {
+			users[user.UserID] = true
+			user.AccessKey = &accessKey
+			user.SecretKey = &secretKey
+			return &user, nil
+		},
+		MockDeleteObjectUser: func(id string) error {
+			delete(users, id)
+			return nil
+		},
+		MockGetBucket: func(name string) (*model.ObjectBucket, error) {
+			if owner, ok := buckets[name]; ok {
+				return &model.ObjectBucket{Name: name, ObjectBucketMetadata: model.ObjectBucketMetadata{Owner: owner}}, nil
+			}
+			return nil, rookclient.RookRestError{Status: http.StatusNotFound}
+		},
+		MockCreateBucket: func(bucket model.ObjectBucketRequest) error {
+			buckets[bucket.Name] = bucket.Owner
+			return nil
+		},
+		MockDeleteBucket: func(name string, purge bool) error {
+			delete(buckets, name)
+			deletedBuckets[name] = purge
+			return nil
+		},
+		MockGetObjectStoreConnectionInfo: func() (*model.ObjectStoreConnectInfo, error) {
+			return &model.ObjectStoreConnectInfo{Host: "rook-ceph-rgw", IPEndpoint: "1.2.3.4:53390"}, nil
+		},
+	}
+
+	// the bucket is created with a dedicated owner
+	b := BucketClaim{ObjectMeta: v1.ObjectMeta{Name: "photos", Namespace: "app", UID: "uid1"}}
+	b.Purge = true
+	err := b.Create(context, rclient)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"app-photos": "rook-bucket-uid1"}, buckets)
+	assert.True(t, users["rook-bucket-uid1"])
+	assert.Equal(t, "app-photos", b.Status.BucketName)
+	assert.True(t, b.Status.OwnerCreated)
+
+	// a bucket owned by another claim is not bound
+	other := BucketClaim{ObjectMeta: v1.ObjectMeta{Name: "other", Namespace: "app2", UID: "uid2"}}
+	other.BucketName = "app-photos"
+	err = other.Create(context, rclient)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", other.Status.BucketName)
+	assert.True(t, other.Status.OwnerCreated)
+	_, err = clientset.CoreV1().Secrets("app2").Get("other", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// only the owner created for the claim is removed when the unbound claim is deleted
+	err = other.Delete(context, rclient)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deletedBuckets))
+	assert.Equal(t, "rook-bucket-uid1", buckets["app-photos"])
+	assert.False(t, users["rook-bucket-uid2"])
+
+	// the bucket settings and the credentials are published in the namespace of the claim
+	configMap, err := clientset.CoreV1().ConfigMaps("app").Get("photos", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"BUCKET_NAME": "app-photos", "BUCKET_HOST": "rook-ceph-rgw.rook", "BUCKET_PORT": "53390"}, configMap.Data)
+	secret, err := clientset.CoreV1().Secrets("app").Get("photos", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"AWS_ACCESS_KEY_ID": "key1", "AWS_SECRET_ACCESS_KEY": "secret1"}, secret.StringData)
+
+	// the bucket is purged and the owner is removed when the claim is deleted
+	err = b.Delete(context, rclient)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"app-photos": true}, deletedBuckets)
+	assert.Equal(t, 0, len(users))
+	_, err = clientset.CoreV1().ConfigMaps("app").Get("photos", metav1.GetOptions{})
+	assert.NotNil(t, err)
+	_, err = clientset.CoreV1().Secrets("app").Get("photos", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// the bucket is retained with the retain policy
+	b = BucketClaim{ObjectMeta: v1.ObjectMeta{Name: "logs", Namespace: "app", UID: "uid3"}}
+	b.ReclaimPolicy = BucketReclaimRetain
+	err = b.Create(context, rclient)
+	assert.Nil(t, err)
+	err = b.Delete(context, rclient)
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-bucket-uid3", buckets["app-logs"])
+	assert.True(t, users["rook-bucket-uid3"])
+	_, err = clientset.CoreV1().ConfigMaps("app").Get("logs", metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rook/rook/pkg/ceph/client"
+	cephmon "github.com/rook/rook/pkg/ceph/mon"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	// the settings that rook generates from the cluster spec and the state of the cluster
+	managedCephSettings = []string{"fsid", "run_dir", "mon_initial_members", "mon_host", "mon_addr", "public_addr",
+		"cluster_addr", "public_network", "cluster_network", "keyring", "osd_data", "osd_objectstore", "log_file"}
+
+	// the types of the daemons and clients that read a section of the ceph config
+	cephConfigSectionTypes = []string{"mon", "osd", "mds", "mgr", "client"}
+)
+
+// applyCephConfig validates the ceph settings in the spec against the running ceph version and saves the valid
+// settings in the config override configmap, where the daemons read them when they start. The changes to the running
+// daemons are applied by the config override check. The settings that are not valid are reported in the status.
+func (c *Cluster) applyCephConfig(settings map[string]map[string]string) error {
+	valid, rejected, validated, err := c.validateCephConfig(settings)
+	if err != nil {
+		return err
+	}
+
+	content := ""
+	if len(valid) > 0 {
+		content, err = cephmon.SerializeCephConfigSettings(valid)
+		if err != nil {
+			return err
+		}
+	}
+
+	cm, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get override configmap. %+v", err)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	if current, ok := cm.Data[k8sutil.CephConfigVal]; !ok || current != content {
+		cm.Data[k8sutil.CephConfigVal] = content
+		if _, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Update(cm); err != nil {
+			return fmt.Errorf("failed to save ceph config settings in override configmap. %+v", err)
+		}
+		logger.Infof("saved ceph config settings for cluster in namespace %s", c.Namespace)
+	}
+
+	for _, r := range rejected {
+		logger.Warningf("rejected ceph setting %s in section %s. %s", r.Name, r.Section, r.Reason)
+	}
+	if !validated {
+		logger.Warningf("ceph settings for cluster in namespace %s were applied without validation", c.Namespace)
+	}
+	c.updateStatus(func(s *ClusterStatus) {
+		s.RejectedCephConfig = rejected
+		s.UnvalidatedCephConfig = !validated
+	})
+	return nil
+}
+
+// validateCephConfig returns the settings that ceph accepts and the settings that are rejected with the reason. The
+// sections must be global, a daemon type such as osd, or a daemon such as osd.3. The settings that rook generates
+// cannot be set. The names are checked against the settings known to the running ceph version. Returns false if the
+// ceph version cannot list its settings, in which case the names are not checked. Returns an error if ceph cannot be
+// queried to validate the settings.
+func (c *Cluster) validateCephConfig(settings map[string]map[string]string) (map[string]map[string]string, []RejectedCephConfigSetting, bool, error) {
+	valid := map[string]map[string]string{}
+	var rejected []RejectedCephConfigSetting
+	if len(settings) == 0 {
+		return valid, rejected, true, nil
+	}
+
+	options, validated, err := client.ListConfigOptions(c.context, c.Namespace)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to validate ceph settings. %+v", err)
+	}
+
+	sections := []string{}
+	for section := range settings {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	for _, section := range sections {
+		keys := []string{}
+		for key := range settings[section] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := settings[section][key]
+			reason := validateCephSetting(section, key, options, validated)
+			if reason != "" {
+				rejected = append(rejected, RejectedCephConfigSetting{Section: section, Name: key, Value: value, Reason: reason})
+				continue
+			}
+
+			if valid[section] == nil {
+				valid[section] = map[string]string{}
+			}
+			valid[section][key] = value
+		}
+	}
+
+	return valid, rejected, validated, nil
+}
+
+// validateCephSetting returns the reason the setting is rejected, or an empty reason if the setting is valid. The
+// name is only checked against the known options if the options were listed.
+func validateCephSetting(section, key string, options map[string]bool, listed bool) string {
+	if !validCephConfigSection(section) {
+		return fmt.Sprintf("unknown section %s", section)
+	}
+
+	name := cephmon.NormalizeConfigKey(key)
+	for _, managed := range managedCephSettings {
+		if name == managed {
+			return "the setting is managed by rook"
+		}
+	}
+
+	if listed && !options[name] {
+		return "unknown setting"
+	}
+	return ""
+}
+
+// validCephConfigSection returns whether ceph reads the section for the daemons and clients
+func validCephConfigSection(section string) bool {
+	if section == "global" {
+		return true
+	}
+	for _, sectionType := range cephConfigSectionTypes {
+		if section == sectionType || (strings.HasPrefix(section, sectionType+".") && len(section) > len(sectionType)+1) {
+			return true
+		}
+	}
+	return false
+}
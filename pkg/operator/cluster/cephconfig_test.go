@@ -0,0 +1,118 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyCephConfig(t *testing.T) {
+	clientset := testop.New(1)
+	cephAvailable := true
+	listSupported := true
+	calls := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			calls++
+			if !cephAvailable {
+				return "", fmt.Errorf("mock failure")
+			}
+			assert.Equal(t, []string{"config", "ls"}, args[0:2])
+			if !listSupported {
+				return "Error EINVAL: invalid command", fmt.Errorf("exit status 22")
+			}
+			return `["osd_max_backfills","osd_pool_default_size","fsid"]`, nil
+		},
+	}
+	c := &Cluster{}
+	c.Namespace = "ns"
+	c.Init(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}, Executor: executor})
+	c.statusWriter = func(status ClusterStatus) error { return nil }
+	assert.Nil(t, c.createConfigOverride())
+
+	settings := map[string]map[string]string{
+		"global":  {"osd pool default size": "3", "fsid": "myfsid", "no_such_setting": "1"},
+		"osd.1":   {"osd_max_backfills": "3"},
+		"osd":     {"osd_max_backfills": "2"},
+		"bogus.1": {"osd_max_backfills": "2"},
+	}
+	err := c.applyCephConfig(settings)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, calls)
+
+	// the valid settings are saved for the daemons
+	cm, err := clientset.CoreV1().ConfigMaps(c.Namespace).Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", cm.Data[k8sutil.ConfigOverrideVal])
+	saved := cm.Data[k8sutil.CephConfigVal]
+	assert.Contains(t, saved, "[global]")
+	assert.Contains(t, saved, "osd pool default size = 3")
+	assert.Contains(t, saved, "[osd]")
+	assert.Contains(t, saved, "osd_max_backfills = 2")
+	assert.Contains(t, saved, "[osd.1]")
+	assert.NotContains(t, saved, "fsid")
+	assert.NotContains(t, saved, "no_such_setting")
+
+	// the rejected settings are reported in the status
+	assert.Equal(t, []RejectedCephConfigSetting{
+		{Section: "bogus.1", Name: "osd_max_backfills", Value: "2", Reason: "unknown section bogus.1"},
+		{Section: "global", Name: "fsid", Value: "myfsid", Reason: "the setting is managed by rook"},
+		{Section: "global", Name: "no_such_setting", Value: "1", Reason: "unknown setting"},
+	}, c.Status.RejectedCephConfig)
+	assert.False(t, c.Status.UnvalidatedCephConfig)
+
+	// the names are not validated on ceph versions that cannot list the settings
+	listSupported = false
+	err = c.applyCephConfig(settings)
+	assert.Nil(t, err)
+	cm, err = clientset.CoreV1().ConfigMaps(c.Namespace).Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, cm.Data[k8sutil.CephConfigVal], "no_such_setting = 1")
+	assert.NotContains(t, cm.Data[k8sutil.CephConfigVal], "fsid")
+	assert.Equal(t, []RejectedCephConfigSetting{
+		{Section: "bogus.1", Name: "osd_max_backfills", Value: "2", Reason: "unknown section bogus.1"},
+		{Section: "global", Name: "fsid", Value: "myfsid", Reason: "the setting is managed by rook"},
+	}, c.Status.RejectedCephConfig)
+	assert.True(t, c.Status.UnvalidatedCephConfig)
+	listSupported = true
+
+	// the previous settings are kept when ceph is not available to validate the settings
+	cephAvailable = false
+	err = c.applyCephConfig(map[string]map[string]string{"osd": {"osd_max_backfills": "4"}})
+	assert.NotNil(t, err)
+	cm, err = clientset.CoreV1().ConfigMaps(c.Namespace).Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, cm.Data[k8sutil.CephConfigVal], "osd_max_backfills = 2")
+
+	// the settings are removed with the rejections
+	cephAvailable = true
+	err = c.applyCephConfig(nil)
+	assert.Nil(t, err)
+	cm, err = clientset.CoreV1().ConfigMaps(c.Namespace).Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", cm.Data[k8sutil.CephConfigVal])
+	assert.Nil(t, c.Status.RejectedCephConfig)
+	assert.False(t, c.Status.UnvalidatedCephConfig)
+}
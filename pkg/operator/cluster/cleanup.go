@@ -0,0 +1,193 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/mon"
+	"github.com/rook/rook/pkg/operator/osd"
+	"github.com/rook/rook/pkg/util"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+const (
+	cleanupAppName    = "rook-cleanup"
+	cleanupJobNameFmt = "rook-cleanup-%s"
+)
+
+// getNodesWithData returns the nodes where the mons and osds have stored data
+func (c *Cluster) getNodesWithData(mons *mon.Cluster, osds *osd.Cluster) (*util.Set, error) {
+	nodes, err := mons.GetNodesWithMons()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the nodes with mons. %+v", err)
+	}
+	osdNodes, err := osds.GetNodesWithOSDs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the nodes with osds. %+v", err)
+	}
+	nodes.AddSet(osdNodes)
+	nodes.Remove("")
+	return nodes, nil
+}
+
+// waitForDataPodsToTerminate waits until the mon and osd pods are gone so the cleanup does not remove data in use
+func (c *Cluster) waitForDataPodsToTerminate(mons *mon.Cluster, osds *osd.Cluster) error {
+	for i := 0; i < c.context.MaxRetries; i++ {
+		nodes, err := c.getNodesWithData(mons, osds)
+		if err != nil {
+			return err
+		}
+		if nodes.Count() == 0 {
+			return nil
+		}
+
+		logger.Infof("waiting for the mon and osd pods to terminate on nodes %+v", nodes.ToSlice())
+		<-time.After(time.Duration(c.context.RetryDelay) * time.Second)
+	}
+
+	return fmt.Errorf("timed out waiting for the mon and osd pods to terminate")
+}
+
+// cleanupHosts starts the cleanup jobs after the mon and osd pods are gone. The privileged jobs are deleted after they
+// complete, or when they do not complete in time.
+func (c *Cluster) cleanupHosts(mons *mon.Cluster, osds *osd.Cluster, nodes *util.Set, fsid string) {
+	if err := c.waitForDataPodsToTerminate(mons, osds); err != nil {
+		logger.Errorf("failed to clean up the hosts of cluster %s. %+v", c.Namespace, err)
+		return
+	}
+	defer c.deleteCleanupJobs(nodes)
+	if err := c.startCleanupJobs(nodes, fsid); err != nil {
+		logger.Errorf("failed to clean up the hosts of cluster %s. %+v", c.Namespace, err)
+		return
+	}
+	if err := c.waitForCleanupJobs(nodes); err != nil {
+		logger.Errorf("failed to clean up the hosts of cluster %s. %+v", c.Namespace, err)
+	}
+}
+
+// startCleanupJobs starts a job on each of the nodes to remove the data and the osd partitions of the cluster
+func (c *Cluster) startCleanupJobs(nodes *util.Set, fsid string) error {
+	for nodeName := range nodes.Iter() {
+		job := c.makeCleanupJob(nodeName, fsid)
+		_, err := c.context.Clientset.BatchV1().Jobs(c.Namespace).Create(job)
+		if err != nil {
+			if !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create cleanup job for node %s. %+v", nodeName, err)
+			}
+			logger.Infof("cleanup job for node %s already exists", nodeName)
+		} else {
+			logger.Infof("cleanup job started for node %s", nodeName)
+		}
+	}
+
+	return nil
+}
+
+// waitForCleanupJobs waits until the cleanup jobs on all the nodes have completed
+func (c *Cluster) waitForCleanupJobs(nodes *util.Set) error {
+	jobs := c.context.Clientset.BatchV1().Jobs(c.Namespace)
+	pending := nodes.Copy()
+	for i := 0; i < c.context.MaxRetries; i++ {
+		for _, nodeName := range pending.ToSlice() {
+			job, err := jobs.Get(fmt.Sprintf(cleanupJobNameFmt, nodeName), metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get cleanup job for node %s. %+v", nodeName, err)
+			}
+			if job.Status.Succeeded > 0 {
+				logger.Infof("cleanup job for node %s completed", nodeName)
+				pending.Remove(nodeName)
+			}
+		}
+		if pending.Count() == 0 {
+			return nil
+		}
+
+		logger.Infof("waiting for the cleanup jobs on nodes %+v to complete", pending.ToSlice())
+		<-time.After(time.Duration(c.context.RetryDelay) * time.Second)
+	}
+
+	return fmt.Errorf("timed out waiting for the cleanup jobs on nodes %+v", pending.ToSlice())
+}
+
+// deleteCleanupJobs removes the cleanup jobs and their pods from the nodes
+func (c *Cluster) deleteCleanupJobs(nodes *util.Set) {
+	jobs := c.context.Clientset.BatchV1().Jobs(c.Namespace)
+	for nodeName := range nodes.Iter() {
+		name := fmt.Sprintf(cleanupJobNameFmt, nodeName)
+		if err := k8sutil.DeleteResource("cleanup job", name, jobs.Delete); err != nil {
+			logger.Warningf("failed to delete job %s. %+v", name, err)
+		}
+	}
+}
+
+func (c *Cluster) makeCleanupJob(nodeName, fsid string) *batch.Job {
+	dataDirSource := v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
+	if c.Spec.DataDirHostPath != "" {
+		dataDirSource = v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: c.Spec.DataDirHostPath}}
+	}
+
+	privileged := true
+	container := v1.Container{
+		Args: []string{
+			"cleanup",
+			fmt.Sprintf("--config-dir=%s", k8sutil.DataDir),
+			fmt.Sprintf("--cluster-name=%s", c.Namespace),
+			fmt.Sprintf("--fsid=%s", fsid),
+		},
+		Name:  cleanupAppName,
+		Image: k8sutil.MakeRookImage(c.Spec.VersionTag),
+		VolumeMounts: []v1.VolumeMount{
+			{Name: k8sutil.DataDirVolume, MountPath: k8sutil.DataDir},
+			{Name: "devices", MountPath: "/dev"},
+		},
+		SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+	}
+
+	labels := map[string]string{
+		k8sutil.AppAttr:     cleanupAppName,
+		k8sutil.ClusterAttr: c.Namespace,
+	}
+	return &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf(cleanupJobNameFmt, nodeName),
+			Namespace: c.Namespace,
+			Labels:    labels,
+		},
+		Spec: batch.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: v1.PodSpec{
+					Containers:    []v1.Container{container},
+					RestartPolicy: v1.RestartPolicyOnFailure,
+					NodeSelector:  map[string]string{apis.LabelHostname: nodeName},
+					Volumes: []v1.Volume{
+						{Name: k8sutil.DataDirVolume, VolumeSource: dataDirSource},
+						{Name: "devices", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/dev"}}},
+					},
+				},
+			},
+		},
+	}
+}
@@ -23,6 +23,8 @@ package cluster
 import (
 	"fmt"
 	"net/http"
+	"reflect"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -34,11 +36,13 @@ import (
 	"github.com/rook/rook/pkg/operator/api"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/kit"
+	"github.com/rook/rook/pkg/operator/mds"
 	"github.com/rook/rook/pkg/operator/mgr"
 	"github.com/rook/rook/pkg/operator/mon"
 	"github.com/rook/rook/pkg/operator/osd"
 	"github.com/rook/rook/pkg/operator/rgw"
 	rookclient "github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/util"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -66,21 +70,60 @@ type Cluster struct {
 	context       *clusterd.Context
 	v1.ObjectMeta `json:"metadata,omitempty"`
 	Spec          `json:"spec"`
+	Status        ClusterStatus `json:"status,omitempty"`
 	mons          *mon.Cluster
 	mgrs          *mgr.Cluster
 	osds          *osd.Cluster
 	apis          *api.Cluster
 	rgws          *rgw.Cluster
 	rclient       rookclient.RookRestClient
+	statusWriter  func(status ClusterStatus) error
+	// the content of the config override that was last applied to the daemons, nil if not known
+	configOverride *string
+	// serializes the changes to the spec and the daemons from the cluster events and the health checks
+	lock sync.Mutex
+	// serializes the changes to the status from the health checks, the cluster events and the upgrade
+	statusLock sync.Mutex
+	// whether the daemons are upgraded or restarted for the config override in the background
+	rolling bool
+	// closed when the restart of the daemons in the background completes
+	rollDone chan struct{}
+	// whether the cluster was deleted. the daemons of a stopped cluster are not created or changed.
+	stopped bool
+	// the latest spec received while the daemons were being restarted, applied after the restart
+	pendingSpec *Spec
 }
 
 // Init assigns the cluster context
 func (c *Cluster) Init(context *clusterd.Context) {
 	c.context = context
+	c.statusWriter = c.writeStatus
 }
 
 // CreateInstance creates a new Rook cluster instance
 func (c *Cluster) CreateInstance() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.stopped {
+		return fmt.Errorf("cluster in namespace %s was deleted", c.Namespace)
+	}
+
+	c.setPhase(ClusterPhaseCreating, reasonCreating, "")
+	if err := c.createInstance(); err != nil {
+		c.setPhase(ClusterPhaseFailed, reasonCreateFailed, err.Error())
+		return err
+	}
+	if !c.rolling {
+		c.setPhase(ClusterPhaseCreated, reasonCreated, "")
+	}
+	return nil
+}
+
+// createInstance starts the cluster daemons or updates them if they are already running
+func (c *Cluster) createInstance() error {
+	if err := c.Spec.Network.Validate(); err != nil {
+		return fmt.Errorf("invalid network settings for cluster in namespace %s. %+v", c.Namespace, err)
+	}
 
 	// Create the namespace if not already created
 	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: c.Namespace}}
@@ -93,42 +136,47 @@ func (c *Cluster) CreateInstance() error {
 
 	// Create a configmap for overriding ceph config settings
 	// These settings should only be modified by a user after they are initialized
-	placeholderConfig := map[string]string{
-		k8sutil.ConfigOverrideVal: "",
+	if err := c.createConfigOverride(); err != nil {
+		return err
 	}
-	cm := &v1.ConfigMap{Data: placeholderConfig}
-	cm.Name = k8sutil.ConfigOverrideName
-	_, err = c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Create(cm)
-	if err != nil && !errors.IsAlreadyExists(err) {
-		return fmt.Errorf("failed to create override configmap %s. %+v", c.Namespace, err)
+	configOverrideHash, err := c.loadConfigOverride()
+	if err != nil {
+		return fmt.Errorf("failed to load the config override. %+v", err)
 	}
 
 	// Start the mon pods
-	c.mons = mon.New(c.context, c.Namespace, c.Spec.DataDirHostPath, c.Spec.VersionTag, c.Spec.Placement.GetMON())
+	c.mons = mon.New(c.context, c.Namespace, c.Spec.DataDirHostPath, c.Spec.VersionTag, c.Spec.Placement.GetMON(), c.Spec.Resources.MON, c.Spec.Network)
+	c.mons.ConfigOverrideHash = configOverrideHash
 	clusterInfo, err := c.mons.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start the mons. %+v", err)
 	}
 
+	// the ceph settings are validated by the mons
+	if err := c.applyCephConfig(c.Spec.CephConfig); err != nil {
+		return fmt.Errorf("failed to apply the ceph config settings. %+v", err)
+	}
+
 	err = c.createInitialCrushMap()
 	if err != nil {
 		return fmt.Errorf("failed to create initial crushmap: %+v", err)
 	}
 
-	c.mgrs = mgr.New(c.context, c.Namespace, c.Spec.VersionTag)
+	c.mgrs = mgr.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Placement.GetMGR(), c.Spec.Resources.MGR, c.Spec.Network)
 	err = c.mgrs.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start the ceph mgr. %+v", err)
 	}
 
-	c.apis = api.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Placement.GetAPI())
+	c.apis = api.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Placement.GetAPI(), c.Spec.Resources.API)
 	err = c.apis.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start the REST api. %+v", err)
 	}
 
 	// Start the OSDs
-	c.osds = osd.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Storage, c.Spec.DataDirHostPath, c.Spec.Placement.GetOSD())
+	c.osds = osd.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Storage, c.Spec.DataDirHostPath, c.Spec.Placement.GetOSD(), c.Spec.Resources.OSD, c.Spec.Network)
+	c.osds.ConfigOverrideHash = configOverrideHash
 	err = c.osds.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start the osds. %+v", err)
@@ -139,10 +187,284 @@ func (c *Cluster) CreateInstance() error {
 		return fmt.Errorf("failed to create client access. %+v", err)
 	}
 
+	if status := c.upgradeStatus(); status != nil && status.ToVersion == c.Spec.VersionTag && !c.rolling {
+		// resume the upgrade that was interrupted
+		c.startUpgrade(c.Spec)
+	}
+
 	logger.Infof("Done creating rook instance in namespace %s", c.Namespace)
 	return nil
 }
 
+// Update applies the changes in the new spec to the running cluster. If any of the changes cannot be applied
+// to an existing cluster, the update is rejected and the cluster continues running with its current spec.
+// While the cluster is paused, changes to the spec are not applied until the cluster is resumed.
+func (c *Cluster) Update(newSpec Spec) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.applySpec(newSpec)
+}
+
+// GetSpec returns the spec that is applied to the cluster
+func (c *Cluster) GetSpec() Spec {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Spec
+}
+
+// Stop cancels the creation of the cluster and the restart of the daemons in the background before the cluster is
+// deleted. The creation holds the cluster lock, so no creation is in progress when Stop returns. The restart of the
+// daemons stops at its next step and Stop waits for it to complete.
+func (c *Cluster) Stop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stopped = true
+	for c.rolling {
+		logger.Infof("waiting for the restart of the daemons of cluster in namespace %s to stop", c.Namespace)
+		done := c.rollDone
+		c.lock.Unlock()
+		<-done
+		c.lock.Lock()
+	}
+}
+
+// IsStopped returns whether the cluster was stopped to be deleted
+func (c *Cluster) IsStopped() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stopped
+}
+
+// IsPaused returns whether the operator control of the cluster is paused
+func (c *Cluster) IsPaused() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Spec.Paused
+}
+
+// applySpec validates and applies the new spec. The cluster lock must be held.
+func (c *Cluster) applySpec(newSpec Spec) error {
+	if c.stopped {
+		return fmt.Errorf("cluster in namespace %s was deleted", c.Namespace)
+	}
+	if newSpec.Paused {
+		c.pause()
+		return nil
+	}
+
+	if c.rolling {
+		// the upgrade stops at the next step if the cluster was paused, and is resumed when the spec is applied
+		c.Spec.Paused = false
+		c.mons.Paused = false
+		logger.Infof("the spec of cluster in namespace %s is applied when the restart of the daemons completes", c.Namespace)
+		c.pendingSpec = &newSpec
+		return nil
+	}
+
+	if err := c.validateUpdate(newSpec); err != nil {
+		err = fmt.Errorf("invalid update for cluster in namespace %s. %+v", c.Namespace, err)
+		c.setPhase(ClusterPhaseFailed, reasonUpdateRejected, err.Error())
+		return err
+	}
+
+	if c.Spec.Paused {
+		return c.resume(newSpec)
+	}
+
+	if reflect.DeepEqual(c.Spec, newSpec) {
+		logger.Infof("no changes to the spec of cluster in namespace %s", c.Namespace)
+		if c.Status.Phase == ClusterPhaseFailed {
+			if ready := c.Status.getCondition(ClusterConditionReady); ready != nil && ready.Reason == reasonUpdateRejected {
+				// the rejected changes were reverted
+				c.setPhase(ClusterPhaseCreated, reasonUpdated, "")
+			}
+		}
+		return nil
+	}
+
+	if c.mons == nil || c.apis == nil || c.osds == nil {
+		return fmt.Errorf("cluster in namespace %s has not finished being created", c.Namespace)
+	}
+
+	c.setPhase(ClusterPhaseUpdating, reasonUpdating, "")
+	if newSpec.VersionTag != c.Spec.VersionTag {
+		// the other changes are applied after the upgrade
+		c.startUpgrade(newSpec)
+		return nil
+	}
+	if err := c.update(newSpec); err != nil {
+		c.setPhase(ClusterPhaseFailed, reasonUpdateFailed, err.Error())
+		return err
+	}
+	c.setPhase(ClusterPhaseCreated, reasonUpdated, "")
+	return nil
+}
+
+// update applies the changes in the new spec to the running daemons. The version is changed by an upgrade.
+func (c *Cluster) update(newSpec Spec) error {
+	logger.Infof("updating cluster in namespace %s", c.Namespace)
+	if !reflect.DeepEqual(c.Spec.CephConfig, newSpec.CephConfig) {
+		if err := c.applyCephConfig(newSpec.CephConfig); err != nil {
+			return fmt.Errorf("failed to apply the ceph config settings. %+v", err)
+		}
+	}
+
+	if !reflect.DeepEqual(c.Spec.Placement.GetMON(), newSpec.Placement.GetMON()) {
+		c.mons.UpdatePlacement(newSpec.Placement.GetMON())
+	}
+
+	if !reflect.DeepEqual(c.Spec.Resources.MON, newSpec.Resources.MON) {
+		c.mons.UpdateResources(newSpec.Resources.MON)
+	}
+
+	if !reflect.DeepEqual(c.Spec.Placement.GetAPI(), newSpec.Placement.GetAPI()) ||
+		!reflect.DeepEqual(c.Spec.Resources.API, newSpec.Resources.API) {
+		c.apis = api.New(c.context, c.Namespace, newSpec.VersionTag, newSpec.Placement.GetAPI(), newSpec.Resources.API)
+		if err := c.apis.Update(); err != nil {
+			return fmt.Errorf("failed to update the REST api. %+v", err)
+		}
+	}
+
+	if !reflect.DeepEqual(c.Spec.Placement.GetMGR(), newSpec.Placement.GetMGR()) ||
+		!reflect.DeepEqual(c.Spec.Resources.MGR, newSpec.Resources.MGR) {
+		c.mgrs = mgr.New(c.context, c.Namespace, newSpec.VersionTag, newSpec.Placement.GetMGR(), newSpec.Resources.MGR, newSpec.Network)
+		if err := c.mgrs.Update(); err != nil {
+			return fmt.Errorf("failed to update the ceph mgr. %+v", err)
+		}
+	}
+
+	if !reflect.DeepEqual(c.Spec.Storage, newSpec.Storage) || !reflect.DeepEqual(c.Spec.Placement.GetOSD(), newSpec.Placement.GetOSD()) ||
+		!reflect.DeepEqual(c.Spec.Resources.OSD, newSpec.Resources.OSD) {
+		if err := c.osds.Update(newSpec.Storage, newSpec.Placement.GetOSD(), newSpec.Resources.OSD); err != nil {
+			return fmt.Errorf("failed to update the osds. %+v", err)
+		}
+	}
+
+	c.Spec = newSpec
+	logger.Infof("Done updating cluster in namespace %s", c.Namespace)
+	return nil
+}
+
+// pause stops the operator from changing the cluster until it is resumed
+func (c *Cluster) pause() {
+	if !c.Spec.Paused {
+		logger.Infof("pausing the operator control of cluster in namespace %s", c.Namespace)
+	}
+	c.Spec.Paused = true
+	c.pendingSpec = nil
+	if c.mons != nil {
+		c.mons.Paused = true
+	}
+}
+
+// resume applies the changes made to the spec while the cluster was paused and reconciles all the cluster resources
+func (c *Cluster) resume(newSpec Spec) error {
+	logger.Infof("resuming the operator control of cluster in namespace %s", c.Namespace)
+	if c.mons == nil || c.apis == nil || c.osds == nil {
+		// the cluster was paused before it was created. it will be created with the new spec.
+		c.Spec = newSpec
+		return nil
+	}
+
+	c.Spec.Paused = false
+	c.mons.Paused = false
+
+	// recreate any resources that were removed and failover the mons while the cluster was paused. the cluster is
+	// reconciled before the changes are applied since an upgrade continues in the background.
+	if err := c.createInstance(); err != nil {
+		err = fmt.Errorf("failed to reconcile cluster in namespace %s after resuming. %+v", c.Namespace, err)
+		c.setPhase(ClusterPhaseFailed, reasonUpdateFailed, err.Error())
+		return err
+	}
+	return c.applySpec(newSpec)
+}
+
+// validateUpdate checks that the new spec only contains changes that can be applied to an existing cluster
+func (c *Cluster) validateUpdate(newSpec Spec) error {
+	if newSpec.DataDirHostPath != c.Spec.DataDirHostPath {
+		return fmt.Errorf("dataDirHostPath cannot be changed from %s to %s", c.Spec.DataDirHostPath, newSpec.DataDirHostPath)
+	}
+	if newSpec.Storage.UseAllNodes != c.Spec.Storage.UseAllNodes {
+		return fmt.Errorf("useAllNodes cannot be changed for an existing cluster")
+	}
+	if !reflect.DeepEqual(newSpec.Network, c.Spec.Network) {
+		// the addresses of the mons and osds are recorded in the monmap and osdmap
+		return fmt.Errorf("network cannot be changed for an existing cluster")
+	}
+	return nil
+}
+
+// Delete tears down all the resources that were created for the cluster. If the cleanup policy requests it, a job
+// is started in the background on each node that ran a mon or osd to remove the data of the cluster from the host
+// after the pods have terminated.
+func (c *Cluster) Delete() error {
+	logger.Infof("deleting cluster %s in namespace %s", c.Name, c.Namespace)
+	c.setPhase(ClusterPhaseDeleting, reasonDeleting, "")
+
+	// create the components from the spec in case the cluster was never fully created by this operator
+	mons := mon.New(c.context, c.Namespace, c.Spec.DataDirHostPath, c.Spec.VersionTag, c.Spec.Placement.GetMON(), c.Spec.Resources.MON, c.Spec.Network)
+	osds := osd.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Storage, c.Spec.DataDirHostPath, c.Spec.Placement.GetOSD(), c.Spec.Resources.OSD, c.Spec.Network)
+
+	// find the nodes with data on them and the fsid that identifies the data before the pods and secrets are removed
+	var nodes *util.Set
+	fsid := ""
+	cleanup := c.Spec.CleanupPolicy == CleanupPolicyDelete
+	if cleanup {
+		var err error
+		if nodes, err = c.getNodesWithData(mons, osds); err != nil {
+			return err
+		}
+		if fsid, err = mons.GetFSID(); err != nil {
+			return err
+		}
+		if fsid == "" {
+			logger.Infof("no data to clean up for cluster %s that was never started", c.Namespace)
+			cleanup = false
+		}
+	}
+
+	// the clients of the cluster are removed before the daemons they depend on
+	if err := rgw.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Placement.GetRGW(), c.Spec.Resources.RGW, c.Spec.Network).Delete(); err != nil {
+		return fmt.Errorf("failed to delete the object store. %+v", err)
+	}
+	if err := mds.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Placement.GetMDS(), c.Spec.Resources.MDS, c.Spec.Network).Delete(); err != nil {
+		return fmt.Errorf("failed to delete the file system. %+v", err)
+	}
+	if err := api.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Placement.GetAPI(), c.Spec.Resources.API).Delete(); err != nil {
+		return fmt.Errorf("failed to delete the REST api. %+v", err)
+	}
+	if err := mgr.New(c.context, c.Namespace, c.Spec.VersionTag, c.Spec.Placement.GetMGR(), c.Spec.Resources.MGR, c.Spec.Network).Delete(); err != nil {
+		return fmt.Errorf("failed to delete the ceph mgr. %+v", err)
+	}
+	if err := osds.Delete(); err != nil {
+		return fmt.Errorf("failed to delete the osds. %+v", err)
+	}
+	if err := mons.Delete(); err != nil {
+		return fmt.Errorf("failed to delete the mons. %+v", err)
+	}
+
+	configMaps := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace)
+	for _, name := range []string{k8sutil.ConfigOverrideName, crushConfigMapName} {
+		if err := k8sutil.DeleteResource("config map", name, configMaps.Delete); err != nil {
+			return err
+		}
+	}
+	clientSecret := fmt.Sprintf("%s-rook-user", c.Namespace)
+	if err := k8sutil.DeleteResource("secret", clientSecret, c.context.Clientset.CoreV1().Secrets(k8sutil.DefaultNamespace).Delete); err != nil {
+		return err
+	}
+
+	if cleanup {
+		// the pods take a while to terminate, so the cleanup jobs are started in the background
+		go c.cleanupHosts(mons, osds, nodes, fsid)
+	} else {
+		logger.Infof("retaining the data in %s on the hosts", c.Spec.DataDirHostPath)
+	}
+
+	logger.Infof("Done deleting cluster %s in namespace %s", c.Name, c.Namespace)
+	return nil
+}
+
 // Monitor watches a cluster for failures and restarts failed components
 func (c *Cluster) Monitor(stopCh <-chan struct{}) {
 	for {
@@ -152,13 +474,50 @@ func (c *Cluster) Monitor(stopCh <-chan struct{}) {
 			return
 
 		case <-time.After(healthCheckInterval):
-			logger.Debugf("checking health of mons")
-			err := c.mons.CheckHealth()
-			if err != nil {
-				logger.Infof("failed to check mon health. %+v", err)
-			}
+			c.checkHealth()
+		}
+	}
+}
+
+// checkHealth fails over the mons, applies the config override and refreshes the status. The spec is not changed by
+// cluster events during the check. The mons are not checked while they are restarted in the background, since a
+// restarting mon is out of quorum and the restart holds the mon lock.
+func (c *Cluster) checkHealth() {
+	c.lock.Lock()
+	mons := c.mons
+	rolling := c.rolling
+	stopped := c.stopped
+	c.lock.Unlock()
+
+	if stopped {
+		return
+	}
+	if !rolling {
+		logger.Debugf("checking health of mons")
+		err := mons.CheckHealth()
+		if err != nil {
+			logger.Infof("failed to check mon health. %+v", err)
 		}
 	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.stopped {
+		return
+	}
+
+	if !c.rolling {
+		logger.Debugf("checking config override of cluster in namespace %s", c.Namespace)
+		if err := c.checkConfigOverride(); err != nil {
+			logger.Warningf("failed to apply the config override. %+v", err)
+		}
+	}
+
+	logger.Debugf("refreshing status of cluster in namespace %s", c.Namespace)
+	c.refreshStatus()
+
+	// retry the upgrade that failed
+	c.applyPendingSpec()
 }
 
 func (c *Cluster) createInitialCrushMap() error {
@@ -55,8 +55,10 @@ func (c *Cluster) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return err
 	}
-	tmp2 := Cluster(tmp)
-	*c = tmp2
+	// the fields are assigned separately since the cluster lock must not be copied
+	c.ObjectMeta = tmp.ObjectMeta
+	c.Spec = tmp.Spec
+	c.Status = tmp.Status
 	return nil
 }
 
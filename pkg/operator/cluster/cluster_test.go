@@ -23,13 +23,19 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/kit"
+	"github.com/rook/rook/pkg/operator/mon"
+	"github.com/rook/rook/pkg/operator/osd"
 	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/rook/rook/pkg/util"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -81,3 +87,218 @@ func TestCreateInitialCrushMap(t *testing.T) {
 	err = c.createInitialCrushMap()
 	assert.Nil(t, err)
 }
+
+func TestValidateUpdate(t *testing.T) {
+	c := &Cluster{Spec: Spec{VersionTag: "v1", DataDirHostPath: "/var/lib/rook"}}
+
+	// storage and placement changes are allowed
+	newSpec := c.Spec
+	newSpec.Storage.Nodes = []osd.Node{{Name: "node1"}}
+	assert.Nil(t, c.validateUpdate(newSpec))
+
+	// the data dir cannot be changed
+	newSpec = c.Spec
+	newSpec.DataDirHostPath = "/tmp/rook"
+	assert.NotNil(t, c.validateUpdate(newSpec))
+
+	// switching between all nodes and specific nodes is not supported
+	newSpec = c.Spec
+	newSpec.Storage.UseAllNodes = true
+	assert.NotNil(t, c.validateUpdate(newSpec))
+
+	// the network of the daemons cannot be changed
+	newSpec = c.Spec
+	newSpec.Network.HostNetwork = true
+	assert.NotNil(t, c.validateUpdate(newSpec))
+
+	// the cluster must be created before it can be updated
+	newSpec = c.Spec
+	newSpec.Storage.Nodes = []osd.Node{{Name: "node1"}}
+	assert.NotNil(t, c.Update(newSpec))
+}
+
+func TestDeleteCluster(t *testing.T) {
+	clientset := testop.New(3)
+	c := &Cluster{Spec: Spec{VersionTag: "myversion", DataDirHostPath: "/var/lib/rook"}}
+	c.Name = "myrook"
+	c.Namespace = "ns"
+	c.Init(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset, MaxRetries: 1}})
+	c.statusWriter = func(status ClusterStatus) error { return nil }
+
+	// create some of the resources of a running cluster
+	for _, name := range []string{k8sutil.ConfigOverrideName, crushConfigMapName, "mon-config"} {
+		_, err := clientset.CoreV1().ConfigMaps(c.Namespace).Create(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		assert.Nil(t, err)
+	}
+	for _, name := range []string{"rook-ceph-mon", "rook-admin", "rook-ceph-mgr0"} {
+		_, err := clientset.CoreV1().Secrets(c.Namespace).Create(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		assert.Nil(t, err)
+	}
+	_, err := clientset.CoreV1().Secrets(k8sutil.DefaultNamespace).Create(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "ns-rook-user"}})
+	assert.Nil(t, err)
+	_, err = clientset.ExtensionsV1beta1().Deployments(c.Namespace).Create(&extensions.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "rook-api"}})
+	assert.Nil(t, err)
+	monLabels := map[string]string{"app": "rook-ceph-mon", "mon_cluster": c.Namespace}
+	_, err = clientset.ExtensionsV1beta1().ReplicaSets(c.Namespace).Create(&extensions.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "mon0", Labels: monLabels}})
+	assert.Nil(t, err)
+
+	// all the resources are removed
+	err = c.Delete()
+	assert.Nil(t, err)
+	configMaps, _ := clientset.CoreV1().ConfigMaps(c.Namespace).List(metav1.ListOptions{})
+	assert.Equal(t, 0, len(configMaps.Items))
+	secrets, _ := clientset.CoreV1().Secrets(c.Namespace).List(metav1.ListOptions{})
+	assert.Equal(t, 0, len(secrets.Items))
+	secrets, _ = clientset.CoreV1().Secrets(k8sutil.DefaultNamespace).List(metav1.ListOptions{})
+	assert.Equal(t, 0, len(secrets.Items))
+	deployments, _ := clientset.ExtensionsV1beta1().Deployments(c.Namespace).List(metav1.ListOptions{})
+	assert.Equal(t, 0, len(deployments.Items))
+	replicaSets, _ := clientset.ExtensionsV1beta1().ReplicaSets(c.Namespace).List(metav1.ListOptions{})
+	assert.Equal(t, 0, len(replicaSets.Items))
+
+	// no cleanup jobs are started when the data is retained
+	jobs, _ := clientset.BatchV1().Jobs(c.Namespace).List(metav1.ListOptions{})
+	assert.Equal(t, 0, len(jobs.Items))
+
+	// deleting again succeeds when the resources are already gone
+	c.Spec.CleanupPolicy = CleanupPolicyDelete
+	err = c.Delete()
+	assert.Nil(t, err)
+}
+
+func TestCleanupJob(t *testing.T) {
+	c := &Cluster{Spec: Spec{VersionTag: "myversion", DataDirHostPath: "/var/lib/rook"}}
+	c.Namespace = "ns"
+
+	job := c.makeCleanupJob("node1", "myfsid")
+	assert.Equal(t, "rook-cleanup-node1", job.Name)
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, "node1", podSpec.NodeSelector["kubernetes.io/hostname"])
+	assert.Equal(t, v1.RestartPolicyOnFailure, podSpec.RestartPolicy)
+	assert.Equal(t, "/var/lib/rook", podSpec.Volumes[0].HostPath.Path)
+	assert.Equal(t, "/dev", podSpec.Volumes[1].HostPath.Path)
+	assert.Equal(t, 1, len(podSpec.Containers))
+	assert.Equal(t, []string{"cleanup", "--config-dir=/var/lib/rook", "--cluster-name=ns", "--fsid=myfsid"}, podSpec.Containers[0].Args)
+	assert.True(t, *podSpec.Containers[0].SecurityContext.Privileged)
+}
+
+func TestCleanupJobsDeleted(t *testing.T) {
+	clientset := testop.New(3)
+	c := &Cluster{Spec: Spec{VersionTag: "myversion", DataDirHostPath: "/var/lib/rook"}}
+	c.Namespace = "ns"
+	c.Init(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset, MaxRetries: 1}})
+	nodes := util.CreateSet([]string{"node1", "node2"})
+
+	err := c.startCleanupJobs(nodes, "myfsid")
+	assert.Nil(t, err)
+	jobs, _ := clientset.BatchV1().Jobs(c.Namespace).List(metav1.ListOptions{})
+	assert.Equal(t, 2, len(jobs.Items))
+
+	// the job on node2 is still running
+	job, err := clientset.BatchV1().Jobs(c.Namespace).Get("rook-cleanup-node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	job.Status.Succeeded = 1
+	_, err = clientset.BatchV1().Jobs(c.Namespace).Update(job)
+	assert.Nil(t, err)
+	assert.NotNil(t, c.waitForCleanupJobs(nodes))
+
+	job, err = clientset.BatchV1().Jobs(c.Namespace).Get("rook-cleanup-node2", metav1.GetOptions{})
+	assert.Nil(t, err)
+	job.Status.Succeeded = 1
+	_, err = clientset.BatchV1().Jobs(c.Namespace).Update(job)
+	assert.Nil(t, err)
+	assert.Nil(t, c.waitForCleanupJobs(nodes))
+
+	// the privileged jobs are removed after they complete
+	c.deleteCleanupJobs(nodes)
+	jobs, _ = clientset.BatchV1().Jobs(c.Namespace).List(metav1.ListOptions{})
+	assert.Equal(t, 0, len(jobs.Items))
+}
+
+func TestStopCluster(t *testing.T) {
+	c := &Cluster{Spec: Spec{VersionTag: "v1", DataDirHostPath: "/var/lib/rook"}}
+	c.Namespace = "ns"
+	c.statusWriter = func(status ClusterStatus) error { return nil }
+
+	// the restart in the background stops at its next step and does not apply the pending spec
+	c.lock.Lock()
+	c.pendingSpec = &Spec{VersionTag: "v1", DataDirHostPath: "/var/lib/rook", Paused: true}
+	step := make(chan struct{})
+	doneCalled := false
+	c.startRoll(func() error {
+		<-step
+		if c.IsStopped() {
+			return fmt.Errorf("stopped")
+		}
+		return nil
+	}, func(err error) {
+		doneCalled = true
+	})
+	c.lock.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+	for !c.IsStopped() {
+		time.Sleep(time.Millisecond)
+	}
+	close(step)
+	<-stopped
+	assert.False(t, c.rolling)
+	assert.False(t, doneCalled)
+
+	// the cluster is not created or changed after it is stopped
+	assert.NotNil(t, c.CreateInstance())
+	assert.NotNil(t, c.Update(Spec{VersionTag: "v2", DataDirHostPath: "/var/lib/rook"}))
+	assert.Equal(t, "v1", c.Spec.VersionTag)
+}
+
+func TestPauseCluster(t *testing.T) {
+	c := &Cluster{Spec: Spec{VersionTag: "v1", DataDirHostPath: "/var/lib/rook"}}
+
+	// changes are not applied or validated while the cluster is paused
+	err := c.Update(Spec{VersionTag: "v1", DataDirHostPath: "/tmp/rook", Paused: true})
+	assert.Nil(t, err)
+	assert.True(t, c.Spec.Paused)
+	assert.Equal(t, "/var/lib/rook", c.Spec.DataDirHostPath)
+
+	// invalid changes are rejected when resuming and the cluster remains paused
+	err = c.Update(Spec{VersionTag: "v1", DataDirHostPath: "/tmp/rook"})
+	assert.NotNil(t, err)
+	assert.True(t, c.Spec.Paused)
+
+	// a cluster paused before it was created picks up the new spec when resumed
+	newSpec := Spec{VersionTag: "v1", DataDirHostPath: "/var/lib/rook"}
+	newSpec.Storage.Nodes = []osd.Node{{Name: "node1"}}
+	err = c.Update(newSpec)
+	assert.Nil(t, err)
+	assert.False(t, c.Spec.Paused)
+	assert.Equal(t, 1, len(c.Spec.Storage.Nodes))
+}
+
+func TestUpdateDuringUpgrade(t *testing.T) {
+	c := &Cluster{Spec: Spec{VersionTag: "v2", DataDirHostPath: "/var/lib/rook"}, mons: &mon.Cluster{}}
+	c.rolling = true
+
+	// the changes are applied after the upgrade in the background completes
+	newSpec := Spec{VersionTag: "v2", DataDirHostPath: "/var/lib/rook"}
+	newSpec.Storage.Nodes = []osd.Node{{Name: "node1"}}
+	err := c.Update(newSpec)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(c.Spec.Storage.Nodes))
+	assert.Equal(t, 1, len(c.pendingSpec.Storage.Nodes))
+
+	// the pending changes are dropped when the cluster is paused
+	err = c.Update(Spec{VersionTag: "v2", DataDirHostPath: "/var/lib/rook", Paused: true})
+	assert.Nil(t, err)
+	assert.True(t, c.Spec.Paused)
+	assert.Nil(t, c.pendingSpec)
+
+	// the cluster is resumed while the upgrade is still running
+	err = c.Update(newSpec)
+	assert.Nil(t, err)
+	assert.False(t, c.Spec.Paused)
+	assert.NotNil(t, c.pendingSpec)
+}
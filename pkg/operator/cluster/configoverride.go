@@ -0,0 +1,304 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"github.com/rook/rook/pkg/ceph/client"
+	cephmon "github.com/rook/rook/pkg/ceph/mon"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the daemon types whose settings can be injected at runtime with ceph tell
+var injectableDaemons = []string{"mon", "osd", "mds"}
+
+// createConfigOverride creates the configmap for overriding ceph config settings. The pods of the daemons mount the
+// override and the ceph settings from the cluster spec, so both keys are added to a configmap created by an older
+// operator.
+func (c *Cluster) createConfigOverride() error {
+	placeholderConfig := map[string]string{
+		k8sutil.ConfigOverrideVal: "",
+		k8sutil.CephConfigVal:     "",
+	}
+	cm := &v1.ConfigMap{Data: placeholderConfig}
+	cm.Name = k8sutil.ConfigOverrideName
+	_, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Create(cm)
+	if err == nil {
+		return nil
+	}
+	if !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create override configmap %s. %+v", c.Namespace, err)
+	}
+
+	cm, err = c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get override configmap %s. %+v", c.Namespace, err)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	missing := false
+	for key := range placeholderConfig {
+		if _, ok := cm.Data[key]; !ok {
+			cm.Data[key] = ""
+			missing = true
+		}
+	}
+	if missing {
+		if _, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Update(cm); err != nil {
+			return fmt.Errorf("failed to update override configmap %s. %+v", c.Namespace, err)
+		}
+	}
+	return nil
+}
+
+// loadConfigOverride reads the config override when the cluster starts and returns its hash. The mons and osds that
+// are started record the hash of the override. If the override changed while the operator was not running, the
+// daemons are restarted by the next check since the settings that were applied to them are not known.
+func (c *Cluster) loadConfigOverride() (string, error) {
+	config, err := k8sutil.GetConfigOverride(c.context.Clientset, c.Namespace)
+	if err != nil {
+		return "", err
+	}
+	hash := k8sutil.ConfigOverrideHash(config)
+	if c.Status.ConfigOverrideHash == "" {
+		// the daemons of a new cluster start with the override
+		c.updateStatus(func(s *ClusterStatus) {
+			s.ConfigOverrideHash = hash
+		})
+	}
+	if c.Status.ConfigOverrideHash == hash {
+		c.configOverride = &config
+	}
+	return hash, nil
+}
+
+// checkConfigOverride applies the changes to the config override to the running daemons. The settings are injected
+// into the mons, osds and mds at runtime if possible. Otherwise the mons are restarted in place one at a time and the
+// osds are restarted node by node in the background, so the health checks are not blocked while the placement groups
+// recover. The hash of the applied override is saved in the status after the daemons are updated. The cluster lock
+// must be held.
+func (c *Cluster) checkConfigOverride() error {
+	if c.Spec.Paused || c.Status.Phase != ClusterPhaseCreated {
+		// the override is applied after the cluster is resumed or the update completes
+		return nil
+	}
+
+	config, err := k8sutil.GetConfigOverride(c.context.Clientset, c.Namespace)
+	if err != nil {
+		return err
+	}
+	hash := k8sutil.ConfigOverrideHash(config)
+	if hash == c.Status.ConfigOverrideHash {
+		return nil
+	}
+	logger.Infof("config override changed for cluster in namespace %s", c.Namespace)
+
+	restart := true
+	if c.configOverride != nil {
+		settings, restartRequired, err := changedSettings(*c.configOverride, config)
+		if err != nil {
+			return fmt.Errorf("invalid config override. %+v", err)
+		}
+		restart = restartRequired || !c.injectSettings(settings)
+	}
+
+	if !restart {
+		// the daemons started from now on record the new override
+		c.mons.ConfigOverrideHash = hash
+		c.osds.ConfigOverrideHash = hash
+		c.setConfigOverride(config, hash)
+		return nil
+	}
+
+	mons := c.mons
+	osds := c.osds
+	c.startRoll(func() error {
+		if err := mons.RestartForConfigOverride(hash); err != nil {
+			return fmt.Errorf("failed to restart mons. %+v", err)
+		}
+		if c.IsStopped() {
+			return fmt.Errorf("cluster was deleted before restarting the osds")
+		}
+		err := osds.RestartForConfigOverride(hash, func(nodeName string) error {
+			if c.IsStopped() {
+				return fmt.Errorf("cluster was deleted after restarting the osds on node %s", nodeName)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restart osds. %+v", err)
+		}
+		return nil
+	}, func(err error) {
+		if err != nil {
+			// the restart is resumed by the next health check unless the override is reverted. the osds unset the noout
+			// flag when their restart fails, so the flag is not left set either way.
+			logger.Warningf("failed to apply the config override. %+v", err)
+		} else {
+			c.setConfigOverride(config, hash)
+		}
+		c.applyPendingSpec()
+	})
+	return nil
+}
+
+// setConfigOverride records the config override that was applied to the daemons. The cluster lock must be held.
+func (c *Cluster) setConfigOverride(config, hash string) {
+	c.configOverride = &config
+	c.updateStatus(func(s *ClusterStatus) {
+		s.ConfigOverrideHash = hash
+	})
+	logger.Infof("config override applied to cluster in namespace %s", c.Namespace)
+}
+
+// injectSettings applies the settings to the running daemons. Returns false if the daemons must be restarted for
+// any of the settings to take effect.
+func (c *Cluster) injectSettings(settings map[string]map[string]string) bool {
+	targets := []string{}
+	for target := range settings {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	live := true
+	for _, target := range targets {
+		applied, err := client.InjectArgs(c.context, c.Namespace, target, settings[target])
+		if err != nil {
+			logger.Warningf("failed to inject settings into %s. %+v", target, err)
+			return false
+		}
+		live = live && applied
+	}
+	return live
+}
+
+// changedSettings compares the settings of the daemons in two config overrides. Returns the changed settings indexed
+// by the ceph tell target (mon.*, osd.3) and whether the daemons must be restarted instead because a setting was
+// removed or is overridden by a more specific section. The sections of the other daemons, such as the clients and
+// the mgr, are not compared since they are read when those daemons restart.
+func changedSettings(oldConfig, newConfig string) (map[string]map[string]string, bool, error) {
+	oldSettings, err := parseConfigOverride(oldConfig)
+	if err != nil {
+		return nil, false, err
+	}
+	newSettings, err := parseConfigOverride(newConfig)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// a setting that was removed cannot be reverted to its default at runtime
+	for section, keys := range oldSettings {
+		for key := range keys {
+			if _, ok := newSettings[section][key]; !ok {
+				logger.Infof("setting %s was removed from section %s", key, section)
+				return nil, true, nil
+			}
+		}
+	}
+
+	settings := map[string]map[string]string{}
+	add := func(target, key, value string) {
+		if settings[target] == nil {
+			settings[target] = map[string]string{}
+		}
+		settings[target][key] = value
+	}
+	for section, keys := range newSettings {
+		for key, value := range keys {
+			if old, ok := oldSettings[section][key]; ok && old == value {
+				continue
+			}
+
+			// injecting the setting into all the daemons of a type would replace the settings of specific daemons
+			if section == "global" {
+				for _, daemon := range injectableDaemons {
+					if overridden(newSettings, daemon, key) {
+						return nil, true, nil
+					}
+					add(daemon+".*", key, value)
+				}
+			} else if !strings.Contains(section, ".") {
+				if overridden(newSettings, section, key) {
+					return nil, true, nil
+				}
+				add(section+".*", key, value)
+			} else {
+				add(section, key, value)
+			}
+		}
+	}
+	return settings, false, nil
+}
+
+// overridden returns whether the setting is set in the section of the daemon type or in the section of a daemon
+func overridden(settings map[string]map[string]string, daemon, key string) bool {
+	for section, keys := range settings {
+		if section != daemon && !strings.HasPrefix(section, daemon+".") {
+			continue
+		}
+		if _, ok := keys[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseConfigOverride returns the settings of the injectable daemons in the override indexed by the section. The
+// keys without a section belong to the global section. The names of the settings are normalized to underscores.
+func parseConfigOverride(config string) (map[string]map[string]string, error) {
+	file, err := ini.Load([]byte(config))
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]map[string]string{}
+	for _, section := range file.Sections() {
+		name := section.Name()
+		if name == ini.DEFAULT_SECTION {
+			name = "global"
+		}
+		if name != "global" && !injectableSection(name) {
+			continue
+		}
+		if settings[name] == nil {
+			settings[name] = map[string]string{}
+		}
+		for _, key := range section.Keys() {
+			settings[name][cephmon.NormalizeConfigKey(key.Name())] = key.Value()
+		}
+	}
+	return settings, nil
+}
+
+// injectableSection returns whether the section configures daemons whose settings can be injected
+func injectableSection(name string) bool {
+	for _, daemon := range injectableDaemons {
+		if name == daemon || strings.HasPrefix(name, daemon+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	"github.com/rook/rook/pkg/operator/mon"
+	"github.com/rook/rook/pkg/operator/osd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+)
+
+func TestChangedSettings(t *testing.T) {
+	oldConfig := `
+[global]
+osd pool default size = 2
+
+[osd]
+osd max backfills = 1
+
+[client]
+rbd cache = true
+`
+
+	// changes to global settings are injected into all the daemons
+	newConfig := `
+[global]
+osd pool default size = 3
+
+[osd]
+osd max backfills = 1
+
+[client]
+rbd cache = false
+`
+	settings, restart, err := changedSettings(oldConfig, newConfig)
+	assert.Nil(t, err)
+	assert.False(t, restart)
+	assert.Equal(t, map[string]map[string]string{
+		"mon.*": {"osd_pool_default_size": "3"},
+		"osd.*": {"osd_pool_default_size": "3"},
+		"mds.*": {"osd_pool_default_size": "3"},
+	}, settings)
+
+	// changes to the settings of a daemon type or a single daemon are only injected into those daemons
+	newConfig = `
+[global]
+osd pool default size = 2
+
+[osd]
+osd max backfills = 2
+
+[osd.3]
+debug osd = 20
+`
+	settings, restart, err = changedSettings(oldConfig, newConfig)
+	assert.Nil(t, err)
+	assert.False(t, restart)
+	assert.Equal(t, map[string]map[string]string{
+		"osd.*": {"osd_max_backfills": "2"},
+		"osd.3": {"debug_osd": "20"},
+	}, settings)
+
+	// the daemons must be restarted when a setting is removed
+	_, restart, err = changedSettings(oldConfig, "[global]\nosd pool default size = 2\n")
+	assert.Nil(t, err)
+	assert.True(t, restart)
+
+	// the daemons must be restarted when a global setting is overridden for a daemon type
+	newConfig = `
+[global]
+osd pool default size = 2
+osd max backfills = 3
+
+[osd]
+osd max backfills = 1
+`
+	_, restart, err = changedSettings(oldConfig, newConfig)
+	assert.Nil(t, err)
+	assert.True(t, restart)
+
+	// no changes
+	settings, restart, err = changedSettings(oldConfig, oldConfig)
+	assert.Nil(t, err)
+	assert.False(t, restart)
+	assert.Equal(t, 0, len(settings))
+}
+
+func TestCheckConfigOverride(t *testing.T) {
+	clientset := testop.New(1)
+	injected := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			injected = append(injected, args[1]+" "+args[3])
+			return "", nil
+		},
+	}
+	c := &Cluster{Status: ClusterStatus{Phase: ClusterPhaseCreated}}
+	c.Namespace = "ns"
+	c.Init(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}, Executor: executor})
+	c.statusWriter = func(status ClusterStatus) error { return nil }
+	c.mons = &mon.Cluster{}
+	c.osds = &osd.Cluster{}
+
+	cm := &v1.ConfigMap{Data: map[string]string{k8sutil.ConfigOverrideVal: "[osd]\nosd max backfills = 1\n"}}
+	cm.Name = k8sutil.ConfigOverrideName
+	_, err := clientset.CoreV1().ConfigMaps(c.Namespace).Create(cm)
+	assert.Nil(t, err)
+
+	// the override of a new cluster is applied when the daemons start
+	hash, err := c.loadConfigOverride()
+	assert.Nil(t, err)
+	assert.Equal(t, hash, c.Status.ConfigOverrideHash)
+	assert.Nil(t, c.checkConfigOverride())
+	assert.Equal(t, 0, len(injected))
+
+	// the changed settings are injected into the running daemons
+	cm.Data[k8sutil.ConfigOverrideVal] = "[osd]\nosd max backfills = 2\n"
+	_, err = clientset.CoreV1().ConfigMaps(c.Namespace).Update(cm)
+	assert.Nil(t, err)
+	assert.Nil(t, c.checkConfigOverride())
+	assert.Equal(t, []string{"osd.* --osd_max_backfills=2"}, injected)
+	newHash := k8sutil.ConfigOverrideHash(cm.Data[k8sutil.ConfigOverrideVal])
+	assert.Equal(t, newHash, c.Status.ConfigOverrideHash)
+	assert.Equal(t, newHash, c.mons.ConfigOverrideHash)
+	assert.Equal(t, newHash, c.osds.ConfigOverrideHash)
+
+	// changes are not applied while the cluster is paused
+	c.Spec.Paused = true
+	cm.Data[k8sutil.ConfigOverrideVal] = "[osd]\nosd max backfills = 3\n"
+	_, err = clientset.CoreV1().ConfigMaps(c.Namespace).Update(cm)
+	assert.Nil(t, err)
+	assert.Nil(t, c.checkConfigOverride())
+	assert.Equal(t, 1, len(injected))
+	assert.Equal(t, newHash, c.Status.ConfigOverrideHash)
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	"github.com/rook/rook/pkg/operator/mds"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	"k8s.io/api/core/v1"
+)
+
+// FilesystemResource is the definition of the file system TPR
+var FilesystemResource = kit.CustomResource{
+	Name:        "filesystem",
+	Group:       k8sutil.CustomResourceGroup,
+	Version:     kit.V1Alpha1,
+	Description: "Managed Rook file systems",
+}
+
+// FilesystemState is the result of the last attempt to apply the spec of a file system
+type FilesystemState string
+
+const (
+	// FilesystemStateCreated means the pools, the file system and the mds daemons match the spec
+	FilesystemStateCreated FilesystemState = "Created"
+	// FilesystemStateFailed means the spec could not be applied to the file system
+	FilesystemStateFailed FilesystemState = "Failed"
+)
+
+// Filesystem is the spec for the file system TPR
+type Filesystem struct {
+	v1.ObjectMeta  `json:"metadata,omitempty"`
+	FilesystemSpec `json:"spec"`
+	Status         FilesystemStatus `json:"status,omitempty"`
+}
+
+// FilesystemSpec represents the spec of a file system
+type FilesystemSpec struct {
+	// The pool settings of the pool that holds the file system metadata
+	MetadataPool PoolSpec `json:"metadataPool"`
+
+	// The pool settings of the pools that hold the file data. The first pool is the default data pool.
+	DataPools []PoolSpec `json:"dataPools"`
+
+	// The mds settings
+	MetadataServer MetadataServerSpec `json:"metadataServer"`
+}
+
+// MetadataServerSpec represents the settings of the mds daemons
+type MetadataServerSpec struct {
+	// The number of active mds ranks
+	ActiveCount int32 `json:"activeCount"`
+
+	// The number of standby mds daemons that take over a failed rank
+	StandbyCount int32 `json:"standbyCount"`
+
+	// The number of standby mds daemons that follow the journal of an active rank
+	StandbyReplayCount int32 `json:"standbyReplayCount"`
+
+	// The placement of the mds pods. Overrides the mds placement of the cluster.
+	Placement k8sutil.Placement `json:"placement"`
+}
+
+// FilesystemStatus is the status of the file system that the operator records in the file system resource
+type FilesystemStatus struct {
+	// Whether the spec was applied to the file system
+	State FilesystemState `json:"state,omitempty"`
+
+	// The reason the spec could not be applied
+	Message string `json:"message,omitempty"`
+}
+
+// Create the pools, the file system and the mds daemons
+func (f *Filesystem) Create(context *clusterd.Context, rclient rookclient.RookRestClient, version string, placement k8sutil.Placement,
+	resources v1.ResourceRequirements, network k8sutil.Network) error {
+	return f.Update(context, rclient, version, placement, resources, network)
+}
+
+// Update the pools, the file system and the mds daemons with the settings in the spec. The resources are created
+// if they do not exist yet. The placement of the cluster is merged with the placement of the mds daemons.
+func (f *Filesystem) Update(context *clusterd.Context, rclient rookclient.RookRestClient, version string, placement k8sutil.Placement,
+	resources v1.ResourceRequirements, network k8sutil.Network) error {
+	if err := f.validate(); err != nil {
+		return fmt.Errorf("invalid file system %s arguments. %+v", f.Name, err)
+	}
+
+	existing, err := f.get(context)
+	if err != nil {
+		return err
+	}
+	if existing != nil && len(existing.DataPools) > len(f.DataPools) {
+		return fmt.Errorf("data pools cannot be removed from file system %s", f.Name)
+	}
+
+	for _, pool := range f.pools() {
+		if err := pool.Update(rclient); err != nil {
+			return fmt.Errorf("failed to create pool %s for file system %s. %+v", pool.Name, f.Name, err)
+		}
+	}
+
+	if existing == nil {
+		logger.Infof("creating file system %s in namespace %s", f.Name, f.Namespace)
+		if err := client.CreateFilesystem(context, f.Namespace, f.Name, f.metadataPoolName(), f.dataPoolName(0)); err != nil {
+			return err
+		}
+		existing = &client.CephFilesystem{DataPools: []string{f.dataPoolName(0)}}
+	}
+	for i := len(existing.DataPools); i < len(f.DataPools); i++ {
+		logger.Infof("adding data pool %s to file system %s", f.dataPoolName(i), f.Name)
+		if err := client.AddDataPool(context, f.Namespace, f.Name, f.dataPoolName(i)); err != nil {
+			return err
+		}
+	}
+
+	details, err := client.GetFilesystem(context, f.Namespace, f.Name)
+	if err != nil {
+		return err
+	}
+	if err := f.setActiveCount(context, details.MDSMap.MaxMDS); err != nil {
+		return err
+	}
+
+	logger.Infof("starting mds for file system %s in namespace %s", f.Name, f.Namespace)
+	c := f.metadataServer(context, version, placement, resources, network)
+	c.FilesystemID = details.ID
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("failed to start mds. %+v", err)
+	}
+	if err := c.Update(); err != nil {
+		return fmt.Errorf("failed to update mds. %+v", err)
+	}
+	return nil
+}
+
+// Delete the file system and stop the mds daemons. The pools are not deleted so that the files are not lost.
+func (f *Filesystem) Delete(context *clusterd.Context) error {
+	c := f.metadataServer(context, "", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	existing, err := f.get(context)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		logger.Infof("file system %s does not exist in namespace %s", f.Name, f.Namespace)
+		return c.Delete()
+	}
+
+	return c.RemoveFilesystem()
+}
+
+// setActiveCount sets max_mds of the file system. When the number of active ranks is reduced, the ranks above
+// the new count are deactivated.
+func (f *Filesystem) setActiveCount(context *clusterd.Context, maxMDS int) error {
+	active := int(f.activeCount())
+	if active == maxMDS {
+		return nil
+	}
+
+	logger.Infof("setting the active mds count of file system %s from %d to %d", f.Name, maxMDS, active)
+	if err := client.SetMaxMDS(context, f.Namespace, f.Name, active); err != nil {
+		return err
+	}
+	for rank := maxMDS - 1; rank >= active; rank-- {
+		if err := client.DeactivateMDS(context, f.Namespace, f.Name, rank); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataServer returns the mds settings for the file system
+func (f *Filesystem) metadataServer(context *clusterd.Context, version string, placement k8sutil.Placement,
+	resources v1.ResourceRequirements, network k8sutil.Network) *mds.Cluster {
+	c := mds.New(context, f.Namespace, version, placement.Merge(f.MetadataServer.Placement), resources, network)
+	c.Filesystem = f.Name
+	c.ActiveCount = f.activeCount()
+	c.Replicas = c.ActiveCount + f.MetadataServer.StandbyCount
+	c.StandbyReplayCount = f.MetadataServer.StandbyReplayCount
+	return c
+}
+
+// get the file system with the same name from ceph, or nil if the file system does not exist
+func (f *Filesystem) get(context *clusterd.Context) (*client.CephFilesystem, error) {
+	filesystems, err := client.ListFilesystems(context, f.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file systems. %+v", err)
+	}
+	for i := range filesystems {
+		if filesystems[i].Name == f.Name {
+			return &filesystems[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// pools returns the metadata and data pools of the file system
+func (f *Filesystem) pools() []*Pool {
+	pools := []*Pool{f.pool(f.metadataPoolName(), f.MetadataPool)}
+	for i, spec := range f.DataPools {
+		pools = append(pools, f.pool(f.dataPoolName(i), spec))
+	}
+	return pools
+}
+
+func (f *Filesystem) pool(name string, spec PoolSpec) *Pool {
+	p := NewPool(spec)
+	p.Name = name
+	p.Namespace = f.Namespace
+	return p
+}
+
+func (f *Filesystem) metadataPoolName() string {
+	return fmt.Sprintf("%s-metadata", f.Name)
+}
+
+func (f *Filesystem) dataPoolName(index int) string {
+	return fmt.Sprintf("%s-data%d", f.Name, index)
+}
+
+func (f *Filesystem) activeCount() int32 {
+	if f.MetadataServer.ActiveCount == 0 {
+		return 1
+	}
+	return f.MetadataServer.ActiveCount
+}
+
+// Validate the file system arguments
+func (f *Filesystem) validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if f.Namespace == "" {
+		return fmt.Errorf("missing namespace")
+	}
+	if len(f.DataPools) == 0 {
+		return fmt.Errorf("at least one data pool is required")
+	}
+	if f.MetadataServer.ActiveCount < 0 || f.MetadataServer.StandbyCount < 0 || f.MetadataServer.StandbyReplayCount < 0 {
+		return fmt.Errorf("the mds counts cannot be negative")
+	}
+
+	// the metadata is stored in omap which is not supported by erasure coded pools
+	metadata := f.pool(f.metadataPoolName(), f.MetadataPool)
+	if metadata.erasureCode() != nil {
+		return fmt.Errorf("the metadata pool must be replicated")
+	}
+	for _, pool := range f.pools() {
+		if err := pool.validate(); err != nil {
+			return fmt.Errorf("invalid pool %s. %+v", pool.Name, err)
+		}
+	}
+	return nil
+}
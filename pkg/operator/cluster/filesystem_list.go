@@ -0,0 +1,70 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FilesystemList is a list of rook file systems from the TPR.
+type FilesystemList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata
+	// More info: http://releases.k8s.io/HEAD/docs/devel/api-conventions.md#metadata
+	Metadata metav1.ListMeta `json:"metadata,omitempty"`
+	// Items is a list of third party objects
+	Items []Filesystem `json:"items"`
+}
+
+// There is known issue with TPR in client-go:
+//   https://github.com/kubernetes/client-go/issues/8
+// Workarounds:
+// - We include `Metadata` field in object explicitly.
+// - we have the code below to work around a known problem with third-party resources and ugorji.
+
+// FilesystemListCopy is for deserialization
+type FilesystemListCopy FilesystemList
+
+// FilesystemCopy is for deserialization
+type FilesystemCopy Filesystem
+
+// UnmarshalJSON deserializes the file system
+func (f *Filesystem) UnmarshalJSON(data []byte) error {
+	tmp := FilesystemCopy{}
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return err
+	}
+	tmp2 := Filesystem(tmp)
+	*f = tmp2
+	return nil
+}
+
+// UnmarshalJSON deserializes the file system list
+func (fl *FilesystemList) UnmarshalJSON(data []byte) error {
+	tmp := FilesystemListCopy{}
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return err
+	}
+	tmp2 := FilesystemList(tmp)
+	*fl = tmp2
+	return nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/rook/rook/pkg/rook/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateFilesystem(t *testing.T) {
+	f := Filesystem{ObjectMeta: v1.ObjectMeta{Name: "myfs", Namespace: "myns"}}
+	f.MetadataPool.Replication.Size = 1
+	f.DataPools = []PoolSpec{{Replication: ReplicationSpec{Size: 1}}}
+	assert.Nil(t, f.validate())
+
+	// the metadata pool must be replicated
+	f.MetadataPool = PoolSpec{ErasureCoding: ErasureCodeSpec{CodingChunks: 1, DataChunks: 2}}
+	assert.NotNil(t, f.validate())
+
+	// a data pool is required
+	f.MetadataPool = PoolSpec{Replication: ReplicationSpec{Size: 1}}
+	f.DataPools = nil
+	assert.NotNil(t, f.validate())
+
+	// the data pools must be valid
+	f.DataPools = []PoolSpec{{Replication: ReplicationSpec{Size: 1}}, {}}
+	assert.NotNil(t, f.validate())
+
+	// the mds counts must not be negative
+	f.DataPools = []PoolSpec{{Replication: ReplicationSpec{Size: 1}}}
+	f.MetadataServer.StandbyCount = -1
+	assert.NotNil(t, f.validate())
+}
+
+func TestCreateFilesystem(t *testing.T) {
+	clientset := testop.New(3)
+	var dataPools []string
+	maxMDS := 1
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] != "fs" && args[0] != "mds" {
+				return `{"key":"mysecurekey"}`, nil
+			}
+			commands = append(commands, cephCommand(args))
+			switch args[1] {
+			case "ls":
+				if dataPools == nil {
+					return `[]`, nil
+				}
+				b, _ := json.Marshal([]client.CephFilesystem{{Name: "myfs", MetadataPool: "myfs-metadata", DataPools: dataPools}})
+				return string(b), nil
+			case "new":
+				dataPools = []string{args[4]}
+			case "add_data_pool":
+				dataPools = append(dataPools, args[3])
+			case "get":
+				return fmt.Sprintf(`{"mdsmap":{"fs_name":"myfs","max_mds":%d},"id":7}`, maxMDS), nil
+			}
+			return "", nil
+		},
+	}
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}, Executor: executor, ConfigDir: configDir}
+
+	pools := []string{}
+	rclient := &test.MockRookRestClient{
+		MockCreatePool: func(pool model.Pool) (string, error) {
+			pools = append(pools, pool.Name)
+			return "", nil
+		},
+	}
+
+	f := Filesystem{ObjectMeta: v1.ObjectMeta{Name: "myfs", Namespace: "myns"}}
+	f.MetadataPool.Replication.Size = 1
+	f.DataPools = []PoolSpec{{Replication: ReplicationSpec{Size: 1}}, {ErasureCoding: ErasureCodeSpec{CodingChunks: 1, DataChunks: 2}}}
+	f.MetadataServer = MetadataServerSpec{ActiveCount: 2, StandbyCount: 1, StandbyReplayCount: 1}
+	err := f.Create(context, rclient, "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	assert.Nil(t, err)
+
+	// the pools and the file system are created and the active count is set
+	assert.Equal(t, []string{"myfs-metadata", "myfs-data0", "myfs-data1"}, pools)
+	assert.Equal(t, []string{"fs ls", "fs new myfs myfs-metadata myfs-data0", "fs add_data_pool myfs myfs-data1", "fs get myfs",
+		"fs set myfs max_mds 2"}, commands)
+
+	// the active, standby and standby-replay daemons are started
+	for _, name := range []string{"rook-ceph-mds-myfs-0", "rook-ceph-mds-myfs-1", "rook-ceph-mds-myfs-2", "rook-ceph-mds-myfs-3"} {
+		_, err := clientset.ExtensionsV1beta1().Deployments("myns").Get(name, metav1.GetOptions{})
+		assert.Nil(t, err)
+	}
+	d, err := clientset.ExtensionsV1beta1().Deployments("myns").Get("rook-ceph-mds-myfs-3", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, d.Spec.Template.Spec.Containers[0].Args, "--mds-standby-for-fscid=7")
+
+	// reducing the active count deactivates the ranks above the count and removes the extra daemons
+	commands = []string{}
+	maxMDS = 3
+	f.MetadataServer = MetadataServerSpec{ActiveCount: 1}
+	err = f.Update(context, rclient, "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"fs ls", "fs get myfs", "fs set myfs max_mds 1", "mds deactivate myfs:2", "mds deactivate myfs:1"}, commands)
+	_, err = clientset.ExtensionsV1beta1().Deployments("myns").Get("rook-ceph-mds-myfs-0", metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, err = clientset.ExtensionsV1beta1().Deployments("myns").Get("rook-ceph-mds-myfs-1", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// data pools cannot be removed
+	f.DataPools = f.DataPools[0:1]
+	err = f.Update(context, rclient, "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	assert.NotNil(t, err)
+
+	// the file system and the daemons are removed when the file system is deleted
+	commands = []string{}
+	err = f.Delete(context)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"fs ls", "fs set myfs cluster_down true", "fs get myfs", "fs rm myfs"}, commands)
+	_, err = clientset.ExtensionsV1beta1().Deployments("myns").Get("rook-ceph-mds-myfs-0", metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+// cephCommand returns the ceph command without the connection and format args
+func cephCommand(args []string) string {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--") {
+			return strings.Join(args[0:i], " ")
+		}
+	}
+	return strings.Join(args, " ")
+}
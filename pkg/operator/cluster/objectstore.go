@@ -0,0 +1,211 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	"github.com/rook/rook/pkg/operator/rgw"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	"k8s.io/api/core/v1"
+)
+
+// ObjectStoreResource is the definition of the object store TPR
+var ObjectStoreResource = kit.CustomResource{
+	Name:        "objectstore",
+	Group:       k8sutil.CustomResourceGroup,
+	Version:     kit.V1Alpha1,
+	Description: "Managed Rook object stores",
+}
+
+// The pools created by rgw for the object store metadata. The names are the defaults of the rgw zone.
+var objectStoreMetadataPools = []string{
+	".rgw.root",
+	"default.rgw.control",
+	"default.rgw.meta",
+	"default.rgw.log",
+	"default.rgw.buckets.index",
+}
+
+// The pool where rgw stores the object data
+const objectStoreDataPool = "default.rgw.buckets.data"
+
+// ObjectStoreState is the result of the last attempt to apply the spec of an object store
+type ObjectStoreState string
+
+const (
+	// ObjectStoreStateCreated means the pools and gateways of the object store match the spec
+	ObjectStoreStateCreated ObjectStoreState = "Created"
+	// ObjectStoreStateFailed means the spec could not be applied to the object store
+	ObjectStoreStateFailed ObjectStoreState = "Failed"
+)
+
+// ObjectStore is the spec for the object store TPR
+type ObjectStore struct {
+	v1.ObjectMeta   `json:"metadata,omitempty"`
+	ObjectStoreSpec `json:"spec"`
+	Status          ObjectStoreStatus `json:"status,omitempty"`
+}
+
+// ObjectStoreSpec represent the spec of an object store
+type ObjectStoreSpec struct {
+	// The pool settings of the pools that hold the object store metadata
+	MetadataPool PoolSpec `json:"metadataPool"`
+
+	// The pool settings of the pool that holds the object data
+	DataPool PoolSpec `json:"dataPool"`
+
+	// The rgw settings
+	Gateway GatewaySpec `json:"gateway"`
+}
+
+// GatewaySpec represents the settings of the rgw pods
+type GatewaySpec struct {
+	// The port the rgw service will be listening on
+	Port int32 `json:"port"`
+
+	// The number of pods in the rgw deployment
+	Instances int32 `json:"instances"`
+
+	// The placement of the rgw pods. Overrides the rgw placement of the cluster.
+	Placement k8sutil.Placement `json:"placement"`
+}
+
+// ObjectStoreStatus is the status of the object store that the operator records in the object store resource
+type ObjectStoreStatus struct {
+	// Whether the spec was applied to the object store
+	State ObjectStoreState `json:"state,omitempty"`
+
+	// The reason the spec could not be applied
+	Message string `json:"message,omitempty"`
+}
+
+// Create the pools and start the gateways of the object store
+func (s *ObjectStore) Create(context *clusterd.Context, rclient rookclient.RookRestClient, version string, placement k8sutil.Placement,
+	resources v1.ResourceRequirements, network k8sutil.Network) error {
+	return s.Update(context, rclient, version, placement, resources, network)
+}
+
+// Update the pools and the gateways of the object store with the settings in the spec. The pools and the gateways
+// are created if they do not exist yet. The placement of the cluster is merged with the placement of the gateways.
+func (s *ObjectStore) Update(context *clusterd.Context, rclient rookclient.RookRestClient, version string, placement k8sutil.Placement,
+	resources v1.ResourceRequirements, network k8sutil.Network) error {
+	if err := s.validate(); err != nil {
+		return fmt.Errorf("invalid object store %s arguments. %+v", s.Name, err)
+	}
+
+	// the pools must exist before rgw starts, otherwise rgw creates them with the default settings
+	for _, pool := range s.pools() {
+		if err := pool.Update(rclient); err != nil {
+			return fmt.Errorf("failed to create pool %s for object store %s. %+v", pool.Name, s.Name, err)
+		}
+	}
+
+	logger.Infof("starting rgw for object store %s in namespace %s", s.Name, s.Namespace)
+	r := s.gateway(context, version, placement, resources, network)
+	if err := r.Start(); err != nil {
+		return fmt.Errorf("failed to start rgw. %+v", err)
+	}
+	if err := r.Update(); err != nil {
+		return fmt.Errorf("failed to update rgw. %+v", err)
+	}
+	return nil
+}
+
+// Delete the gateways of the object store. The pools are not deleted so that the objects are not lost.
+func (s *ObjectStore) Delete(context *clusterd.Context) error {
+	logger.Infof("deleting rgw for object store %s in namespace %s", s.Name, s.Namespace)
+	return rgw.New(context, s.Namespace, "", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{}).Delete()
+}
+
+// gateway returns the rgw settings for the object store
+func (s *ObjectStore) gateway(context *clusterd.Context, version string, placement k8sutil.Placement,
+	resources v1.ResourceRequirements, network k8sutil.Network) *rgw.Cluster {
+	r := rgw.New(context, s.Namespace, version, placement.Merge(s.Gateway.Placement), resources, network)
+	if s.Gateway.Port != 0 {
+		r.Port = s.Gateway.Port
+	}
+	if s.Gateway.Instances != 0 {
+		r.Replicas = s.Gateway.Instances
+	}
+	return r
+}
+
+// pools returns the metadata and data pools of the object store
+func (s *ObjectStore) pools() []*Pool {
+	var pools []*Pool
+	for _, name := range objectStoreMetadataPools {
+		pools = append(pools, s.pool(name, s.MetadataPool))
+	}
+	return append(pools, s.pool(objectStoreDataPool, s.DataPool))
+}
+
+func (s *ObjectStore) pool(name string, spec PoolSpec) *Pool {
+	p := NewPool(spec)
+	p.Name = name
+	p.Namespace = s.Namespace
+	return p
+}
+
+// Validate the object store arguments
+func (s *ObjectStore) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if s.Namespace == "" {
+		return fmt.Errorf("missing namespace")
+	}
+	if s.Gateway.Port < 0 {
+		return fmt.Errorf("invalid gateway port %d", s.Gateway.Port)
+	}
+	if s.Gateway.Instances < 0 {
+		return fmt.Errorf("invalid number of gateway instances %d", s.Gateway.Instances)
+	}
+
+	// the bucket index is stored in omap which is not supported by erasure coded pools
+	metadata := s.pool(objectStoreMetadataPools[0], s.MetadataPool)
+	if metadata.erasureCode() != nil {
+		return fmt.Errorf("the metadata pools must be replicated")
+	}
+	if err := metadata.validate(); err != nil {
+		return fmt.Errorf("invalid metadata pool. %+v", err)
+	}
+	if err := s.pool(objectStoreDataPool, s.DataPool).validate(); err != nil {
+		return fmt.Errorf("invalid data pool. %+v", err)
+	}
+	return nil
+}
+
+// getObjectStore returns the object store in the namespace of the cluster that owns the gateways,
+// or nil if there is no object store
+func (c *Cluster) getObjectStore() (*ObjectStore, error) {
+	b, err := kit.GetRawListNamespaced(c.context.Clientset, ObjectStoreResource, c.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	stores := &ObjectStoreList{}
+	if err := json.Unmarshal(b, stores); err != nil {
+		return nil, err
+	}
+	return stores.Owner(), nil
+}
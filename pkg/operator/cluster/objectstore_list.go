@@ -0,0 +1,88 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ObjectStoreList is a list of rook object stores from the TPR.
+type ObjectStoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata
+	// More info: http://releases.k8s.io/HEAD/docs/devel/api-conventions.md#metadata
+	Metadata metav1.ListMeta `json:"metadata,omitempty"`
+	// Items is a list of third party objects
+	Items []ObjectStore `json:"items"`
+}
+
+// There is known issue with TPR in client-go:
+//   https://github.com/kubernetes/client-go/issues/8
+// Workarounds:
+// - We include `Metadata` field in object explicitly.
+// - we have the code below to work around a known problem with third-party resources and ugorji.
+
+// ObjectStoreListCopy is for deserialization
+type ObjectStoreListCopy ObjectStoreList
+
+// ObjectStoreCopy is for deserialization
+type ObjectStoreCopy ObjectStore
+
+// UnmarshalJSON deserializes the object store
+func (s *ObjectStore) UnmarshalJSON(data []byte) error {
+	tmp := ObjectStoreCopy{}
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return err
+	}
+	tmp2 := ObjectStore(tmp)
+	*s = tmp2
+	return nil
+}
+
+// UnmarshalJSON deserializes the object store list
+func (sl *ObjectStoreList) UnmarshalJSON(data []byte) error {
+	tmp := ObjectStoreListCopy{}
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return err
+	}
+	tmp2 := ObjectStoreList(tmp)
+	*sl = tmp2
+	return nil
+}
+
+// Owner returns the object store that owns the gateways in the namespace. Only one object store is supported
+// per namespace, so the store that was created first is the owner. Returns nil if the list is empty.
+func (sl *ObjectStoreList) Owner() *ObjectStore {
+	var owner *ObjectStore
+	for i := range sl.Items {
+		store := &sl.Items[i]
+		if owner == nil {
+			owner = store
+			continue
+		}
+		created, ownerCreated := store.CreationTimestamp.Time, owner.CreationTimestamp.Time
+		if created.Before(ownerCreated) || (created.Equal(ownerCreated) && store.Name < owner.Name) {
+			owner = store
+		}
+	}
+	return owner
+}
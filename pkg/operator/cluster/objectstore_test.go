@@ -0,0 +1,168 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/rook/rook/pkg/rook/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateObjectStore(t *testing.T) {
+	s := ObjectStore{ObjectMeta: v1.ObjectMeta{Name: "mystore", Namespace: "myns"}}
+	s.MetadataPool.Replication.Size = 1
+	s.DataPool.ErasureCoding.CodingChunks = 1
+	s.DataPool.ErasureCoding.DataChunks = 2
+	assert.Nil(t, s.validate())
+
+	// the metadata pools must be replicated
+	s.MetadataPool.Replication.Size = 0
+	s.MetadataPool.ErasureCoding.CodingChunks = 1
+	s.MetadataPool.ErasureCoding.DataChunks = 2
+	assert.NotNil(t, s.validate())
+
+	// the pool settings are required
+	s.MetadataPool = PoolSpec{}
+	assert.NotNil(t, s.validate())
+	s.MetadataPool.Replication.Size = 1
+	s.DataPool = PoolSpec{}
+	assert.NotNil(t, s.validate())
+
+	// the gateway settings must be valid
+	s.DataPool.Replication.Size = 1
+	s.Gateway.Port = -1
+	assert.NotNil(t, s.validate())
+	s.Gateway.Port = 0
+	s.Gateway.Instances = -1
+	assert.NotNil(t, s.validate())
+}
+
+func TestCreateObjectStore(t *testing.T) {
+	clientset := testop.New(3)
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return "{\"key\":\"mysecurekey\"}", nil
+		},
+	}
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}, Executor: executor, ConfigDir: configDir}
+
+	created := map[string]model.Pool{}
+	rclient := &test.MockRookRestClient{
+		MockGetPools: func() ([]model.Pool, error) {
+			pools := []model.Pool{}
+			for _, pool := range created {
+				pools = append(pools, pool)
+			}
+			return pools, nil
+		},
+		MockCreatePool: func(pool model.Pool) (string, error) {
+			created[pool.Name] = pool
+			return "", nil
+		},
+	}
+
+	s := ObjectStore{ObjectMeta: v1.ObjectMeta{Name: "mystore", Namespace: "myns"}}
+	s.MetadataPool.Replication.Size = 3
+	s.DataPool.ErasureCoding.CodingChunks = 1
+	s.DataPool.ErasureCoding.DataChunks = 2
+	s.Gateway.Port = 80
+	s.Gateway.Instances = 3
+	err := s.Create(context, rclient, "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	assert.Nil(t, err)
+
+	// the metadata and data pools are created with the pool specs
+	names := []string{}
+	for name := range created {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{".rgw.root", "default.rgw.buckets.data", "default.rgw.buckets.index", "default.rgw.control",
+		"default.rgw.log", "default.rgw.meta"}, names)
+	assert.Equal(t, model.Replicated, created["default.rgw.meta"].Type)
+	assert.Equal(t, uint(3), created["default.rgw.meta"].ReplicationConfig.Size)
+	assert.Equal(t, model.ErasureCoded, created["default.rgw.buckets.data"].Type)
+	assert.Equal(t, uint(2), created["default.rgw.buckets.data"].ErasureCodedConfig.DataChunkCount)
+
+	// the gateways are started with the gateway spec
+	d, err := clientset.ExtensionsV1beta1().Deployments("myns").Get("rook-ceph-rgw", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), *d.Spec.Replicas)
+	assert.Equal(t, "rook/rook:myversion", d.Spec.Template.Spec.Containers[0].Image)
+	assert.Contains(t, d.Spec.Template.Spec.Containers[0].Args, "--rgw-port=80")
+	service, err := clientset.CoreV1().Services("myns").Get("rook-ceph-rgw", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(80), service.Spec.Ports[0].Port)
+
+	// the gateway changes are applied to the running gateways
+	s.Gateway.Port = 8080
+	s.Gateway.Instances = 1
+	err = s.Update(context, rclient, "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	assert.Nil(t, err)
+	d, err = clientset.ExtensionsV1beta1().Deployments("myns").Get("rook-ceph-rgw", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), *d.Spec.Replicas)
+	assert.Contains(t, d.Spec.Template.Spec.Containers[0].Args, "--rgw-port=8080")
+	service, err = clientset.CoreV1().Services("myns").Get("rook-ceph-rgw", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(8080), service.Spec.Ports[0].Port)
+
+	// the gateways are removed when the store is deleted, but the pools are kept
+	err = s.Delete(context)
+	assert.Nil(t, err)
+	_, err = clientset.ExtensionsV1beta1().Deployments("myns").Get("rook-ceph-rgw", metav1.GetOptions{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 6, len(created))
+
+	// the gateways are not started with an invalid spec
+	s.DataPool = PoolSpec{}
+	err = s.Create(context, rclient, "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	assert.NotNil(t, err)
+	_, err = clientset.ExtensionsV1beta1().Deployments("myns").Get("rook-ceph-rgw", metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestObjectStoreOwner(t *testing.T) {
+	list := &ObjectStoreList{}
+	assert.Nil(t, list.Owner())
+
+	now := time.Now()
+	list.Items = []ObjectStore{
+		{ObjectMeta: v1.ObjectMeta{Name: "b", CreationTimestamp: metav1.NewTime(now)}},
+		{ObjectMeta: v1.ObjectMeta{Name: "c", CreationTimestamp: metav1.NewTime(now.Add(-time.Minute))}},
+		{ObjectMeta: v1.ObjectMeta{Name: "a", CreationTimestamp: metav1.NewTime(now)}},
+	}
+	assert.Equal(t, "c", list.Owner().Name)
+
+	// the name breaks the tie when the stores were created at the same time
+	list.Items = list.Items[0:1]
+	list.Items = append(list.Items, ObjectStore{ObjectMeta: v1.ObjectMeta{Name: "a", CreationTimestamp: metav1.NewTime(now)}})
+	assert.Equal(t, "a", list.Owner().Name)
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ObjectUserResource is the definition of the object store user TPR
+var ObjectUserResource = kit.CustomResource{
+	Name:        "objectstoreuser",
+	Group:       k8sutil.CustomResourceGroup,
+	Version:     kit.V1Alpha1,
+	Description: "Managed Rook object store users",
+}
+
+// The keys of the credentials in the user secret. The keys are the environment variables of the s3 clients
+// so the secret can be consumed with envFrom.
+const (
+	ObjectUserHostKey      = "AWS_HOST"
+	ObjectUserEndpointKey  = "AWS_ENDPOINT"
+	ObjectUserAccessKey    = "AWS_ACCESS_KEY_ID"
+	ObjectUserSecretKey    = "AWS_SECRET_ACCESS_KEY"
+	objectUserSecretPrefix = "rook-object-user-"
+	objectUserIDPrefix     = "rook-user-"
+
+	// the label with the uid of the resource that the secrets with the credentials are published for
+	objectOwnerAttr = "rook_object_owner"
+)
+
+// ObjectUserState is the result of the last attempt to apply the spec of an object store user
+type ObjectUserState string
+
+const (
+	// ObjectUserStateCreated means the user exists with the settings in the spec and the secret is published
+	ObjectUserStateCreated ObjectUserState = "Created"
+	// ObjectUserStateFailed means the spec could not be applied to the user
+	ObjectUserStateFailed ObjectUserState = "Failed"
+)
+
+// ObjectUser is the spec for the object store user TPR. The id of the user in the object store is derived from the uid
+// of the resource.
+type ObjectUser struct {
+	v1.ObjectMeta  `json:"metadata,omitempty"`
+	ObjectUserSpec `json:"spec"`
+	Status         ObjectUserStatus `json:"status,omitempty"`
+}
+
+// ObjectUserSpec represents the spec of an object store user
+type ObjectUserSpec struct {
+	// The display name of the user. The name of the resource is used if not specified.
+	DisplayName string `json:"displayName"`
+
+	// The email of the user
+	Email string `json:"email"`
+
+	// The namespace where the secret with the credentials is created. Default is the namespace of the user. Other
+	// namespaces must be allowed in the cluster spec.
+	SecretNamespace string `json:"secretNamespace"`
+}
+
+// ObjectUserStatus is the status of the user that the operator records in the user resource
+type ObjectUserStatus struct {
+	// Whether the spec was applied to the user
+	State ObjectUserState `json:"state,omitempty"`
+
+	// The name of the secret with the credentials of the user
+	Secret string `json:"secret,omitempty"`
+
+	// The namespace of the secret with the credentials of the user
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+
+	// The id of the user in the object store
+	UserID string `json:"userId,omitempty"`
+
+	// The reason the spec could not be applied
+	Message string `json:"message,omitempty"`
+}
+
+// Create the user in the object store and publish the credentials in a secret
+func (u *ObjectUser) Create(context *clusterd.Context, rclient rookclient.RookRestClient, allowedSecretNamespaces []string) error {
+	return u.Update(context, rclient, allowedSecretNamespaces)
+}
+
+// Update applies the display name and email to the user in the object store. The user is created if it does not
+// exist yet. The secret with the credentials and the endpoint of the object store is created or updated in the
+// namespace of the resource, or in another namespace that the cluster allows. The result is recorded in the status of
+// the user, which the caller must save.
+func (u *ObjectUser) Update(context *clusterd.Context, rclient rookclient.RookRestClient, allowedSecretNamespaces []string) error {
+	if err := u.validate(allowedSecretNamespaces); err != nil {
+		return fmt.Errorf("invalid object user %s arguments. %+v", u.Name, err)
+	}
+
+	// the id is unique to the resource, so the user is always owned by the resource
+	user, err := applyObjectUser(rclient, u.model(), true)
+	if err != nil {
+		return err
+	}
+	u.Status.UserID = u.userID()
+
+	info, err := rclient.GetObjectStoreConnectionInfo()
+	if err != nil {
+		return fmt.Errorf("failed to get the object store connection info. %+v", err)
+	}
+	if info == nil {
+		return fmt.Errorf("the object store connection info is not available")
+	}
+
+	// the service of the object store is in the namespace of the cluster, which is the namespace of the user
+	secretNamespace := u.secretNamespace()
+	err = saveSecret(context, secretNamespace, u.SecretName(), string(u.UID), map[string]string{
+		ObjectUserHostKey:     fmt.Sprintf("%s.%s", info.Host, u.Namespace),
+		ObjectUserEndpointKey: info.IPEndpoint,
+		ObjectUserAccessKey:   *user.AccessKey,
+		ObjectUserSecretKey:   *user.SecretKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	// remove the secret from the namespace where it was published before
+	if u.Status.SecretNamespace != "" && u.Status.SecretNamespace != secretNamespace {
+		if err := deleteSecret(context, u.Status.SecretNamespace, u.SecretName(), string(u.UID)); err != nil {
+			return err
+		}
+	}
+	u.Status.Secret = u.SecretName()
+	u.Status.SecretNamespace = secretNamespace
+	return nil
+}
+
+// Delete the user created for this resource from the object store, and remove the secret with the credentials
+func (u *ObjectUser) Delete(context *clusterd.Context, rclient rookclient.RookRestClient) error {
+	if u.UID == "" {
+		logger.Infof("object user %s in namespace %s has no uid. no user to delete", u.Name, u.Namespace)
+		return nil
+	}
+
+	logger.Infof("deleting object user %s of resource %s in namespace %s", u.userID(), u.Name, u.Namespace)
+	if err := rclient.DeleteObjectUser(u.userID()); err != nil && !rookclient.IsHttpNotFound(err) {
+		return fmt.Errorf("failed to delete object user %s. %+v", u.userID(), err)
+	}
+
+	secretNamespace := u.Status.SecretNamespace
+	if secretNamespace == "" {
+		secretNamespace = u.secretNamespace()
+	}
+	return deleteSecret(context, secretNamespace, u.SecretName(), string(u.UID))
+}
+
+// SecretName returns the name of the secret with the credentials of the user
+func (u *ObjectUser) SecretName() string {
+	return objectUserSecretPrefix + u.Name
+}
+
+// userID returns the id of the user in the object store. The id is unique to the resource, so a user created outside of
+// rook or for another resource is never taken over, and a user created by a previous attempt is always recognized.
+func (u *ObjectUser) userID() string {
+	return objectUserIDPrefix + string(u.UID)
+}
+
+func (u *ObjectUser) secretNamespace() string {
+	if u.SecretNamespace != "" {
+		return u.SecretNamespace
+	}
+	return u.Namespace
+}
+
+// applyObjectUser creates the user in the object store or updates the display name and email if they changed.
+// The user is returned with its keys. An existing user is only updated if it was created by the caller before, so that
+// users created outside of rook are not taken over.
+func applyObjectUser(rclient rookclient.RookRestClient, desired model.ObjectUser, created bool) (*model.ObjectUser, error) {
+	user, err := rclient.GetObjectUser(desired.UserID)
+	if err != nil && !rookclient.IsHttpNotFound(err) {
+		return nil, fmt.Errorf("failed to get object user %s. %+v", desired.UserID, err)
+	}
+	if user != nil && !created {
+		return nil, fmt.Errorf("object user %s already exists and was not created by rook", desired.UserID)
+	}
+	if user == nil {
+		logger.Infof("creating object user %s", desired.UserID)
+		if user, err = rclient.CreateObjectUser(desired); err != nil {
+			return nil, fmt.Errorf("failed to create object user %s. %+v", desired.UserID, err)
+		}
+	} else if stringValue(user.DisplayName) != stringValue(desired.DisplayName) || stringValue(user.Email) != stringValue(desired.Email) {
+		logger.Infof("updating object user %s", desired.UserID)
+		if user, err = rclient.UpdateObjectUser(desired); err != nil {
+			return nil, fmt.Errorf("failed to update object user %s. %+v", desired.UserID, err)
+		}
+	}
+	if user == nil || user.AccessKey == nil || user.SecretKey == nil {
+		return nil, fmt.Errorf("the keys of object user %s are not available", desired.UserID)
+	}
+	return user, nil
+}
+
+// saveSecret creates the secret with the given data or updates it if the data changed. The secret is labeled with the
+// uid of the resource it is published for. An existing secret that was not created by rook for the resource is not
+// changed.
+func saveSecret(context *clusterd.Context, namespace, name, owner string, data map[string]string) error {
+	secrets := context.Clientset.CoreV1().Secrets(namespace)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{objectOwnerAttr: owner}},
+		StringData: data,
+		Type:       k8sutil.RookType,
+	}
+
+	existing, err := secrets.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get secret %s. %+v", name, err)
+		}
+		if _, err := secrets.Create(secret); err != nil {
+			return fmt.Errorf("failed to create secret %s. %+v", name, err)
+		}
+		logger.Infof("created secret %s in namespace %s", name, namespace)
+		return nil
+	}
+
+	if !secretOwned(existing, owner) {
+		return fmt.Errorf("secret %s in namespace %s was not created by rook for this resource", name, namespace)
+	}
+	if secretDataEqual(existing, data) {
+		return nil
+	}
+	secret.ResourceVersion = existing.ResourceVersion
+	if _, err := secrets.Update(secret); err != nil {
+		return fmt.Errorf("failed to update secret %s. %+v", name, err)
+	}
+	logger.Infof("updated secret %s in namespace %s", name, namespace)
+	return nil
+}
+
+// deleteSecret removes the secret if it was created by rook for the resource
+func deleteSecret(context *clusterd.Context, namespace, name, owner string) error {
+	secrets := context.Clientset.CoreV1().Secrets(namespace)
+	existing, err := secrets.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get secret %s. %+v", name, err)
+	}
+	if !secretOwned(existing, owner) {
+		logger.Warningf("secret %s in namespace %s was not created by rook for the resource. not deleting it", name, namespace)
+		return nil
+	}
+	return k8sutil.DeleteResource("secret", name, secrets.Delete)
+}
+
+// secretOwned returns whether the secret was created by rook for the resource with the uid
+func secretOwned(secret *v1.Secret, owner string) bool {
+	return secret.Type == k8sutil.RookType && secret.Labels[objectOwnerAttr] == owner
+}
+
+// secretDataEqual checks whether the secret already contains the data
+func secretDataEqual(secret *v1.Secret, data map[string]string) bool {
+	if len(secret.Data)+len(secret.StringData) != len(data) {
+		return false
+	}
+	for key, value := range data {
+		if string(secret.Data[key]) != value && secret.StringData[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// model returns the user settings for the rest api
+func (u *ObjectUser) model() model.ObjectUser {
+	displayName := u.DisplayName
+	if displayName == "" {
+		displayName = u.Name
+	}
+	user := model.ObjectUser{UserID: u.userID(), DisplayName: &displayName}
+	if u.Email != "" {
+		email := u.Email
+		user.Email = &email
+	}
+	return user
+}
+
+// Validate the user arguments. The secret can only be published in the namespace of the user or in the namespaces
+// allowed by the cluster.
+func (u *ObjectUser) validate(allowedSecretNamespaces []string) error {
+	if u.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if u.Namespace == "" {
+		return fmt.Errorf("missing namespace")
+	}
+	if u.UID == "" {
+		return fmt.Errorf("missing uid")
+	}
+	if namespace := u.secretNamespace(); namespace != u.Namespace {
+		for _, allowed := range allowedSecretNamespaces {
+			if namespace == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("secret namespace %s is not allowed by the cluster", namespace)
+	}
+	return nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
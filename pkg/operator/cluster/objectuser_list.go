@@ -0,0 +1,70 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ObjectUserList is a list of rook object store users from the TPR.
+type ObjectUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	// Standard list metadata
+	// More info: http://releases.k8s.io/HEAD/docs/devel/api-conventions.md#metadata
+	Metadata metav1.ListMeta `json:"metadata,omitempty"`
+	// Items is a list of third party objects
+	Items []ObjectUser `json:"items"`
+}
+
+// There is known issue with TPR in client-go:
+//   https://github.com/kubernetes/client-go/issues/8
+// Workarounds:
+// - We include `Metadata` field in object explicitly.
+// - we have the code below to work around a known problem with third-party resources and ugorji.
+
+// ObjectUserListCopy is for deserialization
+type ObjectUserListCopy ObjectUserList
+
+// ObjectUserCopy is for deserialization
+type ObjectUserCopy ObjectUser
+
+// UnmarshalJSON deserializes the object store user
+func (u *ObjectUser) UnmarshalJSON(data []byte) error {
+	tmp := ObjectUserCopy{}
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return err
+	}
+	tmp2 := ObjectUser(tmp)
+	*u = tmp2
+	return nil
+}
+
+// UnmarshalJSON deserializes the object store user list
+func (ul *ObjectUserList) UnmarshalJSON(data []byte) error {
+	tmp := ObjectUserListCopy{}
+	err := json.Unmarshal(data, &tmp)
+	if err != nil {
+		return err
+	}
+	tmp2 := ObjectUserList(tmp)
+	*ul = tmp2
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	"github.com/rook/rook/pkg/rook/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestObjectUser(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}
+
+	var users map[string]model.ObjectUser
+	deleted := []string{}
+	newUser := func(user model.ObjectUser, accessKey string) *model.ObjectUser {
+		secretKey := accessKey + "-secret"
+		user.AccessKey = &accessKey
+		user.SecretKey = &secretKey
+		users[user.UserID] = user
+		return &user
+	}
+	users = map[string]model.ObjectUser{}
+	rclient := &test.MockRookRestClient{
+		MockGetObjectUser: func(id string) (*model.ObjectUser, error) {
+			if user, ok := users[id]; ok {
+				return &user, nil
+			}
+			return nil, rookclient.RookRestError{Status: http.StatusNotFound}
+		},
+		MockCreateObjectUser: func(user model.ObjectUser) (*model.ObjectUser, error) {
+			return newUser(user, "key1"), nil
+		},
+		MockUpdateObjectUser: func(user model.ObjectUser) (*model.ObjectUser, error) {
+			return newUser(user, *users[user.UserID].AccessKey), nil
+		},
+		MockDeleteObjectUser: func(id string) error {
+			deleted = append(deleted, id)
+			return nil
+		},
+		MockGetObjectStoreConnectionInfo: func() (*model.ObjectStoreConnectInfo, error) {
+			return &model.ObjectStoreConnectInfo{Host: "rook-ceph-rgw", IPEndpoint: "1.2.3.4:53390"}, nil
+		},
+	}
+
+	// the user id is derived from the uid and the resource name is the display name
+	u := ObjectUser{ObjectMeta: v1.ObjectMeta{Name: "myuser", Namespace: "myns", UID: "123"}}
+	err := u.Create(context, rclient, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "myuser", *users["rook-user-123"].DisplayName)
+	assert.Nil(t, users["rook-user-123"].Email)
+	assert.Equal(t, "rook-user-123", u.Status.UserID)
+	assert.Equal(t, "rook-object-user-myuser", u.Status.Secret)
+	assert.Equal(t, "myns", u.Status.SecretNamespace)
+
+	// the credentials and the endpoint are published in the secret
+	secret, err := clientset.CoreV1().Secrets("myns").Get("rook-object-user-myuser", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"AWS_HOST":              "rook-ceph-rgw.myns",
+		"AWS_ENDPOINT":          "1.2.3.4:53390",
+		"AWS_ACCESS_KEY_ID":     "key1",
+		"AWS_SECRET_ACCESS_KEY": "key1-secret",
+	}, secret.StringData)
+	assert.Equal(t, "123", secret.Labels["rook_object_owner"])
+
+	// the display name and email are updated, also when the status of the event does not record the user yet
+	u.DisplayName = "My User"
+	u.Email = "user@example.com"
+	u.Status = ObjectUserStatus{}
+	err = u.Update(context, rclient, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "My User", *users["rook-user-123"].DisplayName)
+	assert.Equal(t, "user@example.com", *users["rook-user-123"].Email)
+	assert.Equal(t, "rook-user-123", u.Status.UserID)
+
+	// the secret is not published in a namespace that the cluster does not allow
+	u.SecretNamespace = "appns"
+	err = u.Update(context, rclient, []string{"otherns"})
+	assert.NotNil(t, err)
+	_, err = clientset.CoreV1().Secrets("appns").Get("rook-object-user-myuser", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// the secret is moved to the allowed target namespace
+	err = u.Update(context, rclient, []string{"otherns", "appns"})
+	assert.Nil(t, err)
+	assert.Equal(t, "appns", u.Status.SecretNamespace)
+	_, err = clientset.CoreV1().Secrets("appns").Get("rook-object-user-myuser", metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, err = clientset.CoreV1().Secrets("myns").Get("rook-object-user-myuser", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// the user and the secret are removed
+	err = u.Delete(context, rclient)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rook-user-123"}, deleted)
+	_, err = clientset.CoreV1().Secrets("appns").Get("rook-object-user-myuser", metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	// a user that was not created by rook is not changed or deleted
+	newUser(model.ObjectUser{UserID: "admin"}, "adminkey")
+	admin := ObjectUser{ObjectMeta: v1.ObjectMeta{Name: "admin", Namespace: "myns", UID: "456"}}
+	err = admin.Create(context, rclient, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "adminkey", *users["admin"].AccessKey)
+	assert.Nil(t, users["admin"].DisplayName)
+	err = admin.Delete(context, rclient)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rook-user-123", "rook-user-456"}, deleted)
+
+	// a secret that was not created by rook for the user is not updated or deleted
+	_, err = clientset.CoreV1().Secrets("myns").Create(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "rook-object-user-other"},
+		StringData: map[string]string{"password": "mine"}})
+	assert.Nil(t, err)
+	other := ObjectUser{ObjectMeta: v1.ObjectMeta{Name: "other", Namespace: "myns", UID: "789"}}
+	err = other.Create(context, rclient, nil)
+	assert.NotNil(t, err)
+	err = other.Delete(context, rclient)
+	assert.Nil(t, err)
+	secret, err = clientset.CoreV1().Secrets("myns").Get("rook-object-user-other", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"password": "mine"}, secret.StringData)
+
+	// fail if the object store is not running
+	rclient.MockGetObjectStoreConnectionInfo = func() (*model.ObjectStoreConnectInfo, error) {
+		return nil, rookclient.RookRestError{Status: http.StatusNotFound}
+	}
+	err = u.Update(context, rclient, nil)
+	assert.NotNil(t, err)
+}
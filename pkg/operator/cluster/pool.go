@@ -44,10 +44,30 @@ var PoolResource = kit.CustomResource{
 	Description: "Managed Rook pools",
 }
 
+// PoolState is the result of the last attempt to apply the spec of a pool
+type PoolState string
+
+const (
+	// PoolStateCreated means the pool exists with the settings in the spec
+	PoolStateCreated PoolState = "Created"
+	// PoolStateFailed means the pool could not be created or the spec could not be applied to the pool
+	PoolStateFailed PoolState = "Failed"
+)
+
 // Pool is the spec for the CRD
 type Pool struct {
 	v1.ObjectMeta `json:"metadata,omitempty"`
 	PoolSpec      `json:"spec"`
+	Status        PoolStatus `json:"status,omitempty"`
+}
+
+// PoolStatus is the status of the pool that the operator records in the pool resource
+type PoolStatus struct {
+	// Whether the spec was applied to the pool
+	State PoolState `json:"state,omitempty"`
+
+	// The reason the spec could not be applied
+	Message string `json:"message,omitempty"`
 }
 
 // NewPool creates a new pool
@@ -70,7 +90,7 @@ func (p *Pool) Create(rclient rookclient.RookRestClient) error {
 	}
 
 	// create the pool
-	pool := model.Pool{Name: p.Name}
+	pool := model.Pool{Name: p.Name, CrushRoot: p.CrushRoot}
 
 	r := p.replication()
 	if r != nil {
@@ -94,31 +114,99 @@ func (p *Pool) Create(rclient rookclient.RookRestClient) error {
 	return nil
 }
 
+// Update applies the changes in the spec to an existing pool. The pool is created if it does not exist yet.
+// Only the replication size can be changed. Changes to the type of the pool or the erasure code settings are rejected.
+func (p *Pool) Update(rclient rookclient.RookRestClient) error {
+	if err := p.validate(); err != nil {
+		return fmt.Errorf("invalid pool %s arguments. %+v", p.Name, err)
+	}
+
+	existing, err := p.get(rclient)
+	if err != nil {
+		return fmt.Errorf("failed to get pool %s. %+v", p.Name, err)
+	}
+	if existing == nil {
+		return p.Create(rclient)
+	}
+
+	if err := p.validateUpdate(*existing); err != nil {
+		return fmt.Errorf("invalid update for pool %s. %+v", p.Name, err)
+	}
+
+	r := p.replication()
+	if r == nil || r.Size == existing.ReplicationConfig.Size {
+		logger.Infof("no changes to pool %s in namespace %s", p.Name, p.Namespace)
+		return nil
+	}
+
+	logger.Infof("updating pool %s in namespace %s from replicas %d to %d", p.Name, p.Namespace, existing.ReplicationConfig.Size, r.Size)
+	pool := model.Pool{Name: p.Name, Type: model.Replicated}
+	pool.ReplicationConfig.Size = r.Size
+	if _, err := rclient.UpdatePool(pool); err != nil {
+		return fmt.Errorf("failed to update pool %s. %+v", p.Name, err)
+	}
+	return nil
+}
+
 // Delete the pool
 func (p *Pool) Delete(rclient rookclient.RookRestClient) error {
 	// check if the pool  exists
 	exists, err := p.exists(rclient)
-	if err == nil && !exists {
+	if err != nil {
+		return fmt.Errorf("failed to check if pool %s exists. %+v", p.Name, err)
+	}
+	if !exists {
 		return nil
 	}
 
-	logger.Infof("TODO: delete pool %s from namespace %s", p.Name, p.Namespace)
-	//return p.client.DeletePool(p.PoolSpec.Name)
+	logger.Infof("deleting pool %s from namespace %s", p.Name, p.Namespace)
+	if _, err := rclient.DeletePool(p.Name); err != nil {
+		return fmt.Errorf("failed to delete pool %s. %+v", p.Name, err)
+	}
 	return nil
 }
 
 // Check if the pool exists
 func (p *Pool) exists(rclient rookclient.RookRestClient) (bool, error) {
+	pool, err := p.get(rclient)
+	return pool != nil, err
+}
+
+// get the pool with the same name, or nil if the pool does not exist
+func (p *Pool) get(rclient rookclient.RookRestClient) (*model.Pool, error) {
 	pools, err := rclient.GetPools()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	for _, pool := range pools {
-		if pool.Name == p.Name {
-			return true, nil
+	for i := range pools {
+		if pools[i].Name == p.Name {
+			return &pools[i], nil
 		}
 	}
-	return false, nil
+	return nil, nil
+}
+
+// validateUpdate checks that the spec only contains changes that can be applied to the existing pool
+func (p *Pool) validateUpdate(existing model.Pool) error {
+	if existing.Type == model.ErasureCoded {
+		ec := p.erasureCode()
+		if ec == nil {
+			return fmt.Errorf("an erasure coded pool cannot be changed to a replicated pool")
+		}
+		if ec.DataChunks != existing.ErasureCodedConfig.DataChunkCount || ec.CodingChunks != existing.ErasureCodedConfig.CodingChunkCount {
+			return fmt.Errorf("the erasure code settings cannot be changed from %d data and %d coding chunks",
+				existing.ErasureCodedConfig.DataChunkCount, existing.ErasureCodedConfig.CodingChunkCount)
+		}
+		return nil
+	}
+
+	if p.replication() == nil {
+		return fmt.Errorf("a replicated pool cannot be changed to an erasure coded pool")
+	}
+	if p.CrushRoot != existing.CrushRoot {
+		return fmt.Errorf("the crush root cannot be changed from %q", existing.CrushRoot)
+	}
+	return nil
 }
 
 // Validate the pool arguments
@@ -135,6 +223,9 @@ func (p *Pool) validate() error {
 	if p.replication() == nil && p.erasureCode() == nil {
 		return fmt.Errorf("neither replication nor erasure code settings were specified")
 	}
+	if p.CrushRoot != "" && p.replication() == nil {
+		return fmt.Errorf("only the replicas of a replicated pool can be placed under a crush root")
+	}
 	return nil
 }
 
@@ -19,6 +19,7 @@ which also has the apache 2.0 license.
 package cluster
 
 import (
+	"fmt"
 	"testing"
 
 	"k8s.io/api/core/v1"
@@ -64,6 +65,15 @@ func TestValidatePool(t *testing.T) {
 	p.ErasureCoding.DataChunks = 2
 	err = p.validate()
 	assert.Nil(t, err)
+
+	// only replicated pools can be placed under a crush root
+	p.CrushRoot = "zone1"
+	err = p.validate()
+	assert.NotNil(t, err)
+	p.ErasureCoding = ErasureCodeSpec{}
+	p.Replication.Size = 3
+	err = p.validate()
+	assert.Nil(t, err)
 }
 
 func TestCreatePool(t *testing.T) {
@@ -89,6 +99,7 @@ func TestCreatePool(t *testing.T) {
 }
 
 func TestDeletePool(t *testing.T) {
+	deleted := []string{}
 	rclient := &test.MockRookRestClient{
 		MockGetPools: func() ([]model.Pool, error) {
 			pools := []model.Pool{
@@ -96,6 +107,10 @@ func TestDeletePool(t *testing.T) {
 			}
 			return pools, nil
 		},
+		MockDeletePool: func(name string) (string, error) {
+			deleted = append(deleted, name)
+			return "", nil
+		},
 	}
 
 	// delete a pool that exists
@@ -105,6 +120,7 @@ func TestDeletePool(t *testing.T) {
 	assert.True(t, exists)
 	err = p.Delete(rclient)
 	assert.Nil(t, err)
+	assert.Equal(t, []string{"mypool"}, deleted)
 
 	// succeed even if the pool doesn't exist
 	p = Pool{ObjectMeta: v1.ObjectMeta{Name: "otherpool", Namespace: "myns"}}
@@ -113,4 +129,94 @@ func TestDeletePool(t *testing.T) {
 	assert.False(t, exists)
 	err = p.Delete(rclient)
 	assert.Nil(t, err)
+	assert.Equal(t, []string{"mypool"}, deleted)
+
+	// fail if the pools cannot be listed
+	rclient.MockGetPools = func() ([]model.Pool, error) {
+		return nil, fmt.Errorf("mock failure")
+	}
+	err = p.Delete(rclient)
+	assert.NotNil(t, err)
+}
+
+func TestUpdatePool(t *testing.T) {
+	var updated *model.Pool
+	created := false
+	rclient := &test.MockRookRestClient{
+		MockGetPools: func() ([]model.Pool, error) {
+			replicated := model.Pool{Name: "replpool", Type: model.Replicated}
+			replicated.ReplicationConfig.Size = 1
+			ec := model.Pool{Name: "ecpool", Type: model.ErasureCoded}
+			ec.ErasureCodedConfig.DataChunkCount = 2
+			ec.ErasureCodedConfig.CodingChunkCount = 1
+			return []model.Pool{replicated, ec}, nil
+		},
+		MockCreatePool: func(pool model.Pool) (string, error) {
+			created = true
+			return "", nil
+		},
+		MockUpdatePool: func(pool model.Pool) (string, error) {
+			updated = &pool
+			return "", nil
+		},
+	}
+
+	// the replication size is updated
+	p := Pool{ObjectMeta: v1.ObjectMeta{Name: "replpool", Namespace: "myns"}}
+	p.Replication.Size = 3
+	err := p.Update(rclient)
+	assert.Nil(t, err)
+	assert.Equal(t, "replpool", updated.Name)
+	assert.Equal(t, uint(3), updated.ReplicationConfig.Size)
+
+	// the crush root cannot be changed
+	updated = nil
+	p.CrushRoot = "zone1"
+	err = p.Update(rclient)
+	assert.NotNil(t, err)
+	assert.Nil(t, updated)
+	p.CrushRoot = ""
+
+	// no update if the size did not change
+	updated = nil
+	p.Replication.Size = 1
+	err = p.Update(rclient)
+	assert.Nil(t, err)
+	assert.Nil(t, updated)
+
+	// a replicated pool cannot be changed to erasure coded
+	p.Replication.Size = 0
+	p.ErasureCoding.DataChunks = 2
+	p.ErasureCoding.CodingChunks = 1
+	err = p.Update(rclient)
+	assert.NotNil(t, err)
+
+	// the erasure code settings cannot be changed
+	p = Pool{ObjectMeta: v1.ObjectMeta{Name: "ecpool", Namespace: "myns"}}
+	p.ErasureCoding.DataChunks = 3
+	p.ErasureCoding.CodingChunks = 1
+	err = p.Update(rclient)
+	assert.NotNil(t, err)
+
+	// an erasure coded pool cannot be changed to replicated
+	p.ErasureCoding = ErasureCodeSpec{}
+	p.Replication.Size = 2
+	err = p.Update(rclient)
+	assert.NotNil(t, err)
+
+	// no changes to the erasure coded pool
+	p.Replication.Size = 0
+	p.ErasureCoding.DataChunks = 2
+	p.ErasureCoding.CodingChunks = 1
+	err = p.Update(rclient)
+	assert.Nil(t, err)
+	assert.Nil(t, updated)
+	assert.False(t, created)
+
+	// the pool is created if it does not exist
+	p = Pool{ObjectMeta: v1.ObjectMeta{Name: "newpool", Namespace: "myns"}}
+	p.Replication.Size = 1
+	err = p.Update(rclient)
+	assert.Nil(t, err)
+	assert.True(t, created)
 }
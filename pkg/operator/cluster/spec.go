@@ -23,6 +23,7 @@ package cluster
 import (
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/osd"
+	"k8s.io/api/core/v1"
 )
 
 // Spec for the cluster
@@ -41,10 +42,37 @@ type Spec struct {
 	// The placement-related configuration to pass to kubernetes (affinity, node selector, tolerations).
 	Placement PlacementSpec `json:"placement,omitempty"`
 
+	// The cpu and memory requests and limits of the daemons. The osds of a node can override them in the storage spec.
+	Resources ResourceSpec `json:"resources,omitempty"`
+
+	// The host networking and the public and cluster networks of the daemons
+	Network k8sutil.Network `json:"network,omitempty"`
+
+	// Ceph settings indexed by the section (global, osd, osd.3) and the name of the setting. The settings are
+	// validated against the running ceph version and merged into the config that rook generates for the daemons.
+	CephConfig map[string]map[string]string `json:"cephConfig,omitempty"`
+
 	// A spec for available storage in the cluster and how it should be used
 	Storage osd.StorageSpec `json:"storage"`
+
+	// The namespaces other than the cluster namespace where the object store users may publish their credentials
+	ObjectUserSecretNamespaces []string `json:"objectUserSecretNamespaces,omitempty"`
+
+	// The policy for cleaning up the hosts when the cluster is deleted. By default the data is retained on the hosts.
+	CleanupPolicy CleanupPolicy `json:"cleanupPolicy,omitempty"`
 }
 
+// CleanupPolicy specifies what happens to the data on the hosts when the cluster is deleted
+type CleanupPolicy string
+
+const (
+	// CleanupPolicyRetain leaves the data dir and the osd partitions on the hosts
+	CleanupPolicyRetain CleanupPolicy = "retain"
+
+	// CleanupPolicyDelete removes the contents of the data dir and the partitions created by rook osds from the hosts
+	CleanupPolicyDelete CleanupPolicy = "delete"
+)
+
 // PoolSpec is the specific spec for the redundancy
 type PoolSpec struct {
 	// The replication settings
@@ -52,6 +80,9 @@ type PoolSpec struct {
 
 	// The erasure code setteings
 	ErasureCoding ErasureCodeSpec `json:"erasureCode"`
+
+	// The CRUSH bucket, for example a zone, that the replicas of a replicated pool are placed under
+	CrushRoot string `json:"crushRoot"`
 }
 
 // ReplicationSpec specifies the number of replicas
@@ -74,6 +105,7 @@ type PlacementSpec struct {
 	All k8sutil.Placement `json:"all,omitempty"`
 	API k8sutil.Placement `json:"api,omitempty"`
 	MDS k8sutil.Placement `json:"mds,omitempty"`
+	MGR k8sutil.Placement `json:"mgr,omitempty"`
 	MON k8sutil.Placement `json:"mon,omitempty"`
 	OSD k8sutil.Placement `json:"osd,omitempty"`
 	RGW k8sutil.Placement `json:"rgw,omitempty"`
@@ -85,6 +117,9 @@ func (p PlacementSpec) GetAPI() k8sutil.Placement { return p.All.Merge(p.API) }
 // GetMDS returns the placement for the MDS service
 func (p PlacementSpec) GetMDS() k8sutil.Placement { return p.All.Merge(p.MDS) }
 
+// GetMGR returns the placement for the MGR service
+func (p PlacementSpec) GetMGR() k8sutil.Placement { return p.All.Merge(p.MGR) }
+
 // GetMON returns the placement for the MON service
 func (p PlacementSpec) GetMON() k8sutil.Placement { return p.All.Merge(p.MON) }
 
@@ -93,3 +128,14 @@ func (p PlacementSpec) GetOSD() k8sutil.Placement { return p.All.Merge(p.OSD) }
 
 // GetRGW returns the placement for the RGW service
 func (p PlacementSpec) GetRGW() k8sutil.Placement { return p.All.Merge(p.RGW) }
+
+// ResourceSpec is the set of cpu and memory requests and limits of each type of daemon in the rook cluster. The
+// limits of a daemon are not set if they are not in the spec.
+type ResourceSpec struct {
+	API v1.ResourceRequirements `json:"api,omitempty"`
+	MDS v1.ResourceRequirements `json:"mds,omitempty"`
+	MGR v1.ResourceRequirements `json:"mgr,omitempty"`
+	MON v1.ResourceRequirements `json:"mon,omitempty"`
+	OSD v1.ResourceRequirements `json:"osd,omitempty"`
+	RGW v1.ResourceRequirements `json:"rgw,omitempty"`
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rook/rook/pkg/ceph/client"
+	"github.com/rook/rook/pkg/operator/kit"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterPhase is the stage of the lifecycle of the cluster
+type ClusterPhase string
+
+// ClusterConditionType is the aspect of the cluster that a condition describes
+type ClusterConditionType string
+
+const (
+	// ClusterPhaseCreating means the operator is starting the cluster daemons
+	ClusterPhaseCreating ClusterPhase = "Creating"
+	// ClusterPhaseCreated means all the cluster daemons have been started and the latest spec is applied
+	ClusterPhaseCreated ClusterPhase = "Created"
+	// ClusterPhaseUpdating means the operator is applying changes to the spec
+	ClusterPhaseUpdating ClusterPhase = "Updating"
+	// ClusterPhaseFailed means the cluster could not be created or the last update could not be applied
+	ClusterPhaseFailed ClusterPhase = "Failed"
+	// ClusterPhaseDeleting means the operator is removing the cluster resources
+	ClusterPhaseDeleting ClusterPhase = "Deleting"
+
+	// ClusterConditionReady is true when the cluster daemons have been created
+	ClusterConditionReady ClusterConditionType = "Ready"
+	// ClusterConditionHealthy is true when the ceph health is HEALTH_OK
+	ClusterConditionHealthy ClusterConditionType = "Healthy"
+	// ClusterConditionMonQuorum is true when all the mons are in quorum
+	ClusterConditionMonQuorum ClusterConditionType = "MonQuorum"
+
+	reasonCreating          = "ClusterCreating"
+	reasonCreated           = "ClusterCreated"
+	reasonCreateFailed      = "ClusterCreateFailed"
+	reasonUpdating          = "ClusterUpdating"
+	reasonUpdated           = "ClusterUpdated"
+	reasonUpdateFailed      = "ClusterUpdateFailed"
+	reasonUpdateRejected    = "ClusterUpdateRejected"
+	reasonDeleting          = "ClusterDeleting"
+	reasonStatusUnavailable = "StatusUnavailable"
+	reasonQuorumFormed      = "QuorumFormed"
+	reasonMonsOutOfQuorum   = "MonsOutOfQuorum"
+)
+
+// ClusterStatus is the status of the cluster that the operator records in the cluster resource
+type ClusterStatus struct {
+	// The stage of the lifecycle of the cluster
+	Phase ClusterPhase `json:"phase,omitempty"`
+
+	// Details about the current phase, such as the reason a create or update failed
+	Message string `json:"message,omitempty"`
+
+	// The latest observations of the state of the cluster
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+
+	// The overall ceph health (HEALTH_OK, HEALTH_WARN or HEALTH_ERR)
+	Health string `json:"health,omitempty"`
+
+	// The mons in the monmap and the quorum
+	Mons *MonStatus `json:"mons,omitempty"`
+
+	// The number of osds in the osdmap that are up and in
+	OSDs *OSDStatus `json:"osds,omitempty"`
+
+	// The progress of a version upgrade. Nil if no upgrade is in progress.
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+
+	// The hash of the config override that was applied to the daemons
+	ConfigOverrideHash string `json:"configOverrideHash,omitempty"`
+
+	// The ceph settings in the spec that were not applied to the daemons
+	RejectedCephConfig []RejectedCephConfigSetting `json:"rejectedCephConfig,omitempty"`
+
+	// Whether the ceph settings in the spec were applied without validating the names against the running ceph
+	// version, which cannot list its settings before mimic
+	UnvalidatedCephConfig bool `json:"unvalidatedCephConfig,omitempty"`
+}
+
+// RejectedCephConfigSetting is a ceph setting in the spec that was not applied and the reason
+type RejectedCephConfigSetting struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Reason  string `json:"reason"`
+}
+
+// ClusterCondition describes an aspect of the state of the cluster
+type ClusterCondition struct {
+	Type               ClusterConditionType `json:"type"`
+	Status             v1.ConditionStatus   `json:"status"`
+	Reason             string               `json:"reason,omitempty"`
+	Message            string               `json:"message,omitempty"`
+	LastTransitionTime metav1.Time          `json:"lastTransitionTime,omitempty"`
+}
+
+// MonStatus is the state of the mons as reported by ceph
+type MonStatus struct {
+	// The names of the mons that are in quorum
+	Quorum []string `json:"quorum"`
+
+	// The address of each mon in the monmap, indexed by the mon name
+	Endpoints map[string]string `json:"endpoints"`
+}
+
+// OSDStatus is the state of the osds as reported by ceph
+type OSDStatus struct {
+	Total int `json:"total"`
+	Up    int `json:"up"`
+	In    int `json:"in"`
+}
+
+// UpgradeStatus records the progress of a rolling upgrade so an interrupted upgrade can be resumed
+type UpgradeStatus struct {
+	// The version the cluster is being upgraded from
+	FromVersion string `json:"fromVersion"`
+
+	// The version the cluster is being upgraded to
+	ToVersion string `json:"toVersion"`
+
+	// The daemons that are currently being upgraded
+	Step string `json:"step"`
+
+	// The nodes where the osds have already been upgraded
+	CompletedNodes []string `json:"completedNodes,omitempty"`
+}
+
+// setPhase records the phase of the cluster along with the Ready condition that corresponds to it
+func (c *Cluster) setPhase(phase ClusterPhase, reason, message string) {
+	c.updateStatus(func(status *ClusterStatus) {
+		status.Phase = phase
+		status.Message = message
+
+		ready := v1.ConditionTrue
+		if phase == ClusterPhaseDeleting || reason == reasonCreating || reason == reasonCreateFailed {
+			ready = v1.ConditionFalse
+		}
+		status.setCondition(ClusterConditionReady, ready, reason, message)
+	})
+}
+
+// refreshStatus queries ceph for the health, mon quorum and osd counts of the cluster and records them in the status
+func (c *Cluster) refreshStatus() {
+	cephStatus, err := client.Status(c.context, c.Namespace)
+	if err != nil {
+		// keep the last known state, but indicate that it could not be refreshed
+		logger.Infof("failed to get status of cluster in namespace %s. %+v", c.Namespace, err)
+		c.updateStatus(func(status *ClusterStatus) {
+			status.setCondition(ClusterConditionHealthy, v1.ConditionUnknown, reasonStatusUnavailable, err.Error())
+		})
+		return
+	}
+
+	c.updateStatus(func(status *ClusterStatus) {
+		status.applyCephStatus(cephStatus)
+	})
+}
+
+// applyCephStatus sets the health, mons and osds from the status reported by ceph
+func (s *ClusterStatus) applyCephStatus(cephStatus client.CephStatus) {
+	s.Health = cephStatus.Health.OverallStatus
+	healthy := v1.ConditionFalse
+	if s.Health == client.CephHealthOK {
+		healthy = v1.ConditionTrue
+	}
+	summaries := []string{}
+	for _, summary := range cephStatus.Health.Summary {
+		summaries = append(summaries, summary.Summary)
+	}
+	s.setCondition(ClusterConditionHealthy, healthy, s.Health, strings.Join(summaries, "; "))
+
+	quorum := append([]string{}, cephStatus.QuorumNames...)
+	sort.Strings(quorum)
+	mons := &MonStatus{Quorum: quorum, Endpoints: map[string]string{}}
+	outOfQuorum := []string{}
+	for _, m := range cephStatus.MonMap.Mons {
+		mons.Endpoints[m.Name] = m.Address
+		if !monInQuorum(m.Rank, cephStatus.Quorum) {
+			outOfQuorum = append(outOfQuorum, m.Name)
+		}
+	}
+	s.Mons = mons
+	if len(outOfQuorum) == 0 {
+		s.setCondition(ClusterConditionMonQuorum, v1.ConditionTrue, reasonQuorumFormed, "")
+	} else {
+		sort.Strings(outOfQuorum)
+		s.setCondition(ClusterConditionMonQuorum, v1.ConditionFalse, reasonMonsOutOfQuorum,
+			fmt.Sprintf("mons not in quorum: %s", strings.Join(outOfQuorum, ",")))
+	}
+
+	osdMap := cephStatus.OsdMap.OsdMap
+	s.OSDs = &OSDStatus{Total: osdMap.NumOsd, Up: osdMap.NumUpOsd, In: osdMap.NumInOsd}
+}
+
+func monInQuorum(rank int, quorum []int) bool {
+	for _, q := range quorum {
+		if q == rank {
+			return true
+		}
+	}
+	return false
+}
+
+// setCondition adds or updates the condition of the given type. The transition time only changes with the status.
+func (s *ClusterStatus) setCondition(conditionType ClusterConditionType, status v1.ConditionStatus, reason, message string) {
+	for i := range s.Conditions {
+		condition := &s.Conditions[i]
+		if condition.Type != conditionType {
+			continue
+		}
+		if condition.Status != status {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		condition.Status = status
+		condition.Reason = reason
+		condition.Message = message
+		return
+	}
+
+	s.Conditions = append(s.Conditions, ClusterCondition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+}
+
+// getCondition returns the condition of the given type, or nil if it has not been set
+func (s *ClusterStatus) getCondition(conditionType ClusterConditionType) *ClusterCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// updateStatus applies the changes to the status and saves it to the cluster resource if anything changed. Saving the
+// status generates an update event for the cluster, so unchanged status is not saved.
+func (c *Cluster) updateStatus(update func(status *ClusterStatus)) {
+	c.statusLock.Lock()
+	defer c.statusLock.Unlock()
+
+	before, _ := json.Marshal(c.Status)
+	update(&c.Status)
+	after, _ := json.Marshal(c.Status)
+	if bytes.Equal(before, after) {
+		return
+	}
+	if err := c.saveStatus(); err != nil {
+		logger.Warningf("failed to save status of cluster in namespace %s. %+v", c.Namespace, err)
+	}
+}
+
+// saveStatus writes the status to the cluster resource
+func (c *Cluster) saveStatus() error {
+	if c.statusWriter == nil {
+		return fmt.Errorf("cluster in namespace %s is not initialized", c.Namespace)
+	}
+	return c.statusWriter(c.Status)
+}
+
+// writeStatus replaces the status in the cluster resource
+func (c *Cluster) writeStatus(status ClusterStatus) error {
+	err := kit.UpdateRawResourceStatus(c.context.Clientset, ClusterResource, c.Namespace, c.Name, status)
+	if errors.IsNotFound(err) {
+		logger.Debugf("cluster %s in namespace %s no longer exists. status not saved", c.Name, c.Namespace)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save status of cluster %s. %+v", c.Name, err)
+	}
+	return nil
+}
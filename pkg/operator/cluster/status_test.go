@@ -0,0 +1,141 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	cephStatusResponseRaw = `{"health":{"summary":[{"severity":"HEALTH_WARN","summary":"1 mons down, quorum 0,1 mon1,mon2"}],"overall_status":"HEALTH_WARN"},` +
+		`"quorum":[0,1],"quorum_names":["mon2","mon1"],"monmap":{"mons":[{"rank":0,"name":"mon1","addr":"10.0.0.1:6790/0"},` +
+		`{"rank":1,"name":"mon2","addr":"10.0.0.2:6790/0"},{"rank":2,"name":"mon3","addr":"10.0.0.3:6790/0"}]},` +
+		`"osdmap":{"osdmap":{"num_osds":3,"num_up_osds":2,"num_in_osds":3}}}`
+)
+
+func newStatusTestCluster(executor *exectest.MockExecutor) (*Cluster, *int) {
+	c := &Cluster{}
+	c.Name = "myrook"
+	c.Namespace = "ns"
+	c.Init(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: testop.New(1)}, Executor: executor})
+
+	saves := 0
+	c.statusWriter = func(status ClusterStatus) error {
+		saves++
+		return nil
+	}
+	return c, &saves
+}
+
+func TestRefreshStatus(t *testing.T) {
+	response := cephStatusResponseRaw
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "status" {
+				return response, nil
+			}
+			return "", fmt.Errorf("unexpected command %s", args[0])
+		},
+	}
+	c, saves := newStatusTestCluster(executor)
+
+	c.refreshStatus()
+	assert.Equal(t, 1, *saves)
+	assert.Equal(t, "HEALTH_WARN", c.Status.Health)
+	assert.Equal(t, []string{"mon1", "mon2"}, c.Status.Mons.Quorum)
+	assert.Equal(t, 3, len(c.Status.Mons.Endpoints))
+	assert.Equal(t, "10.0.0.3:6790/0", c.Status.Mons.Endpoints["mon3"])
+	assert.Equal(t, OSDStatus{Total: 3, Up: 2, In: 3}, *c.Status.OSDs)
+
+	healthy := c.Status.getCondition(ClusterConditionHealthy)
+	assert.Equal(t, v1.ConditionFalse, healthy.Status)
+	assert.Equal(t, "HEALTH_WARN", healthy.Reason)
+	assert.Equal(t, "1 mons down, quorum 0,1 mon1,mon2", healthy.Message)
+	quorum := c.Status.getCondition(ClusterConditionMonQuorum)
+	assert.Equal(t, v1.ConditionFalse, quorum.Status)
+	assert.Equal(t, reasonMonsOutOfQuorum, quorum.Reason)
+	assert.Equal(t, "mons not in quorum: mon3", quorum.Message)
+
+	// the status is not saved again if nothing changed
+	c.refreshStatus()
+	assert.Equal(t, 1, *saves)
+
+	// the conditions are updated when the cluster is healthy
+	response = `{"health":{"overall_status":"HEALTH_OK"},"quorum":[0],"quorum_names":["mon1"],` +
+		`"monmap":{"mons":[{"rank":0,"name":"mon1","addr":"10.0.0.1:6790/0"}]},"osdmap":{"osdmap":{"num_osds":3,"num_up_osds":3,"num_in_osds":3}}}`
+	c.refreshStatus()
+	assert.Equal(t, 2, *saves)
+	assert.Equal(t, "HEALTH_OK", c.Status.Health)
+	assert.Equal(t, v1.ConditionTrue, c.Status.getCondition(ClusterConditionHealthy).Status)
+	assert.Equal(t, v1.ConditionTrue, c.Status.getCondition(ClusterConditionMonQuorum).Status)
+	assert.Equal(t, 2, len(c.Status.Conditions))
+
+	// the last known state is kept if the status cannot be retrieved
+	executor.MockExecuteCommandWithOutputFile = func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+		return "", fmt.Errorf("mock failure")
+	}
+	c.refreshStatus()
+	assert.Equal(t, 3, *saves)
+	assert.Equal(t, "HEALTH_OK", c.Status.Health)
+	healthy = c.Status.getCondition(ClusterConditionHealthy)
+	assert.Equal(t, v1.ConditionUnknown, healthy.Status)
+	assert.Equal(t, reasonStatusUnavailable, healthy.Reason)
+}
+
+func TestSetPhase(t *testing.T) {
+	c, saves := newStatusTestCluster(&exectest.MockExecutor{})
+
+	c.setPhase(ClusterPhaseCreating, reasonCreating, "")
+	assert.Equal(t, 1, *saves)
+	assert.Equal(t, ClusterPhaseCreating, c.Status.Phase)
+	ready := c.Status.getCondition(ClusterConditionReady)
+	assert.Equal(t, v1.ConditionFalse, ready.Status)
+	transition := ready.LastTransitionTime
+
+	// the same phase is not saved again
+	c.setPhase(ClusterPhaseCreating, reasonCreating, "")
+	assert.Equal(t, 1, *saves)
+
+	c.setPhase(ClusterPhaseFailed, reasonCreateFailed, "mock failure")
+	assert.Equal(t, 2, *saves)
+	assert.Equal(t, ClusterPhaseFailed, c.Status.Phase)
+	assert.Equal(t, "mock failure", c.Status.Message)
+	ready = c.Status.getCondition(ClusterConditionReady)
+	assert.Equal(t, v1.ConditionFalse, ready.Status)
+	assert.Equal(t, reasonCreateFailed, ready.Reason)
+	assert.Equal(t, transition, ready.LastTransitionTime)
+
+	c.setPhase(ClusterPhaseCreated, reasonCreated, "")
+	assert.Equal(t, 3, *saves)
+	assert.Equal(t, "", c.Status.Message)
+	assert.Equal(t, v1.ConditionTrue, c.Status.getCondition(ClusterConditionReady).Status)
+
+	// the cluster remains ready while it is updated
+	c.setPhase(ClusterPhaseUpdating, reasonUpdating, "")
+	assert.Equal(t, v1.ConditionTrue, c.Status.getCondition(ClusterConditionReady).Status)
+	assert.Equal(t, 1, len(c.Status.Conditions))
+
+	c.setPhase(ClusterPhaseDeleting, reasonDeleting, "")
+	assert.Equal(t, v1.ConditionFalse, c.Status.getCondition(ClusterConditionReady).Status)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/operator/api"
+	"github.com/rook/rook/pkg/operator/mds"
+	"github.com/rook/rook/pkg/operator/mgr"
+	"github.com/rook/rook/pkg/operator/rgw"
+)
+
+const (
+	upgradeStepMons = "mon"
+	upgradeStepMgrs = "mgr"
+	upgradeStepOSDs = "osd"
+	upgradeStepAPI  = "api"
+	upgradeStepRGW  = "rgw"
+	upgradeStepMDS  = "mds"
+)
+
+type upgradeStep struct {
+	name string
+	run  func(version string) error
+}
+
+// startUpgrade upgrades the daemons to the version of the new spec in the background. The rest of the new spec is
+// applied after the upgrade completes. A failed upgrade is retried by the health check. The cluster lock must be held.
+func (c *Cluster) startUpgrade(newSpec Spec) {
+	c.pendingSpec = &newSpec
+	version := newSpec.VersionTag
+	c.startRoll(func() error {
+		return c.upgrade(newSpec)
+	}, func(err error) {
+		if c.Spec.Paused {
+			logger.Infof("upgrade of cluster in namespace %s to version %s stopped while paused. %+v", c.Namespace, version, err)
+			return
+		}
+		if err != nil {
+			logger.Errorf("failed to upgrade cluster in namespace %s. %+v", c.Namespace, err)
+			c.setPhase(ClusterPhaseFailed, reasonUpdateFailed, err.Error())
+			return
+		}
+		c.Spec.VersionTag = version
+		c.setPhase(ClusterPhaseCreated, reasonUpdated, "")
+		c.applyPendingSpec()
+	})
+}
+
+// startRoll runs a restart of the daemons in the background so that the cluster events and the health checks are not
+// blocked while the daemons are restarted one at a time. The changes to the spec received during the roll are applied
+// when it completes. done is called with the cluster lock held, unless the cluster was stopped. The roll must check
+// whether the cluster was stopped between its steps. The cluster lock must be held.
+func (c *Cluster) startRoll(roll func() error, done func(err error)) {
+	c.rolling = true
+	rollDone := make(chan struct{})
+	c.rollDone = rollDone
+	go func() {
+		defer close(rollDone)
+		err := roll()
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.rolling = false
+		if c.stopped {
+			logger.Infof("stopped restarting the daemons of deleted cluster in namespace %s. %+v", c.Namespace, err)
+			return
+		}
+		done(err)
+	}()
+}
+
+// applyPendingSpec applies the spec received while the daemons were restarted. The cluster lock must be held.
+func (c *Cluster) applyPendingSpec() {
+	if c.rolling || c.stopped || c.pendingSpec == nil || c.Spec.Paused {
+		return
+	}
+	newSpec := *c.pendingSpec
+	c.pendingSpec = nil
+	if err := c.applySpec(newSpec); err != nil {
+		logger.Errorf("failed to update cluster in namespace %s. %+v", c.Namespace, err)
+	}
+}
+
+// upgrade the daemons of the cluster to the version of the spec. The mons are upgraded first, then the mgr, the osds,
+// and finally the api, rgw and mds. The progress is saved in the cluster status after each step so that an interrupted
+// upgrade is resumed where it stopped. The upgrade runs without the cluster lock, so it only reads the given spec and
+// stops at the next step if the cluster is paused.
+func (c *Cluster) upgrade(spec Spec) error {
+	version := spec.VersionTag
+	status := c.upgradeStatus()
+	if status == nil || status.ToVersion != version {
+		status = &UpgradeStatus{FromVersion: c.GetSpec().VersionTag, ToVersion: version, Step: upgradeStepMons}
+	}
+	logger.Infof("upgrading cluster in namespace %s from version %s to %s", c.Namespace, status.FromVersion, version)
+
+	steps := c.upgradeSteps(spec, status)
+	started := false
+	for _, step := range steps {
+		// skip the steps that were completed before the upgrade was interrupted
+		if !started && step.name != status.Step {
+			continue
+		}
+		started = true
+
+		if c.IsPaused() {
+			return fmt.Errorf("cluster is paused before upgrading %s", step.name)
+		}
+		if c.IsStopped() {
+			return fmt.Errorf("cluster was deleted before upgrading %s", step.name)
+		}
+		if status.Step != step.name {
+			*status = UpgradeStatus{FromVersion: status.FromVersion, ToVersion: version, Step: step.name}
+		}
+		c.setUpgradeStatus(status)
+
+		logger.Infof("upgrading %s to version %s", step.name, version)
+		if err := step.run(version); err != nil {
+			return fmt.Errorf("failed to upgrade %s to version %s. %+v", step.name, version, err)
+		}
+	}
+	if !started {
+		return fmt.Errorf("unknown upgrade step %s", status.Step)
+	}
+
+	c.setUpgradeStatus(nil)
+	logger.Infof("Done upgrading cluster in namespace %s to version %s", c.Namespace, version)
+	return nil
+}
+
+// upgradeSteps returns the steps of the upgrade with the settings of the spec. The osd step records the completed nodes
+// in the status.
+func (c *Cluster) upgradeSteps(spec Spec, status *UpgradeStatus) []upgradeStep {
+	c.lock.Lock()
+	mons := c.mons
+	osds := c.osds
+	c.lock.Unlock()
+
+	return []upgradeStep{
+		{name: upgradeStepMons, run: func(version string) error {
+			return mons.Upgrade(version)
+		}},
+		{name: upgradeStepMgrs, run: func(version string) error {
+			mgrs := mgr.New(c.context, c.Namespace, version, spec.Placement.GetMGR(), spec.Resources.MGR, spec.Network)
+			if err := mgrs.Update(); err != nil {
+				return err
+			}
+			c.lock.Lock()
+			defer c.lock.Unlock()
+			c.mgrs = mgrs
+			return nil
+		}},
+		{name: upgradeStepOSDs, run: func(version string) error {
+			return osds.Upgrade(version, status.CompletedNodes, func(nodeName string) error {
+				status.CompletedNodes = append(append([]string{}, status.CompletedNodes...), nodeName)
+				c.setUpgradeStatus(status)
+				if c.IsStopped() {
+					return fmt.Errorf("cluster was deleted after upgrading the osds on node %s", nodeName)
+				}
+				return nil
+			})
+		}},
+		{name: upgradeStepAPI, run: func(version string) error {
+			apis := api.New(c.context, c.Namespace, version, spec.Placement.GetAPI(), spec.Resources.API)
+			if err := apis.Update(); err != nil {
+				return err
+			}
+			c.lock.Lock()
+			defer c.lock.Unlock()
+			c.apis = apis
+			return nil
+		}},
+		{name: upgradeStepRGW, run: func(version string) error {
+			// keep the gateway settings of the object store if there is one
+			store, err := c.getObjectStore()
+			if err != nil {
+				return fmt.Errorf("failed to get the object store. %+v", err)
+			}
+			if store == nil {
+				return rgw.New(c.context, c.Namespace, version, spec.Placement.GetRGW(), spec.Resources.RGW, spec.Network).Update()
+			}
+			return store.gateway(c.context, version, spec.Placement.GetRGW(), spec.Resources.RGW, spec.Network).Update()
+		}},
+		{name: upgradeStepMDS, run: func(version string) error {
+			return mds.New(c.context, c.Namespace, version, spec.Placement.GetMDS(), spec.Resources.MDS, spec.Network).Upgrade()
+		}},
+	}
+}
+
+// upgradeStatus returns a copy of the progress of the upgrade, or nil if no upgrade is in progress
+func (c *Cluster) upgradeStatus() *UpgradeStatus {
+	c.statusLock.Lock()
+	defer c.statusLock.Unlock()
+	if c.Status.Upgrade == nil {
+		return nil
+	}
+	return copyUpgradeStatus(c.Status.Upgrade)
+}
+
+// setUpgradeStatus records a copy of the progress of the upgrade in the cluster status
+func (c *Cluster) setUpgradeStatus(status *UpgradeStatus) {
+	saved := copyUpgradeStatus(status)
+	c.updateStatus(func(s *ClusterStatus) {
+		s.Upgrade = saved
+	})
+}
+
+// copyUpgradeStatus returns a copy of the status that is not changed by the upgrade in the background
+func copyUpgradeStatus(status *UpgradeStatus) *UpgradeStatus {
+	if status == nil {
+		return nil
+	}
+	copied := *status
+	if status.CompletedNodes != nil {
+		copied.CompletedNodes = append([]string{}, status.CompletedNodes...)
+	}
+	return &copied
+}
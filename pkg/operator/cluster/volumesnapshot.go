@@ -0,0 +1,145 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cluster to manage a rook cluster.
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
+	"k8s.io/api/core/v1"
+)
+
+// VolumeSnapshotResource is the definition of the volume snapshot TPR
+var VolumeSnapshotResource = kit.CustomResource{
+	Name:        "volumesnapshot",
+	Group:       k8sutil.CustomResourceGroup,
+	Version:     kit.V1Alpha1,
+	Description: "Managed Rook snapshots of block volumes",
+}
+
+// VolumeSnapshotClassResource is the definition of the volume snapshot class TPR
+var VolumeSnapshotClassResource = kit.CustomResource{
+	Name:        "volumesnapshotclass",
+	Group:       k8sutil.CustomResourceGroup,
+	Version:     kit.V1Alpha1,
+	Description: "Classes of Rook snapshots of block volumes",
+}
+
+// the prefix of the rbd snapshots that are taken for the volume snapshots
+const volumeSnapshotPrefix = "k8s-snapshot-"
+
+// SnapshotDeletionPolicy is what happens to the rbd snapshot when the volume snapshot is deleted
+type SnapshotDeletionPolicy string
+
+const (
+	// SnapshotDeletionDelete deletes the rbd snapshot when the volume snapshot is deleted
+	SnapshotDeletionDelete SnapshotDeletionPolicy = "Delete"
+	// SnapshotDeletionRetain keeps the rbd snapshot when the volume snapshot is deleted
+	SnapshotDeletionRetain SnapshotDeletionPolicy = "Retain"
+)
+
+// VolumeSnapshot is the spec for the volume snapshot TPR. The snapshot is created in the namespace of the claim whose
+// volume is snapshotted. The volume can be restored by provisioning a new claim from the snapshot.
+type VolumeSnapshot struct {
+	v1.ObjectMeta      `json:"metadata,omitempty"`
+	VolumeSnapshotSpec `json:"spec"`
+	Status             VolumeSnapshotStatus `json:"status,omitempty"`
+}
+
+// VolumeSnapshotSpec represents the spec of a volume snapshot
+type VolumeSnapshotSpec struct {
+	// The name of the claim in the same namespace whose volume is snapshotted
+	ClaimName string `json:"claimName"`
+
+	// The name of the volume snapshot class in the namespace of the cluster. The snapshot is deleted with the
+	// volume snapshot if no class is specified.
+	SnapshotClassName string `json:"snapshotClassName"`
+}
+
+// VolumeSnapshotStatus is the status of the snapshot that the operator records in the snapshot resource. The status can
+// be changed by the users of the namespace, so the operator only reports the snapshot in it. The rbd snapshot is always
+// found again from the volume of the claim.
+type VolumeSnapshotStatus struct {
+	// Whether the snapshot was taken and new claims can be provisioned from it
+	Ready bool `json:"ready"`
+
+	// The minimum size of the claims that are provisioned from the snapshot
+	RestoreSize string `json:"restoreSize,omitempty"`
+
+	// The namespace of the cluster where the volume was provisioned
+	ClusterNamespace string `json:"clusterNamespace,omitempty"`
+
+	// The rbd snapshot of the volume
+	PoolName     string `json:"poolName,omitempty"`
+	ImageName    string `json:"imageName,omitempty"`
+	SnapshotName string `json:"snapshotName,omitempty"`
+
+	// Whether the rbd snapshot is deleted with the volume snapshot
+	DeletionPolicy SnapshotDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// The reason the snapshot could not be taken
+	Message string `json:"message,omitempty"`
+}
+
+// VolumeSnapshotClass is the spec for the volume snapshot class TPR. The classes are created in the namespace of the
+// cluster by the administrator of the cluster.
+type VolumeSnapshotClass struct {
+	v1.ObjectMeta           `json:"metadata,omitempty"`
+	VolumeSnapshotClassSpec `json:"spec"`
+}
+
+// VolumeSnapshotClassSpec represents the spec of a volume snapshot class
+type VolumeSnapshotClassSpec struct {
+	// Whether the rbd snapshot is deleted or retained when the volume snapshot is deleted. Default is Delete.
+	DeletionPolicy SnapshotDeletionPolicy `json:"deletionPolicy"`
+}
+
+// SnapshotName returns the name of the rbd snapshot of the volume. The name contains the uid of the resource so that a
+// volume snapshot that is created again with the same name does not adopt a snapshot that was retained before.
+func (s *VolumeSnapshot) SnapshotName() string {
+	return fmt.Sprintf("%s%s-%s", volumeSnapshotPrefix, s.Name, s.UID)
+}
+
+// Validate the snapshot arguments
+func (s *VolumeSnapshot) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if s.Namespace == "" {
+		return fmt.Errorf("missing namespace")
+	}
+	if s.UID == "" {
+		return fmt.Errorf("missing uid")
+	}
+	if s.ClaimName == "" {
+		return fmt.Errorf("missing claimName")
+	}
+	return nil
+}
+
+// Policy returns the deletion policy of the class
+func (c *VolumeSnapshotClass) Policy() (SnapshotDeletionPolicy, error) {
+	switch c.DeletionPolicy {
+	case "":
+		return SnapshotDeletionDelete, nil
+	case SnapshotDeletionDelete, SnapshotDeletionRetain:
+		return c.DeletionPolicy, nil
+	}
+	return "", fmt.Errorf("invalid deletion policy %s of snapshot class %s", c.DeletionPolicy, c.Name)
+}
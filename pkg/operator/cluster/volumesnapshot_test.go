@@ -0,0 +1,59 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+)
+
+func TestValidateVolumeSnapshot(t *testing.T) {
+	s := VolumeSnapshot{ObjectMeta: v1.ObjectMeta{Name: "snap1", Namespace: "app", UID: "uid1"}}
+
+	// the claim is required
+	assert.NotNil(t, s.Validate())
+	s.ClaimName = "claim1"
+	assert.Nil(t, s.Validate())
+	assert.Equal(t, "k8s-snapshot-snap1-uid1", s.SnapshotName())
+
+	// the name does not come from the status
+	s.Status.SnapshotName = "othersnap"
+	assert.Equal(t, "k8s-snapshot-snap1-uid1", s.SnapshotName())
+
+	// a snapshot created again with the same name has another snapshot
+	s.UID = "uid2"
+	assert.Equal(t, "k8s-snapshot-snap1-uid2", s.SnapshotName())
+	s.UID = ""
+	assert.NotNil(t, s.Validate())
+}
+
+func TestVolumeSnapshotClassPolicy(t *testing.T) {
+	c := VolumeSnapshotClass{ObjectMeta: v1.ObjectMeta{Name: "class1", Namespace: "rook"}}
+	policy, err := c.Policy()
+	assert.Nil(t, err)
+	assert.Equal(t, SnapshotDeletionDelete, policy)
+
+	c.DeletionPolicy = SnapshotDeletionRetain
+	policy, err = c.Policy()
+	assert.Nil(t, err)
+	assert.Equal(t, SnapshotDeletionRetain, policy)
+
+	c.DeletionPolicy = "Recycle"
+	_, err = c.Policy()
+	assert.NotNil(t, err)
+}
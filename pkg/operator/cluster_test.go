@@ -22,8 +22,12 @@ import (
 	"testing"
 
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
 	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/kit"
+	testrook "github.com/rook/rook/pkg/rook/test"
 	"github.com/stretchr/testify/assert"
+	kwatch "k8s.io/apimachinery/pkg/watch"
 )
 
 func TestTrackCluster(t *testing.T) {
@@ -55,6 +59,67 @@ func TestTrackCluster(t *testing.T) {
 	// stop tracking the cluster
 	mgr.stopTrack(c1)
 	checkClusterTracked(t, mgr, c1, false, false)
+
+	// a cluster created later in the namespace is not untracked by the previous cluster
+	err = mgr.startTrack(c2)
+	assert.Nil(t, err)
+	mgr.stopTrack(c1)
+	checkClusterTracked(t, mgr, c2, true, true)
+}
+
+func TestIsPaused(t *testing.T) {
+	mgr := newClusterManager(&clusterd.Context{}, []inclusterInitiator{})
+	c := &cluster.Cluster{}
+	c.Name = "myname"
+	c.Namespace = "myns"
+
+	// an unknown cluster is not paused
+	assert.False(t, mgr.isPaused("myns"))
+
+	err := mgr.startTrack(c)
+	assert.Nil(t, err)
+	assert.False(t, mgr.isPaused("myns"))
+
+	c.Spec.Paused = true
+	assert.True(t, mgr.isPaused("myns"))
+	assert.False(t, mgr.isPaused("otherns"))
+}
+
+func TestPausedPoolDeleted(t *testing.T) {
+	mgr := newClusterManager(&clusterd.Context{}, []inclusterInitiator{})
+	c := &cluster.Cluster{}
+	c.Name = "myname"
+	c.Namespace = "myns"
+	c.Spec.Paused = true
+	err := mgr.startTrack(c)
+	assert.Nil(t, err)
+
+	deleted := []string{}
+	rclient := &testrook.MockRookRestClient{
+		MockGetPools: func() ([]model.Pool, error) {
+			return []model.Pool{{Name: "mypool"}}, nil
+		},
+		MockDeletePool: func(name string) (string, error) {
+			deleted = append(deleted, name)
+			return "", nil
+		},
+	}
+	p := &poolManager{namespace: c.Namespace, rclient: rclient, clusterMgr: mgr}
+
+	// the pool is not deleted while the cluster is paused
+	event := &kit.RawEvent{Type: kwatch.Deleted, Object: []byte(`{"metadata":{"name":"mypool","namespace":"myns"}}`)}
+	err = p.handlePoolEvent(event)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deleted))
+
+	// the pool is deleted when the cluster is resumed
+	c.Spec.Paused = false
+	p.deleted.replay(p.handlePoolEvent)
+	assert.Equal(t, []string{"mypool"}, deleted)
+
+	// the event is only replayed once
+	p.deleted.replay(p.handlePoolEvent)
+	assert.Equal(t, 1, len(deleted))
 }
 
 func checkClusterTracked(t *testing.T, mgr *clusterManager, c *cluster.Cluster, nameTracked, namespaceTracked bool) {
@@ -0,0 +1,199 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operator to manage Kubernetes storage.
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/kit"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	kwatch "k8s.io/apimachinery/pkg/watch"
+)
+
+type filesystemInitiator struct {
+	context *clusterd.Context
+}
+
+type filesystemManager struct {
+	namespace  string
+	context    *clusterd.Context
+	rclient    rookclient.RookRestClient
+	clusterMgr *clusterManager
+	deleted    pausedDeletes
+}
+
+type filesystemEvent struct {
+	Type   kwatch.EventType
+	Object *cluster.Filesystem
+}
+
+func newFilesystemInitiator(context *clusterd.Context) *filesystemInitiator {
+	return &filesystemInitiator{context: context}
+}
+
+func (f *filesystemInitiator) Create(clusterMgr *clusterManager, namespace string) (resourceManager, error) {
+	rclient, err := clusterMgr.getRookClient(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api client for file system tpr for cluster in namespace %s. %+v", namespace, err)
+	}
+	return &filesystemManager{context: f.context, namespace: namespace, rclient: rclient, clusterMgr: clusterMgr}, nil
+}
+
+func (f *filesystemInitiator) Resource() kit.CustomResource {
+	return cluster.FilesystemResource
+}
+
+// Run the tpr manager until the caller signals with an EndWatch()
+func (f *filesystemManager) Manage() {
+	for {
+
+		// load and initialize the file systems
+		watchVersion, err := f.Load()
+		if err != nil {
+			logger.Errorf("cannot load %s file system tpr. %+v. retrying...", f.namespace, err)
+		} else {
+			// watch for added/updated/deleted file systems
+			watcher := kit.NewWatcher(f.context.KubeContext, cluster.FilesystemResource, f.namespace, watchVersion, f.handleFilesystemEvent, nil)
+			if err := watcher.Watch(); err != nil {
+				logger.Errorf("failed to watch %s file system tpr. %+v. retrying...", f.namespace, err)
+			}
+		}
+
+		<-time.After(time.Second * time.Duration(f.context.RetryDelay))
+	}
+}
+
+func (f *filesystemManager) handleFilesystemEvent(event *kit.RawEvent) error {
+	fs := &filesystemEvent{
+		Type:   event.Type,
+		Object: &cluster.Filesystem{},
+	}
+	err := json.Unmarshal(event.Object, fs.Object)
+	if err != nil {
+		return fmt.Errorf("fail to unmarshal Filesystem from data (%s): %v", fs.Object, err)
+	}
+
+	if f.clusterMgr.isPaused(f.namespace) {
+		// the file systems will be reconciled when the cluster is resumed
+		if event.Type == kwatch.Deleted {
+			// the deleted file systems are not listed when the cluster is resumed, so the event is replayed then
+			f.deleted.add(event)
+		}
+		logger.Infof("deferring %s event for file system %s while the cluster in namespace %s is paused", event.Type, fs.Object.Name, f.namespace)
+		return nil
+	}
+
+	c, err := f.clusterMgr.getCluster(f.namespace)
+	if err != nil {
+		return err
+	}
+	spec := c.GetSpec()
+	version, placement, resources, network := spec.VersionTag, spec.Placement.GetMDS(), spec.Resources.MDS, spec.Network
+
+	switch event.Type {
+	case kwatch.Added:
+		err := fs.Object.Create(f.context, f.rclient, version, placement, resources, network)
+		if err != nil {
+			logger.Errorf("failed to create file system %s. %+v", fs.Object.Name, err)
+		}
+		f.updateStatus(fs.Object, err)
+
+	case kwatch.Modified:
+		// if the file system is modified, allow the file system to be created if it wasn't already
+		err := fs.Object.Update(f.context, f.rclient, version, placement, resources, network)
+		if err != nil {
+			logger.Errorf("failed to update file system %s. %+v", fs.Object.Name, err)
+		}
+		f.updateStatus(fs.Object, err)
+
+	case kwatch.Deleted:
+		if err := fs.Object.Delete(f.context); err != nil {
+			logger.Errorf("failed to delete file system %s. %+v", fs.Object.Name, err)
+		}
+	}
+	return nil
+}
+
+func (f *filesystemManager) Load() (string, error) {
+	// Check if the file systems have all been created
+	logger.Info("finding existing file systems...")
+	fsList, err := f.getFilesystemList()
+	if err != nil {
+		return "", err
+	}
+
+	if f.clusterMgr.isPaused(f.namespace) {
+		logger.Infof("found %d file systems. not checking them while the cluster in namespace %s is paused.", len(fsList.Items), f.namespace)
+		return fsList.Metadata.ResourceVersion, nil
+	}
+
+	// delete the file systems that were deleted while the cluster was paused
+	f.deleted.replay(f.handleFilesystemEvent)
+
+	c, err := f.clusterMgr.getCluster(f.namespace)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Infof("found %d file systems. ensuring they exist.", len(fsList.Items))
+	for i := range fsList.Items {
+		item := fsList.Items[i]
+		logger.Infof("checking file system %s in namespace %s", item.Name, item.Namespace)
+		spec := c.GetSpec()
+		err := item.Update(f.context, f.rclient, spec.VersionTag, spec.Placement.GetMDS(), spec.Resources.MDS, spec.Network)
+		if err != nil {
+			logger.Warningf("failed to check that file system %s exists in namespace %s. %+v", item.Name, item.Namespace, err)
+		}
+		f.updateStatus(&item, err)
+	}
+
+	return fsList.Metadata.ResourceVersion, nil
+}
+
+// updateStatus records in the file system resource whether the spec was applied. Saving the status generates a
+// modified event for the file system, so the status is only saved when it changes.
+func (f *filesystemManager) updateStatus(fs *cluster.Filesystem, err error) {
+	status := cluster.FilesystemStatus{State: cluster.FilesystemStateCreated}
+	if err != nil {
+		status = cluster.FilesystemStatus{State: cluster.FilesystemStateFailed, Message: err.Error()}
+	}
+	if fs.Status == status {
+		return
+	}
+
+	if err := kit.UpdateRawResourceStatus(f.context.Clientset, cluster.FilesystemResource, fs.Namespace, fs.Name, status); err != nil {
+		logger.Warningf("failed to save status of file system %s. %+v", fs.Name, err)
+	}
+}
+
+func (f *filesystemManager) getFilesystemList() (*cluster.FilesystemList, error) {
+	b, err := kit.GetRawListNamespaced(f.context.Clientset, cluster.FilesystemResource, f.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	filesystems := &cluster.FilesystemList{}
+	if err := json.Unmarshal(b, filesystems); err != nil {
+		return nil, err
+	}
+	return filesystems, nil
+}
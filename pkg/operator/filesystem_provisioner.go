@@ -0,0 +1,261 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operator to manage Kubernetes storage.
+package operator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubernetes-incubator/external-storage/lib/controller"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/mds"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// the annotation that records on the PV the file system of the volume
+	filesystemAnnotation = "rook.io/filesystem"
+
+	// the secret with the key of the volume user is created in the namespace of the claim
+	filesystemSecretPrefix = "rook-fs-"
+	filesystemSecretKey    = "key"
+)
+
+// rookFilesystemProvisioner provisions shared file system volumes that can be mounted by many pods. Each volume is a
+// directory of the file system with a quota of the requested size and a cephx user that can only access the directory.
+type rookFilesystemProvisioner struct {
+	clusterManager *clusterManager
+}
+
+type filesystemProvisionerConfig struct {
+	// Required: the file system to provision volumes from
+	filesystem string
+
+	// Optional: Namespace of the cluster. Default is `rook`
+	clusterNamespace string
+
+	// Optional: Name of the cluster. Default is `rook`
+	clusterName string
+}
+
+func newRookFilesystemProvisioner(clusterManager *clusterManager) controller.Provisioner {
+	return &rookFilesystemProvisioner{
+		clusterManager: clusterManager,
+	}
+}
+
+// Provision creates a directory in the file system and returns a CephFS PV restricted to the directory
+func (p *rookFilesystemProvisioner) Provision(options controller.VolumeOptions) (*v1.PersistentVolume, error) {
+	if options.PVC.Spec.Selector != nil {
+		return nil, fmt.Errorf("claim Selector is not supported")
+	}
+
+	cfg, err := parseFilesystemClassParameters(options.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.clusterManager.isPaused(cfg.clusterNamespace) {
+		return nil, fmt.Errorf("cannot provision volumes while the cluster in namespace %s is paused", cfg.clusterNamespace)
+	}
+
+	rookClient, err := p.clusterManager.getRookClient(cfg.clusterNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get rook client: %v", err)
+	}
+
+	capacity := options.PVC.Spec.Resources.Requests[v1.ResourceName(v1.ResourceStorage)]
+	size := uint64(capacity.Value())
+	if err := p.runVolumeJob(cfg, options.PVName, size, false); err != nil {
+		return nil, fmt.Errorf("Failed to create the directory of volume %s in file system %s: %v", options.PVName, cfg.filesystem, err)
+	}
+
+	volume, err := rookClient.CreateFilesystemVolume(model.FilesystemVolume{
+		Filesystem: cfg.filesystem,
+		Name:       options.PVName,
+		Size:       size,
+	})
+	var pv *v1.PersistentVolume
+	if err == nil {
+		logger.Infof("Rook file system volume created: %s", volume.Path)
+		pv, err = p.makeVolume(options, cfg, volume, rookClient)
+	}
+	if err != nil {
+		// do not leave behind a directory or a user that no volume refers to
+		if deleteErr := rookClient.DeleteFilesystemVolume(cfg.filesystem, options.PVName); deleteErr != nil {
+			logger.Warningf("failed to delete the user of volume %s from file system %s. %+v", options.PVName, cfg.filesystem, deleteErr)
+		}
+		if deleteErr := p.runVolumeJob(cfg, options.PVName, size, true); deleteErr != nil {
+			logger.Warningf("failed to delete the directory of volume %s from file system %s. %+v", options.PVName, cfg.filesystem, deleteErr)
+		}
+		return nil, fmt.Errorf("Failed to create volume %s in file system %s: %v", options.PVName, cfg.filesystem, err)
+	}
+
+	logger.Infof("successfully created Rook file system volume %+v", pv.Spec.PersistentVolumeSource.CephFS)
+	return pv, nil
+}
+
+// makeVolume publishes the key of the volume user in a secret in the namespace of the claim and returns the PV
+func (p *rookFilesystemProvisioner) makeVolume(options controller.VolumeOptions, cfg *filesystemProvisionerConfig,
+	volume *model.FilesystemVolume, rookClient rookclient.RookRestClient) (*v1.PersistentVolume, error) {
+
+	rookClientInfo, err := rookClient.GetClientAccessInfo()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get rook client information: %v", err)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: filesystemSecretPrefix + options.PVName, Namespace: options.PVC.Namespace},
+		StringData: map[string]string{filesystemSecretKey: volume.SecretKey},
+		Type:       k8sutil.RookType,
+	}
+	if _, err := p.clusterManager.context.Clientset.CoreV1().Secrets(secret.Namespace).Create(secret); err != nil {
+		return nil, fmt.Errorf("Failed to create secret %s for volume %s: %v", secret.Name, options.PVName, err)
+	}
+
+	return &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        options.PVName,
+			Annotations: cfg.annotations(),
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: options.PersistentVolumeReclaimPolicy,
+			AccessModes:                   options.PVC.Spec.AccessModes,
+			Capacity: v1.ResourceList{
+				v1.ResourceName(v1.ResourceStorage): options.PVC.Spec.Resources.Requests[v1.ResourceName(v1.ResourceStorage)],
+			},
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CephFS: &v1.CephFSVolumeSource{
+					Monitors:  processMonAddresses(rookClientInfo.MonAddresses),
+					Path:      volume.Path,
+					User:      volume.UserName,
+					SecretRef: &v1.LocalObjectReference{Name: secret.Name},
+					ReadOnly:  false,
+				},
+			},
+		},
+	}, nil
+}
+
+// Delete removes the directory and the user of the volume and the secret with the key of the user
+func (p *rookFilesystemProvisioner) Delete(volume *v1.PersistentVolume) error {
+	cfg, err := parseFilesystemVolumeAnnotations(volume)
+	if err != nil {
+		return err
+	}
+
+	if p.clusterManager.isPaused(cfg.clusterNamespace) {
+		return fmt.Errorf("cannot delete volumes while the cluster in namespace %s is paused", cfg.clusterNamespace)
+	}
+
+	rookClient, err := p.clusterManager.getRookClient(cfg.clusterNamespace)
+	if err != nil {
+		return fmt.Errorf("Failed to get rook client: %v", err)
+	}
+
+	if err := rookClient.DeleteFilesystemVolume(cfg.filesystem, volume.Name); err != nil {
+		return fmt.Errorf("Failed to delete volume %s from file system %s: %v", volume.Name, cfg.filesystem, err)
+	}
+	if err := p.runVolumeJob(cfg, volume.Name, 0, true); err != nil {
+		return fmt.Errorf("Failed to delete the directory of volume %s from file system %s: %v", volume.Name, cfg.filesystem, err)
+	}
+
+	source := volume.Spec.PersistentVolumeSource.CephFS
+	if source.SecretRef != nil && volume.Spec.ClaimRef != nil {
+		secrets := p.clusterManager.context.Clientset.CoreV1().Secrets(volume.Spec.ClaimRef.Namespace)
+		if err := k8sutil.DeleteResource("file system volume secret", source.SecretRef.Name, secrets.Delete); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runVolumeJob creates or removes the directory of a volume in a job of the cluster with the version of the cluster
+func (p *rookFilesystemProvisioner) runVolumeJob(cfg *filesystemProvisionerConfig, name string, size uint64, remove bool) error {
+	c, err := p.clusterManager.getCluster(cfg.clusterNamespace)
+	if err != nil {
+		return err
+	}
+	return mds.RunVolumeJob(p.clusterManager.context, cfg.clusterNamespace, c.GetSpec().VersionTag, cfg.filesystem, name, size, remove)
+}
+
+// annotations returns the PV annotations that record where the volume was provisioned
+func (c *filesystemProvisionerConfig) annotations() map[string]string {
+	return map[string]string{
+		filesystemAnnotation:       c.filesystem,
+		clusterNamespaceAnnotation: c.clusterNamespace,
+		clusterNameAnnotation:      c.clusterName,
+	}
+}
+
+// parseFilesystemVolumeAnnotations reads the file system and cluster of a volume from the PV
+func parseFilesystemVolumeAnnotations(volume *v1.PersistentVolume) (*filesystemProvisionerConfig, error) {
+	if volume.Spec.PersistentVolumeSource.CephFS == nil {
+		return nil, fmt.Errorf("volume %s is not a rook file system volume", volume.Name)
+	}
+
+	cfg := &filesystemProvisionerConfig{
+		filesystem:       volume.Annotations[filesystemAnnotation],
+		clusterNamespace: volume.Annotations[clusterNamespaceAnnotation],
+		clusterName:      volume.Annotations[clusterNameAnnotation],
+	}
+	if len(cfg.filesystem) == 0 {
+		return nil, fmt.Errorf("the file system of volume %s is unknown", volume.Name)
+	}
+	if len(cfg.clusterNamespace) == 0 {
+		cfg.clusterNamespace = k8sutil.Namespace
+	}
+	if len(cfg.clusterName) == 0 {
+		cfg.clusterName = k8sutil.Namespace
+	}
+	return cfg, nil
+}
+
+func parseFilesystemClassParameters(params map[string]string) (*filesystemProvisionerConfig, error) {
+	var cfg filesystemProvisionerConfig
+
+	for k, v := range params {
+		switch strings.ToLower(k) {
+		case "filesystem":
+			cfg.filesystem = v
+		case "clusternamespace":
+			cfg.clusterNamespace = v
+		case "clustername":
+			cfg.clusterName = v
+		default:
+			return nil, fmt.Errorf("invalid option %q for volume plugin %s", k, "rookFilesystemProvisioner")
+		}
+	}
+
+	if len(cfg.filesystem) == 0 {
+		return nil, fmt.Errorf("StorageClass for provisioner %s must contain 'filesystem' parameter", "rookFilesystemProvisioner")
+	}
+
+	// Namespace and cluster name have the same default name
+	if len(cfg.clusterNamespace) == 0 {
+		cfg.clusterNamespace = k8sutil.Namespace
+	}
+	if len(cfg.clusterName) == 0 {
+		cfg.clusterName = k8sutil.Namespace
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package operator
+
+import (
+	"testing"
+
+	"github.com/kubernetes-incubator/external-storage/lib/controller"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/rook/rook/pkg/rook/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseFilesystemClassParameters(t *testing.T) {
+	cfg, err := parseFilesystemClassParameters(map[string]string{"filesystem": "myfs"})
+	assert.Nil(t, err)
+	assert.Equal(t, filesystemProvisionerConfig{filesystem: "myfs", clusterNamespace: "rook", clusterName: "rook"}, *cfg)
+
+	cfg, err = parseFilesystemClassParameters(map[string]string{"filesystem": "myfs", "clusterNamespace": "ns", "clusterName": "name"})
+	assert.Nil(t, err)
+	assert.Equal(t, filesystemProvisionerConfig{filesystem: "myfs", clusterNamespace: "ns", clusterName: "name"}, *cfg)
+
+	// the file system is required
+	_, err = parseFilesystemClassParameters(map[string]string{"clusterNamespace": "ns"})
+	assert.NotNil(t, err)
+
+	_, err = parseFilesystemClassParameters(map[string]string{"filesystem": "myfs", "pool": "mypool"})
+	assert.EqualError(t, err, "invalid option \"pool\" for volume plugin rookFilesystemProvisioner")
+}
+
+func TestMakeFilesystemVolume(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}
+	p := &rookFilesystemProvisioner{clusterManager: newClusterManager(context, nil)}
+	rclient := &test.MockRookRestClient{
+		MockGetClientAccessInfo: func() (model.ClientAccessInfo, error) {
+			return model.ClientAccessInfo{MonAddresses: []string{"10.0.0.1:6790/0", "10.0.0.2:6790/0"}}, nil
+		},
+	}
+
+	options := controller.VolumeOptions{
+		PVName:                        "pvc-1",
+		PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+		PVC: &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "claim1", Namespace: "app"},
+			Spec: v1.PersistentVolumeClaimSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+				Resources:   v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+			},
+		},
+	}
+	cfg, _ := parseFilesystemClassParameters(map[string]string{"filesystem": "myfs"})
+	volume := &model.FilesystemVolume{Filesystem: "myfs", Name: "pvc-1", Path: "/volumes/pvc-1", Size: 1024 * 1024 * 1024,
+		UserName: "myfs-pvc-1", SecretKey: "volumekey"}
+
+	pv, err := p.makeVolume(options, cfg, volume, rclient)
+	assert.Nil(t, err)
+	assert.Equal(t, []v1.PersistentVolumeAccessMode{v1.ReadWriteMany}, pv.Spec.AccessModes)
+	assert.Equal(t, "myfs", pv.Annotations[filesystemAnnotation])
+	source := pv.Spec.PersistentVolumeSource.CephFS
+	assert.Equal(t, []string{"10.0.0.1:6790", "10.0.0.2:6790"}, source.Monitors)
+	assert.Equal(t, "/volumes/pvc-1", source.Path)
+	assert.Equal(t, "myfs-pvc-1", source.User)
+	assert.Equal(t, "rook-fs-pvc-1", source.SecretRef.Name)
+
+	// the key of the user is in the namespace of the claim
+	secret, err := clientset.CoreV1().Secrets("app").Get("rook-fs-pvc-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "volumekey", secret.StringData["key"])
+
+	// the settings of the class are read back from the PV
+	cfg, err = parseFilesystemVolumeAnnotations(pv)
+	assert.Nil(t, err)
+	assert.Equal(t, filesystemProvisionerConfig{filesystem: "myfs", clusterNamespace: "rook", clusterName: "rook"}, *cfg)
+
+	// only file system volumes are supported
+	pv.Spec.PersistentVolumeSource.CephFS = nil
+	_, err = parseFilesystemVolumeAnnotations(pv)
+	assert.NotNil(t, err)
+}
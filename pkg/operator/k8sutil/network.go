@@ -0,0 +1,93 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package k8sutil for Kubernetes helpers.
+package k8sutil
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"k8s.io/api/core/v1"
+)
+
+const (
+	// PublicNetworkEnvVar is the env var with the public network of the cluster
+	PublicNetworkEnvVar = "ROOKD_PUBLIC_NETWORK"
+	// ClusterNetworkEnvVar is the env var with the cluster network of the cluster
+	ClusterNetworkEnvVar = "ROOKD_PRIVATE_NETWORK"
+)
+
+// Network is the network configuration of the ceph daemons
+type Network struct {
+	// Whether the daemons run in the network namespace of the host instead of the pod network
+	HostNetwork bool `json:"hostNetwork,omitempty"`
+
+	// The network of the clients and the daemons in CIDR notation (e.g., 192.168.0.0/24)
+	PublicNetwork string `json:"publicNetwork,omitempty"`
+
+	// The network of the replication and recovery traffic between the osds in CIDR notation (e.g., 10.0.0.0/24)
+	ClusterNetwork string `json:"clusterNetwork,omitempty"`
+}
+
+// Validate the network settings
+func (n Network) Validate() error {
+	return clusterd.VerifyNetworkInfo(clusterd.NetworkInfo{PublicNetwork: n.PublicNetwork, ClusterNetwork: n.ClusterNetwork})
+}
+
+// ApplyToPodSpec runs the pod in the host network if it is requested
+func (n Network) ApplyToPodSpec(t *v1.PodSpec) {
+	if n.HostNetwork {
+		t.HostNetwork = true
+		// the pods still need to resolve the names of the services in the cluster
+		t.DNSPolicy = v1.DNSClusterFirstWithHostNet
+	}
+}
+
+// EnvVars returns the env vars that pass the networks to the daemons so they are rendered in the ceph config
+func (n Network) EnvVars() []v1.EnvVar {
+	envVars := []v1.EnvVar{}
+	if n.PublicNetwork != "" {
+		envVars = append(envVars, v1.EnvVar{Name: PublicNetworkEnvVar, Value: n.PublicNetwork})
+	}
+	if n.ClusterNetwork != "" {
+		envVars = append(envVars, v1.EnvVar{Name: ClusterNetworkEnvVar, Value: n.ClusterNetwork})
+	}
+	return envVars
+}
+
+// PublicAddress returns the address of the node in the public network. If no public network is set or the node does
+// not report an address in the public network, no address is returned.
+func (n Network) PublicAddress(node *v1.Node) (string, error) {
+	if n.PublicNetwork == "" {
+		return "", nil
+	}
+	_, network, err := net.ParseCIDR(n.PublicNetwork)
+	if err != nil {
+		return "", fmt.Errorf("invalid public network %s. %+v", n.PublicNetwork, err)
+	}
+
+	for _, address := range node.Status.Addresses {
+		if address.Type != v1.NodeInternalIP && address.Type != v1.NodeExternalIP {
+			continue
+		}
+		if ip := net.ParseIP(address.Address); ip != nil && network.Contains(ip) {
+			return address.Address, nil
+		}
+	}
+	return "", nil
+}
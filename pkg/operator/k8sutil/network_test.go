@@ -0,0 +1,73 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package k8sutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+)
+
+func TestNetworkValidate(t *testing.T) {
+	assert.Nil(t, Network{}.Validate())
+	assert.Nil(t, Network{PublicNetwork: "192.168.0.0/24", ClusterNetwork: "10.0.0.0/24"}.Validate())
+	assert.NotNil(t, Network{PublicNetwork: "192.168.0.1"}.Validate())
+	assert.NotNil(t, Network{ClusterNetwork: "10.0.0"}.Validate())
+}
+
+func TestNetworkPodSpec(t *testing.T) {
+	// the pod network is used by default
+	spec := v1.PodSpec{}
+	n := Network{}
+	n.ApplyToPodSpec(&spec)
+	assert.False(t, spec.HostNetwork)
+	assert.Equal(t, v1.DNSPolicy(""), spec.DNSPolicy)
+	assert.Equal(t, 0, len(n.EnvVars()))
+
+	n = Network{HostNetwork: true, PublicNetwork: "192.168.0.0/24", ClusterNetwork: "10.0.0.0/24"}
+	n.ApplyToPodSpec(&spec)
+	assert.True(t, spec.HostNetwork)
+	assert.Equal(t, v1.DNSClusterFirstWithHostNet, spec.DNSPolicy)
+	assert.Equal(t, []v1.EnvVar{
+		{Name: PublicNetworkEnvVar, Value: "192.168.0.0/24"},
+		{Name: ClusterNetworkEnvVar, Value: "10.0.0.0/24"},
+	}, n.EnvVars())
+}
+
+func TestNetworkPublicAddress(t *testing.T) {
+	node := &v1.Node{Status: v1.NodeStatus{Addresses: []v1.NodeAddress{
+		{Type: v1.NodeHostName, Address: "node1"},
+		{Type: v1.NodeInternalIP, Address: "10.0.0.5"},
+		{Type: v1.NodeExternalIP, Address: "192.168.0.5"},
+	}}}
+
+	// no address is selected without a public network
+	addr, err := Network{}.PublicAddress(node)
+	assert.Nil(t, err)
+	assert.Equal(t, "", addr)
+
+	addr, err = Network{PublicNetwork: "192.168.0.0/24"}.PublicAddress(node)
+	assert.Nil(t, err)
+	assert.Equal(t, "192.168.0.5", addr)
+
+	addr, err = Network{PublicNetwork: "172.16.0.0/16"}.PublicAddress(node)
+	assert.Nil(t, err)
+	assert.Equal(t, "", addr)
+
+	_, err = Network{PublicNetwork: "172.16.0.0"}.PublicAddress(node)
+	assert.NotNil(t, err)
+}
@@ -24,8 +24,10 @@ import (
 // Placement encapsulates the various kubernetes options that control where
 // pods are scheduled and executed.
 type Placement struct {
-	NodeAffinity *v1.NodeAffinity `json:"nodeAffinity,omitempty"`
-	Tolerations  []v1.Toleration  `json:"tolerations,omitemtpy"`
+	NodeAffinity    *v1.NodeAffinity    `json:"nodeAffinity,omitempty"`
+	PodAffinity     *v1.PodAffinity     `json:"podAffinity,omitempty"`
+	PodAntiAffinity *v1.PodAntiAffinity `json:"podAntiAffinity,omitempty"`
+	Tolerations     []v1.Toleration     `json:"tolerations,omitemtpy"`
 }
 
 // ApplyToPodSpec adds placement to a pod spec
@@ -36,6 +38,18 @@ func (p Placement) ApplyToPodSpec(t *v1.PodSpec) {
 		}
 		t.Affinity.NodeAffinity = p.NodeAffinity
 	}
+	if p.PodAffinity != nil {
+		if t.Affinity == nil {
+			t.Affinity = &v1.Affinity{}
+		}
+		t.Affinity.PodAffinity = p.PodAffinity
+	}
+	if p.PodAntiAffinity != nil {
+		if t.Affinity == nil {
+			t.Affinity = &v1.Affinity{}
+		}
+		t.Affinity.PodAntiAffinity = p.PodAntiAffinity
+	}
 	if p.Tolerations != nil {
 		t.Tolerations = p.Tolerations
 	}
@@ -49,6 +63,12 @@ func (p Placement) Merge(with Placement) Placement {
 	if with.NodeAffinity != nil {
 		ret.NodeAffinity = with.NodeAffinity
 	}
+	if with.PodAffinity != nil {
+		ret.PodAffinity = with.PodAffinity
+	}
+	if with.PodAntiAffinity != nil {
+		ret.PodAntiAffinity = with.PodAntiAffinity
+	}
 	if with.Tolerations != nil {
 		ret.Tolerations = with.Tolerations
 	}
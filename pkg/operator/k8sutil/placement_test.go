@@ -22,6 +22,7 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestPlacement_spec(t *testing.T) {
@@ -34,6 +35,12 @@ nodeAffinity:
         operator: In
         values:
           - bar
+podAntiAffinity:
+  requiredDuringSchedulingIgnoredDuringExecution:
+  - labelSelector:
+      matchLabels:
+        app: foo
+    topologyKey: failure-domain.beta.kubernetes.io/zone
 tolerations:
   - key: foo
     operator: Exists`)
@@ -64,6 +71,14 @@ tolerations:
 				},
 			},
 		},
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+					TopologyKey:   "failure-domain.beta.kubernetes.io/zone",
+				},
+			},
+		},
 		Tolerations: []v1.Toleration{
 			{
 				Key:      "foo",
@@ -105,6 +120,19 @@ func TestPlacement_ApplyToPodSpec(t *testing.T) {
 	ps = &v1.PodSpec{Affinity: &v1.Affinity{NodeAffinity: nap}, Tolerations: to}
 	p.ApplyToPodSpec(ps)
 	assert.Equal(t, expected, ps)
+
+	// pod affinity and anti-affinity
+	pa := placementTestGetPodAffinity()
+	paa := placementTestGetPodAntiAffinity()
+	p = Placement{PodAffinity: pa, PodAntiAffinity: paa}
+	ps = &v1.PodSpec{Affinity: &v1.Affinity{NodeAffinity: na}}
+	p.ApplyToPodSpec(ps)
+	assert.Equal(t, &v1.PodSpec{Affinity: &v1.Affinity{NodeAffinity: na, PodAffinity: pa, PodAntiAffinity: paa}}, ps)
+
+	p = Placement{PodAntiAffinity: paa}
+	ps = &v1.PodSpec{}
+	p.ApplyToPodSpec(ps)
+	assert.Equal(t, &v1.PodSpec{Affinity: &v1.Affinity{PodAntiAffinity: paa}}, ps)
 }
 
 func TestPlacement_Merge(t *testing.T) {
@@ -130,6 +158,20 @@ func TestPlacement_Merge(t *testing.T) {
 	expected = Placement{NodeAffinity: na, Tolerations: to}
 	merged = original.Merge(with)
 	assert.Equal(t, expected, merged)
+
+	pa := placementTestGetPodAffinity()
+	paa := placementTestGetPodAntiAffinity()
+	original = Placement{NodeAffinity: na, PodAntiAffinity: &v1.PodAntiAffinity{}}
+	with = Placement{PodAffinity: pa, PodAntiAffinity: paa}
+	expected = Placement{NodeAffinity: na, PodAffinity: pa, PodAntiAffinity: paa}
+	merged = original.Merge(with)
+	assert.Equal(t, expected, merged)
+
+	original = Placement{PodAffinity: pa, PodAntiAffinity: paa}
+	with = Placement{Tolerations: to}
+	expected = Placement{PodAffinity: pa, PodAntiAffinity: paa, Tolerations: to}
+	merged = original.Merge(with)
+	assert.Equal(t, expected, merged)
 }
 
 func placementTestGetTolerations(key, value string) []v1.Toleration {
@@ -176,3 +218,28 @@ func placementTestGetNodeAffinity() *v1.NodeAffinity {
 		},
 	}
 }
+
+func placementTestGetPodAffinity() *v1.PodAffinity {
+	return &v1.PodAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+			{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+				TopologyKey:   "kubernetes.io/hostname",
+			},
+		},
+	}
+}
+
+func placementTestGetPodAntiAffinity() *v1.PodAntiAffinity {
+	return &v1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+			{
+				Weight: 10,
+				PodAffinityTerm: v1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bar"}},
+					TopologyKey:   "kubernetes.io/hostname",
+				},
+			},
+		},
+	}
+}
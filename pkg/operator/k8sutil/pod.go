@@ -21,11 +21,19 @@ which also has the apache 2.0 license.
 package k8sutil
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 const (
@@ -35,6 +43,8 @@ const (
 	ClusterAttr = "rook_cluster"
 	// VersionAttr version label
 	VersionAttr = "rook_version"
+	// ConfigOverrideHashAttr is the annotation with the hash of the config override that the pod was started with
+	ConfigOverrideHashAttr = "rook_config_override_hash"
 	// PodIPEnvVar pod IP env var
 	PodIPEnvVar = "ROOKD_PRIVATE_IPV4"
 	// DefaultRepoPrefix repo prefix
@@ -43,10 +53,17 @@ const (
 	ConfigOverrideName = "rook-config-override"
 	// ConfigOverrideVal config override value
 	ConfigOverrideVal = "config"
-	repoPrefixEnvVar  = "ROOKD_REPO_PREFIX"
-	defaultVersion    = "latest"
-	configMountDir    = "/etc/rook"
-	overrideFilename  = "override.conf"
+	// CephConfigVal is the key in the config override configmap of the validated ceph settings from the cluster spec
+	CephConfigVal      = "cephConfig"
+	repoPrefixEnvVar   = "ROOKD_REPO_PREFIX"
+	defaultVersion     = "latest"
+	configMountDir     = "/etc/rook"
+	overrideFilename   = "override.conf"
+	cephConfigFilename = "ceph-config.conf"
+	// PodInfoName is the name of the volume with the annotations of the pod
+	PodInfoName            = "rook-pod-info"
+	podInfoMountDir        = "/etc/rook-pod"
+	podAnnotationsFilename = "annotations"
 )
 
 // ConfigOverrideMount is an override mount
@@ -56,7 +73,10 @@ func ConfigOverrideMount() v1.VolumeMount {
 
 // ConfigOverrideVolume is an override volume
 func ConfigOverrideVolume() v1.Volume {
-	cmSource := &v1.ConfigMapVolumeSource{Items: []v1.KeyToPath{{Key: ConfigOverrideVal, Path: overrideFilename}}}
+	cmSource := &v1.ConfigMapVolumeSource{Items: []v1.KeyToPath{
+		{Key: ConfigOverrideVal, Path: overrideFilename},
+		{Key: CephConfigVal, Path: cephConfigFilename},
+	}}
 	cmSource.Name = ConfigOverrideName
 	return v1.Volume{Name: ConfigOverrideName, VolumeSource: v1.VolumeSource{ConfigMap: cmSource}}
 }
@@ -66,6 +86,114 @@ func ConfigOverrideEnvVar() v1.EnvVar {
 	return v1.EnvVar{Name: "ROOKD_CEPH_CONFIG_OVERRIDE", Value: path.Join(configMountDir, overrideFilename)}
 }
 
+// CephConfigEnvVar is the path of the ceph settings from the cluster spec in the config override volume
+func CephConfigEnvVar() v1.EnvVar {
+	return v1.EnvVar{Name: "ROOKD_CEPH_CONFIG", Value: path.Join(configMountDir, cephConfigFilename)}
+}
+
+// PodInfoVolume is the volume with the annotations of the pod. The kubelet updates the annotations in the volume of a
+// running pod, so the daemons can be signaled by changing the annotations of their pods.
+func PodInfoVolume() v1.Volume {
+	source := &v1.DownwardAPIVolumeSource{Items: []v1.DownwardAPIVolumeFile{
+		{Path: podAnnotationsFilename, FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.annotations"}},
+	}}
+	return v1.Volume{Name: PodInfoName, VolumeSource: v1.VolumeSource{DownwardAPI: source}}
+}
+
+// PodInfoMount is the mount of the volume with the annotations of the pod
+func PodInfoMount() v1.VolumeMount {
+	return v1.VolumeMount{Name: PodInfoName, MountPath: podInfoMountDir}
+}
+
+// PodAnnotationsEnvVar is the path of the annotations of the pod in the pod info volume
+func PodAnnotationsEnvVar() v1.EnvVar {
+	return v1.EnvVar{Name: "ROOKD_POD_ANNOTATIONS", Value: path.Join(podInfoMountDir, podAnnotationsFilename)}
+}
+
+// HasPodInfoVolume returns whether the pod mounts the volume with its annotations
+func HasPodInfoVolume(spec v1.PodSpec) bool {
+	for _, volume := range spec.Volumes {
+		if volume.Name == PodInfoName {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePodAnnotations parses the annotations of a pod in the format of the downward api, one key="value" per line
+func ParsePodAnnotations(content string) map[string]string {
+	annotations := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value, err := strconv.Unquote(parts[1])
+		if err != nil {
+			continue
+		}
+		annotations[parts[0]] = value
+	}
+	return annotations
+}
+
+// WatchPodAnnotation polls the annotations of the pod in the file and calls changed when the value of the annotation
+// differs from its value when the watch started. The watch stops after the change.
+func WatchPodAnnotation(annotationsPath, key string, interval time.Duration, changed func(value string)) error {
+	read := func() (string, error) {
+		content, err := ioutil.ReadFile(annotationsPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the pod annotations. %+v", err)
+		}
+		return ParsePodAnnotations(string(content))[key], nil
+	}
+	initial, err := read()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			<-time.After(interval)
+			value, err := read()
+			if err != nil {
+				logger.Warningf("%+v", err)
+				continue
+			}
+			if value != initial {
+				changed(value)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// GetConfigOverride returns the content of the config override configmap in the namespace: the ceph settings from
+// the cluster spec followed by the override, which takes precedence. The content is empty if the configmap does not
+// exist.
+func GetConfigOverride(clientset kubernetes.Interface, namespace string) (string, error) {
+	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(ConfigOverrideName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get config override configmap. %+v", err)
+	}
+	content := []string{}
+	for _, key := range []string{CephConfigVal, ConfigOverrideVal} {
+		if cm.Data[key] != "" {
+			content = append(content, cm.Data[key])
+		}
+	}
+	return strings.Join(content, "\n"), nil
+}
+
+// ConfigOverrideHash returns the hash of the content of the config override that is recorded on the pods
+func ConfigOverrideHash(config string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(config)))
+}
+
 // NamespaceEnvVar namespace env var
 func NamespaceEnvVar() v1.EnvVar {
 	return v1.EnvVar{Name: "ROOKD_NAMESPACE", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}}
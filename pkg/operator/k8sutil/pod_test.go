@@ -17,10 +17,14 @@ package k8sutil
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestMakeRookImage(t *testing.T) {
@@ -36,3 +40,54 @@ func TestMakeRookImageWithEnv(t *testing.T) {
 func TestDefaultVersion(t *testing.T) {
 	assert.Equal(t, fmt.Sprintf("rook/rook:%s", defaultVersion), MakeRookImage(""))
 }
+
+func TestConfigOverride(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+
+	// the content is empty if the configmap does not exist
+	config, err := GetConfigOverride(clientset, "ns")
+	assert.Nil(t, err)
+	assert.Equal(t, "", config)
+
+	cm := &v1.ConfigMap{Data: map[string]string{ConfigOverrideVal: "[osd]\nosd max backfills = 2\n"}}
+	cm.Name = ConfigOverrideName
+	_, err = clientset.CoreV1().ConfigMaps("ns").Create(cm)
+	assert.Nil(t, err)
+	config, err = GetConfigOverride(clientset, "ns")
+	assert.Nil(t, err)
+	assert.Equal(t, "[osd]\nosd max backfills = 2\n", config)
+
+	// the hash changes with the content
+	assert.Equal(t, ConfigOverrideHash(config), ConfigOverrideHash("[osd]\nosd max backfills = 2\n"))
+	assert.NotEqual(t, ConfigOverrideHash(config), ConfigOverrideHash(""))
+
+	// the settings from the cluster spec come before the override
+	cm.Data[CephConfigVal] = "[global]\nosd pool default size = 3\n"
+	_, err = clientset.CoreV1().ConfigMaps("ns").Update(cm)
+	assert.Nil(t, err)
+	config, err = GetConfigOverride(clientset, "ns")
+	assert.Nil(t, err)
+	assert.Equal(t, "[global]\nosd pool default size = 3\n\n[osd]\nosd max backfills = 2\n", config)
+}
+
+func TestPodAnnotations(t *testing.T) {
+	annotations := ParsePodAnnotations("rook_version=\"v1\"\nrook_config_override_hash=\"abc\\\"d\"\ninvalid\n")
+	assert.Equal(t, map[string]string{"rook_version": "v1", "rook_config_override_hash": "abc\"d"}, annotations)
+
+	// the change of the annotation is reported
+	file, err := ioutil.TempFile("", "")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	assert.Nil(t, ioutil.WriteFile(file.Name(), []byte("rook_config_override_hash=\"hash1\"\n"), 0644))
+	changed := make(chan string, 2)
+	err = WatchPodAnnotation(file.Name(), ConfigOverrideHashAttr, time.Millisecond, func(value string) { changed <- value })
+	assert.Nil(t, err)
+	// the kubelet replaces the file atomically
+	assert.Nil(t, ioutil.WriteFile(file.Name()+".new", []byte("rook_config_override_hash=\"hash2\"\n"), 0644))
+	assert.Nil(t, os.Rename(file.Name()+".new", file.Name()))
+	assert.Equal(t, "hash2", <-changed)
+
+	// the annotations must exist when the watch starts
+	err = WatchPodAnnotation("/nonexistent/annotations", ConfigOverrideHashAttr, time.Millisecond, func(value string) {})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package k8sutil for Kubernetes helpers.
+package k8sutil
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeleteFunc is the signature of the Delete method of the typed kubernetes clients
+type DeleteFunc func(name string, options *metav1.DeleteOptions) error
+
+// DeleteResource deletes a kubernetes resource with foreground propagation so the pods owned by the resource
+// are removed along with it. A resource that does not exist is not considered an error.
+func DeleteResource(kind, name string, deleteFunc DeleteFunc) error {
+	propagation := metav1.DeletePropagationForeground
+	options := &metav1.DeleteOptions{PropagationPolicy: &propagation}
+	if err := deleteFunc(name, options); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s %s. %+v", kind, name, err)
+		}
+		logger.Infof("%s %s was already deleted", kind, name)
+		return nil
+	}
+
+	logger.Infof("deleted %s %s", kind, name)
+	return nil
+}
@@ -21,6 +21,7 @@ which also has the apache 2.0 license.
 package kit
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -159,3 +160,41 @@ func GetRawListNamespaced(clientset kubernetes.Interface, resource CustomResourc
 func GetRawList(clientset kubernetes.Interface, resource CustomResource) ([]byte, error) {
 	return GetRawListNamespaced(clientset, resource, "")
 }
+
+// GetRawResource retrieves a single custom resource of the given type
+func GetRawResource(clientset kubernetes.Interface, resource CustomResource, namespace, name string) ([]byte, error) {
+	restcli := clientset.CoreV1().RESTClient()
+	uri := fmt.Sprintf("%s/%s", resourceURI(resource, namespace), name)
+	return restcli.Get().RequestURI(uri).DoRaw()
+}
+
+// UpdateRawResource replaces a single custom resource of the given type with the serialized object
+func UpdateRawResource(clientset kubernetes.Interface, resource CustomResource, namespace, name string, body []byte) ([]byte, error) {
+	restcli := clientset.CoreV1().RESTClient()
+	uri := fmt.Sprintf("%s/%s", resourceURI(resource, namespace), name)
+	return restcli.Put().RequestURI(uri).Body(body).DoRaw()
+}
+
+// UpdateRawResourceStatus replaces the status of a single custom resource of the given type. The latest version of the
+// resource is retrieved first so that changes to the rest of the resource are not overwritten. If the resource does
+// not exist or is deleted before the status is saved, the not found error is returned.
+func UpdateRawResourceStatus(clientset kubernetes.Interface, resource CustomResource, namespace, name string, status interface{}) error {
+	raw, err := GetRawResource(clientset, resource, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return fmt.Errorf("failed to unmarshal %s %s. %+v", resource.Name, name, err)
+	}
+	obj["status"] = status
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s %s. %+v", resource.Name, name, err)
+	}
+
+	// the api error is returned as is so that the callers can check for a resource that was deleted
+	_, err = UpdateRawResource(clientset, resource, namespace, name, body)
+	return err
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/ceph/client"
 	cephmds "github.com/rook/rook/pkg/ceph/mds"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
@@ -35,61 +36,271 @@ import (
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-mds")
 
 const (
-	appName            = "rook-ceph-mds"
-	dataPoolSuffix     = "-data"
-	metadataPoolSuffix = "-metadata"
-	keyringName        = "keyring"
+	appName        = "rook-ceph-mds"
+	keyringName    = "keyring"
+	fileSystemAttr = "rook_file_system"
+	// the name of the deployment and the secret of the single mds started by operators before the file system TPR.
+	// the deployment does not have the labels of the mds deployments.
+	legacyMDSName = appName
 )
 
 // Cluster for mds management
 type Cluster struct {
 	Namespace string
 	Version   string
-	Replicas  int32
-	context   *clusterd.Context
-	dataDir   string
-	placement k8sutil.Placement
+	// The file system served by the mds daemons
+	Filesystem string
+	// The ID of the file system in ceph that the standby-replay daemons follow
+	FilesystemID int
+	// The number of active and standby mds daemons
+	Replicas int32
+	// The number of active ranks of the file system
+	ActiveCount int32
+	// The number of standby-replay daemons that follow the journal of the active ranks
+	StandbyReplayCount int32
+	context            *clusterd.Context
+	dataDir            string
+	placement          k8sutil.Placement
+	resources          v1.ResourceRequirements
+	network            k8sutil.Network
+}
+
+// mdsConfig is the configuration of a single mds daemon
+type mdsConfig struct {
+	// The name of the deployment and the secret with the keyring
+	resourceName string
+	// The id of the mds in ceph
+	id string
+	// The rank followed by a standby-replay daemon, or -1 if the daemon is not a standby-replay daemon
+	standbyForRank int
 }
 
 // New creates an instance of the mds manager
-func New(context *clusterd.Context, namespace, version string, placement k8sutil.Placement) *Cluster {
+func New(context *clusterd.Context, namespace, version string, placement k8sutil.Placement, resources v1.ResourceRequirements, network k8sutil.Network) *Cluster {
 	return &Cluster{
-		context:   context,
-		Namespace: namespace,
-		placement: placement,
-		Version:   version,
-		Replicas:  1,
-		dataDir:   k8sutil.DataDir,
+		context:     context,
+		Namespace:   namespace,
+		placement:   placement,
+		resources:   resources,
+		network:     network,
+		Version:     version,
+		Replicas:    1,
+		ActiveCount: 1,
+		dataDir:     k8sutil.DataDir,
 	}
 }
 
-// Start the mds manager
+// Start the mds daemons of the file system that are not running yet
 func (c *Cluster) Start() error {
-	logger.Infof("start running mds")
+	if c.Filesystem == "" {
+		return fmt.Errorf("missing file system name")
+	}
+	logger.Infof("start running mds for file system %s", c.Filesystem)
+
+	for _, config := range c.mdsConfigs() {
+		err := c.createKeyring(c.context.Clientset, config)
+		if err != nil {
+			return fmt.Errorf("failed to create mds keyring. %+v", err)
+		}
+
+		// start the deployment
+		deployment := c.makeDeployment(config)
+		_, err = c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Create(deployment)
+		if err != nil {
+			if !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create mds deployment %s. %+v", config.resourceName, err)
+			}
+			logger.Infof("mds deployment %s already exists", config.resourceName)
+		} else {
+			logger.Infof("mds deployment %s started", config.resourceName)
+		}
+	}
+
+	// the mds of the previous operator is replaced by the mds daemons of the file system
+	return c.deleteLegacyMDS()
+}
 
-	id := "mds1"
-	err := c.createKeyring(c.context.Clientset, id)
+// Update the mds deployments of the file system with the current settings if the file system was started.
+// The deployments of the mds daemons that are no longer needed are removed.
+func (c *Cluster) Update() error {
+	deployments, err := c.getDeployments()
 	if err != nil {
-		return fmt.Errorf("failed to create mds keyring. %+v", err)
+		return err
+	}
+	if len(deployments) == 0 {
+		logger.Infof("mds is not running for file system %s in namespace %s", c.Filesystem, c.Namespace)
+		return nil
+	}
+
+	configs := map[string]mdsConfig{}
+	for _, config := range c.mdsConfigs() {
+		configs[config.resourceName] = config
+	}
+
+	for _, d := range deployments {
+		config, ok := configs[d.Name]
+		if !ok {
+			logger.Infof("removing mds %s that is no longer needed", d.Name)
+			if err := c.deleteMDS(d.Name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Update(c.makeDeployment(config)); err != nil {
+			return fmt.Errorf("failed to update mds deployment %s. %+v", d.Name, err)
+		}
+		logger.Infof("mds deployment %s updated", d.Name)
+	}
+	return nil
+}
+
+// Upgrade all the mds deployments in the namespace to the version of the cluster. The other settings of the
+// deployments are not changed.
+func (c *Cluster) Upgrade() error {
+	deployments, err := c.getDeployments()
+	if err != nil {
+		return err
+	}
+
+	for i := range deployments {
+		d := &deployments[i]
+		d.Spec.Template.Spec.Containers[0].Image = k8sutil.MakeRookImage(c.Version)
+		if _, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Update(d); err != nil {
+			return fmt.Errorf("failed to upgrade mds deployment %s. %+v", d.Name, err)
+		}
+		logger.Infof("mds deployment %s upgraded to version %s", d.Name, c.Version)
+	}
+	return nil
+}
+
+// Delete the mds deployments and keyrings of the file system, or of all the file systems in the namespace
+// if the file system is not set
+func (c *Cluster) Delete() error {
+	logger.Infof("deleting mds in namespace %s", c.Namespace)
+	deployments, err := c.getDeployments()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deployments {
+		if err := c.deleteMDS(d.Name); err != nil {
+			return err
+		}
+	}
+	if c.Filesystem != "" {
+		// the mds of the previous operator served the only file system
+		return c.deleteLegacyMDS()
+	}
+	return nil
+}
+
+// RemoveFilesystem stops the mds daemons of the file system and removes the file system from ceph.
+// The pools of the file system are not deleted.
+func (c *Cluster) RemoveFilesystem() error {
+	logger.Infof("removing file system %s", c.Filesystem)
+
+	// mark the file system as down before removing it
+	if err := client.MarkFilesystemAsDown(c.context, c.Namespace, c.Filesystem); err != nil {
+		return err
 	}
 
-	// start the deployment
-	deployment := c.makeDeployment(id)
-	_, err = c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Create(deployment)
+	// mark each mds associated with the file system as failed
+	details, err := client.GetFilesystem(c.context, c.Namespace, c.Filesystem)
 	if err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return fmt.Errorf("failed to create mds deployment. %+v", err)
+		return err
+	}
+	for _, info := range details.MDSMap.Info {
+		if err := client.FailMDS(c.context, c.Namespace, info.GID); err != nil {
+			return err
 		}
-		logger.Infof("mds deployment already exists")
-	} else {
-		logger.Infof("mds deployment started")
 	}
 
+	if err := c.Delete(); err != nil {
+		return fmt.Errorf("failed to stop mds. %+v", err)
+	}
+
+	// permanently remove the file system
+	if err := client.RemoveFilesystem(c.context, c.Namespace, c.Filesystem); err != nil {
+		return err
+	}
+
+	logger.Infof("removed file system %s", c.Filesystem)
 	return nil
 }
 
-func (c *Cluster) createKeyring(clientset kubernetes.Interface, id string) error {
-	_, err := clientset.CoreV1().Secrets(c.Namespace).Get(appName, metav1.GetOptions{})
+func (c *Cluster) deleteMDS(name string) error {
+	if err := k8sutil.DeleteResource("mds deployment", name, c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Delete); err != nil {
+		return err
+	}
+	return k8sutil.DeleteResource("mds secret", name, c.context.Clientset.CoreV1().Secrets(c.Namespace).Delete)
+}
+
+// deleteLegacyMDS removes the deployment and the secret of the mds started by the previous operator, if any
+func (c *Cluster) deleteLegacyMDS() error {
+	legacy, err := c.getLegacyDeployment()
+	if err != nil || legacy == nil {
+		return err
+	}
+	logger.Infof("removing mds %s of the previous operator", legacy.Name)
+	return c.deleteMDS(legacy.Name)
+}
+
+// getDeployments returns the mds deployments of the file system, or of all the file systems in the namespace
+// if the file system is not set. The mds of the previous operator is included with the deployments of all the file
+// systems.
+func (c *Cluster) getDeployments() ([]extensions.Deployment, error) {
+	selector := fmt.Sprintf("%s=%s,%s=%s", k8sutil.AppAttr, appName, k8sutil.ClusterAttr, c.Namespace)
+	if c.Filesystem != "" {
+		selector = fmt.Sprintf("%s,%s=%s", selector, fileSystemAttr, c.Filesystem)
+	}
+	deployments, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mds deployments. %+v", err)
+	}
+	if c.Filesystem != "" {
+		return deployments.Items, nil
+	}
+
+	legacy, err := c.getLegacyDeployment()
+	if err != nil {
+		return nil, err
+	}
+	if legacy != nil {
+		return append(deployments.Items, *legacy), nil
+	}
+	return deployments.Items, nil
+}
+
+// getLegacyDeployment returns the deployment of the mds started by the previous operator, or nil if there is none
+func (c *Cluster) getLegacyDeployment() (*extensions.Deployment, error) {
+	d, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Get(legacyMDSName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get mds deployment %s. %+v", legacyMDSName, err)
+	}
+	return d, nil
+}
+
+// mdsConfigs returns the settings of each mds daemon of the file system. The active and standby daemons are
+// followed by the standby-replay daemons, which are spread over the active ranks.
+func (c *Cluster) mdsConfigs() []mdsConfig {
+	var configs []mdsConfig
+	for i := 0; i < int(c.Replicas+c.StandbyReplayCount); i++ {
+		id := fmt.Sprintf("%s-%d", c.Filesystem, i)
+		config := mdsConfig{resourceName: fmt.Sprintf("%s-%s", appName, id), id: id, standbyForRank: -1}
+		if i >= int(c.Replicas) && c.ActiveCount > 0 {
+			config.standbyForRank = (i - int(c.Replicas)) % int(c.ActiveCount)
+		}
+		configs = append(configs, config)
+	}
+	return configs
+}
+
+func (c *Cluster) createKeyring(clientset kubernetes.Interface, config mdsConfig) error {
+	_, err := clientset.CoreV1().Secrets(c.Namespace).Get(config.resourceName, metav1.GetOptions{})
 	if err == nil {
 		logger.Infof("the mds keyring was already generated")
 		return nil
@@ -99,7 +310,7 @@ func (c *Cluster) createKeyring(clientset kubernetes.Interface, id string) error
 	}
 
 	// get-or-create-key for the user account
-	keyring, err := cephmds.CreateKeyring(c.context, c.Namespace, id)
+	keyring, err := cephmds.CreateKeyring(c.context, c.Namespace, config.id)
 	if err != nil {
 		return fmt.Errorf("failed to create mds keyring. %+v", err)
 	}
@@ -109,7 +320,7 @@ func (c *Cluster) createKeyring(clientset kubernetes.Interface, id string) error
 		keyringName: keyring,
 	}
 	secret := &v1.Secret{
-		ObjectMeta: metav1.ObjectMeta{Name: appName, Namespace: c.Namespace},
+		ObjectMeta: metav1.ObjectMeta{Name: config.resourceName, Namespace: c.Namespace},
 		StringData: secrets,
 		Type:       k8sutil.RookType,
 	}
@@ -121,13 +332,14 @@ func (c *Cluster) createKeyring(clientset kubernetes.Interface, id string) error
 	return nil
 }
 
-func (c *Cluster) makeDeployment(id string) *extensions.Deployment {
+func (c *Cluster) makeDeployment(config mdsConfig) *extensions.Deployment {
 	deployment := &extensions.Deployment{}
-	deployment.Name = appName
+	deployment.Name = config.resourceName
 	deployment.Namespace = c.Namespace
+	deployment.Labels = c.getLabels()
 
 	podSpec := v1.PodSpec{
-		Containers:    []v1.Container{c.mdsContainer(id)},
+		Containers:    []v1.Container{c.mdsContainer(config)},
 		RestartPolicy: v1.RestartPolicyAlways,
 		Volumes: []v1.Volume{
 			{Name: k8sutil.DataDirVolume, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
@@ -135,6 +347,7 @@ func (c *Cluster) makeDeployment(id string) *extensions.Deployment {
 		},
 	}
 	c.placement.ApplyToPodSpec(&podSpec)
+	c.network.ApplyToPodSpec(&podSpec)
 
 	podTemplateSpec := v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
@@ -145,33 +358,43 @@ func (c *Cluster) makeDeployment(id string) *extensions.Deployment {
 		Spec: podSpec,
 	}
 
-	deployment.Spec = extensions.DeploymentSpec{Template: podTemplateSpec, Replicas: &c.Replicas}
+	replicas := int32(1)
+	deployment.Spec = extensions.DeploymentSpec{Template: podTemplateSpec, Replicas: &replicas}
 
 	return deployment
 }
 
-func (c *Cluster) mdsContainer(id string) v1.Container {
+func (c *Cluster) mdsContainer(config mdsConfig) v1.Container {
+	args := []string{
+		"mds",
+		fmt.Sprintf("--config-dir=%s", k8sutil.DataDir),
+		fmt.Sprintf("--mds-id=%s", config.id),
+	}
+	if config.standbyForRank >= 0 {
+		args = append(args,
+			"--mds-standby-replay=true",
+			fmt.Sprintf("--mds-standby-for-rank=%d", config.standbyForRank),
+			fmt.Sprintf("--mds-standby-for-fscid=%d", c.FilesystemID))
+	}
 
 	return v1.Container{
-		Args: []string{
-			"mds",
-			fmt.Sprintf("--config-dir=%s", k8sutil.DataDir),
-			fmt.Sprintf("--mds-id=%s", id),
-		},
-		Name:  appName,
-		Image: k8sutil.MakeRookImage(c.Version),
+		Args:      args,
+		Name:      appName,
+		Image:     k8sutil.MakeRookImage(c.Version),
+		Resources: c.resources,
 		VolumeMounts: []v1.VolumeMount{
 			{Name: k8sutil.DataDirVolume, MountPath: k8sutil.DataDir},
 			k8sutil.ConfigOverrideMount(),
 		},
-		Env: []v1.EnvVar{
-			{Name: "ROOKD_MDS_KEYRING", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: appName}, Key: keyringName}}},
+		Env: append([]v1.EnvVar{
+			{Name: "ROOKD_MDS_KEYRING", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: config.resourceName}, Key: keyringName}}},
 			opmon.ClusterNameEnvVar(c.Namespace),
 			opmon.EndpointEnvVar(),
 			opmon.SecretEnvVar(),
 			opmon.AdminSecretEnvVar(),
 			k8sutil.ConfigOverrideEnvVar(),
-		},
+			k8sutil.CephConfigEnvVar(),
+		}, c.network.EnvVars()...),
 	}
 }
 
@@ -179,5 +402,6 @@ func (c *Cluster) getLabels() map[string]string {
 	return map[string]string{
 		k8sutil.AppAttr:     appName,
 		k8sutil.ClusterAttr: c.Namespace,
+		fileSystemAttr:      c.Filesystem,
 	}
 }
@@ -27,6 +27,8 @@ import (
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -43,49 +45,199 @@ func TestStartMDS(t *testing.T) {
 		Executor:    executor,
 		ConfigDir:   configDir,
 		KubeContext: kit.KubeContext{Clientset: testop.New(3)}}
-	c := New(context, "ns", "myversion", k8sutil.Placement{})
+	c := New(context, "ns", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.Filesystem = "myfs"
+	c.Replicas = 2
 	defer os.RemoveAll(c.dataDir)
 
 	// start a basic cluster
 	err := c.Start()
 	assert.Nil(t, err)
-	validateStart(t, c)
+	validateStart(t, c, "rook-ceph-mds-myfs-0", "rook-ceph-mds-myfs-1")
 
 	// starting again should be a no-op
 	err = c.Start()
 	assert.Nil(t, err)
-	validateStart(t, c)
+	validateStart(t, c, "rook-ceph-mds-myfs-0", "rook-ceph-mds-myfs-1")
+
+	// the file system name is required
+	c.Filesystem = ""
+	err = c.Start()
+	assert.NotNil(t, err)
+}
+
+func validateStart(t *testing.T, c *Cluster, names ...string) {
+	deployments, err := c.getDeployments()
+	assert.Nil(t, err)
+	assert.Equal(t, len(names), len(deployments))
+
+	for _, name := range names {
+		r, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Get(name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, name, r.Name)
+		assert.Equal(t, int32(1), *r.Spec.Replicas)
+
+		secret, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(name, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, name, secret.Name)
+	}
+}
+
+func TestUpdateMDS(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return "{\"key\":\"mysecurekey\"}", nil
+		},
+	}
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		Executor:    executor,
+		ConfigDir:   configDir,
+		KubeContext: kit.KubeContext{Clientset: testop.New(3)}}
+
+	// start three daemons for one file system and one for another
+	c := New(context, "ns", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.Filesystem = "myfs"
+	c.Replicas = 3
+	assert.Nil(t, c.Start())
+	other := New(context, "ns", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	other.Filesystem = "otherfs"
+	assert.Nil(t, other.Start())
+
+	// the extra daemons are removed when the count is reduced
+	c.Replicas = 1
+	c.StandbyReplayCount = 1
+	assert.Nil(t, c.Start())
+	assert.Nil(t, c.Update())
+	validateStart(t, c, "rook-ceph-mds-myfs-0", "rook-ceph-mds-myfs-1")
+	_, err := context.Clientset.CoreV1().Secrets("ns").Get("rook-ceph-mds-myfs-2", metav1.GetOptions{})
+	assert.NotNil(t, err)
+	validateStart(t, other, "rook-ceph-mds-otherfs-0")
+
+	// the upgrade changes the version of all the daemons
+	upgrade := New(context, "ns", "newversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	assert.Nil(t, upgrade.Upgrade())
+	deployments, err := upgrade.getDeployments()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(deployments))
+	for _, d := range deployments {
+		assert.Equal(t, "rook/rook:newversion", d.Spec.Template.Spec.Containers[0].Image)
+	}
+
+	// deleting without a file system removes the daemons of all the file systems
+	assert.Nil(t, upgrade.Delete())
+	deployments, err = upgrade.getDeployments()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deployments))
 }
 
-func validateStart(t *testing.T, c *Cluster) {
+func TestLegacyMDS(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return "{\"key\":\"mysecurekey\"}", nil
+		},
+	}
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		Executor:    executor,
+		ConfigDir:   configDir,
+		KubeContext: kit.KubeContext{Clientset: testop.New(3)}}
+
+	// the mds started by the previous operator does not have the labels of the file system
+	legacy := &extensions.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mds", Namespace: "ns"}}
+	legacy.Spec.Template.Spec.Containers = []v1.Container{{Image: "rook/rook:oldversion"}}
+	_, err := context.Clientset.ExtensionsV1beta1().Deployments("ns").Create(legacy)
+	assert.Nil(t, err)
+	_, err = context.Clientset.CoreV1().Secrets("ns").Create(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mds"}})
+	assert.Nil(t, err)
 
-	r, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Get(appName, metav1.GetOptions{})
+	// the legacy mds is upgraded with the mds of all the file systems
+	upgrade := New(context, "ns", "newversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	assert.Nil(t, upgrade.Upgrade())
+	d, err := context.Clientset.ExtensionsV1beta1().Deployments("ns").Get("rook-ceph-mds", metav1.GetOptions{})
 	assert.Nil(t, err)
-	assert.Equal(t, appName, r.Name)
+	assert.Equal(t, "rook/rook:newversion", d.Spec.Template.Spec.Containers[0].Image)
+
+	// the legacy mds is replaced when the mds of the file system are started
+	c := New(context, "ns", "newversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.Filesystem = "myfs"
+	assert.Nil(t, c.Start())
+	validateStart(t, c, "rook-ceph-mds-myfs-0")
+	_, err = context.Clientset.ExtensionsV1beta1().Deployments("ns").Get("rook-ceph-mds", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = context.Clientset.CoreV1().Secrets("ns").Get("rook-ceph-mds", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
 }
 
 func TestPodSpecs(t *testing.T) {
-	c := New(nil, "ns", "myversion", k8sutil.Placement{})
-	mdsID := "mds1"
+	c := New(nil, "ns", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.Filesystem = "myfs"
+	configs := c.mdsConfigs()
+	assert.Equal(t, 1, len(configs))
 
-	d := c.makeDeployment(mdsID)
+	d := c.makeDeployment(configs[0])
 	assert.NotNil(t, d)
-	assert.Equal(t, appName, d.Name)
+	assert.Equal(t, "rook-ceph-mds-myfs-0", d.Name)
 	assert.Equal(t, v1.RestartPolicyAlways, d.Spec.Template.Spec.RestartPolicy)
 	assert.Equal(t, 2, len(d.Spec.Template.Spec.Volumes))
 	assert.Equal(t, "rook-data", d.Spec.Template.Spec.Volumes[0].Name)
 
-	assert.Equal(t, appName, d.ObjectMeta.Name)
 	assert.Equal(t, appName, d.Spec.Template.ObjectMeta.Labels["app"])
 	assert.Equal(t, c.Namespace, d.Spec.Template.ObjectMeta.Labels["rook_cluster"])
+	assert.Equal(t, "myfs", d.Spec.Template.ObjectMeta.Labels["rook_file_system"])
 	assert.Equal(t, 0, len(d.ObjectMeta.Annotations))
 
 	cont := d.Spec.Template.Spec.Containers[0]
 	assert.Equal(t, "rook/rook:myversion", cont.Image)
 	assert.Equal(t, 2, len(cont.VolumeMounts))
-	assert.Equal(t, 6, len(cont.Env))
+	assert.Equal(t, 7, len(cont.Env))
+	assert.Equal(t, "rook-ceph-mds-myfs-0", cont.Env[0].ValueFrom.SecretKeyRef.Name)
 
+	assert.Equal(t, 3, len(cont.Args))
 	assert.Equal(t, "mds", cont.Args[0])
 	assert.Equal(t, "--config-dir=/var/lib/rook", cont.Args[1])
-	assert.Equal(t, "--mds-id=mds1", cont.Args[2])
+	assert.Equal(t, "--mds-id=myfs-0", cont.Args[2])
+}
+
+func TestStandbyReplay(t *testing.T) {
+	c := New(nil, "ns", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.Filesystem = "myfs"
+	c.FilesystemID = 3
+	c.ActiveCount = 2
+	c.Replicas = 3
+	c.StandbyReplayCount = 3
+
+	// the standby-replay daemons follow the active ranks in turn
+	configs := c.mdsConfigs()
+	assert.Equal(t, 6, len(configs))
+	ranks := []int{}
+	for _, config := range configs {
+		ranks = append(ranks, config.standbyForRank)
+	}
+	assert.Equal(t, []int{-1, -1, -1, 0, 1, 0}, ranks)
+
+	cont := c.makeDeployment(configs[4]).Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "--mds-id=myfs-4", cont.Args[2])
+	assert.Equal(t, "--mds-standby-replay=true", cont.Args[3])
+	assert.Equal(t, "--mds-standby-for-rank=1", cont.Args[4])
+	assert.Equal(t, "--mds-standby-for-fscid=3", cont.Args[5])
+}
+
+func TestMakeVolumeJob(t *testing.T) {
+	job := makeVolumeJob("ns", "myversion", "myfs", "pvc-1", 1024, false)
+	assert.Equal(t, "rook-fs-volume-", job.GenerateName)
+	assert.Equal(t, "ns", job.Namespace)
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "rook/rook:myversion", container.Image)
+	assert.Equal(t, []string{"filesystem-volume", "--config-dir=/var/lib/rook", "--filesystem=myfs", "--volume=pvc-1", "--size=1024",
+		"--remove=false"}, container.Args)
+	assert.True(t, *container.SecurityContext.Privileged)
+	assert.Equal(t, v1.RestartPolicyOnFailure, job.Spec.Template.Spec.RestartPolicy)
+
+	// the volume cannot refer to other directories of the file system
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: testop.New(1)}}
+	err := RunVolumeJob(context, "ns", "myversion", "myfs", "../pvc-1", 1024, true)
+	assert.NotNil(t, err)
 }
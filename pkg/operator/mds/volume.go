@@ -0,0 +1,120 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mds for file systems.
+package mds
+
+import (
+	"fmt"
+	"time"
+
+	cephmds "github.com/rook/rook/pkg/ceph/mds"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	opmon "github.com/rook/rook/pkg/operator/mon"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	volumeAppName       = "rook-fs-volume"
+	volumeJobNamePrefix = "rook-fs-volume-"
+)
+
+// RunVolumeJob creates the directory of a file system volume with a quota of the given size, or removes the directory
+// with all its content, in a job of the cluster. Mounting the file system requires a privileged pod, so the directory
+// is managed by a short lived job instead of the api. Returns after the job completes.
+func RunVolumeJob(context *clusterd.Context, namespace, version, fsName, volumeName string, size uint64, remove bool) error {
+	if err := cephmds.ValidateVolumeName(volumeName); err != nil {
+		return err
+	}
+
+	jobs := context.Clientset.BatchV1().Jobs(namespace)
+	job, err := jobs.Create(makeVolumeJob(namespace, version, fsName, volumeName, size, remove))
+	if err != nil {
+		return fmt.Errorf("failed to create the job for volume %s of file system %s. %+v", volumeName, fsName, err)
+	}
+	logger.Infof("started job %s for volume %s of file system %s", job.Name, volumeName, fsName)
+	defer func() {
+		if err := k8sutil.DeleteResource("file system volume job", job.Name, jobs.Delete); err != nil {
+			logger.Warningf("failed to delete job %s. %+v", job.Name, err)
+		}
+	}()
+
+	for i := 0; i < context.MaxRetries; i++ {
+		<-time.After(time.Duration(context.RetryDelay) * time.Second)
+		job, err = jobs.Get(job.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get job for volume %s. %+v", volumeName, err)
+		}
+		if job.Status.Succeeded > 0 {
+			logger.Infof("job %s for volume %s of file system %s completed", job.Name, volumeName, fsName)
+			return nil
+		}
+		logger.Infof("waiting for job %s for volume %s to complete", job.Name, volumeName)
+	}
+	return fmt.Errorf("timed out waiting for the job for volume %s of file system %s", volumeName, fsName)
+}
+
+func makeVolumeJob(namespace, version, fsName, volumeName string, size uint64, remove bool) *batch.Job {
+	privileged := true
+	container := v1.Container{
+		Args: []string{
+			"filesystem-volume",
+			fmt.Sprintf("--config-dir=%s", k8sutil.DataDir),
+			fmt.Sprintf("--filesystem=%s", fsName),
+			fmt.Sprintf("--volume=%s", volumeName),
+			fmt.Sprintf("--size=%d", size),
+			fmt.Sprintf("--remove=%t", remove),
+		},
+		Name:  volumeAppName,
+		Image: k8sutil.MakeRookImage(version),
+		VolumeMounts: []v1.VolumeMount{
+			{Name: k8sutil.DataDirVolume, MountPath: k8sutil.DataDir},
+		},
+		Env: []v1.EnvVar{
+			opmon.SecretEnvVar(),
+			opmon.AdminSecretEnvVar(),
+			opmon.EndpointEnvVar(),
+			opmon.ClusterNameEnvVar(namespace),
+		},
+		SecurityContext: &v1.SecurityContext{Privileged: &privileged},
+	}
+	labels := map[string]string{
+		k8sutil.AppAttr:     volumeAppName,
+		k8sutil.ClusterAttr: namespace,
+	}
+	return &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: volumeJobNamePrefix,
+			Namespace:    namespace,
+			Labels:       labels,
+		},
+		Spec: batch.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: v1.PodSpec{
+					Containers:    []v1.Container{container},
+					RestartPolicy: v1.RestartPolicyOnFailure,
+					Volumes: []v1.Volume{
+						{Name: k8sutil.DataDirVolume, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+					},
+				},
+			},
+		},
+	}
+}
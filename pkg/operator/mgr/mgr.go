@@ -44,13 +44,19 @@ type Cluster struct {
 	Version   string
 	Replicas  int
 	context   *clusterd.Context
+	placement k8sutil.Placement
+	resources v1.ResourceRequirements
+	network   k8sutil.Network
 	dataDir   string
 }
 
 // New creates an instance of the mgr
-func New(context *clusterd.Context, namespace, version string) *Cluster {
+func New(context *clusterd.Context, namespace, version string, placement k8sutil.Placement, resources v1.ResourceRequirements, network k8sutil.Network) *Cluster {
 	return &Cluster{
 		context:   context,
+		placement: placement,
+		resources: resources,
+		network:   network,
 		Namespace: namespace,
 		Version:   version,
 		Replicas:  1,
@@ -85,6 +91,37 @@ func (c *Cluster) Start() error {
 	return nil
 }
 
+// Update the mgr deployments with the current settings
+func (c *Cluster) Update() error {
+	logger.Infof("updating mgrs in namespace %s", c.Namespace)
+	for i := 0; i < c.Replicas; i++ {
+		name := fmt.Sprintf("%s%d", appName, i)
+		deployment := c.makeDeployment(name)
+		if _, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Update(deployment); err != nil {
+			return fmt.Errorf("failed to update mgr deployment %s. %+v", name, err)
+		}
+		logger.Infof("%s deployment updated", name)
+	}
+
+	return nil
+}
+
+// Delete the mgr deployments and their keyrings
+func (c *Cluster) Delete() error {
+	logger.Infof("deleting mgrs in namespace %s", c.Namespace)
+	for i := 0; i < c.Replicas; i++ {
+		name := fmt.Sprintf("%s%d", appName, i)
+		if err := k8sutil.DeleteResource("mgr deployment", name, c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Delete); err != nil {
+			return err
+		}
+		if err := k8sutil.DeleteResource("mgr secret", name, c.context.Clientset.CoreV1().Secrets(c.Namespace).Delete); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (c *Cluster) makeDeployment(name string) *extensions.Deployment {
 	deployment := &extensions.Deployment{}
 	deployment.Name = name
@@ -105,6 +142,8 @@ func (c *Cluster) makeDeployment(name string) *extensions.Deployment {
 			},
 		},
 	}
+	c.placement.ApplyToPodSpec(&podSpec.Spec)
+	c.network.ApplyToPodSpec(&podSpec.Spec)
 
 	replicas := int32(1)
 	deployment.Spec = extensions.DeploymentSpec{Template: podSpec, Replicas: &replicas}
@@ -118,13 +157,14 @@ func (c *Cluster) mgrContainer(name string) v1.Container {
 			"mgr",
 			fmt.Sprintf("--config-dir=%s", k8sutil.DataDir),
 		},
-		Name:  name,
-		Image: k8sutil.MakeRookImage(c.Version),
+		Name:      name,
+		Image:     k8sutil.MakeRookImage(c.Version),
+		Resources: c.resources,
 		VolumeMounts: []v1.VolumeMount{
 			{Name: k8sutil.DataDirVolume, MountPath: k8sutil.DataDir},
 			k8sutil.ConfigOverrideMount(),
 		},
-		Env: []v1.EnvVar{
+		Env: append([]v1.EnvVar{
 			{Name: "ROOKD_MGR_NAME", Value: name},
 			{Name: "ROOKD_MGR_KEYRING", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: name}, Key: keyringName}}},
 			opmon.ClusterNameEnvVar(c.Namespace),
@@ -132,7 +172,8 @@ func (c *Cluster) mgrContainer(name string) v1.Container {
 			opmon.SecretEnvVar(),
 			opmon.AdminSecretEnvVar(),
 			k8sutil.ConfigOverrideEnvVar(),
-		},
+			k8sutil.CephConfigEnvVar(),
+		}, c.network.EnvVars()...),
 	}
 }
 
@@ -22,11 +22,13 @@ import (
 	"testing"
 
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/kit"
 	testop "github.com/rook/rook/pkg/operator/test"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -43,7 +45,7 @@ func TestStartMGR(t *testing.T) {
 		Executor:    executor,
 		ConfigDir:   configDir,
 		KubeContext: kit.KubeContext{Clientset: testop.New(3)}}
-	c := New(context, "ns", "myversion")
+	c := New(context, "ns", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 	defer os.RemoveAll(c.dataDir)
 
 	// start a basic service
@@ -68,7 +70,7 @@ func validateStart(t *testing.T, c *Cluster) {
 }
 
 func TestPodSpec(t *testing.T) {
-	c := New(nil, "ns", "myversion")
+	c := New(nil, "ns", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 
 	d := c.makeDeployment("mgr1")
 	assert.NotNil(t, d)
@@ -85,8 +87,32 @@ func TestPodSpec(t *testing.T) {
 	cont := d.Spec.Template.Spec.Containers[0]
 	assert.Equal(t, "rook/rook:myversion", cont.Image)
 	assert.Equal(t, 2, len(cont.VolumeMounts))
-	assert.Equal(t, 7, len(cont.Env))
+	assert.Equal(t, 8, len(cont.Env))
 
 	assert.Equal(t, "mgr", cont.Args[0])
 	assert.Equal(t, "--config-dir=/var/lib/rook", cont.Args[1])
 }
+
+func TestPodSpecPlacement(t *testing.T) {
+	antiAffinity := &v1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+			{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": appName}}, TopologyKey: "kubernetes.io/hostname"},
+		},
+	}
+	c := New(nil, "ns", "myversion", k8sutil.Placement{PodAntiAffinity: antiAffinity}, v1.ResourceRequirements{}, k8sutil.Network{})
+
+	d := c.makeDeployment("mgr1")
+	assert.Equal(t, antiAffinity, d.Spec.Template.Spec.Affinity.PodAntiAffinity)
+	assert.Nil(t, d.Spec.Template.Spec.Affinity.NodeAffinity)
+}
+
+func TestPodSpecResources(t *testing.T) {
+	resources := v1.ResourceRequirements{
+		Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("512Mi")},
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("250m"), v1.ResourceMemory: resource.MustParse("256Mi")},
+	}
+	c := New(nil, "ns", "myversion", k8sutil.Placement{}, resources, k8sutil.Network{})
+
+	d := c.makeDeployment("mgr1")
+	assert.Equal(t, resources, d.Spec.Template.Spec.Containers[0].Resources)
+}
@@ -28,6 +28,13 @@ import (
 
 // CheckHealth for the monitors
 func (c *Cluster) CheckHealth() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.Paused {
+		logger.Debugf("skipping mon health check while the cluster in namespace %s is paused", c.Namespace)
+		return nil
+	}
 	logger.Debugf("Checking health for mons. %+v", c.clusterInfo)
 
 	// connect to the mons
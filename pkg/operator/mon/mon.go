@@ -19,8 +19,10 @@ package mon
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/pkg/capnslog"
@@ -30,6 +32,7 @@ import (
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/util"
 	"k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -65,22 +68,33 @@ type Cluster struct {
 	Port            int32
 	clusterInfo     *mon.ClusterInfo
 	placement       k8sutil.Placement
+	resources       v1.ResourceRequirements
+	network         k8sutil.Network
 	maxMonID        int
 	waitForStart    bool
 	dataDirHostPath string
+	// The hash of the config override that is recorded on the mon pods
+	ConfigOverrideHash string
+	// lock serializes the health checks with an upgrade or a restart since all of them can replace mons
+	lock sync.Mutex
 }
 
 // monConfig for a single monitor
 type monConfig struct {
 	Name string
 	Port int32
+	// The address of the node in the public network that the mon advertises when it runs in the host network
+	PublicIP string
 }
 
 // New creates an instance of a mon cluster
-func New(context *clusterd.Context, namespace, dataDirHostPath, version string, placement k8sutil.Placement) *Cluster {
+func New(context *clusterd.Context, namespace, dataDirHostPath, version string, placement k8sutil.Placement,
+	resources v1.ResourceRequirements, network k8sutil.Network) *Cluster {
 	return &Cluster{
 		context:         context,
 		placement:       placement,
+		resources:       resources,
+		network:         network,
 		dataDirHostPath: dataDirHostPath,
 		Namespace:       namespace,
 		Version:         version,
@@ -117,6 +131,210 @@ func (c *Cluster) Start() (*mon.ClusterInfo, error) {
 	return c.clusterInfo, nil
 }
 
+// UpdatePlacement sets the placement that will be applied to mons started after the update.
+// Running mons are pinned to their nodes and are not moved.
+func (c *Cluster) UpdatePlacement(placement k8sutil.Placement) {
+	c.placement = placement
+}
+
+// UpdateResources sets the resource requests and limits of the mons started after the update. The running mons keep
+// their resources until they are replaced by a failover or an upgrade.
+func (c *Cluster) UpdateResources(resources v1.ResourceRequirements) {
+	c.resources = resources
+}
+
+// Upgrade restarts the mons one at a time with the given version. The mons are restarted in place, so they keep their
+// names and addresses, and each mon must be back in quorum before the next mon is restarted. Mons that are already
+// running the version are not restarted, so an interrupted upgrade can be resumed.
+func (c *Cluster) Upgrade(version string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	logger.Infof("upgrading mons in namespace %s to version %s", c.Namespace, version)
+	c.Version = version
+	image := k8sutil.MakeRookImage(version)
+	err := c.restartMons(func(rs *extensions.ReplicaSet) bool {
+		return rs.Spec.Template.Annotations[k8sutil.VersionAttr] == version
+	}, func(meta *metav1.ObjectMeta, spec *v1.PodSpec) {
+		setAnnotation(meta, k8sutil.VersionAttr, version)
+		spec.Containers[0].Image = image
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("done upgrading mons to version %s", version)
+	return nil
+}
+
+// RestartForConfigOverride restarts the mons in place one at a time so they read the config override with the given
+// hash. The hash is set in the annotations of the mon pod, which the mon watches to exit and be restarted by the
+// kubelet in the same pod, so the mon keeps its name and address. The mons must all be in quorum before a mon is
+// restarted. Mons that were started with the config override are not restarted, so an interrupted restart can be
+// resumed.
+func (c *Cluster) RestartForConfigOverride(hash string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	logger.Infof("restarting mons in namespace %s to apply the config override", c.Namespace)
+	c.ConfigOverrideHash = hash
+	err := c.restartMons(func(rs *extensions.ReplicaSet) bool {
+		return rs.Spec.Template.Annotations[k8sutil.ConfigOverrideHashAttr] == hash
+	}, func(meta *metav1.ObjectMeta, spec *v1.PodSpec) {
+		setAnnotation(meta, k8sutil.ConfigOverrideHashAttr, hash)
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("done restarting mons to apply the config override")
+	return nil
+}
+
+// restartMons restarts the mons in place one at a time in the order of their names. The mons whose replica set is
+// current are not restarted. A mon is only restarted while all the mons in the monmap are in quorum.
+func (c *Cluster) restartMons(current func(rs *extensions.ReplicaSet) bool, update func(meta *metav1.ObjectMeta, spec *v1.PodSpec)) error {
+	names := []string{}
+	for name := range c.clusterInfo.Monitors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rs, err := c.context.Clientset.Extensions().ReplicaSets(c.Namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				// the health check fails over the mon with the new settings
+				logger.Warningf("mon %s is not running", name)
+				continue
+			}
+			return fmt.Errorf("failed to get mon %s. %+v", name, err)
+		}
+		if current(rs) {
+			logger.Infof("mon %s is current", name)
+			continue
+		}
+
+		if err := c.checkQuorum(); err != nil {
+			return fmt.Errorf("cannot restart mon %s. %+v", name, err)
+		}
+		if err := c.restartMon(rs, update); err != nil {
+			return fmt.Errorf("failed to restart mon %s. %+v", name, err)
+		}
+	}
+	return nil
+}
+
+// restartMon applies the update to the replica set of the mon for the pods started from now on, and to the running
+// pod. Changing the image or the config override hash of the running pod makes the kubelet restart its container, so
+// the mon keeps the address of the pod. Returns after the mon is back in quorum.
+func (c *Cluster) restartMon(rs *extensions.ReplicaSet, update func(meta *metav1.ObjectMeta, spec *v1.PodSpec)) error {
+	update(&rs.Spec.Template.ObjectMeta, &rs.Spec.Template.Spec)
+	if _, err := c.context.Clientset.Extensions().ReplicaSets(c.Namespace).Update(rs); err != nil {
+		return fmt.Errorf("failed to update replica set. %+v", err)
+	}
+
+	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("mon=%s", rs.Name)}
+	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(options)
+	if err != nil {
+		return fmt.Errorf("failed to get pod. %+v", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		restarts := containerRestarts(*pod)
+		image := pod.Spec.Containers[0].Image
+		update(&pod.ObjectMeta, &pod.Spec)
+		if pod.Spec.Containers[0].Image == image && !k8sutil.HasPodInfoVolume(pod.Spec) {
+			// the mon was started before it could watch its annotations. it is updated when its pod is replaced.
+			logger.Warningf("mon %s in pod %s cannot be restarted in place. it is updated when the pod is restarted", rs.Name, pod.Name)
+			continue
+		}
+		if _, err := c.context.Clientset.CoreV1().Pods(c.Namespace).Update(pod); err != nil {
+			return fmt.Errorf("failed to update pod %s. %+v", pod.Name, err)
+		}
+		logger.Infof("restarting mon %s in pod %s", rs.Name, pod.Name)
+		if err := c.waitForContainerRestart(pod.Name, restarts); err != nil {
+			return err
+		}
+	}
+
+	return c.waitForMonsToJoin([]*monConfig{{Name: rs.Name}})
+}
+
+// waitForContainerRestart waits until the container of the pod has restarted and is running
+func (c *Cluster) waitForContainerRestart(podName string, restarts int32) error {
+	if !c.waitForStart {
+		return nil
+	}
+
+	for i := 0; i < c.context.MaxRetries; i++ {
+		<-time.After(time.Duration(c.context.RetryDelay) * time.Second)
+		pod, err := c.context.Clientset.CoreV1().Pods(c.Namespace).Get(podName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get pod %s. %+v", podName, err)
+		}
+		if containerRestarts(*pod) > restarts && pod.Status.ContainerStatuses[0].State.Running != nil {
+			logger.Infof("pod %s restarted", podName)
+			return nil
+		}
+		logger.Infof("waiting for pod %s to restart", podName)
+	}
+	return fmt.Errorf("timed out waiting for pod %s to restart", podName)
+}
+
+// containerRestarts returns the number of times the container of the mon pod was restarted
+func containerRestarts(pod v1.Pod) int32 {
+	if len(pod.Status.ContainerStatuses) == 0 {
+		return 0
+	}
+	return pod.Status.ContainerStatuses[0].RestartCount
+}
+
+// setAnnotation sets the annotation in the metadata of a pod or pod template
+func setAnnotation(meta *metav1.ObjectMeta, key, value string) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[key] = value
+}
+
+// checkQuorum returns an error if any mon in the monmap is out of quorum
+func (c *Cluster) checkQuorum() error {
+	status, err := client.GetMonStatus(c.context, c.clusterInfo.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get mon status. %+v", err)
+	}
+	for _, m := range status.MonMap.Mons {
+		if !monInQuorum(m, status.Quorum) {
+			return fmt.Errorf("mon %s is not in quorum", m.Name)
+		}
+	}
+	return nil
+}
+
+// Delete the mon replica sets along with the secrets and config that identify the mons
+func (c *Cluster) Delete() error {
+	logger.Infof("deleting mons in namespace %s", c.Namespace)
+	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s,%s=%s", k8sutil.AppAttr, appName, monClusterAttr, c.Namespace)}
+	replicaSets, err := c.context.Clientset.Extensions().ReplicaSets(c.Namespace).List(options)
+	if err != nil {
+		return fmt.Errorf("failed to list mon replica sets. %+v", err)
+	}
+	for _, rs := range replicaSets.Items {
+		if err := k8sutil.DeleteResource("mon replica set", rs.Name, c.context.Clientset.Extensions().ReplicaSets(c.Namespace).Delete); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range []string{appName, "rook-admin"} {
+		if err := k8sutil.DeleteResource("mon secret", name, c.context.Clientset.CoreV1().Secrets(c.Namespace).Delete); err != nil {
+			return err
+		}
+	}
+
+	return k8sutil.DeleteResource("mon config map", monConfigMapName, c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Delete)
+}
+
 func monInQuorum(monitor client.MonMapEntry, quorum []int) bool {
 	for _, rank := range quorum {
 		if rank == monitor.Rank {
@@ -241,21 +459,30 @@ func (c *Cluster) createMonSecretsAndSave() error {
 }
 
 func (c *Cluster) startPods(mons []*monConfig) error {
-	// schedule the mons on different nodes if we have enough nodes to be unique
-	availableNodes, err := c.getAvailableMonNodes()
-	if err != nil {
-		return fmt.Errorf("failed to get available nodes for mons. %+v", err)
-	}
-
 	preexisted := len(c.clusterInfo.Monitors)
 	nodeIndex := 0
 	for _, m := range mons {
+		// schedule the mons on different nodes if we have enough nodes to be unique. The nodes are detected again
+		// for each mon so that the anti-affinity of the mons takes the mons already started into account.
+		availableNodes, err := c.getAvailableMonNodes()
+		if err != nil {
+			return fmt.Errorf("failed to get available nodes for mons. %+v", err)
+		}
+
 		// pick one of the available nodes where the mon will be assigned
 		node := availableNodes[nodeIndex%len(availableNodes)]
 		nodeIndex++
 
+		// in the host network the mon must bind to the address of the node in the public network
+		if c.network.HostNetwork {
+			m.PublicIP, err = c.network.PublicAddress(&node)
+			if err != nil {
+				return fmt.Errorf("failed to get the public address of node %s for mon %s. %+v", node.Name, m.Name, err)
+			}
+		}
+
 		// start the mon
-		err := c.startMon(m, node.Name)
+		err = c.startMon(m, node.Name)
 		if err != nil {
 			return fmt.Errorf("failed to create pod %s. %+v", m.Name, err)
 		}
@@ -329,7 +556,7 @@ func (c *Cluster) waitForPodToStart(name string) (string, error) {
 		pod := pods.Items[0]
 		if pod.Status.Phase == v1.PodRunning {
 			logger.Infof("pod %s started", pod.Name)
-			return pod.Status.PodIP, nil
+			return podAddress(pod), nil
 		}
 		status = string(pod.Status.Phase)
 	}
@@ -337,6 +564,19 @@ func (c *Cluster) waitForPodToStart(name string) (string, error) {
 	return "", fmt.Errorf("timed out waiting for pod %s to start", name)
 }
 
+// podAddress returns the address that the mon in the pod binds to. A mon in the host network binds to the public
+// address of its node if it was set on the pod, otherwise the mon binds to the pod ip.
+func podAddress(pod v1.Pod) string {
+	for _, container := range pod.Spec.Containers {
+		for _, env := range container.Env {
+			if env.Name == k8sutil.PodIPEnvVar && env.Value != "" {
+				return env.Value
+			}
+		}
+	}
+	return pod.Status.PodIP
+}
+
 func (c *Cluster) saveMonConfig() error {
 	configMap := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -430,8 +670,23 @@ func (c *Cluster) getAvailableMonNodes() ([]v1.Node, error) {
 	}
 	logger.Infof("there are %d nodes available for mons (existing mons=%d)", len(nodes.Items), len(c.clusterInfo.Monitors))
 
+	// the nodes must match the placement and must not run a pod that the mons are required to avoid
+	placement := c.getPlacement()
+	validNodes := []v1.Node{}
+	for _, node := range nodes.Items {
+		if validNode(node, placement) {
+			validNodes = append(validNodes, node)
+		}
+	}
+	if placement.PodAntiAffinity != nil && len(placement.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0 {
+		validNodes, err = c.filterAntiAffinity(validNodes, nodes.Items, placement.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the pod anti-affinity of the mons. %+v", err)
+		}
+	}
+
 	// get the nodes that have mons assigned
-	nodesInUse, err := c.getNodesWithMons()
+	nodesInUse, err := c.GetNodesWithMons()
 	if err != nil {
 		logger.Warningf("could not get nodes with mons. %+v", err)
 		nodesInUse = util.NewSet()
@@ -439,29 +694,131 @@ func (c *Cluster) getAvailableMonNodes() ([]v1.Node, error) {
 
 	// choose nodes for the new mons that don't have mons currently
 	availableNodes := []v1.Node{}
-	for _, node := range nodes.Items {
-		if !nodesInUse.Contains(node.Name) && validNode(node, c.placement) {
+	for _, node := range validNodes {
+		if !nodesInUse.Contains(node.Name) {
 			availableNodes = append(availableNodes, node)
 		}
 	}
 	logger.Infof("Found %d running nodes without mons", len(availableNodes))
 
-	// if all nodes already have mons, just add all nodes to be available
-	if len(availableNodes) == 0 {
-		logger.Infof("All nodes are running mons. Adding all %d nodes to the availability.", len(nodes.Items))
-		for _, node := range nodes.Items {
-			if validNode(node, c.placement) {
-				availableNodes = append(availableNodes, node)
-			}
-		}
+	// if all nodes already have mons, just add all nodes to be available. Mons in the host network cannot share a
+	// node since they would all bind to the mon port of the node.
+	if len(availableNodes) == 0 && !c.network.HostNetwork {
+		logger.Infof("All nodes are running mons. Adding all %d valid nodes to the availability.", len(validNodes))
+		availableNodes = validNodes
 	}
 	if len(availableNodes) == 0 {
 		return nil, fmt.Errorf("no nodes are available for mons")
 	}
 
+	// prefer the nodes that are not running a pod that the mons prefer to avoid
+	if placement.PodAntiAffinity != nil && len(placement.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) > 0 {
+		terms := []v1.PodAffinityTerm{}
+		for _, term := range placement.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			terms = append(terms, term.PodAffinityTerm)
+		}
+		preferredNodes, err := c.filterAntiAffinity(availableNodes, nodes.Items, terms)
+		if err != nil {
+			logger.Warningf("could not check the preferred pod anti-affinity of the mons. %+v", err)
+		} else if len(preferredNodes) > 0 {
+			availableNodes = preferredNodes
+		}
+	}
+
 	return availableNodes, nil
 }
 
+// getPlacement returns the placement of the mons. Unless the placement specifies the pod anti-affinity of the mons,
+// the mons of the cluster prefer to run on different nodes.
+func (c *Cluster) getPlacement() k8sutil.Placement {
+	placement := c.placement
+	if placement.PodAntiAffinity == nil {
+		placement.PodAntiAffinity = &v1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: v1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{k8sutil.AppAttr: appName, monClusterAttr: c.Namespace},
+						},
+						TopologyKey: apis.LabelHostname,
+					},
+				},
+			},
+		}
+	}
+	return placement
+}
+
+// filterAntiAffinity returns the candidate nodes whose topology domains do not run any pod matched by the
+// anti-affinity terms. The domains of the pods are looked up in all the nodes.
+func (c *Cluster) filterAntiAffinity(candidates, nodes []v1.Node, terms []v1.PodAffinityTerm) ([]v1.Node, error) {
+	// the topology domains of the pods matched by each term
+	domains := make([]*util.Set, len(terms))
+	for i, term := range terms {
+		domains[i] = util.NewSet()
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %+v. %+v", term.LabelSelector, err)
+		}
+		namespaces := term.Namespaces
+		if len(namespaces) == 0 {
+			namespaces = []string{c.Namespace}
+		}
+		for _, namespace := range namespaces {
+			pods, err := c.context.Clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list pods in namespace %s. %+v", namespace, err)
+			}
+			for _, pod := range pods.Items {
+				if node := podNode(pod, nodes); node != nil {
+					if domain, ok := nodeTopology(*node, term.TopologyKey); ok {
+						domains[i].Add(domain)
+					}
+				}
+			}
+		}
+	}
+
+	filtered := []v1.Node{}
+	for _, node := range candidates {
+		allowed := true
+		for i, term := range terms {
+			if domain, ok := nodeTopology(node, term.TopologyKey); ok && domains[i].Contains(domain) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered, nil
+}
+
+// podNode returns the node where a pod is running or is assigned by its node selector
+func podNode(pod v1.Pod, nodes []v1.Node) *v1.Node {
+	hostname := pod.Spec.NodeSelector[apis.LabelHostname]
+	for i, node := range nodes {
+		if (pod.Spec.NodeName != "" && node.Name == pod.Spec.NodeName) ||
+			(hostname != "" && (node.Name == hostname || node.Labels[apis.LabelHostname] == hostname)) {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
+
+// nodeTopology returns the topology domain of a node. A node is in its own hostname domain even if it is not labeled.
+func nodeTopology(node v1.Node, topologyKey string) (string, bool) {
+	if value, ok := node.Labels[topologyKey]; ok {
+		return value, true
+	}
+	if topologyKey == apis.LabelHostname {
+		return node.Name, true
+	}
+	return "", false
+}
+
 func validNode(node v1.Node, placement k8sutil.Placement) bool {
 	// a node cannot be disabled
 	if node.Spec.Unschedulable {
@@ -513,7 +870,20 @@ func validNode(node v1.Node, placement k8sutil.Placement) bool {
 	return false
 }
 
-func (c *Cluster) getNodesWithMons() (*util.Set, error) {
+// GetFSID returns the fsid of the cluster from the mon secrets, or an empty fsid if the mons were never started
+func (c *Cluster) GetFSID() (string, error) {
+	secrets, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(appName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get mon secrets. %+v", err)
+	}
+	return string(secrets.Data[fsidSecretName]), nil
+}
+
+// GetNodesWithMons returns the names of the nodes where mon pods are scheduled
+func (c *Cluster) GetNodesWithMons() (*util.Set, error) {
 	options := metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", appName)}
 	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(options)
 	if err != nil {
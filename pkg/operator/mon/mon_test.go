@@ -16,6 +16,7 @@ limitations under the License.
 package mon
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"path"
@@ -34,6 +35,7 @@ import (
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -55,7 +57,7 @@ func TestStartMonPods(t *testing.T) {
 		Executor:    executor,
 		ConfigDir:   configDir,
 	}
-	c := New(context, namespace, "", "myversion", k8sutil.Placement{})
+	c := New(context, namespace, "", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 
 	// start a basic cluster
 	// an error is expected since mocking always creates pods that are not running
@@ -91,7 +93,7 @@ func TestSaveMonEndpoints(t *testing.T) {
 	clientset := test.New(1)
 	configDir, _ := ioutil.TempDir("", "")
 	defer os.RemoveAll(configDir)
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}, ConfigDir: configDir}, "ns", "", "myversion", k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}, ConfigDir: configDir}, "ns", "", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 	c.clusterInfo = test.CreateClusterInfo(1)
 
 	// create the initial config map
@@ -126,7 +128,7 @@ func TestCheckHealth(t *testing.T) {
 		ConfigDir:   configDir,
 		Executor:    executor,
 	}
-	c := New(context, "ns", "", "myversion", k8sutil.Placement{})
+	c := New(context, "ns", "", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 	c.clusterInfo = test.CreateClusterInfo(1)
 	c.waitForStart = false
 	defer os.RemoveAll(c.context.ConfigDir)
@@ -134,6 +136,17 @@ func TestCheckHealth(t *testing.T) {
 	err := c.CheckHealth()
 	assert.Nil(t, err)
 
+	// the mons are not checked or failed over while the cluster is paused
+	c.Paused = true
+	statusFunc := executor.MockExecuteCommandWithOutputFile
+	executor.MockExecuteCommandWithOutputFile = func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+		return "", fmt.Errorf("mon status should not be checked while paused")
+	}
+	err = c.CheckHealth()
+	assert.Nil(t, err)
+	c.Paused = false
+	executor.MockExecuteCommandWithOutputFile = statusFunc
+
 	c.maxMonID = 10
 	err = c.failoverMon("mon1")
 	assert.Nil(t, err)
@@ -143,6 +156,126 @@ func TestCheckHealth(t *testing.T) {
 	assert.Equal(t, "mon11=:6790", cm.Data["endpoints"])
 }
 
+func TestUpgradeMons(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return clienttest.MonInQuorumResponse(), nil
+		},
+	}
+	clientset := test.New(1)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		KubeContext: kit.KubeContext{Clientset: clientset, RetryDelay: 1, MaxRetries: 1},
+		ConfigDir:   configDir,
+		Executor:    executor,
+	}
+	c := New(context, "ns", "", "v1", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.clusterInfo = test.CreateClusterInfo(2)
+	c.maxMonID = 2
+	c.waitForStart = false
+
+	// mon2 is already running the new version
+	rs := &extensions.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "mon2", Namespace: c.Namespace}}
+	rs.Spec.Template.Annotations = map[string]string{k8sutil.VersionAttr: "v2"}
+	_, err := clientset.Extensions().ReplicaSets(c.Namespace).Create(rs)
+	assert.Nil(t, err)
+	rs = c.makeReplicaSet(&monConfig{Name: "mon1", Port: 6790}, "node0")
+	_, err = clientset.Extensions().ReplicaSets(c.Namespace).Create(rs)
+	assert.Nil(t, err)
+	pod := c.makeMonPod(&monConfig{Name: "mon1", Port: 6790}, "node0")
+	pod.Name = "mon1-abcde"
+	_, err = clientset.CoreV1().Pods(c.Namespace).Create(pod)
+	assert.Nil(t, err)
+
+	// mon1 is restarted in place with the new version
+	err = c.Upgrade("v2")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", c.Version)
+	assert.Equal(t, 2, c.maxMonID)
+	assert.Equal(t, 2, len(c.clusterInfo.Monitors))
+	assert.NotNil(t, c.clusterInfo.Monitors["mon1"])
+	assert.NotNil(t, c.clusterInfo.Monitors["mon2"])
+
+	rs, err = clientset.Extensions().ReplicaSets(c.Namespace).Get("mon1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", rs.Spec.Template.Annotations[k8sutil.VersionAttr])
+	assert.Equal(t, "rook/rook:v2", rs.Spec.Template.Spec.Containers[0].Image)
+	pod, err = clientset.CoreV1().Pods(c.Namespace).Get("mon1-abcde", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", pod.Annotations[k8sutil.VersionAttr])
+	assert.Equal(t, "rook/rook:v2", pod.Spec.Containers[0].Image)
+
+	// upgrading again is a no-op
+	err = c.Upgrade("v2")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, c.maxMonID)
+}
+
+func TestRestartMonsForConfigOverride(t *testing.T) {
+	outOfQuorum := false
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if outOfQuorum && args[0] == "mon_status" {
+				resp := client.MonStatusResponse{Quorum: []int{0}}
+				resp.MonMap.Mons = []client.MonMapEntry{{Name: "mon1", Rank: 0}, {Name: "mon2", Rank: 1}}
+				serialized, _ := json.Marshal(resp)
+				return string(serialized), nil
+			}
+			return clienttest.MonInQuorumResponse(), nil
+		},
+	}
+	clientset := test.New(1)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		KubeContext: kit.KubeContext{Clientset: clientset, RetryDelay: 1, MaxRetries: 1},
+		ConfigDir:   configDir,
+		Executor:    executor,
+	}
+	c := New(context, "ns", "", "v1", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.clusterInfo = test.CreateClusterInfo(2)
+	c.maxMonID = 2
+	c.waitForStart = false
+
+	// mon2 was already started with the new config override
+	rs := &extensions.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "mon2", Namespace: c.Namespace}}
+	rs.Spec.Template.Annotations = map[string]string{k8sutil.ConfigOverrideHashAttr: "hash2"}
+	_, err := clientset.Extensions().ReplicaSets(c.Namespace).Create(rs)
+	assert.Nil(t, err)
+
+	rs = c.makeReplicaSet(&monConfig{Name: "mon1", Port: 6790}, "node0")
+	_, err = clientset.Extensions().ReplicaSets(c.Namespace).Create(rs)
+	assert.Nil(t, err)
+	pod := c.makeMonPod(&monConfig{Name: "mon1", Port: 6790}, "node0")
+	pod.Name = "mon1-abcde"
+	_, err = clientset.CoreV1().Pods(c.Namespace).Create(pod)
+	assert.Nil(t, err)
+
+	// mon1 is not restarted while a mon is out of quorum
+	outOfQuorum = true
+	err = c.RestartForConfigOverride("hash2")
+	assert.NotNil(t, err)
+	pod, err = clientset.CoreV1().Pods(c.Namespace).Get("mon1-abcde", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", pod.Annotations[k8sutil.ConfigOverrideHashAttr])
+
+	// mon1 is restarted in place with the config override, so it keeps its name and address
+	outOfQuorum = false
+	err = c.RestartForConfigOverride("hash2")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, c.maxMonID)
+	assert.NotNil(t, c.clusterInfo.Monitors["mon1"])
+	assert.Nil(t, c.clusterInfo.Monitors["mon3"])
+	rs, err = clientset.Extensions().ReplicaSets(c.Namespace).Get("mon1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "hash2", rs.Spec.Template.Annotations[k8sutil.ConfigOverrideHashAttr])
+	pod, err = clientset.CoreV1().Pods(c.Namespace).Get("mon1-abcde", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "hash2", pod.Annotations[k8sutil.ConfigOverrideHashAttr])
+	assert.True(t, k8sutil.HasPodInfoVolume(pod.Spec))
+}
+
 func TestMonInQuourm(t *testing.T) {
 	entry := client.MonMapEntry{Name: "foo", Rank: 23}
 	quorum := []int{}
@@ -183,7 +316,7 @@ func TestMonID(t *testing.T) {
 
 func TestAvailableMonNodes(t *testing.T) {
 	clientset := test.New(1)
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "", "myversion", k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 	c.clusterInfo = test.CreateClusterInfo(0)
 	nodes, err := c.getAvailableMonNodes()
 	assert.Nil(t, err)
@@ -200,7 +333,7 @@ func TestAvailableMonNodes(t *testing.T) {
 
 func TestAvailableNodesInUse(t *testing.T) {
 	clientset := test.New(3)
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "", "myversion", k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 	c.clusterInfo = test.CreateClusterInfo(0)
 
 	// all three nodes are available by default
@@ -227,11 +360,17 @@ func TestAvailableNodesInUse(t *testing.T) {
 	nodes, err = c.getAvailableMonNodes()
 	assert.Nil(t, err)
 	assert.Equal(t, 3, len(nodes))
+
+	// mons in the host network cannot share a node
+	c.network.HostNetwork = true
+	nodes, err = c.getAvailableMonNodes()
+	assert.NotNil(t, err)
+	assert.Nil(t, nodes)
 }
 
 func TestTaintedNodes(t *testing.T) {
 	clientset := test.New(3)
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "", "myversion", k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 	c.clusterInfo = test.CreateClusterInfo(0)
 
 	nodes, err := c.getAvailableMonNodes()
@@ -264,7 +403,7 @@ func TestTaintedNodes(t *testing.T) {
 
 func TestNodeAffinity(t *testing.T) {
 	clientset := test.New(3)
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "", "myversion", k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 	c.clusterInfo = test.CreateClusterInfo(0)
 
 	nodes, err := c.getAvailableMonNodes()
@@ -301,3 +440,55 @@ func TestNodeAffinity(t *testing.T) {
 	assert.Equal(t, nodes[1].Name, cleanNodes[0].Name)
 	assert.Equal(t, nodes[2].Name, cleanNodes[1].Name)
 }
+
+func TestPodAntiAffinity(t *testing.T) {
+	clientset := test.New(3)
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "", "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.clusterInfo = test.CreateClusterInfo(0)
+
+	// two of the nodes are in the same zone
+	nodes, err := c.getAvailableMonNodes()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(nodes))
+	nodes[0].Labels = map[string]string{"zone": "a"}
+	nodes[1].Labels = map[string]string{"zone": "a"}
+	nodes[2].Labels = map[string]string{"zone": "b"}
+	for i := range nodes {
+		clientset.CoreV1().Nodes().Update(&nodes[i])
+	}
+
+	// the mons are required to run in different zones
+	c.placement.PodAntiAffinity = &v1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+			{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{k8sutil.AppAttr: appName}},
+				TopologyKey:   "zone",
+			},
+		},
+	}
+	pod := c.makeMonPod(&monConfig{Name: "rook-ceph-mon0"}, nodes[0].Name)
+	_, err = clientset.CoreV1().Pods(c.Namespace).Create(pod)
+	assert.Nil(t, err)
+	availableNodes, err := c.getAvailableMonNodes()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(availableNodes))
+	assert.Equal(t, nodes[2].Name, availableNodes[0].Name)
+
+	// no node is available when all the zones are running a mon
+	pod = c.makeMonPod(&monConfig{Name: "rook-ceph-mon1"}, nodes[2].Name)
+	_, err = clientset.CoreV1().Pods(c.Namespace).Create(pod)
+	assert.Nil(t, err)
+	_, err = c.getAvailableMonNodes()
+	assert.NotNil(t, err)
+
+	// the mons only prefer to run in different zones
+	c.placement.PodAntiAffinity = &v1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+			{Weight: 100, PodAffinityTerm: c.placement.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[0]},
+		},
+	}
+	availableNodes, err = c.getAvailableMonNodes()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(availableNodes))
+	assert.Equal(t, nodes[1].Name, availableNodes[0].Name)
+}
@@ -65,6 +65,7 @@ func (c *Cluster) makeReplicaSet(config *monConfig, nodeName string) *extensions
 	rs.Namespace = c.Namespace
 
 	pod := c.makeMonPod(config, nodeName)
+	rs.Labels = pod.Labels
 	replicaCount := int32(1)
 	rs.Spec = extensions.ReplicaSetSpec{
 		Template: v1.PodTemplateSpec{
@@ -91,9 +92,11 @@ func (c *Cluster) makeMonPod(config *monConfig, nodeName string) *v1.Pod {
 		Volumes: []v1.Volume{
 			{Name: k8sutil.DataDirVolume, VolumeSource: dataDirSource},
 			k8sutil.ConfigOverrideVolume(),
+			k8sutil.PodInfoVolume(),
 		},
 	}
-	c.placement.ApplyToPodSpec(&podSpec)
+	c.getPlacement().ApplyToPodSpec(&podSpec)
+	c.network.ApplyToPodSpec(&podSpec)
 
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -106,10 +109,17 @@ func (c *Cluster) makeMonPod(config *monConfig, nodeName string) *v1.Pod {
 	}
 
 	k8sutil.SetPodVersion(pod, k8sutil.VersionAttr, c.Version)
+	if c.ConfigOverrideHash != "" {
+		pod.Annotations[k8sutil.ConfigOverrideHashAttr] = c.ConfigOverrideHash
+	}
 	return pod
 }
 
 func (c *Cluster) monContainer(config *monConfig, fsid string) v1.Container {
+	podIP := v1.EnvVar{Name: k8sutil.PodIPEnvVar, ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "status.podIP"}}}
+	if config.PublicIP != "" {
+		podIP = v1.EnvVar{Name: k8sutil.PodIPEnvVar, Value: config.PublicIP}
+	}
 
 	return v1.Container{
 		Args: []string{
@@ -119,8 +129,9 @@ func (c *Cluster) monContainer(config *monConfig, fsid string) v1.Container {
 			fmt.Sprintf("--port=%d", config.Port),
 			fmt.Sprintf("--fsid=%s", fsid),
 		},
-		Name:  appName,
-		Image: k8sutil.MakeRookImage(c.Version),
+		Name:      appName,
+		Image:     k8sutil.MakeRookImage(c.Version),
+		Resources: c.resources,
 		Ports: []v1.ContainerPort{
 			{
 				Name:          "client",
@@ -131,14 +142,17 @@ func (c *Cluster) monContainer(config *monConfig, fsid string) v1.Container {
 		VolumeMounts: []v1.VolumeMount{
 			{Name: k8sutil.DataDirVolume, MountPath: k8sutil.DataDir},
 			k8sutil.ConfigOverrideMount(),
+			k8sutil.PodInfoMount(),
 		},
-		Env: []v1.EnvVar{
-			{Name: k8sutil.PodIPEnvVar, ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "status.podIP"}}},
+		Env: append([]v1.EnvVar{
+			podIP,
 			ClusterNameEnvVar(c.Namespace),
 			EndpointEnvVar(),
 			SecretEnvVar(),
 			AdminSecretEnvVar(),
 			k8sutil.ConfigOverrideEnvVar(),
-		},
+			k8sutil.CephConfigEnvVar(),
+			k8sutil.PodAnnotationsEnvVar(),
+		}, c.network.EnvVars()...),
 	}
 }
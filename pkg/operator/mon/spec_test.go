@@ -26,6 +26,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func TestPodSpecs(t *testing.T) {
@@ -35,7 +36,7 @@ func TestPodSpecs(t *testing.T) {
 
 func testPodSpec(t *testing.T, dataDir string) {
 	clientset := testop.New(1)
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", dataDir, "myversion", k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", dataDir, "myversion", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 	c.clusterInfo = testop.CreateClusterInfo(0)
 	config := &monConfig{Name: "mon0", Port: 6790}
 
@@ -43,9 +44,10 @@ func testPodSpec(t *testing.T, dataDir string) {
 	assert.NotNil(t, pod)
 	assert.Equal(t, "mon0", pod.Name)
 	assert.Equal(t, v1.RestartPolicyAlways, pod.Spec.RestartPolicy)
-	assert.Equal(t, 2, len(pod.Spec.Volumes))
+	assert.Equal(t, 3, len(pod.Spec.Volumes))
 	assert.Equal(t, "rook-data", pod.Spec.Volumes[0].Name)
 	assert.Equal(t, k8sutil.ConfigOverrideName, pod.Spec.Volumes[1].Name)
+	assert.Equal(t, k8sutil.PodInfoName, pod.Spec.Volumes[2].Name)
 	if dataDir == "" {
 		assert.NotNil(t, pod.Spec.Volumes[0].EmptyDir)
 		assert.Nil(t, pod.Spec.Volumes[0].HostPath)
@@ -62,8 +64,8 @@ func testPodSpec(t *testing.T, dataDir string) {
 
 	cont := pod.Spec.Containers[0]
 	assert.Equal(t, "rook/rook:myversion", cont.Image)
-	assert.Equal(t, 2, len(cont.VolumeMounts))
-	assert.Equal(t, 6, len(cont.Env))
+	assert.Equal(t, 3, len(cont.VolumeMounts))
+	assert.Equal(t, 8, len(cont.Env))
 
 	logger.Infof("Command : %+v", cont.Command)
 	assert.Equal(t, "mon", cont.Args[0])
@@ -71,4 +73,66 @@ func testPodSpec(t *testing.T, dataDir string) {
 	assert.Equal(t, "--name=mon0", cont.Args[2])
 	assert.Equal(t, "--port=6790", cont.Args[3])
 	assert.Equal(t, fmt.Sprintf("--fsid=%s", c.clusterInfo.FSID), cont.Args[4])
+
+	// the mons prefer to run on different nodes by default
+	antiAffinity := pod.Spec.Affinity.PodAntiAffinity
+	assert.Equal(t, 1, len(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution))
+	term := antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm
+	assert.Equal(t, "kubernetes.io/hostname", term.TopologyKey)
+	assert.Equal(t, map[string]string{"app": appName, "mon_cluster": "ns"}, term.LabelSelector.MatchLabels)
+	assert.Nil(t, antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+}
+
+func TestPodSpecAntiAffinity(t *testing.T) {
+	antiAffinity := &v1.PodAntiAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{TopologyKey: "failure-domain.beta.kubernetes.io/zone"}},
+	}
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: testop.New(1)}}, "ns", "", "myversion",
+		k8sutil.Placement{PodAntiAffinity: antiAffinity}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.clusterInfo = testop.CreateClusterInfo(0)
+
+	// the anti-affinity of the placement replaces the default
+	pod := c.makeMonPod(&monConfig{Name: "mon0", Port: 6790}, "foo")
+	assert.Equal(t, antiAffinity, pod.Spec.Affinity.PodAntiAffinity)
+}
+
+func TestPodSpecResources(t *testing.T) {
+	resources := v1.ResourceRequirements{
+		Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+		Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+	}
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: testop.New(1)}}, "ns", "", "myversion",
+		k8sutil.Placement{}, resources, k8sutil.Network{})
+	c.clusterInfo = testop.CreateClusterInfo(0)
+
+	pod := c.makeMonPod(&monConfig{Name: "mon0", Port: 6790}, "foo")
+	assert.Equal(t, resources, pod.Spec.Containers[0].Resources)
+
+	// the new resources are applied to the mons started after the update
+	c.UpdateResources(v1.ResourceRequirements{})
+	pod = c.makeMonPod(&monConfig{Name: "mon1", Port: 6790}, "foo")
+	assert.Equal(t, v1.ResourceRequirements{}, pod.Spec.Containers[0].Resources)
+}
+
+func TestPodSpecHostNetwork(t *testing.T) {
+	network := k8sutil.Network{HostNetwork: true, PublicNetwork: "192.168.0.0/24"}
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: testop.New(1)}}, "ns", "", "myversion",
+		k8sutil.Placement{}, v1.ResourceRequirements{}, network)
+	c.clusterInfo = testop.CreateClusterInfo(0)
+
+	// the mon binds to the pod ip if the node has no address in the public network
+	pod := c.makeMonPod(&monConfig{Name: "mon0", Port: 6790}, "foo")
+	assert.True(t, pod.Spec.HostNetwork)
+	assert.Equal(t, v1.DNSClusterFirstWithHostNet, pod.Spec.DNSPolicy)
+	cont := pod.Spec.Containers[0]
+	assert.Equal(t, 9, len(cont.Env))
+	assert.Equal(t, k8sutil.PodIPEnvVar, cont.Env[0].Name)
+	assert.NotNil(t, cont.Env[0].ValueFrom)
+	assert.Equal(t, v1.EnvVar{Name: k8sutil.PublicNetworkEnvVar, Value: "192.168.0.0/24"}, cont.Env[8])
+	assert.Equal(t, "", podAddress(*pod))
+
+	// the mon binds to the public address of the node
+	pod = c.makeMonPod(&monConfig{Name: "mon0", Port: 6790, PublicIP: "192.168.0.5"}, "foo")
+	assert.Equal(t, v1.EnvVar{Name: k8sutil.PodIPEnvVar, Value: "192.168.0.5"}, pod.Spec.Containers[0].Env[0])
+	assert.Equal(t, "192.168.0.5", podAddress(*pod))
 }
@@ -0,0 +1,222 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operator to manage Kubernetes storage.
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/kit"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	kwatch "k8s.io/apimachinery/pkg/watch"
+)
+
+type objectStoreInitiator struct {
+	context *clusterd.Context
+}
+
+type objectStoreManager struct {
+	namespace  string
+	context    *clusterd.Context
+	rclient    rookclient.RookRestClient
+	clusterMgr *clusterManager
+	deleted    pausedDeletes
+}
+
+type objectStoreEvent struct {
+	Type   kwatch.EventType
+	Object *cluster.ObjectStore
+}
+
+func newObjectStoreInitiator(context *clusterd.Context) *objectStoreInitiator {
+	return &objectStoreInitiator{context: context}
+}
+
+func (o *objectStoreInitiator) Create(clusterMgr *clusterManager, namespace string) (resourceManager, error) {
+	rclient, err := clusterMgr.getRookClient(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api client for object store tpr for cluster in namespace %s. %+v", namespace, err)
+	}
+	return &objectStoreManager{context: o.context, namespace: namespace, rclient: rclient, clusterMgr: clusterMgr}, nil
+}
+
+func (o *objectStoreInitiator) Resource() kit.CustomResource {
+	return cluster.ObjectStoreResource
+}
+
+// Run the tpr manager until the caller signals with an EndWatch()
+func (o *objectStoreManager) Manage() {
+	for {
+
+		// load and initialize the object stores
+		watchVersion, err := o.Load()
+		if err != nil {
+			logger.Errorf("cannot load %s object store tpr. %+v. retrying...", o.namespace, err)
+		} else {
+			// watch for added/updated/deleted object stores
+			watcher := kit.NewWatcher(o.context.KubeContext, cluster.ObjectStoreResource, o.namespace, watchVersion, o.handleObjectStoreEvent, nil)
+			if err := watcher.Watch(); err != nil {
+				logger.Errorf("failed to watch %s object store tpr. %+v. retrying...", o.namespace, err)
+			}
+		}
+
+		<-time.After(time.Second * time.Duration(o.context.RetryDelay))
+	}
+}
+
+func (o *objectStoreManager) handleObjectStoreEvent(event *kit.RawEvent) error {
+	store := &objectStoreEvent{
+		Type:   event.Type,
+		Object: &cluster.ObjectStore{},
+	}
+	err := json.Unmarshal(event.Object, store.Object)
+	if err != nil {
+		return fmt.Errorf("fail to unmarshal ObjectStore from data (%s): %v", store.Object, err)
+	}
+
+	if o.clusterMgr.isPaused(o.namespace) {
+		// the object stores will be reconciled when the cluster is resumed
+		if event.Type == kwatch.Deleted {
+			// the deleted object stores are not listed when the cluster is resumed, so the event is replayed then
+			o.deleted.add(event)
+		}
+		logger.Infof("deferring %s event for object store %s while the cluster in namespace %s is paused", event.Type, store.Object.Name, o.namespace)
+		return nil
+	}
+
+	switch event.Type {
+	case kwatch.Added, kwatch.Modified:
+		err := o.apply(store.Object)
+		if err != nil {
+			logger.Errorf("failed to apply object store %s. %+v", store.Object.Name, err)
+		}
+		o.updateStatus(store.Object, err)
+
+	case kwatch.Deleted:
+		if err := o.delete(store.Object); err != nil {
+			logger.Errorf("failed to delete object store %s. %+v", store.Object.Name, err)
+		}
+	}
+	return nil
+}
+
+func (o *objectStoreManager) Load() (string, error) {
+	// Check if the object stores have all been created
+	logger.Info("finding existing object stores...")
+	storeList, err := o.getObjectStoreList()
+	if err != nil {
+		return "", err
+	}
+
+	if o.clusterMgr.isPaused(o.namespace) {
+		logger.Infof("found %d object stores. not checking them while the cluster in namespace %s is paused.", len(storeList.Items), o.namespace)
+		return storeList.Metadata.ResourceVersion, nil
+	}
+
+	// delete the object stores that were deleted while the cluster was paused
+	o.deleted.replay(o.handleObjectStoreEvent)
+
+	logger.Infof("found %d object stores. ensuring they exist.", len(storeList.Items))
+	for i := range storeList.Items {
+		item := storeList.Items[i]
+		logger.Infof("checking object store %s in namespace %s", item.Name, item.Namespace)
+		err := o.apply(&item)
+		if err != nil {
+			logger.Warningf("failed to check object store %s in namespace %s. %+v", item.Name, item.Namespace, err)
+		}
+		o.updateStatus(&item, err)
+	}
+
+	return storeList.Metadata.ResourceVersion, nil
+}
+
+// apply creates or updates the object store if it owns the gateways in the namespace
+func (o *objectStoreManager) apply(store *cluster.ObjectStore) error {
+	owner, err := o.getOwner()
+	if err != nil {
+		return fmt.Errorf("failed to get the object stores. %+v", err)
+	}
+	if owner != nil && owner.Name != store.Name {
+		return fmt.Errorf("object store %s already exists in namespace %s. only one object store per namespace is supported", owner.Name, o.namespace)
+	}
+
+	c, err := o.clusterMgr.getCluster(o.namespace)
+	if err != nil {
+		return err
+	}
+	spec := c.GetSpec()
+	return store.Update(o.context, o.rclient, spec.VersionTag, spec.Placement.GetRGW(), spec.Resources.RGW, spec.Network)
+}
+
+// delete removes the gateways of the object store. If another object store remains in the namespace, the gateways
+// are kept and the remaining store takes them over.
+func (o *objectStoreManager) delete(store *cluster.ObjectStore) error {
+	owner, err := o.getOwner()
+	if err != nil {
+		return fmt.Errorf("failed to get the object stores. %+v", err)
+	}
+	if owner != nil {
+		logger.Infof("object store %s now owns the gateways in namespace %s", owner.Name, o.namespace)
+		err := o.apply(owner)
+		o.updateStatus(owner, err)
+		return err
+	}
+
+	return store.Delete(o.context)
+}
+
+// updateStatus records in the object store resource whether the spec was applied. Saving the status generates a
+// modified event for the object store, so the status is only saved when it changes.
+func (o *objectStoreManager) updateStatus(store *cluster.ObjectStore, err error) {
+	status := cluster.ObjectStoreStatus{State: cluster.ObjectStoreStateCreated}
+	if err != nil {
+		status = cluster.ObjectStoreStatus{State: cluster.ObjectStoreStateFailed, Message: err.Error()}
+	}
+	if store.Status == status {
+		return
+	}
+
+	if err := kit.UpdateRawResourceStatus(o.context.Clientset, cluster.ObjectStoreResource, store.Namespace, store.Name, status); err != nil {
+		logger.Warningf("failed to save status of object store %s. %+v", store.Name, err)
+	}
+}
+
+// getOwner returns the object store that owns the gateways in the namespace, or nil if there is none
+func (o *objectStoreManager) getOwner() (*cluster.ObjectStore, error) {
+	stores, err := o.getObjectStoreList()
+	if err != nil {
+		return nil, err
+	}
+	return stores.Owner(), nil
+}
+
+func (o *objectStoreManager) getObjectStoreList() (*cluster.ObjectStoreList, error) {
+	b, err := kit.GetRawListNamespaced(o.context.Clientset, cluster.ObjectStoreResource, o.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	stores := &cluster.ObjectStoreList{}
+	if err := json.Unmarshal(b, stores); err != nil {
+		return nil, err
+	}
+	return stores, nil
+}
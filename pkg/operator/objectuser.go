@@ -0,0 +1,203 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operator to manage Kubernetes storage.
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/kit"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	kwatch "k8s.io/apimachinery/pkg/watch"
+)
+
+type objectUserInitiator struct {
+	context *clusterd.Context
+}
+
+type objectUserManager struct {
+	namespace  string
+	context    *clusterd.Context
+	rclient    rookclient.RookRestClient
+	clusterMgr *clusterManager
+	deleted    pausedDeletes
+}
+
+type objectUserEvent struct {
+	Type   kwatch.EventType
+	Object *cluster.ObjectUser
+}
+
+func newObjectUserInitiator(context *clusterd.Context) *objectUserInitiator {
+	return &objectUserInitiator{context: context}
+}
+
+func (u *objectUserInitiator) Create(clusterMgr *clusterManager, namespace string) (resourceManager, error) {
+	rclient, err := clusterMgr.getRookClient(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api client for object user tpr for cluster in namespace %s. %+v", namespace, err)
+	}
+	return &objectUserManager{context: u.context, namespace: namespace, rclient: rclient, clusterMgr: clusterMgr}, nil
+}
+
+func (u *objectUserInitiator) Resource() kit.CustomResource {
+	return cluster.ObjectUserResource
+}
+
+// Run the tpr manager until the caller signals with an EndWatch()
+func (u *objectUserManager) Manage() {
+	for {
+
+		// load and initialize the object users
+		watchVersion, err := u.Load()
+		if err != nil {
+			logger.Errorf("cannot load %s object user tpr. %+v. retrying...", u.namespace, err)
+		} else {
+			// watch for added/updated/deleted object users
+			watcher := kit.NewWatcher(u.context.KubeContext, cluster.ObjectUserResource, u.namespace, watchVersion, u.handleObjectUserEvent, nil)
+			if err := watcher.Watch(); err != nil {
+				logger.Errorf("failed to watch %s object user tpr. %+v. retrying...", u.namespace, err)
+			}
+		}
+
+		<-time.After(time.Second * time.Duration(u.context.RetryDelay))
+	}
+}
+
+func (u *objectUserManager) handleObjectUserEvent(event *kit.RawEvent) error {
+	user := &objectUserEvent{
+		Type:   event.Type,
+		Object: &cluster.ObjectUser{},
+	}
+	err := json.Unmarshal(event.Object, user.Object)
+	if err != nil {
+		return fmt.Errorf("fail to unmarshal ObjectUser from data (%s): %v", user.Object, err)
+	}
+
+	if u.clusterMgr.isPaused(u.namespace) {
+		// the object users will be reconciled when the cluster is resumed
+		if event.Type == kwatch.Deleted {
+			// the deleted object users are not listed when the cluster is resumed, so the event is replayed then
+			u.deleted.add(event)
+		}
+		logger.Infof("deferring %s event for object user %s while the cluster in namespace %s is paused", event.Type, user.Object.Name, u.namespace)
+		return nil
+	}
+
+	switch event.Type {
+	case kwatch.Added:
+		saved := user.Object.Status
+		err := user.Object.Create(u.context, u.rclient, u.allowedSecretNamespaces())
+		if err != nil {
+			logger.Errorf("failed to create object user %s. %+v", user.Object.Name, err)
+		}
+		u.updateStatus(user.Object, saved, err)
+
+	case kwatch.Modified:
+		// if the object user is modified, allow the object user to be created if it wasn't already
+		saved := user.Object.Status
+		err := user.Object.Update(u.context, u.rclient, u.allowedSecretNamespaces())
+		if err != nil {
+			logger.Errorf("failed to update object user %s. %+v", user.Object.Name, err)
+		}
+		u.updateStatus(user.Object, saved, err)
+
+	case kwatch.Deleted:
+		if err := user.Object.Delete(u.context, u.rclient); err != nil {
+			logger.Errorf("failed to delete object user %s. %+v", user.Object.Name, err)
+		}
+	}
+	return nil
+}
+
+func (u *objectUserManager) Load() (string, error) {
+	// Check if the object users have all been created
+	logger.Info("finding existing object users...")
+	userList, err := u.getObjectUserList()
+	if err != nil {
+		return "", err
+	}
+
+	if u.clusterMgr.isPaused(u.namespace) {
+		logger.Infof("found %d object users. not checking them while the cluster in namespace %s is paused.", len(userList.Items), u.namespace)
+		return userList.Metadata.ResourceVersion, nil
+	}
+
+	// delete the object users that were deleted while the cluster was paused
+	u.deleted.replay(u.handleObjectUserEvent)
+
+	logger.Infof("found %d object users. ensuring they exist.", len(userList.Items))
+	for i := range userList.Items {
+		item := userList.Items[i]
+		logger.Infof("checking object user %s in namespace %s", item.Name, item.Namespace)
+		saved := item.Status
+		err := item.Update(u.context, u.rclient, u.allowedSecretNamespaces())
+		if err != nil {
+			logger.Warningf("failed to check that object user %s exists in namespace %s. %+v", item.Name, item.Namespace, err)
+		}
+		u.updateStatus(&item, saved, err)
+	}
+
+	return userList.Metadata.ResourceVersion, nil
+}
+
+// updateStatus records in the object user resource whether the spec was applied. The user and the secret recorded by
+// the update are kept when it fails, so they are removed when the resource is deleted. Saving the status generates a
+// modified event for the object user, so the status is only saved when it changes.
+func (u *objectUserManager) updateStatus(user *cluster.ObjectUser, saved cluster.ObjectUserStatus, err error) {
+	status := user.Status
+	status.State = cluster.ObjectUserStateCreated
+	status.Message = ""
+	if err != nil {
+		status.State = cluster.ObjectUserStateFailed
+		status.Message = err.Error()
+	}
+	if saved == status {
+		return
+	}
+
+	if err := kit.UpdateRawResourceStatus(u.context.Clientset, cluster.ObjectUserResource, user.Namespace, user.Name, status); err != nil {
+		logger.Warningf("failed to save status of object user %s. %+v", user.Name, err)
+	}
+}
+
+// allowedSecretNamespaces returns the namespaces other than the cluster namespace where the cluster allows the object
+// users to publish their credentials
+func (u *objectUserManager) allowedSecretNamespaces() []string {
+	c, err := u.clusterMgr.getCluster(u.namespace)
+	if err != nil {
+		return nil
+	}
+	return c.GetSpec().ObjectUserSecretNamespaces
+}
+
+func (u *objectUserManager) getObjectUserList() (*cluster.ObjectUserList, error) {
+	b, err := kit.GetRawListNamespaced(u.context.Clientset, cluster.ObjectUserResource, u.namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	users := &cluster.ObjectUserList{}
+	if err := json.Unmarshal(b, users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
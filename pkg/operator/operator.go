@@ -22,6 +22,7 @@ package operator
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/coreos/pkg/capnslog"
@@ -37,9 +38,10 @@ const (
 	initRetryDelay = 10 * time.Second
 )
 
-// volume provisioner constant
+// volume provisioner constants
 const (
-	provisionerName = "rook.io/block"
+	provisionerName           = "rook.io/block"
+	filesystemProvisionerName = "rook.io/filesystem"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "operator")
@@ -52,6 +54,8 @@ type Operator struct {
 	// The cluster is global because you create multiple clusers in k8s
 	clusterMgr        *clusterManager
 	volumeProvisioner controller.Provisioner
+	fsProvisioner     controller.Provisioner
+	volumeResizer     *volumeResizer
 }
 
 type inclusterInitiator interface {
@@ -64,19 +68,57 @@ type resourceManager interface {
 	Manage()
 }
 
+// pausedDeletes keeps the events of the resources that were deleted while their cluster was paused. The added and
+// modified resources are reconciled from the list of resources when the cluster is resumed, but the deleted resources
+// are no longer listed.
+type pausedDeletes struct {
+	events []*kit.RawEvent
+	sync.Mutex
+}
+
+func (d *pausedDeletes) add(event *kit.RawEvent) {
+	d.Lock()
+	defer d.Unlock()
+	d.events = append(d.events, event)
+}
+
+// replay handles the kept events in the order they were received
+func (d *pausedDeletes) replay(handler func(event *kit.RawEvent) error) {
+	d.Lock()
+	events := d.events
+	d.events = nil
+	d.Unlock()
+
+	for _, event := range events {
+		if err := handler(event); err != nil {
+			logger.Warningf("failed to handle %s event. %+v", event.Type, err)
+		}
+	}
+}
+
 // New creates an operator instance
 func New(context *clusterd.Context) *Operator {
 
 	poolInitiator := newPoolInitiator(context)
-	clusterMgr := newClusterManager(context, []inclusterInitiator{poolInitiator})
+	objectStoreInitiator := newObjectStoreInitiator(context)
+	filesystemInitiator := newFilesystemInitiator(context)
+	objectUserInitiator := newObjectUserInitiator(context)
+	bucketClaimInitiator := newBucketClaimInitiator(context)
+	volumeSnapshotInitiator := newVolumeSnapshotInitiator(context)
+	clusterMgr := newClusterManager(context, []inclusterInitiator{poolInitiator, objectStoreInitiator, filesystemInitiator, objectUserInitiator,
+		bucketClaimInitiator, volumeSnapshotInitiator})
 	volumeProvisioner := newRookVolumeProvisioner(clusterMgr)
 
-	schemes := []kit.CustomResource{cluster.ClusterResource, cluster.PoolResource}
+	schemes := []kit.CustomResource{cluster.ClusterResource, cluster.PoolResource, cluster.ObjectStoreResource, cluster.FilesystemResource,
+		cluster.ObjectUserResource, cluster.BucketClaimResource, cluster.VolumeSnapshotResource,
+		cluster.VolumeSnapshotClassResource}
 	return &Operator{
 		context:           context,
 		clusterMgr:        clusterMgr,
 		resources:         schemes,
 		volumeProvisioner: volumeProvisioner,
+		fsProvisioner:     newRookFilesystemProvisioner(clusterMgr),
+		volumeResizer:     newVolumeResizer(context, clusterMgr),
 	}
 }
 
@@ -107,6 +149,18 @@ func (o *Operator) Run() error {
 	)
 	go pc.Run(wait.NeverStop)
 
+	// Run the provisioner of the shared file system volumes
+	fspc := controller.NewProvisionController(
+		o.context.Clientset,
+		filesystemProvisionerName,
+		o.fsProvisioner,
+		serverVersion.GitVersion,
+	)
+	go fspc.Run(wait.NeverStop)
+
+	// expand the volumes when their claims request more storage
+	go o.volumeResizer.Run(wait.NeverStop)
+
 	// watch for changes to the rook clusters
 	o.clusterMgr.Manage()
 	return nil
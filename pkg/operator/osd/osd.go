@@ -19,18 +19,23 @@ package osd
 
 import (
 	"fmt"
-
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"strconv"
 
 	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/ceph/client"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	opmon "github.com/rook/rook/pkg/operator/mon"
+	"github.com/rook/rook/pkg/util"
 	"k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/kubernetes/pkg/kubelet/apis"
 )
@@ -40,6 +45,9 @@ var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-osd")
 const (
 	appName    = "rook-ceph-osd"
 	appNameFmt = "rook-ceph-osd-%s"
+	nooutFlag  = "noout"
+	// the osds can take much longer to recover than the pods take to start
+	cleanPGsRetryFactor = 10
 )
 
 // Cluster keeps track of the OSDs
@@ -47,18 +55,25 @@ type Cluster struct {
 	context         *clusterd.Context
 	Namespace       string
 	placement       k8sutil.Placement
+	resources       v1.ResourceRequirements
+	network         k8sutil.Network
 	Keyring         string
 	Version         string
 	Storage         StorageSpec
 	dataDirHostPath string
+	// The hash of the config override that is recorded on the osd pods
+	ConfigOverrideHash string
 }
 
 // New creates an instance of the OSD manager
-func New(context *clusterd.Context, namespace, version string, storageSpec StorageSpec, dataDirHostPath string, placement k8sutil.Placement) *Cluster {
+func New(context *clusterd.Context, namespace, version string, storageSpec StorageSpec, dataDirHostPath string,
+	placement k8sutil.Placement, resources v1.ResourceRequirements, network k8sutil.Network) *Cluster {
 	return &Cluster{
 		context:         context,
 		Namespace:       namespace,
 		placement:       placement,
+		resources:       resources,
+		network:         network,
 		Version:         version,
 		Storage:         storageSpec,
 		dataDirHostPath: dataDirHostPath,
@@ -85,21 +100,333 @@ func (c *Cluster) Start() error {
 		for i := range c.Storage.Nodes {
 			// fully resolve the storage config for this node
 			n := c.Storage.resolveNode(c.Storage.Nodes[i].Name)
+			if err := c.startNode(n); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Update the osds to match the new storage spec, placement and resources. Replica sets are started for nodes added to
+// the spec, restarted for nodes whose storage settings changed, and removed for nodes that are no longer in the spec.
+func (c *Cluster) Update(storage StorageSpec, placement k8sutil.Placement, resources v1.ResourceRequirements) error {
+	if storage.UseAllNodes != c.Storage.UseAllNodes {
+		return fmt.Errorf("changing useAllNodes for an existing cluster is not supported")
+	}
 
-			// create the replicaSet that will run the OSDs for this node
-			rs := c.makeReplicaSet(n.Name, n.Devices, n.Directories, n.Selection, n.Config)
-			_, err := c.context.Clientset.Extensions().ReplicaSets(c.Namespace).Create(rs)
-			if err != nil {
-				if !errors.IsAlreadyExists(err) {
-					return fmt.Errorf("failed to create osd replica set for node %s. %+v", n.Name, err)
-				}
-				logger.Infof("osd replica set already exists for node %s", n.Name)
-			} else {
-				logger.Infof("osd replica set started for node %s", n.Name)
+	logger.Infof("updating osds in namespace %s", c.Namespace)
+	oldStorage := c.Storage
+	oldResources := c.resources
+	placementChanged := !reflect.DeepEqual(c.placement, placement)
+	c.Storage = storage
+	c.placement = placement
+	c.resources = resources
+
+	if c.Storage.UseAllNodes {
+		// the daemon set pods will pick up the new settings as they are restarted
+		ds := c.makeDaemonSet(c.Storage.Selection, c.Storage.Config)
+		if _, err := c.context.Clientset.Extensions().DaemonSets(c.Namespace).Update(ds); err != nil {
+			return fmt.Errorf("failed to update osd daemon set. %+v", err)
+		}
+		logger.Infof("osd daemon set updated")
+		return nil
+	}
+
+	for i := range c.Storage.Nodes {
+		n := c.Storage.resolveNode(c.Storage.Nodes[i].Name)
+		old := oldStorage.resolveNode(n.Name)
+		if old == nil {
+			// a new node was added to the cluster
+			if err := c.startNode(n); err != nil {
+				return err
 			}
+			continue
 		}
+
+		resourcesChanged := !reflect.DeepEqual(resolveResources(old, oldResources), resolveResources(n, c.resources))
+		if storageChanged(old, n) {
+			// the storage settings for the node changed, restart its osds with the new settings
+			logger.Infof("storage settings changed for node %s", n.Name)
+			if err := c.updateNode(n); err != nil {
+				return err
+			}
+			if err := c.deleteNodePods(n.Name, func(pod v1.Pod) bool { return false }); err != nil {
+				return err
+			}
+		} else if placementChanged || resourcesChanged {
+			// only the placement or the resources changed. they will apply the next time the osd pod is started.
+			if err := c.updateNode(n); err != nil {
+				return err
+			}
+		}
+	}
+
+	// remove the osds from nodes that are no longer in the spec
+	for _, old := range oldStorage.Nodes {
+		if c.Storage.resolveNode(old.Name) == nil {
+			if err := c.removeNode(old.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Upgrade restarts the osds node by node with the given version. The noout flag is set while the osds are restarting
+// so the data is not rebalanced, and the placement groups must return to active+clean before the next node is upgraded.
+// Nodes in the completed list are skipped. The nodeDone callback is called after each node is upgraded to record progress.
+func (c *Cluster) Upgrade(version string, completed []string, nodeDone func(nodeName string) error) error {
+	logger.Infof("upgrading osds in namespace %s to version %s", c.Namespace, version)
+	c.Version = version
+	image := k8sutil.MakeRookImage(version)
+	err := c.restartNodes(completed, nodeDone, func(pod v1.Pod) bool {
+		return pod.Spec.Containers[0].Image == image
+	})
+	if err != nil {
+		return err
 	}
 
+	logger.Infof("done upgrading osds to version %s", version)
+	return nil
+}
+
+// RestartForConfigOverride restarts the osds node by node so they read the config override with the given hash. The
+// osds are restarted with the noout flag and health checks of an upgrade. The osd pods that were started with the
+// config override are not restarted, so an interrupted restart can be resumed. The nodeDone callback is called after
+// the osds of each node are restarted and stops the restart if it returns an error. The noout flag is unset when the
+// restart fails, since the restart might not be resumed if the config override is reverted or the cluster is deleted.
+func (c *Cluster) RestartForConfigOverride(hash string, nodeDone func(nodeName string) error) error {
+	logger.Infof("restarting osds in namespace %s to apply the config override", c.Namespace)
+	c.ConfigOverrideHash = hash
+	err := c.restartNodes([]string{}, nodeDone, func(pod v1.Pod) bool {
+		return pod.Annotations[k8sutil.ConfigOverrideHashAttr] == hash
+	})
+	if err != nil {
+		if unsetErr := client.UnsetOSDFlag(c.context, c.Namespace, nooutFlag); unsetErr != nil {
+			logger.Warningf("failed to unset the noout flag after the osd restart failed. %+v", unsetErr)
+		}
+		return err
+	}
+
+	logger.Infof("done restarting osds to apply the config override")
+	return nil
+}
+
+// restartNodes updates the osd pod templates and restarts the osd pods that are not current one node at a time
+func (c *Cluster) restartNodes(completed []string, nodeDone func(nodeName string) error, current func(pod v1.Pod) bool) error {
+	if err := client.SetOSDFlag(c.context, c.Namespace, nooutFlag); err != nil {
+		return err
+	}
+
+	var nodes []string
+	if c.Storage.UseAllNodes {
+		// the daemon set pods will be restarted with the new template one node at a time
+		ds := c.makeDaemonSet(c.Storage.Selection, c.Storage.Config)
+		if _, err := c.context.Clientset.Extensions().DaemonSets(c.Namespace).Update(ds); err != nil {
+			return fmt.Errorf("failed to update osd daemon set. %+v", err)
+		}
+		nodeSet, err := c.GetNodesWithOSDs()
+		if err != nil {
+			return err
+		}
+		nodes = nodeSet.ToSlice()
+	} else {
+		for _, n := range c.Storage.Nodes {
+			nodes = append(nodes, n.Name)
+		}
+	}
+	sort.Strings(nodes)
+
+	done := util.CreateSet(completed)
+	for _, nodeName := range nodes {
+		if done.Contains(nodeName) {
+			logger.Infof("osds on node %s were already restarted", nodeName)
+			continue
+		}
+		if err := c.restartNode(nodeName, current); err != nil {
+			return fmt.Errorf("failed to restart osds on node %s. %+v", nodeName, err)
+		}
+		if err := nodeDone(nodeName); err != nil {
+			return err
+		}
+	}
+
+	return client.UnsetOSDFlag(c.context, c.Namespace, nooutFlag)
+}
+
+func (c *Cluster) restartNode(nodeName string, current func(pod v1.Pod) bool) error {
+	logger.Infof("restarting osds on node %s", nodeName)
+	if !c.Storage.UseAllNodes {
+		if err := c.updateNode(c.Storage.resolveNode(nodeName)); err != nil {
+			return err
+		}
+	}
+	if err := c.deleteNodePods(nodeName, current); err != nil {
+		return err
+	}
+
+	if err := c.waitForNodeRestart(nodeName, current); err != nil {
+		return err
+	}
+	return c.waitForCleanPGs()
+}
+
+// updateNode replaces the pod template of the replica set of the node. The running osd pods keep their settings until
+// they are restarted.
+func (c *Cluster) updateNode(n *Node) error {
+	rs := c.makeReplicaSet(n)
+	if _, err := c.context.Clientset.Extensions().ReplicaSets(c.Namespace).Update(rs); err != nil {
+		return fmt.Errorf("failed to update osd replica set for node %s. %+v", n.Name, err)
+	}
+	return nil
+}
+
+// deleteNodePods deletes the osd pods on the node that are not current so they are restarted from the updated template
+func (c *Cluster) deleteNodePods(nodeName string, current func(pod v1.Pod) bool) error {
+	pods, err := c.getNodePods(nodeName)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		if current(pod) {
+			continue
+		}
+		if err := k8sutil.DeleteResource("osd pod", pod.Name, c.context.Clientset.CoreV1().Pods(c.Namespace).Delete); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForNodeRestart waits for the osd pods on the node to be running and current
+func (c *Cluster) waitForNodeRestart(nodeName string, current func(pod v1.Pod) bool) error {
+	for i := 0; i < c.context.MaxRetries; i++ {
+		pods, err := c.getNodePods(nodeName)
+		if err != nil {
+			return err
+		}
+
+		restarted := len(pods) > 0
+		for _, pod := range pods {
+			if !current(pod) || pod.Status.Phase != v1.PodRunning || pod.DeletionTimestamp != nil {
+				restarted = false
+			}
+		}
+		if restarted {
+			logger.Infof("osds on node %s are running", nodeName)
+			return nil
+		}
+
+		logger.Infof("waiting for osds on node %s to restart", nodeName)
+		<-time.After(time.Duration(c.context.RetryDelay) * time.Second)
+	}
+
+	return fmt.Errorf("timed out waiting for osds on node %s to restart", nodeName)
+}
+
+// waitForCleanPGs waits for the placement groups to be active+clean after the osds on a node were restarted
+func (c *Cluster) waitForCleanPGs() error {
+	for i := 0; i < c.context.MaxRetries*cleanPGsRetryFactor; i++ {
+		status, err := client.Status(c.context, c.Namespace)
+		if err != nil {
+			logger.Warningf("failed to get ceph status. %+v", err)
+		} else if client.IsClusterClean(status) {
+			return nil
+		} else {
+			logger.Infof("waiting for placement groups to be active+clean. %+v", status.PgMap.PgsByState)
+		}
+		<-time.After(time.Duration(c.context.RetryDelay) * time.Second)
+	}
+
+	return fmt.Errorf("timed out waiting for placement groups to be active+clean")
+}
+
+func (c *Cluster) getNodePods(nodeName string) ([]v1.Pod, error) {
+	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(metav1.ListOptions{LabelSelector: c.labelSelector()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list osd pods. %+v", err)
+	}
+
+	nodePods := []v1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == nodeName {
+			nodePods = append(nodePods, pod)
+		}
+	}
+	return nodePods, nil
+}
+
+// Delete the osd daemon set or the replica sets for all nodes. The data on the osd devices is not removed.
+func (c *Cluster) Delete() error {
+	logger.Infof("deleting osds in namespace %s", c.Namespace)
+	if err := k8sutil.DeleteResource("osd daemon set", appName, c.context.Clientset.Extensions().DaemonSets(c.Namespace).Delete); err != nil {
+		return err
+	}
+
+	// delete the replica sets by label in case nodes were removed from the spec while the operator was down
+	replicaSets, err := c.context.Clientset.Extensions().ReplicaSets(c.Namespace).List(metav1.ListOptions{LabelSelector: c.labelSelector()})
+	if err != nil {
+		return fmt.Errorf("failed to list osd replica sets. %+v", err)
+	}
+	for _, rs := range replicaSets.Items {
+		if err := k8sutil.DeleteResource("osd replica set", rs.Name, c.context.Clientset.Extensions().ReplicaSets(c.Namespace).Delete); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetNodesWithOSDs returns the names of the nodes where osd pods are running
+func (c *Cluster) GetNodesWithOSDs() (*util.Set, error) {
+	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(metav1.ListOptions{LabelSelector: c.labelSelector()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list osd pods. %+v", err)
+	}
+	nodes := util.NewSet()
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" {
+			nodes.Add(pod.Spec.NodeName)
+		}
+	}
+	return nodes, nil
+}
+
+func (c *Cluster) labelSelector() string {
+	return fmt.Sprintf("%s=%s,%s=%s", k8sutil.AppAttr, appName, k8sutil.ClusterAttr, c.Namespace)
+}
+
+func (c *Cluster) startNode(n *Node) error {
+	// create the replicaSet that will run the OSDs for this node
+	rs := c.makeReplicaSet(n)
+	_, err := c.context.Clientset.Extensions().ReplicaSets(c.Namespace).Create(rs)
+	if err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create osd replica set for node %s. %+v", n.Name, err)
+		}
+		logger.Infof("osd replica set already exists for node %s", n.Name)
+	} else {
+		logger.Infof("osd replica set started for node %s", n.Name)
+	}
+	return nil
+}
+
+func (c *Cluster) removeNode(nodeName string) error {
+	// The osds on the node will be marked down and then out by ceph, after which the data will
+	// be rebalanced to the remaining osds.
+	logger.Infof("removing osd replica set for node %s", nodeName)
+	propagation := metav1.DeletePropagationForeground
+	options := &metav1.DeleteOptions{PropagationPolicy: &propagation}
+	err := c.context.Clientset.Extensions().ReplicaSets(c.Namespace).Delete(fmt.Sprintf(appNameFmt, nodeName), options)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove osd replica set for node %s. %+v", nodeName, err)
+		}
+		logger.Infof("osd replica set for node %s was already removed", nodeName)
+	}
 	return nil
 }
 
@@ -108,21 +435,22 @@ func (c *Cluster) makeDaemonSet(selection Selection, config Config) *extensions.
 	ds.Name = appName
 	ds.Namespace = c.Namespace
 
-	podSpec := c.podTemplateSpec(nil, nil, selection, config)
+	podSpec := c.podTemplateSpec(nil, nil, selection, config, c.resources)
+	ds.Labels = podSpec.Labels
 
 	ds.Spec = extensions.DaemonSetSpec{Template: podSpec}
 	return ds
 }
 
-func (c *Cluster) makeReplicaSet(nodeName string, devices []Device, directories []Directory,
-	selection Selection, config Config) *extensions.ReplicaSet {
+func (c *Cluster) makeReplicaSet(n *Node) *extensions.ReplicaSet {
 
 	rs := &extensions.ReplicaSet{}
-	rs.Name = fmt.Sprintf(appNameFmt, nodeName)
+	rs.Name = fmt.Sprintf(appNameFmt, n.Name)
 	rs.Namespace = c.Namespace
 
-	podSpec := c.podTemplateSpec(devices, directories, selection, config)
-	podSpec.Spec.NodeSelector = map[string]string{apis.LabelHostname: nodeName}
+	podSpec := c.podTemplateSpec(n.Devices, n.Directories, n.Selection, n.Config, resolveResources(n, c.resources))
+	podSpec.Spec.NodeSelector = map[string]string{apis.LabelHostname: n.Name}
+	rs.Labels = podSpec.Labels
 
 	replicaCount := int32(1)
 
@@ -134,7 +462,8 @@ func (c *Cluster) makeReplicaSet(nodeName string, devices []Device, directories
 	return rs
 }
 
-func (c *Cluster) podTemplateSpec(devices []Device, directories []Directory, selection Selection, config Config) v1.PodTemplateSpec {
+func (c *Cluster) podTemplateSpec(devices []Device, directories []Directory, selection Selection, config Config,
+	resources v1.ResourceRequirements) v1.PodTemplateSpec {
 	// by default, the data/config dir will be an empty volume
 	dataDirSource := v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
 	if c.dataDirHostPath != "" {
@@ -159,11 +488,17 @@ func (c *Cluster) podTemplateSpec(devices []Device, directories []Directory, sel
 	}
 
 	podSpec := v1.PodSpec{
-		Containers:    []v1.Container{c.osdContainer(devices, directories, selection, config)},
+		Containers:    []v1.Container{c.osdContainer(devices, directories, selection, config, resources)},
 		RestartPolicy: v1.RestartPolicyAlways,
 		Volumes:       volumes,
 	}
 	c.placement.ApplyToPodSpec(&podSpec)
+	c.network.ApplyToPodSpec(&podSpec)
+
+	annotations := map[string]string{}
+	if c.ConfigOverrideHash != "" {
+		annotations[k8sutil.ConfigOverrideHashAttr] = c.ConfigOverrideHash
+	}
 
 	return v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
@@ -172,13 +507,14 @@ func (c *Cluster) podTemplateSpec(devices []Device, directories []Directory, sel
 				k8sutil.AppAttr:     appName,
 				k8sutil.ClusterAttr: c.Namespace,
 			},
-			Annotations: map[string]string{},
+			Annotations: annotations,
 		},
 		Spec: podSpec,
 	}
 }
 
-func (c *Cluster) osdContainer(devices []Device, directories []Directory, selection Selection, config Config) v1.Container {
+func (c *Cluster) osdContainer(devices []Device, directories []Directory, selection Selection, config Config,
+	resources v1.ResourceRequirements) v1.Container {
 
 	envVars := []v1.EnvVar{
 		nodeNameEnvVar(),
@@ -188,6 +524,7 @@ func (c *Cluster) osdContainer(devices []Device, directories []Directory, select
 		opmon.AdminSecretEnvVar(),
 		k8sutil.ConfigDirEnvVar(),
 		k8sutil.ConfigOverrideEnvVar(),
+		k8sutil.CephConfigEnvVar(),
 	}
 
 	// only 1 of device list, device filter and use all devices can be specified.  We prioritize in that order.
@@ -245,12 +582,20 @@ func (c *Cluster) osdContainer(devices []Device, directories []Directory, select
 		envVars = append(envVars, locationEnvVar(config.Location))
 	}
 
+	if _, ok := resources.Limits[v1.ResourceMemory]; ok {
+		// the osds size their caches to stay within the memory limit
+		envVars = append(envVars, osdMemoryLimitEnvVar())
+	}
+
+	envVars = append(envVars, c.network.EnvVars()...)
+
 	privileged := true
 	return v1.Container{
 		// Set the hostname so we have the pod's host in the crush map rather than the pod container name
 		Args:            []string{"osd"},
 		Name:            appName,
 		Image:           k8sutil.MakeRookImage(c.Version),
+		Resources:       resources,
 		VolumeMounts:    volumeMounts,
 		Env:             envVars,
 		SecurityContext: &v1.SecurityContext{Privileged: &privileged},
@@ -296,3 +641,8 @@ func osdJournalSizeEnvVar(journalSize int) v1.EnvVar {
 func locationEnvVar(location string) v1.EnvVar {
 	return v1.EnvVar{Name: "ROOKD_LOCATION", Value: location}
 }
+
+func osdMemoryLimitEnvVar() v1.EnvVar {
+	return v1.EnvVar{Name: "ROOKD_OSD_MEMORY_LIMIT", ValueFrom: &v1.EnvVarSource{
+		ResourceFieldRef: &v1.ResourceFieldSelector{Resource: "limits.memory", Divisor: resource.MustParse("1Mi")}}}
+}
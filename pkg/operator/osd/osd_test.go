@@ -16,6 +16,7 @@ limitations under the License.
 package osd
 
 import (
+	"sort"
 	"strconv"
 	"testing"
 
@@ -23,15 +24,19 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/kit"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/kubernetes/pkg/kubelet/apis"
 )
 
 func TestStartDaemonset(t *testing.T) {
 	clientset := fake.NewSimpleClientset()
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", StorageSpec{}, "", k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", StorageSpec{}, "", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 
 	// Start the first time
 	err := c.Start()
@@ -45,11 +50,11 @@ func TestStartDaemonset(t *testing.T) {
 func TestPodContainer(t *testing.T) {
 	cluster := &Cluster{Namespace: "myosd", Version: "23"}
 	config := Config{}
-	c := cluster.podTemplateSpec([]Device{}, []Directory{}, Selection{}, config)
+	c := cluster.podTemplateSpec([]Device{}, []Directory{}, Selection{}, config, v1.ResourceRequirements{})
 	assert.NotNil(t, c)
 	assert.Equal(t, 1, len(c.Spec.Containers))
 	container := c.Spec.Containers[0]
-	assert.Equal(t, 7, len(container.Env))
+	assert.Equal(t, 8, len(container.Env))
 	assert.Equal(t, "osd", container.Args[0])
 }
 
@@ -67,10 +72,10 @@ func testPodDevices(t *testing.T, dataDir, deviceFilter string, allDevices bool)
 	}
 
 	clientset := fake.NewSimpleClientset()
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", storageSpec, dataDir, k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", storageSpec, dataDir, k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 
 	n := c.Storage.resolveNode(storageSpec.Nodes[0].Name)
-	replicaSet := c.makeReplicaSet(n.Name, n.Devices, n.Directories, n.Selection, n.Config)
+	replicaSet := c.makeReplicaSet(n)
 	assert.NotNil(t, replicaSet)
 	assert.Equal(t, "rook-ceph-osd-node1", replicaSet.Name)
 	assert.Equal(t, c.Namespace, replicaSet.Namespace)
@@ -140,10 +145,10 @@ func TestStorageSpecDevicesAndDirectories(t *testing.T) {
 	}
 
 	clientset := fake.NewSimpleClientset()
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", storageSpec, "", k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", storageSpec, "", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 
 	n := c.Storage.resolveNode(storageSpec.Nodes[0].Name)
-	replicaSet := c.makeReplicaSet(n.Name, n.Devices, n.Directories, n.Selection, n.Config)
+	replicaSet := c.makeReplicaSet(n)
 	assert.NotNil(t, replicaSet)
 
 	// pod spec should have a volume for the given dir
@@ -182,10 +187,10 @@ func TestStorageSpecConfig(t *testing.T) {
 	}
 
 	clientset := fake.NewSimpleClientset()
-	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", storageSpec, "", k8sutil.Placement{})
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", storageSpec, "", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
 
 	n := c.Storage.resolveNode(storageSpec.Nodes[0].Name)
-	replicaSet := c.makeReplicaSet(n.Name, n.Devices, n.Directories, n.Selection, n.Config)
+	replicaSet := c.makeReplicaSet(n)
 	assert.NotNil(t, replicaSet)
 
 	container := replicaSet.Spec.Template.Spec.Containers[0]
@@ -196,3 +201,193 @@ func TestStorageSpecConfig(t *testing.T) {
 	verifyEnvVar(t, container.Env, "ROOKD_OSD_JOURNAL_SIZE", strconv.Itoa(30), true)
 	verifyEnvVar(t, container.Env, "ROOKD_LOCATION", "rack=foo", true)
 }
+
+func TestUpdateNodes(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	storageSpec := StorageSpec{Nodes: []Node{{Name: "node1"}, {Name: "node2"}}}
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", storageSpec, "", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	err := c.Start()
+	assert.Nil(t, err)
+	verifyReplicaSets(t, clientset, []string{"rook-ceph-osd-node1", "rook-ceph-osd-node2"})
+
+	// add a node and remove a node
+	newSpec := StorageSpec{Nodes: []Node{{Name: "node2"}, {Name: "node3"}}}
+	err = c.Update(newSpec, k8sutil.Placement{}, v1.ResourceRequirements{})
+	assert.Nil(t, err)
+	verifyReplicaSets(t, clientset, []string{"rook-ceph-osd-node2", "rook-ceph-osd-node3"})
+
+	// change the storage settings of a node
+	pod := v1.Pod{Spec: v1.PodSpec{NodeName: "node2"}}
+	pod.Name = "osd2"
+	pod.Labels = map[string]string{k8sutil.AppAttr: appName, k8sutil.ClusterAttr: "ns"}
+	_, err = clientset.CoreV1().Pods("ns").Create(&pod)
+	assert.Nil(t, err)
+	clientset.ClearActions()
+	newSpec = StorageSpec{Nodes: []Node{{Name: "node2", Devices: []Device{{Name: "sdb"}}}, {Name: "node3"}}}
+	err = c.Update(newSpec, k8sutil.Placement{}, v1.ResourceRequirements{})
+	assert.Nil(t, err)
+	verifyReplicaSets(t, clientset, []string{"rook-ceph-osd-node2", "rook-ceph-osd-node3"})
+	rs, err := clientset.Extensions().ReplicaSets("ns").Get("rook-ceph-osd-node2", metav1.GetOptions{})
+	assert.Nil(t, err)
+	verifyEnvVar(t, rs.Spec.Template.Spec.Containers[0].Env, "ROOKD_DATA_DEVICES", "sdb", true)
+
+	// the replica set is updated in place and its pod is restarted with the new settings
+	for _, action := range clientset.Actions() {
+		assert.False(t, action.Matches("delete", "replicasets"))
+	}
+	_, err = clientset.CoreV1().Pods("ns").Get("osd2", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// switching to all nodes is not supported
+	err = c.Update(StorageSpec{UseAllNodes: true}, k8sutil.Placement{}, v1.ResourceRequirements{})
+	assert.NotNil(t, err)
+	verifyReplicaSets(t, clientset, []string{"rook-ceph-osd-node2", "rook-ceph-osd-node3"})
+}
+
+func TestNodeResources(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clusterResources := v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi")}}
+	nodeResources := v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}}
+	storageSpec := StorageSpec{Nodes: []Node{{Name: "node1"}, {Name: "node2", Resources: nodeResources}}}
+	c := New(&clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}, "ns", "myversion", storageSpec, "", k8sutil.Placement{}, clusterResources, k8sutil.Network{})
+	err := c.Start()
+	assert.Nil(t, err)
+
+	// the resources of the node override the resources of the cluster
+	rs, err := clientset.Extensions().ReplicaSets("ns").Get("rook-ceph-osd-node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	container := rs.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, clusterResources, container.Resources)
+	verifyEnvVar(t, container.Env, "ROOKD_OSD_MEMORY_LIMIT", "", true)
+	rs, err = clientset.Extensions().ReplicaSets("ns").Get("rook-ceph-osd-node2", metav1.GetOptions{})
+	assert.Nil(t, err)
+	container = rs.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, nodeResources, container.Resources)
+	verifyEnvVar(t, container.Env, "ROOKD_OSD_MEMORY_LIMIT", "", false)
+
+	// the new resources are applied to the replica sets
+	newResources := v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")}}
+	err = c.Update(storageSpec, k8sutil.Placement{}, newResources)
+	assert.Nil(t, err)
+	verifyReplicaSets(t, clientset, []string{"rook-ceph-osd-node1", "rook-ceph-osd-node2"})
+	rs, err = clientset.Extensions().ReplicaSets("ns").Get("rook-ceph-osd-node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, newResources, rs.Spec.Template.Spec.Containers[0].Resources)
+
+	// the resources are not storage settings of the node
+	assert.False(t, storageChanged(&Node{Name: "node1"}, &Node{Name: "node1", Resources: nodeResources}))
+	assert.True(t, storageChanged(&Node{Name: "node1"}, &Node{Name: "node1", Devices: []Device{{Name: "sda"}}}))
+}
+
+func TestUpgradeNodes(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	flags := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "osd" {
+				flags = append(flags, args[1]+" "+args[2])
+				return "", nil
+			}
+			return `{"pgmap":{"num_pgs":10,"pgs_by_state":[{"state_name":"active+clean","count":10}]}}`, nil
+		},
+	}
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset, MaxRetries: 1}, Executor: executor}
+	storageSpec := StorageSpec{Nodes: []Node{{Name: "node1"}, {Name: "node2"}, {Name: "node3"}}}
+	c := New(context, "ns", "v1", storageSpec, "", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	err := c.Start()
+	assert.Nil(t, err)
+
+	// the osd pods on node2 were restarted with the new version
+	pod := v1.Pod{Spec: v1.PodSpec{NodeName: "node2", Containers: []v1.Container{{Image: k8sutil.MakeRookImage("v2")}}}}
+	pod.Name = "osd2"
+	pod.Labels = map[string]string{k8sutil.AppAttr: appName, k8sutil.ClusterAttr: "ns"}
+	pod.Status.Phase = v1.PodRunning
+	_, err = clientset.CoreV1().Pods("ns").Create(&pod)
+	assert.Nil(t, err)
+
+	// node1 was already upgraded, and node3 does not have a running osd pod to wait for
+	upgraded := []string{}
+	err = c.Upgrade("v2", []string{"node1"}, func(nodeName string) error {
+		upgraded = append(upgraded, nodeName)
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"node2"}, upgraded)
+	assert.Equal(t, []string{"set noout"}, flags)
+	rs, err := clientset.Extensions().ReplicaSets("ns").Get("rook-ceph-osd-node2", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, k8sutil.MakeRookImage("v2"), rs.Spec.Template.Spec.Containers[0].Image)
+	rs, err = clientset.Extensions().ReplicaSets("ns").Get("rook-ceph-osd-node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, k8sutil.MakeRookImage("v1"), rs.Spec.Template.Spec.Containers[0].Image)
+
+	// the noout flag is cleared after all the nodes are upgraded
+	pod.Name = "osd3"
+	pod.Spec.NodeName = "node3"
+	_, err = clientset.CoreV1().Pods("ns").Create(&pod)
+	assert.Nil(t, err)
+	err = c.Upgrade("v2", []string{"node1", "node2"}, func(nodeName string) error {
+		upgraded = append(upgraded, nodeName)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"node2", "node3"}, upgraded)
+	assert.Equal(t, []string{"set noout", "set noout", "unset noout"}, flags)
+}
+
+func TestRestartForConfigOverride(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	flags := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "osd" {
+				flags = append(flags, args[1]+" "+args[2])
+				return "", nil
+			}
+			return `{"pgmap":{"num_pgs":10,"pgs_by_state":[{"state_name":"active+clean","count":10}]}}`, nil
+		},
+	}
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset, MaxRetries: 1}, Executor: executor}
+	storageSpec := StorageSpec{Nodes: []Node{{Name: "node1"}}}
+	c := New(context, "ns", "v1", storageSpec, "", k8sutil.Placement{}, v1.ResourceRequirements{}, k8sutil.Network{})
+	c.ConfigOverrideHash = "hash1"
+	err := c.Start()
+	assert.Nil(t, err)
+	rs, err := clientset.Extensions().ReplicaSets("ns").Get("rook-ceph-osd-node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "hash1", rs.Spec.Template.Annotations[k8sutil.ConfigOverrideHashAttr])
+
+	// the osd pod was already restarted with the new config override
+	pod := v1.Pod{Spec: v1.PodSpec{NodeName: "node1", Containers: []v1.Container{{Image: k8sutil.MakeRookImage("v1")}}}}
+	pod.Name = "osd1"
+	pod.Labels = map[string]string{k8sutil.AppAttr: appName, k8sutil.ClusterAttr: "ns"}
+	pod.Annotations = map[string]string{k8sutil.ConfigOverrideHashAttr: "hash2"}
+	pod.Status.Phase = v1.PodRunning
+	_, err = clientset.CoreV1().Pods("ns").Create(&pod)
+	assert.Nil(t, err)
+
+	err = c.RestartForConfigOverride("hash2", func(nodeName string) error { return nil })
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"set noout", "unset noout"}, flags)
+	rs, err = clientset.Extensions().ReplicaSets("ns").Get("rook-ceph-osd-node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "hash2", rs.Spec.Template.Annotations[k8sutil.ConfigOverrideHashAttr])
+	_, err = clientset.CoreV1().Pods("ns").Get("osd1", metav1.GetOptions{})
+	assert.Nil(t, err)
+
+	// the noout flag is unset when the osds are not restarted, so it is not left set if the restart is not resumed
+	err = c.RestartForConfigOverride("hash3", func(nodeName string) error { return nil })
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"set noout", "unset noout", "set noout", "unset noout"}, flags)
+}
+
+func verifyReplicaSets(t *testing.T, clientset *fake.Clientset, expected []string) {
+	replicaSets, err := clientset.Extensions().ReplicaSets("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	names := []string{}
+	for _, rs := range replicaSets.Items {
+		names = append(names, rs.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, expected, names)
+}
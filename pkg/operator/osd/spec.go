@@ -19,6 +19,7 @@ package osd
 
 import (
 	cephosd "github.com/rook/rook/pkg/ceph/osd"
+	"k8s.io/api/core/v1"
 )
 
 // StorageSpec CRD settings
@@ -34,6 +35,8 @@ type Node struct {
 	Name        string      `json:"name,omitempty"`
 	Devices     []Device    `json:"devices,omitempty"`
 	Directories []Directory `json:"directories,omitempty"`
+	// The resources of the osds on the node, overriding the resources of the osds in the cluster spec
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
 	Selection
 	Config
 }
@@ -18,7 +18,10 @@ limitations under the License.
 package osd
 
 import (
+	"reflect"
+
 	cephosd "github.com/rook/rook/pkg/ceph/osd"
+	"k8s.io/api/core/v1"
 )
 
 // AnyUseAllDevices gets whether to use all devices
@@ -96,6 +99,23 @@ func (s *StorageSpec) resolveNodeConfig(node *Node) {
 	resolveString(&(node.Config.Location), s.Config.Location, "")
 }
 
+// resolveResources returns the resources of the osds on the node. The resources set on the node take precedence over
+// the resources of the osds in the cluster.
+func resolveResources(node *Node, clusterResources v1.ResourceRequirements) v1.ResourceRequirements {
+	if len(node.Resources.Limits) > 0 || len(node.Resources.Requests) > 0 {
+		return node.Resources
+	}
+	return clusterResources
+}
+
+// storageChanged returns whether the storage settings of the node changed. The osds must be restarted with the new
+// settings, while a change to the resources only applies the next time the osd pod is started.
+func storageChanged(old, node *Node) bool {
+	oldStorage, newStorage := *old, *node
+	oldStorage.Resources, newStorage.Resources = v1.ResourceRequirements{}, v1.ResourceRequirements{}
+	return !reflect.DeepEqual(oldStorage, newStorage)
+}
+
 func (s *Selection) getUseAllDevices() bool {
 	return s.UseAllDevices != nil && *(s.UseAllDevices)
 }
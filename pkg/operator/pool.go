@@ -41,6 +41,8 @@ type poolManager struct {
 	watchVersion string
 	context      *clusterd.Context
 	rclient      rookclient.RookRestClient
+	clusterMgr   *clusterManager
+	deleted      pausedDeletes
 }
 
 type poolEvent struct {
@@ -57,7 +59,7 @@ func (p *poolInitiator) Create(clusterMgr *clusterManager, namespace string) (re
 	if err != nil {
 		return nil, fmt.Errorf("failed to get api client for pool tpr for cluster in namespace %s. %+v", namespace, err)
 	}
-	return &poolManager{context: p.context, namespace: namespace, rclient: rclient}, nil
+	return &poolManager{context: p.context, namespace: namespace, rclient: rclient, clusterMgr: clusterMgr}, nil
 }
 
 func (p *poolInitiator) Resource() kit.CustomResource {
@@ -94,19 +96,31 @@ func (p *poolManager) handlePoolEvent(event *kit.RawEvent) error {
 		return fmt.Errorf("fail to unmarshal Pool from data (%s): %v", pool.Object, err)
 	}
 
+	if p.clusterMgr.isPaused(p.namespace) {
+		// the pools will be reconciled when the cluster is resumed
+		if event.Type == kwatch.Deleted {
+			// the deleted pools are not listed when the cluster is resumed, so the event is replayed then
+			p.deleted.add(event)
+		}
+		logger.Infof("deferring %s event for pool %s while the cluster in namespace %s is paused", event.Type, pool.Object.Name, p.namespace)
+		return nil
+	}
+
 	switch event.Type {
 	case kwatch.Added:
-		if err := pool.Object.Create(p.rclient); err != nil {
+		err := pool.Object.Create(p.rclient)
+		if err != nil {
 			logger.Errorf("failed to create pool %s. %+v", pool.Object.Name, err)
-			break
 		}
+		p.updateStatus(pool.Object, err)
 
 	case kwatch.Modified:
 		// if the pool is modified, allow the pool to be created if it wasn't already
-		if err := pool.Object.Create(p.rclient); err != nil {
-			logger.Errorf("failed to create (modify) pool %s. %+v", pool.Object.Name, err)
-			break
+		err := pool.Object.Update(p.rclient)
+		if err != nil {
+			logger.Errorf("failed to update pool %s. %+v", pool.Object.Name, err)
 		}
+		p.updateStatus(pool.Object, err)
 
 	case kwatch.Deleted:
 		err := pool.Object.Delete(p.rclient)
@@ -126,19 +140,45 @@ func (p *poolManager) Load() (string, error) {
 		return "", err
 	}
 
+	if p.clusterMgr.isPaused(p.namespace) {
+		logger.Infof("found %d pools. not checking them while the cluster in namespace %s is paused.", len(poolList.Items), p.namespace)
+		return poolList.Metadata.ResourceVersion, nil
+	}
+
+	// delete the pools that were deleted while the cluster was paused
+	p.deleted.replay(p.handlePoolEvent)
+
 	logger.Infof("found %d pools. ensuring they exist.", len(poolList.Items))
 	for i := range poolList.Items {
 		// ensure the pool exists
 		item := poolList.Items[i]
 		logger.Infof("checking pool %s in namespace %s", item.Name, item.Namespace)
-		if err := item.Create(p.rclient); err != nil {
+		err := item.Update(p.rclient)
+		if err != nil {
 			logger.Warningf("failed to check that pool %s exists in namespace %s. %+v", item.Name, item.Namespace, err)
 		}
+		p.updateStatus(&item, err)
 	}
 
 	return poolList.Metadata.ResourceVersion, nil
 }
 
+// updateStatus records in the pool resource whether the spec was applied. Saving the status generates a modified
+// event for the pool, so the status is only saved when it changes.
+func (p *poolManager) updateStatus(pool *cluster.Pool, err error) {
+	status := cluster.PoolStatus{State: cluster.PoolStateCreated}
+	if err != nil {
+		status = cluster.PoolStatus{State: cluster.PoolStateFailed, Message: err.Error()}
+	}
+	if pool.Status == status {
+		return
+	}
+
+	if err := kit.UpdateRawResourceStatus(p.context.Clientset, cluster.PoolResource, pool.Namespace, pool.Name, status); err != nil {
+		logger.Warningf("failed to save status of pool %s. %+v", pool.Name, err)
+	}
+}
+
 func (p *poolManager) getPoolList() (*cluster.PoolList, error) {
 	b, err := kit.GetRawListNamespaced(p.context.Clientset, cluster.PoolResource, p.namespace)
 	if err != nil {
@@ -18,14 +18,23 @@ limitations under the License.
 package operator
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/kubernetes-incubator/external-storage/lib/controller"
 	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/cluster"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/kit"
 	rookclient "github.com/rook/rook/pkg/rook/client"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
 )
@@ -34,13 +43,60 @@ const (
 	imageNameMaxLen = 100 // image name should be under 100 chars to support kernels older than 4.7
 	imageNamePrefix = "k8s-dynamic"
 	rbdIDPrefix     = "rbd_id."
+	defaultFSType   = "ext4"
+
+	// the annotations that record on the PV where the volume was provisioned
+	poolAnnotation             = "rook.io/pool"
+	clusterNamespaceAnnotation = "rook.io/clusterNamespace"
+	clusterNameAnnotation      = "rook.io/clusterName"
+	zoneAnnotation             = "rook.io/zone"
+
+	// the node label of the zone and the alpha annotation of the nodes that can access a PV. The scheduler only places
+	// the pods of the claims of a zone volume on the nodes of the zone.
+	zoneLabel              = "failure-domain.beta.kubernetes.io/zone"
+	nodeAffinityAnnotation = "volume.alpha.kubernetes.io/node-affinity"
+
+	// the claim annotation that requests the volume to be cloned from a snapshot, as [<pool>/]<image>@<snapshot>. The
+	// image must be the volume of a claim in the same namespace. The annotation is also recorded on the PV of the clone.
+	cloneFromAnnotation = "rook.io/cloneFrom"
+
+	// the claim annotation that requests the volume to be restored from a volume snapshot in the namespace of the claim.
+	// The annotation is also recorded on the PV of the restored volume.
+	snapshotAnnotation = "rook.io/snapshot"
+
+	// the annotation that records on the PV the cephx user that maps the volume. The user is shared by the volumes of
+	// the same cluster, namespace and pool and is deleted with the last of those volumes.
+	userAnnotation = "rook.io/user"
+
+	// the prefix of the cephx users that map the volumes. Only the users with the prefix can be deleted with the api.
+	volumeUserPrefix = model.ClientUserPrefix
+
+	// the key of the cephx user in the secret that is consumed by the rbd volume plugin
+	volumeUserSecretKey = "key"
+
+	// the max length of the secret names, which are dns subdomains, and the length of the hash that keeps the
+	// sanitized secret names of different users distinct
+	secretNameMaxLen  = 253
+	secretNameHashLen = 8
+)
+
+var (
+	// the names that are valid dns subdomains, and the characters that are replaced in the other names
+	validSecretName        = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	invalidSecretNameChars = regexp.MustCompile(`[^a-z0-9]+`)
 )
 
+// rookVolumeProvisioner provisions volumes from any number of storage classes and clusters. The settings of the storage
+// class are recorded on the PV so that the volume can be deleted from the same pool and cluster. The only state kept
+// between calls is the volumes that were provisioned with a cephx user before their PV is created.
 type rookVolumeProvisioner struct {
 	clusterManager *clusterManager
 
-	// Configuration of rook volume provisioner
-	provConfig provisionerConfig
+	// serializes the creation and deletion of the cephx users that are shared by volumes
+	userLock sync.Mutex
+
+	// the user of each volume that was provisioned but might not have a PV yet
+	provisioned map[string]string
 }
 
 type provisionerConfig struct {
@@ -52,6 +108,22 @@ type provisionerConfig struct {
 
 	// Optional: Name of the cluster. Default is `rook`
 	clusterName string
+
+	// Optional: The file system of the volume, ext4 or xfs. Default is `ext4`
+	fsType string
+
+	// Optional: The settings of the rbd image. The rbd defaults are used if not specified.
+	imageFormat   int
+	imageFeatures []string
+	stripeUnit    uint64
+	stripeCount   uint64
+
+	// Optional: The pool that stores the image data, for example an erasure coded pool
+	dataPool string
+
+	// Optional: The zone of the volumes. The images are placed in the `<pool>-<zone>` pool, which keeps the replicas
+	// under the CRUSH bucket of the zone.
+	zone string
 }
 
 func newRookVolumeProvisioner(clusterManager *clusterManager) controller.Provisioner {
@@ -74,38 +146,114 @@ func (p *rookVolumeProvisioner) Provision(options controller.VolumeOptions) (*v1
 	if err != nil {
 		return nil, err
 	}
-	p.provConfig = *cfg
 
-	logger.Infof("creating volume with configuration %+v", p.provConfig)
+	if p.clusterManager.isPaused(cfg.clusterNamespace) {
+		return nil, fmt.Errorf("cannot provision volumes while the cluster in namespace %s is paused", cfg.clusterNamespace)
+	}
+
+	cloneSource, err := parseCloneSource(options.PVC, cfg.pool)
+	if err != nil {
+		return nil, err
+	}
+	if cloneSource != nil {
+		if err := p.verifyCloneSource(options.PVC.Namespace, cfg.clusterNamespace, cloneSource); err != nil {
+			return nil, err
+		}
+	}
+	if snapshotName := options.PVC.Annotations[snapshotAnnotation]; snapshotName != "" {
+		if cloneSource != nil {
+			return nil, fmt.Errorf("the %s and %s annotations cannot be used together", cloneFromAnnotation, snapshotAnnotation)
+		}
+		if cloneSource, err = p.getVolumeSnapshotSource(options.PVC.Namespace, snapshotName, cfg.clusterNamespace); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Infof("creating volume with configuration %+v", *cfg)
 
 	capacity := options.PVC.Spec.Resources.Requests[v1.ResourceName(v1.ResourceStorage)]
 	requestBytes := capacity.Value()
 
 	imageName := createImageName(options.PVName)
 
-	rookClient, err := p.clusterManager.getRookClient(p.provConfig.clusterNamespace)
+	rookClient, err := p.clusterManager.getRookClient(cfg.clusterNamespace)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get rook client: %v", err)
 	}
 
-	res, err := createVolume(imageName, p.provConfig.pool, requestBytes, rookClient)
+	if cfg.zone != "" {
+		// the image is placed in the pool of the zone instead of the pool of the storage class
+		if cfg.pool, err = ensureZonePool(cfg.pool, cfg.zone, rookClient); err != nil {
+			return nil, err
+		}
+	}
+
+	var res string
+	if cloneSource != nil {
+		res, err = cloneVolume(imageName, cfg, cloneSource, requestBytes, rookClient)
+	} else {
+		res, err = createVolume(imageName, cfg, requestBytes, rookClient)
+	}
 	if err != nil {
 		return nil, err
 	}
 	logger.Infof("Rook block image created: %s", res)
 
+	pv, err := p.makeVolume(options, cfg, imageName, capacity, rookClient)
+	if err != nil {
+		// do not leave behind an image that no PV refers to
+		image := model.BlockImage{Name: imageName, PoolName: cfg.pool}
+		if _, deleteErr := rookClient.DeleteBlockImage(image); deleteErr != nil {
+			logger.Warningf("failed to delete image %s/%s after the volume could not be created. %+v", cfg.pool, imageName, deleteErr)
+		}
+		return nil, err
+	}
+
+	logger.Infof("successfully created Rook Block volume %+v", pv.Spec.PersistentVolumeSource.RBD)
+	return pv, nil
+}
+
+// makeVolume ensures the cephx user of the namespace and pool of the claim exists and returns the PV that maps the image
+// with the key of that user
+func (p *rookVolumeProvisioner) makeVolume(options controller.VolumeOptions, cfg *provisionerConfig, imageName string,
+	capacity resource.Quantity, rookClient rookclient.RookRestClient) (*v1.PersistentVolume, error) {
+
 	rookClientInfo, err := rookClient.GetClientAccessInfo()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get rook client information: %v", err)
 	}
 	monitors := processMonAddresses(rookClientInfo.MonAddresses)
-	radosUser := fmt.Sprintf("%s-rook-user", p.provConfig.clusterName)
-	secretRef := new(v1.LocalObjectReference)
-	secretRef.Name = fmt.Sprintf("%s-rook-user", p.provConfig.clusterName)
+
+	radosUser, err := p.createVolumeUser(options.PVName, options.PVC.Namespace, cfg.clusterNamespace, cfg.pool, rookClient)
+	if err != nil {
+		return nil, err
+	}
+	secretRef := &v1.LocalObjectReference{Name: volumeSecretName(radosUser)}
+
+	annotations := cfg.annotations()
+	annotations[userAnnotation] = radosUser
+	if cloneFrom := options.PVC.Annotations[cloneFromAnnotation]; strings.TrimSpace(cloneFrom) != "" {
+		annotations[cloneFromAnnotation] = cloneFrom
+	}
+	if snapshotName := options.PVC.Annotations[snapshotAnnotation]; snapshotName != "" {
+		annotations[snapshotAnnotation] = snapshotName
+	}
+
+	var labels map[string]string
+	if cfg.zone != "" {
+		labels = map[string]string{zoneLabel: cfg.zone}
+		affinity, err := zoneNodeAffinity(cfg.zone)
+		if err != nil {
+			return nil, err
+		}
+		annotations[nodeAffinityAnnotation] = affinity
+	}
 
 	pv := &v1.PersistentVolume{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: options.PVName,
+			Name:        options.PVName,
+			Labels:      labels,
+			Annotations: annotations,
 		},
 		Spec: v1.PersistentVolumeSpec{
 			PersistentVolumeReclaimPolicy: options.PersistentVolumeReclaimPolicy,
@@ -116,54 +264,420 @@ func (p *rookVolumeProvisioner) Provision(options controller.VolumeOptions) (*v1
 			PersistentVolumeSource: v1.PersistentVolumeSource{
 				RBD: &v1.RBDVolumeSource{
 					RBDImage:     imageName,
-					RBDPool:      p.provConfig.pool,
+					RBDPool:      cfg.pool,
 					CephMonitors: monitors,
 					RadosUser:    radosUser,
 					SecretRef:    secretRef,
-					FSType:       "ext4",
+					FSType:       cfg.fsType,
 					ReadOnly:     false,
 				},
 			},
 		},
 	}
-	logger.Infof("successfully created Rook Block volume %+v", pv.Spec.PersistentVolumeSource.RBD)
 	return pv, nil
 }
 
-// createVolume creates a rook block volume.
-func createVolume(image, pool string, size int64, client rookclient.RookRestClient) (string, error) {
+// zonePoolName returns the name of the pool that places the images of the pool in the zone
+func zonePoolName(pool, zone string) string {
+	return fmt.Sprintf("%s-%s", pool, zone)
+}
+
+// ensureZonePool creates the pool of the zone with the replication size of the pool of the storage class if it does not
+// exist yet. The replicas of the zone pool are placed on different hosts under the CRUSH bucket of the zone.
+func ensureZonePool(pool, zone string, client rookclient.RookRestClient) (string, error) {
+	pools, err := client.GetPools()
+	if err != nil {
+		return "", fmt.Errorf("Failed to get pools: %v", err)
+	}
+
+	name := zonePoolName(pool, zone)
+	var base *model.Pool
+	for i := range pools {
+		switch pools[i].Name {
+		case name:
+			if pools[i].CrushRoot != zone {
+				return "", fmt.Errorf("pool %s is not placed in zone %s", name, zone)
+			}
+			return name, nil
+		case pool:
+			base = &pools[i]
+		}
+	}
+	if base == nil {
+		return "", fmt.Errorf("pool %s not found", pool)
+	}
+	if base.Type != model.Replicated {
+		return "", fmt.Errorf("the volumes of zone %s can only be placed in a replicated pool. pool %s is %s", zone, pool,
+			model.PoolTypeToString(base.Type))
+	}
+
+	logger.Infof("creating pool %s for the volumes of pool %s in zone %s", name, pool, zone)
+	zonePool := model.Pool{Name: name, Type: model.Replicated, ReplicationConfig: base.ReplicationConfig, CrushRoot: zone}
+	if _, err := client.CreatePool(zonePool); err != nil {
+		return "", fmt.Errorf("Failed to create pool %s: %v", name, err)
+	}
+	return name, nil
+}
+
+// zoneNodeAffinity returns the node affinity annotation that restricts a PV to the nodes of the zone
+func zoneNodeAffinity(zone string) (string, error) {
+	affinity := v1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{{
+				MatchExpressions: []v1.NodeSelectorRequirement{{Key: zoneLabel, Operator: v1.NodeSelectorOpIn, Values: []string{zone}}},
+			}},
+		},
+	}
+	b, err := json.Marshal(affinity)
+	if err != nil {
+		return "", fmt.Errorf("Failed to marshal the node affinity of zone %s: %v", zone, err)
+	}
+	return string(b), nil
+}
+
+// volumeUserName returns the name of the cephx user that maps the volumes of a namespace from a pool of the cluster.
+// The names are separated with dots, which are not allowed in namespaces, so that the users of different clusters and
+// namespaces cannot collide.
+func volumeUserName(clusterNamespace, namespace, pool string) string {
+	return fmt.Sprintf("%s%s.%s.%s", volumeUserPrefix, clusterNamespace, namespace, pool)
+}
+
+// volumeSecretName returns the name of the secret with the key of the volume user in the namespace of the claims. The
+// user name is used if it is a valid secret name. The pool names can contain characters that are not allowed in secret
+// names, in which case the invalid characters are replaced and a hash of the user name is appended.
+func volumeSecretName(user string) string {
+	if len(user) <= secretNameMaxLen && validSecretName.MatchString(user) {
+		return user
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(user)))[:secretNameHashLen]
+	name := strings.Trim(invalidSecretNameChars.ReplaceAllString(strings.ToLower(user), "-"), "-")
+	if maxLen := secretNameMaxLen - secretNameHashLen - 1; len(name) > maxLen {
+		name = strings.TrimRight(name[:maxLen], "-")
+	}
+	return fmt.Sprintf("%s-%s", name, hash)
+}
+
+// createVolumeUser creates the cephx user of the namespace and pool if it does not exist yet and publishes its key in
+// a secret in the namespace. The user can only access the images of the pool. The volume is
+// recorded until it is deleted so that the user is not deleted before the PV of the volume is created.
+func (p *rookVolumeProvisioner) createVolumeUser(pvName, namespace, clusterNamespace, pool string, rookClient rookclient.RookRestClient) (string, error) {
+	name := volumeUserName(clusterNamespace, namespace, pool)
+	secretName := volumeSecretName(name)
+	p.userLock.Lock()
+	defer p.userLock.Unlock()
+
+	user, err := rookClient.CreateClientUser(model.ClientUser{Name: name, Pool: pool})
+	if err != nil {
+		return "", fmt.Errorf("Failed to create rook client user %s: %v", name, err)
+	}
+	if user == nil || user.Key == "" {
+		return "", fmt.Errorf("the key of rook client user %s is not available", name)
+	}
+
+	secrets := p.clusterManager.context.Clientset.CoreV1().Secrets(namespace)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: namespace},
+		StringData: map[string]string{volumeUserSecretKey: user.Key},
+		Type:       k8sutil.RbdType,
+	}
+	existing, err := secrets.Get(secretName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return "", fmt.Errorf("Failed to get secret %s: %v", secretName, err)
+		}
+		if _, err := secrets.Create(secret); err != nil {
+			return "", fmt.Errorf("Failed to create secret %s: %v", secretName, err)
+		}
+		logger.Infof("created secret %s for the volumes of pool %s in namespace %s", secretName, pool, namespace)
+		p.setProvisioned(pvName, name)
+		return name, nil
+	}
+
+	if string(existing.Data[volumeUserSecretKey]) != user.Key && existing.StringData[volumeUserSecretKey] != user.Key {
+		secret.ResourceVersion = existing.ResourceVersion
+		if _, err := secrets.Update(secret); err != nil {
+			return "", fmt.Errorf("Failed to update secret %s: %v", secretName, err)
+		}
+		logger.Infof("updated secret %s for the volumes of pool %s in namespace %s", secretName, pool, namespace)
+	}
+	p.setProvisioned(pvName, name)
+	return name, nil
+}
+
+// setProvisioned records the user of a volume that was provisioned. The user lock must be held.
+func (p *rookVolumeProvisioner) setProvisioned(pvName, user string) {
+	if p.provisioned == nil {
+		p.provisioned = map[string]string{}
+	}
+	p.provisioned[pvName] = user
+}
+
+// deleteVolumeUser deletes the cephx user of the volume and its secret if no other volume of the cluster is mapped
+// with the same user. Volumes that were provisioned with the shared cluster user are ignored.
+func (p *rookVolumeProvisioner) deleteVolumeUser(volume *v1.PersistentVolume, clusterNamespace string, rookClient rookclient.RookRestClient) error {
+	name := volume.Annotations[userAnnotation]
+	if name == "" {
+		return nil
+	}
+	p.userLock.Lock()
+	defer p.userLock.Unlock()
+	delete(p.provisioned, volume.Name)
+
+	volumes, err := p.clusterManager.context.Clientset.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("Failed to list volumes: %v", err)
+	}
+	for _, other := range volumes.Items {
+		// the provisioned volumes are tracked by their PV from now on
+		delete(p.provisioned, other.Name)
+		if other.Name == volume.Name || other.Annotations[userAnnotation] != name {
+			continue
+		}
+		otherCfg, err := parseVolumeAnnotations(&other)
+		if err == nil && otherCfg.clusterNamespace == clusterNamespace {
+			logger.Infof("rook client user %s is still used by volume %s", name, other.Name)
+			return nil
+		}
+	}
+
+	for pvName, user := range p.provisioned {
+		if user == name {
+			logger.Infof("rook client user %s is still used by the provisioned volume %s", name, pvName)
+			return nil
+		}
+	}
+
+	logger.Infof("deleting rook client user %s of the last volume %s", name, volume.Name)
+	if err := rookClient.DeleteClientUser(name); err != nil && !rookclient.IsHttpNotFound(err) {
+		return fmt.Errorf("Failed to delete rook client user %s: %v", name, err)
+	}
+	if volume.Spec.ClaimRef != nil {
+		secrets := p.clusterManager.context.Clientset.CoreV1().Secrets(volume.Spec.ClaimRef.Namespace)
+		if err := k8sutil.DeleteResource("volume user secret", volumeSecretName(name), secrets.Delete); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createVolume creates a rook block volume with the image settings of the storage class.
+func createVolume(image string, cfg *provisionerConfig, size int64, client rookclient.RookRestClient) (string, error) {
 	newImage := model.BlockImage{
-		Name:     image,
-		PoolName: pool,
-		Size:     uint64(size),
+		Name:         image,
+		PoolName:     cfg.pool,
+		Size:         uint64(size),
+		Format:       cfg.imageFormat,
+		Features:     cfg.imageFeatures,
+		StripeUnit:   cfg.stripeUnit,
+		StripeCount:  cfg.stripeCount,
+		DataPoolName: cfg.dataPool,
 	}
 
 	res, err := client.CreateBlockImage(newImage)
 	if err != nil {
-		return "", fmt.Errorf("Failed to create rook block image %s/%s: %v", pool, image, err)
+		return "", fmt.Errorf("Failed to create rook block image %s/%s: %v", cfg.pool, image, err)
 	}
 
 	return res, nil
 }
 
+// cloneVolume creates a rook block volume as a copy-on-write clone of a protected snapshot. The clone has the size of
+// the snapshot and is grown if the claim requests more storage.
+func cloneVolume(image string, cfg *provisionerConfig, source *model.BlockSnapshot, size int64, client rookclient.RookRestClient) (string, error) {
+	snapshot, err := findBlockSnapshot(*source, client)
+	if err != nil {
+		return "", err
+	}
+	if snapshot == nil {
+		return "", fmt.Errorf("snapshot %s of rook block image %s/%s not found", source.Name, source.PoolName, source.ImageName)
+	}
+	if uint64(size) < snapshot.Size {
+		return "", fmt.Errorf("the claim requests %d bytes, less than the %d bytes of snapshot %s", size, snapshot.Size, source.Name)
+	}
+
+	clone := model.BlockImage{
+		Name:         image,
+		PoolName:     cfg.pool,
+		Features:     cfg.imageFeatures,
+		StripeUnit:   cfg.stripeUnit,
+		StripeCount:  cfg.stripeCount,
+		DataPoolName: cfg.dataPool,
+	}
+	res, err := client.CloneBlockSnapshot(*source, clone)
+	if err != nil {
+		return "", fmt.Errorf("Failed to clone snapshot %s of rook block image %s/%s: %v", source.Name, source.PoolName, source.ImageName, err)
+	}
+
+	if uint64(size) > snapshot.Size {
+		clone.Size = uint64(size)
+		if _, err := client.ResizeBlockImage(clone, false); err != nil {
+			// do not leave behind a clone with the wrong size
+			if _, deleteErr := client.DeleteBlockImage(clone); deleteErr != nil {
+				logger.Warningf("failed to delete clone %s/%s after the resize failed. %+v", cfg.pool, image, deleteErr)
+			}
+			return "", fmt.Errorf("Failed to resize clone %s/%s: %v", cfg.pool, image, err)
+		}
+	}
+
+	return res, nil
+}
+
+// getVolumeSnapshotSource returns the rbd snapshot of a volume snapshot in the namespace of the claim
+func (p *rookVolumeProvisioner) getVolumeSnapshotSource(namespace, name, clusterNamespace string) (*model.BlockSnapshot, error) {
+	raw, err := kit.GetRawResource(p.clusterManager.context.Clientset, cluster.VolumeSnapshotResource, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get volume snapshot %s: %v", name, err)
+	}
+	var snapshot cluster.VolumeSnapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal volume snapshot %s: %v", name, err)
+	}
+	rookClient, err := p.clusterManager.getRookClient(clusterNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get rook client: %v", err)
+	}
+	return volumeSnapshotSource(p.clusterManager.context, &snapshot, clusterNamespace, rookClient)
+}
+
+// verifyCloneSource checks that the image of the snapshot is the volume of a claim in the namespace of the new claim,
+// so that a claim cannot clone the data of the volumes of other namespaces or of images that rook did not provision
+func (p *rookVolumeProvisioner) verifyCloneSource(namespace, clusterNamespace string, source *model.BlockSnapshot) error {
+	volumes, err := p.clusterManager.context.Clientset.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("Failed to list volumes: %v", err)
+	}
+	for i := range volumes.Items {
+		volume := &volumes.Items[i]
+		rbd := volume.Spec.PersistentVolumeSource.RBD
+		if rbd == nil || rbd.RBDImage != source.ImageName || rbd.RBDPool != source.PoolName {
+			continue
+		}
+		cfg, err := parseVolumeAnnotations(volume)
+		if err != nil || cfg.clusterNamespace != clusterNamespace {
+			continue
+		}
+		if volume.Status.Phase == v1.VolumeBound && volume.Spec.ClaimRef != nil && volume.Spec.ClaimRef.Namespace == namespace {
+			return nil
+		}
+	}
+	return fmt.Errorf("image %s/%s is not the volume of a claim in namespace %s. only the volumes of the namespace can be cloned",
+		source.PoolName, source.ImageName, namespace)
+}
+
+// parseCloneSource reads the snapshot that the claim requests the volume to be cloned from. The pool of the storage
+// class is used if the annotation does not specify the pool. Nil is returned if the claim does not request a clone.
+func parseCloneSource(claim *v1.PersistentVolumeClaim, defaultPool string) (*model.BlockSnapshot, error) {
+	value := strings.TrimSpace(claim.Annotations[cloneFromAnnotation])
+	if value == "" {
+		return nil, nil
+	}
+
+	source := &model.BlockSnapshot{PoolName: defaultPool}
+	parts := strings.Split(value, "@")
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid %s annotation %q. expected [<pool>/]<image>@<snapshot>", cloneFromAnnotation, value)
+	}
+	source.Name = parts[1]
+
+	image := strings.Split(parts[0], "/")
+	switch len(image) {
+	case 1:
+		source.ImageName = image[0]
+	case 2:
+		source.PoolName = image[0]
+		source.ImageName = image[1]
+	default:
+		return nil, fmt.Errorf("invalid %s annotation %q. expected [<pool>/]<image>@<snapshot>", cloneFromAnnotation, value)
+	}
+	if source.PoolName == "" || source.ImageName == "" {
+		return nil, fmt.Errorf("invalid %s annotation %q. expected [<pool>/]<image>@<snapshot>", cloneFromAnnotation, value)
+	}
+
+	return source, nil
+}
+
 // Delete removes the storage asset that was created by Provision represented
-// by the given PV.
+// by the given PV. The pool and cluster of the volume are read from the PV.
 func (p *rookVolumeProvisioner) Delete(volume *v1.PersistentVolume) error {
-	rookClient, err := p.clusterManager.getRookClient(p.provConfig.clusterNamespace)
+	cfg, err := parseVolumeAnnotations(volume)
+	if err != nil {
+		return err
+	}
+
+	if p.clusterManager.isPaused(cfg.clusterNamespace) {
+		return fmt.Errorf("cannot delete volumes while the cluster in namespace %s is paused", cfg.clusterNamespace)
+	}
+
+	rookClient, err := p.clusterManager.getRookClient(cfg.clusterNamespace)
 	if err != nil {
 		return fmt.Errorf("Failed to get rook client: %v", err)
 	}
 
 	image := model.BlockImage{
 		Name:     volume.Spec.PersistentVolumeSource.RBD.RBDImage,
-		PoolName: p.provConfig.pool,
+		PoolName: cfg.pool,
 	}
 
 	_, err = rookClient.DeleteBlockImage(image)
 	if err != nil {
-		return fmt.Errorf("Failed to delete rook block image %s/%s: %v", p.provConfig.pool, volume.Name, err)
+		return fmt.Errorf("Failed to delete rook block image %s/%s: %v", cfg.pool, volume.Name, err)
 	}
-	return nil
+
+	return p.deleteVolumeUser(volume, cfg.clusterNamespace, rookClient)
+}
+
+// annotations returns the PV annotations that record where the volume was provisioned
+func (c *provisionerConfig) annotations() map[string]string {
+	annotations := map[string]string{
+		poolAnnotation:             c.pool,
+		clusterNamespaceAnnotation: c.clusterNamespace,
+		clusterNameAnnotation:      c.clusterName,
+	}
+	if c.zone != "" {
+		annotations[zoneAnnotation] = c.zone
+	}
+	return annotations
+}
+
+// parseVolumeAnnotations reads the pool and cluster of a volume from the PV. Volumes that were provisioned before the
+// annotations were recorded fall back to the pool of the rbd source and the default cluster.
+func parseVolumeAnnotations(volume *v1.PersistentVolume) (*provisionerConfig, error) {
+	if volume.Spec.PersistentVolumeSource.RBD == nil {
+		return nil, fmt.Errorf("volume %s is not a rook block volume", volume.Name)
+	}
+
+	cfg := &provisionerConfig{
+		pool:             volume.Annotations[poolAnnotation],
+		clusterNamespace: volume.Annotations[clusterNamespaceAnnotation],
+		clusterName:      volume.Annotations[clusterNameAnnotation],
+		zone:             volume.Annotations[zoneAnnotation],
+	}
+	if len(cfg.pool) == 0 {
+		cfg.pool = volume.Spec.PersistentVolumeSource.RBD.RBDPool
+	}
+	if len(cfg.clusterNamespace) == 0 {
+		cfg.clusterNamespace = k8sutil.Namespace
+	}
+	if len(cfg.clusterName) == 0 {
+		cfg.clusterName = k8sutil.Namespace
+	}
+
+	if len(cfg.pool) == 0 {
+		return nil, fmt.Errorf("the pool of volume %s is unknown", volume.Name)
+	}
+	return cfg, nil
+}
+
+// the rbd image features that can be enabled in the storage class
+var supportedImageFeatures = map[string]bool{
+	"layering":       true,
+	"striping":       true,
+	"exclusive-lock": true,
+	"object-map":     true,
+	"fast-diff":      true,
+	"deep-flatten":   true,
+	"journaling":     true,
 }
 
 func parseClassParameters(params map[string]string) (*provisionerConfig, error) {
@@ -180,6 +694,36 @@ func parseClassParameters(params map[string]string) (*provisionerConfig, error)
 			cfg.clusterNamespace = v
 		case "clustername":
 			cfg.clusterName = v
+		case "fstype":
+			cfg.fsType = v
+		case "imageformat":
+			format, err := strconv.Atoi(v)
+			if err != nil || (format != 1 && format != 2) {
+				return nil, fmt.Errorf("invalid imageFormat %q. the image format must be 1 or 2", v)
+			}
+			cfg.imageFormat = format
+		case "imagefeatures":
+			for _, feature := range strings.Split(v, ",") {
+				if feature = strings.TrimSpace(feature); feature != "" {
+					cfg.imageFeatures = append(cfg.imageFeatures, feature)
+				}
+			}
+		case "stripeunit":
+			unit, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stripeUnit %q. %+v", v, err)
+			}
+			cfg.stripeUnit = unit
+		case "stripecount":
+			count, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stripeCount %q. %+v", v, err)
+			}
+			cfg.stripeCount = count
+		case "datapool":
+			cfg.dataPool = v
+		case "zone":
+			cfg.zone = v
 		default:
 			return nil, fmt.Errorf("invalid option %q for volume plugin %s", k, "rookVolumeProvisioner")
 		}
@@ -197,6 +741,28 @@ func parseClassParameters(params map[string]string) (*provisionerConfig, error)
 		cfg.clusterName = defaultCluster
 	}
 
+	if len(cfg.fsType) == 0 {
+		cfg.fsType = defaultFSType
+	}
+	if cfg.fsType != "ext4" && cfg.fsType != "xfs" {
+		return nil, fmt.Errorf("invalid fsType %q. the file system must be ext4 or xfs", cfg.fsType)
+	}
+
+	for _, feature := range cfg.imageFeatures {
+		if !supportedImageFeatures[feature] {
+			return nil, fmt.Errorf("invalid image feature %q", feature)
+		}
+	}
+	if cfg.imageFormat == 1 && (len(cfg.imageFeatures) > 0 || len(cfg.dataPool) > 0) {
+		return nil, fmt.Errorf("image features and data pools require image format 2")
+	}
+	if (cfg.stripeUnit == 0) != (cfg.stripeCount == 0) {
+		return nil, fmt.Errorf("stripeUnit and stripeCount must be specified together")
+	}
+	if cfg.zone != "" && len(cfg.dataPool) > 0 {
+		return nil, fmt.Errorf("the data pool of a zone cannot be specified")
+	}
+
 	return &cfg, nil
 }
 
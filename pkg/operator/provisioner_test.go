@@ -22,7 +22,16 @@ import (
 
 	"strings"
 
+	"github.com/kubernetes-incubator/external-storage/lib/controller"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/rook/rook/pkg/rook/test"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestProcessMonAddresses(t *testing.T) {
@@ -60,6 +69,46 @@ func TestParseClassParametersDefault(t *testing.T) {
 	assert.Equal(t, "rook", provConfig.clusterName)
 }
 
+func TestParseClassParametersImageSettings(t *testing.T) {
+	cfg := map[string]string{
+		"pool":          "testPool",
+		"fsType":        "xfs",
+		"imageFormat":   "2",
+		"imageFeatures": "layering, exclusive-lock,object-map,fast-diff",
+		"stripeUnit":    "65536",
+		"stripeCount":   "16",
+		"dataPool":      "ecPool",
+	}
+
+	provConfig, err := parseClassParameters(cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, "xfs", provConfig.fsType)
+	assert.Equal(t, 2, provConfig.imageFormat)
+	assert.Equal(t, []string{"layering", "exclusive-lock", "object-map", "fast-diff"}, provConfig.imageFeatures)
+	assert.Equal(t, uint64(65536), provConfig.stripeUnit)
+	assert.Equal(t, uint64(16), provConfig.stripeCount)
+	assert.Equal(t, "ecPool", provConfig.dataPool)
+
+	// ext4 is the default file system
+	provConfig, err = parseClassParameters(map[string]string{"pool": "testPool"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ext4", provConfig.fsType)
+
+	// invalid settings
+	invalid := []map[string]string{
+		{"pool": "testPool", "fsType": "btrfs"},
+		{"pool": "testPool", "imageFormat": "3"},
+		{"pool": "testPool", "imageFeatures": "layering,compression"},
+		{"pool": "testPool", "imageFormat": "1", "imageFeatures": "layering"},
+		{"pool": "testPool", "stripeUnit": "65536"},
+		{"pool": "testPool", "stripeCount": "-1"},
+	}
+	for _, params := range invalid {
+		_, err = parseClassParameters(params)
+		assert.NotNil(t, err, fmt.Sprintf("%v", params))
+	}
+}
+
 func TestParseClassParametersNoPool(t *testing.T) {
 	cfg := make(map[string]string)
 	cfg["clusterNamespace"] = "mynamespace"
@@ -79,6 +128,340 @@ func TestParseClassParametersInvalidOption(t *testing.T) {
 	assert.EqualError(t, err, "invalid option \"foo\" for volume plugin rookVolumeProvisioner")
 }
 
+func TestParseVolumeAnnotations(t *testing.T) {
+	class, err := parseClassParameters(map[string]string{"pool": "testPool", "clusterNamespace": "mynamespace", "clusterName": "myname"})
+	assert.Nil(t, err)
+
+	// the settings of the storage class are read back from the PV
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv1", Annotations: class.annotations()},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{RBD: &v1.RBDVolumeSource{RBDImage: "image1", RBDPool: "otherPool"}},
+		},
+	}
+	cfg, err := parseVolumeAnnotations(pv)
+	assert.Nil(t, err)
+	assert.Equal(t, "testPool", cfg.pool)
+	assert.Equal(t, "mynamespace", cfg.clusterNamespace)
+	assert.Equal(t, "myname", cfg.clusterName)
+
+	// volumes provisioned without the annotations use the rbd pool and the default cluster
+	pv.Annotations = nil
+	cfg, err = parseVolumeAnnotations(pv)
+	assert.Nil(t, err)
+	assert.Equal(t, "otherPool", cfg.pool)
+	assert.Equal(t, "rook", cfg.clusterNamespace)
+	assert.Equal(t, "rook", cfg.clusterName)
+
+	// only rbd volumes are supported
+	pv.Spec.PersistentVolumeSource.RBD = nil
+	_, err = parseVolumeAnnotations(pv)
+	assert.NotNil(t, err)
+}
+
+func TestParseCloneSource(t *testing.T) {
+	claim := &v1.PersistentVolumeClaim{}
+
+	// no clone requested
+	source, err := parseCloneSource(claim, "classPool")
+	assert.Nil(t, err)
+	assert.Nil(t, source)
+
+	// the pool of the class is the default
+	claim.Annotations = map[string]string{cloneFromAnnotation: "golden@snap1"}
+	source, err = parseCloneSource(claim, "classPool")
+	assert.Nil(t, err)
+	assert.Equal(t, model.BlockSnapshot{Name: "snap1", ImageName: "golden", PoolName: "classPool"}, *source)
+
+	claim.Annotations[cloneFromAnnotation] = "otherPool/golden@snap1"
+	source, err = parseCloneSource(claim, "classPool")
+	assert.Nil(t, err)
+	assert.Equal(t, model.BlockSnapshot{Name: "snap1", ImageName: "golden", PoolName: "otherPool"}, *source)
+
+	for _, invalid := range []string{"golden", "golden@", "@snap1", "a/b/c@snap1", "/golden@snap1", "golden@snap1@snap2"} {
+		claim.Annotations[cloneFromAnnotation] = invalid
+		_, err = parseCloneSource(claim, "classPool")
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestVerifyCloneSource(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}
+	p := &rookVolumeProvisioner{clusterManager: newClusterManager(context, nil)}
+	source := &model.BlockSnapshot{Name: "snap1", ImageName: "golden", PoolName: "replicapool"}
+
+	// an image that is not the volume of a claim cannot be cloned
+	err := p.verifyCloneSource("app", "rook", source)
+	assert.NotNil(t, err)
+
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv1", Annotations: map[string]string{poolAnnotation: "replicapool", clusterNamespaceAnnotation: "rook"}},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{RBD: &v1.RBDVolumeSource{RBDImage: "golden", RBDPool: "replicapool"}},
+			ClaimRef:               &v1.ObjectReference{Namespace: "app", Name: "golden-db"},
+		},
+		Status: v1.PersistentVolumeStatus{Phase: v1.VolumeBound},
+	}
+	_, err = clientset.CoreV1().PersistentVolumes().Create(pv)
+	assert.Nil(t, err)
+
+	// the volume of a claim in the same namespace can be cloned
+	err = p.verifyCloneSource("app", "rook", source)
+	assert.Nil(t, err)
+
+	// the volumes of other namespaces and clusters cannot be cloned
+	err = p.verifyCloneSource("other", "rook", source)
+	assert.NotNil(t, err)
+	err = p.verifyCloneSource("app", "rook2", source)
+	assert.NotNil(t, err)
+}
+
+func TestCloneVolume(t *testing.T) {
+	var cloned *model.BlockImage
+	var resized *model.BlockImage
+	c := &test.MockRookRestClient{
+		MockGetBlockSnapshots: func(poolName, imageName string) ([]model.BlockSnapshot, error) {
+			return []model.BlockSnapshot{{Name: "snap1", ImageName: imageName, PoolName: poolName, Size: 1024 * 1024 * 1024}}, nil
+		},
+		MockCloneBlockSnapshot: func(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error) {
+			assert.Equal(t, model.BlockSnapshot{Name: "snap1", ImageName: "golden", PoolName: "goldPool"}, snapshot)
+			cloned = &clone
+			return "succeeded cloning snapshot snap1 to image " + clone.Name, nil
+		},
+		MockResizeBlockImage: func(image model.BlockImage, force bool) (string, error) {
+			assert.False(t, force)
+			resized = &image
+			return "", nil
+		},
+	}
+	cfg, err := parseClassParameters(map[string]string{"pool": "testPool", "imageFeatures": "layering"})
+	assert.Nil(t, err)
+	source := &model.BlockSnapshot{Name: "snap1", ImageName: "golden", PoolName: "goldPool"}
+
+	// the clone has the size of the snapshot
+	_, err = cloneVolume("image1", cfg, source, 1024*1024*1024, c)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BlockImage{Name: "image1", PoolName: "testPool", Features: []string{"layering"}}, *cloned)
+	assert.Nil(t, resized)
+
+	// the clone is grown to the requested size
+	_, err = cloneVolume("image2", cfg, source, 2*1024*1024*1024, c)
+	assert.Nil(t, err)
+	assert.Equal(t, "image2", resized.Name)
+	assert.Equal(t, uint64(2*1024*1024*1024), resized.Size)
+
+	// the claim cannot be smaller than the snapshot
+	cloned = nil
+	_, err = cloneVolume("image3", cfg, source, 1024*1024, c)
+	assert.NotNil(t, err)
+	assert.Nil(t, cloned)
+
+	// the snapshot must exist
+	source.Name = "snap2"
+	_, err = cloneVolume("image4", cfg, source, 1024*1024*1024, c)
+	assert.NotNil(t, err)
+	assert.Nil(t, cloned)
+}
+
+func TestVolumeUser(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}
+	p := &rookVolumeProvisioner{clusterManager: newClusterManager(context, nil)}
+	users := map[string]model.ClientUser{}
+	deleted := []string{}
+	c := &test.MockRookRestClient{
+		MockCreateClientUser: func(user model.ClientUser) (*model.ClientUser, error) {
+			user.Key = user.Name + "-key"
+			users[user.Name] = user
+			return &user, nil
+		},
+		MockDeleteClientUser: func(name string) error {
+			deleted = append(deleted, name)
+			return nil
+		},
+	}
+	cfg, err := parseClassParameters(map[string]string{"pool": "testPool"})
+	assert.Nil(t, err)
+	newVolume := func(pvName, namespace string) *v1.PersistentVolume {
+		options := controller.VolumeOptions{
+			PVName: pvName,
+			PVC:    &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: pvName, Namespace: namespace}},
+		}
+		pv, err := p.makeVolume(options, cfg, "image-"+pvName, resource.MustParse("1Gi"), c)
+		assert.Nil(t, err)
+		pv.Spec.ClaimRef = &v1.ObjectReference{Name: pvName, Namespace: namespace}
+		_, err = clientset.CoreV1().PersistentVolumes().Create(pv)
+		assert.Nil(t, err)
+		return pv
+	}
+
+	// the volume is mapped with a user that can only access the pool
+	pv1 := newVolume("pv1", "app1")
+	assert.Equal(t, model.ClientUser{Name: "rook-rook.app1.testPool", Pool: "testPool", Key: "rook-rook.app1.testPool-key"}, users["rook-rook.app1.testPool"])
+	source := pv1.Spec.PersistentVolumeSource.RBD
+	assert.Equal(t, "rook-rook.app1.testPool", source.RadosUser)
+	assert.Equal(t, "rook-rook-app1-testpool-d2b6b11e", source.SecretRef.Name)
+	assert.Equal(t, "rook-rook.app1.testPool", pv1.Annotations[userAnnotation])
+
+	// the key of the user is published in the namespace of the claim
+	secret, err := clientset.CoreV1().Secrets("app1").Get("rook-rook-app1-testpool-d2b6b11e", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"key": "rook-rook.app1.testPool-key"}, secret.StringData)
+	assert.Equal(t, v1.SecretType("kubernetes.io/rbd"), secret.Type)
+
+	// the volumes of the same namespace share the user, other namespaces get their own user
+	pv2 := newVolume("pv2", "app1")
+	assert.Equal(t, "rook-rook.app1.testPool", pv2.Spec.PersistentVolumeSource.RBD.RadosUser)
+	pv3 := newVolume("pv3", "app2")
+	assert.Equal(t, "rook-rook.app2.testPool", pv3.Spec.PersistentVolumeSource.RBD.RadosUser)
+	assert.Equal(t, 2, len(users))
+
+	// the users of other clusters have different names
+	otherCfg, err := parseClassParameters(map[string]string{"pool": "testPool", "clusterNamespace": "other"})
+	assert.Nil(t, err)
+	options := controller.VolumeOptions{
+		PVName: "pv4",
+		PVC:    &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pv4", Namespace: "app1"}},
+	}
+	pv4, err := p.makeVolume(options, otherCfg, "image-pv4", resource.MustParse("1Gi"), c)
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-other.app1.testPool", pv4.Spec.PersistentVolumeSource.RBD.RadosUser)
+
+	// the user is kept while a provisioned volume has no PV yet
+	options.PVName = "pv5"
+	options.PVC = &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "pv5", Namespace: "app3"}}
+	pv5, err := p.makeVolume(options, cfg, "image-pv5", resource.MustParse("1Gi"), c)
+	assert.Nil(t, err)
+	pv6 := newVolume("pv6", "app3")
+	clientset.CoreV1().PersistentVolumes().Delete(pv6.Name, &metav1.DeleteOptions{})
+	err = p.deleteVolumeUser(pv6, "rook", c)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deleted))
+	err = p.deleteVolumeUser(pv5, "rook", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rook-rook.app3.testPool"}, deleted)
+	deleted = []string{}
+
+	// the user is kept while another volume is mapped with it
+	err = p.deleteVolumeUser(pv1, "rook", c)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deleted))
+	clientset.CoreV1().PersistentVolumes().Delete(pv1.Name, &metav1.DeleteOptions{})
+
+	// the user and the secret are deleted with the last volume
+	err = p.deleteVolumeUser(pv2, "rook", c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rook-rook.app1.testPool"}, deleted)
+	_, err = clientset.CoreV1().Secrets("app1").Get("rook-rook-app1-testpool-d2b6b11e", metav1.GetOptions{})
+	assert.NotNil(t, err)
+	_, err = clientset.CoreV1().Secrets("app2").Get("rook-rook-app2-testpool-a8b1d46c", metav1.GetOptions{})
+	assert.Nil(t, err)
+
+	// volumes mapped with the shared cluster user do not delete any user
+	delete(pv3.Annotations, userAnnotation)
+	err = p.deleteVolumeUser(pv3, "rook", c)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deleted))
+}
+
+func TestVolumeSecretName(t *testing.T) {
+	// the user name is the secret name if it is valid
+	assert.Equal(t, "rook-rook.app.pool1", volumeSecretName("rook-rook.app.pool1"))
+
+	// the pools with characters that are not allowed in secret names get a sanitized name with the hash of the user
+	assert.Equal(t, "rook-rook-app-my-pool-fa30cf71", volumeSecretName("rook-rook.app.My_Pool"))
+	assert.NotEqual(t, volumeSecretName("rook-rook.app.My_Pool"), volumeSecretName("rook-rook.app.my-pool"))
+
+	// the long names are truncated
+	name := volumeSecretName("rook-rook.app." + strings.Repeat("Pool", 70))
+	assert.Equal(t, 253, len(name))
+	assert.Equal(t, "-d4c537c1", name[244:])
+}
+
+func TestEnsureZonePool(t *testing.T) {
+	var created *model.Pool
+	pools := []model.Pool{
+		{Name: "replicapool", Type: model.Replicated, ReplicationConfig: model.ReplicatedPoolConfig{Size: 3}},
+		{Name: "ecpool", Type: model.ErasureCoded},
+	}
+	c := &test.MockRookRestClient{
+		MockGetPools: func() ([]model.Pool, error) {
+			return pools, nil
+		},
+		MockCreatePool: func(pool model.Pool) (string, error) {
+			created = &pool
+			pools = append(pools, pool)
+			return "", nil
+		},
+	}
+
+	// the pool of the zone is created with the replication of the pool of the storage class
+	pool, err := ensureZonePool("replicapool", "zone1", c)
+	assert.Nil(t, err)
+	assert.Equal(t, "replicapool-zone1", pool)
+	assert.Equal(t, model.Pool{Name: "replicapool-zone1", Type: model.Replicated, ReplicationConfig: model.ReplicatedPoolConfig{Size: 3},
+		CrushRoot: "zone1"}, *created)
+
+	// the existing pool of the zone is used
+	created = nil
+	pool, err = ensureZonePool("replicapool", "zone1", c)
+	assert.Nil(t, err)
+	assert.Equal(t, "replicapool-zone1", pool)
+	assert.Nil(t, created)
+
+	// a pool with the same name that is not placed in the zone is not used
+	pools[2].CrushRoot = ""
+	_, err = ensureZonePool("replicapool", "zone1", c)
+	assert.NotNil(t, err)
+
+	// the pool of the storage class must be a replicated pool
+	_, err = ensureZonePool("ecpool", "zone1", c)
+	assert.NotNil(t, err)
+	_, err = ensureZonePool("otherpool", "zone1", c)
+	assert.NotNil(t, err)
+	assert.Nil(t, created)
+}
+
+func TestZoneVolume(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}
+	p := &rookVolumeProvisioner{clusterManager: newClusterManager(context, nil)}
+	c := &test.MockRookRestClient{
+		MockCreateClientUser: func(user model.ClientUser) (*model.ClientUser, error) {
+			user.Key = "key"
+			return &user, nil
+		},
+	}
+
+	// a zone cannot use a data pool
+	_, err := parseClassParameters(map[string]string{"pool": "replicapool", "zone": "zone1", "dataPool": "ecpool"})
+	assert.NotNil(t, err)
+
+	cfg, err := parseClassParameters(map[string]string{"pool": "replicapool", "zone": "zone1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "zone1", cfg.zone)
+	cfg.pool = zonePoolName(cfg.pool, cfg.zone)
+	options := controller.VolumeOptions{
+		PVName: "pv1",
+		PVC:    &v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "claim1", Namespace: "app"}},
+	}
+	pv, err := p.makeVolume(options, cfg, "image1", resource.MustParse("1Gi"), c)
+	assert.Nil(t, err)
+
+	// the volume is restricted to the nodes of the zone
+	assert.Equal(t, map[string]string{"failure-domain.beta.kubernetes.io/zone": "zone1"}, pv.Labels)
+	assert.Equal(t, `{"requiredDuringSchedulingIgnoredDuringExecution":{"nodeSelectorTerms":[{"matchExpressions":`+
+		`[{"key":"failure-domain.beta.kubernetes.io/zone","operator":"In","values":["zone1"]}]}]}}`, pv.Annotations[nodeAffinityAnnotation])
+	assert.Equal(t, "replicapool-zone1", pv.Spec.PersistentVolumeSource.RBD.RBDPool)
+
+	// the zone and the pool of the zone are read back from the PV
+	volumeCfg, err := parseVolumeAnnotations(pv)
+	assert.Nil(t, err)
+	assert.Equal(t, "zone1", volumeCfg.zone)
+	assert.Equal(t, "replicapool-zone1", volumeCfg.pool)
+}
+
 func TestCreateImageName(t *testing.T) {
 	// use a PV name that is typical, it should not be truncated because the resultant image name is not over max length
 	pvName := "pvc-023d0ff3-261d-11e7-aa63-001c42669caf"
@@ -0,0 +1,131 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operator to manage Kubernetes storage.
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// the annotation set by the provision controller on the PVs it provisioned
+	provisionedByAnnotation = "pv.kubernetes.io/provisioned-by"
+
+	// the claims are checked again periodically in case a resize failed
+	resizeResyncPeriod = 5 * time.Minute
+)
+
+// volumeResizer expands the rbd images of the provisioned volumes when their claims request more storage. Volumes are
+// never shrunk by the resizer.
+type volumeResizer struct {
+	context        *clusterd.Context
+	clusterManager *clusterManager
+}
+
+func newVolumeResizer(context *clusterd.Context, clusterManager *clusterManager) *volumeResizer {
+	return &volumeResizer{context: context, clusterManager: clusterManager}
+}
+
+// Run watches the claims in all namespaces until the stop channel is closed
+func (r *volumeResizer) Run(stopCh <-chan struct{}) {
+	source := cache.NewListWatchFromClient(r.context.Clientset.CoreV1().RESTClient(), "persistentvolumeclaims", v1.NamespaceAll, fields.Everything())
+	_, controller := cache.NewInformer(source, &v1.PersistentVolumeClaim{}, resizeResyncPeriod, cache.ResourceEventHandlerFuncs{
+		AddFunc: r.onClaimChanged,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			r.onClaimChanged(newObj)
+		},
+	})
+	controller.Run(stopCh)
+}
+
+func (r *volumeResizer) onClaimChanged(obj interface{}) {
+	claim, ok := obj.(*v1.PersistentVolumeClaim)
+	if !ok {
+		return
+	}
+	if err := r.resize(claim); err != nil {
+		logger.Errorf("failed to resize volume of claim %s in namespace %s. %+v", claim.Name, claim.Namespace, err)
+	}
+}
+
+// resize expands the image of the volume bound to the claim if the claim requests more storage than the capacity of
+// the volume. The capacity of the volume and the claim are updated after the image is resized.
+func (r *volumeResizer) resize(claim *v1.PersistentVolumeClaim) error {
+	if claim.Status.Phase != v1.ClaimBound || claim.Spec.VolumeName == "" {
+		return nil
+	}
+
+	pv, err := r.context.Clientset.CoreV1().PersistentVolumes().Get(claim.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get volume %s. %+v", claim.Spec.VolumeName, err)
+	}
+	if pv.Annotations[provisionedByAnnotation] != provisionerName || pv.Spec.PersistentVolumeSource.RBD == nil {
+		return nil
+	}
+
+	requested := claim.Spec.Resources.Requests[v1.ResourceStorage]
+	capacity := pv.Spec.Capacity[v1.ResourceStorage]
+	if requested.Cmp(capacity) <= 0 {
+		return nil
+	}
+
+	cfg, err := parseVolumeAnnotations(pv)
+	if err != nil {
+		return err
+	}
+	if r.clusterManager.isPaused(cfg.clusterNamespace) {
+		return fmt.Errorf("cannot resize volumes while the cluster in namespace %s is paused", cfg.clusterNamespace)
+	}
+	rookClient, err := r.clusterManager.getRookClient(cfg.clusterNamespace)
+	if err != nil {
+		return fmt.Errorf("Failed to get rook client: %v", err)
+	}
+
+	logger.Infof("resizing volume %s from %s to %s", pv.Name, capacity.String(), requested.String())
+	image := model.BlockImage{
+		Name:     pv.Spec.PersistentVolumeSource.RBD.RBDImage,
+		PoolName: cfg.pool,
+		Size:     uint64(requested.Value()),
+	}
+	if _, err := rookClient.ResizeBlockImage(image, false); err != nil {
+		return fmt.Errorf("failed to resize rook block image %s/%s: %v", cfg.pool, image.Name, err)
+	}
+
+	pv.Spec.Capacity[v1.ResourceStorage] = requested
+	if _, err := r.context.Clientset.CoreV1().PersistentVolumes().Update(pv); err != nil {
+		return fmt.Errorf("failed to update the capacity of volume %s. %+v", pv.Name, err)
+	}
+
+	if claim.Status.Capacity == nil {
+		claim.Status.Capacity = v1.ResourceList{}
+	}
+	claim.Status.Capacity[v1.ResourceStorage] = requested
+	if _, err := r.context.Clientset.CoreV1().PersistentVolumeClaims(claim.Namespace).UpdateStatus(claim); err != nil {
+		return fmt.Errorf("failed to update the capacity of claim %s. %+v", claim.Name, err)
+	}
+
+	logger.Infof("resized volume %s to %s", pv.Name, requested.String())
+	return nil
+}
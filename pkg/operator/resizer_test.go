@@ -0,0 +1,111 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package operator
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/api"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResizeVolume(t *testing.T) {
+	// serve the rook api of the cluster
+	resized := []model.BlockImage{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var image model.BlockImage
+		assert.Equal(t, "PUT", r.Method)
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&image))
+		resized = append(resized, image)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	apiPort, _ := strconv.Atoi(port)
+
+	clientset := testop.New(3)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}
+	clientset.CoreV1().Services("rook").Create(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: api.DeploymentName, Namespace: "rook"},
+		Spec:       v1.ServiceSpec{ClusterIP: "127.0.0.1", Ports: []v1.ServicePort{{Port: int32(apiPort)}}},
+	})
+	clusterMgr := newClusterManager(context, nil)
+	c := &cluster.Cluster{ObjectMeta: v1.ObjectMeta{Name: "rook", Namespace: "rook"}}
+	c.Init(context)
+	clusterMgr.clusters["rook"] = c
+	resizer := newVolumeResizer(context, clusterMgr)
+
+	// a provisioned volume of 1Gi bound to a claim
+	annotations := map[string]string{provisionedByAnnotation: provisionerName, poolAnnotation: "mypool", clusterNamespaceAnnotation: "rook"}
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv1", Annotations: annotations},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity:               v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+			PersistentVolumeSource: v1.PersistentVolumeSource{RBD: &v1.RBDVolumeSource{RBDImage: "image1", RBDPool: "mypool"}},
+		},
+	}
+	clientset.CoreV1().PersistentVolumes().Create(pv)
+	claim := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim1", Namespace: "app"},
+		Spec: v1.PersistentVolumeClaimSpec{
+			VolumeName: "pv1",
+			Resources:  v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")}},
+		},
+		Status: v1.PersistentVolumeClaimStatus{Phase: v1.ClaimBound},
+	}
+	clientset.CoreV1().PersistentVolumeClaims("app").Create(claim)
+
+	// the volume is not resized if the claim did not grow
+	err := resizer.resize(claim)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(resized))
+
+	// the image is grown to the requested size
+	claim.Spec.Resources.Requests[v1.ResourceStorage] = resource.MustParse("2Gi")
+	err = resizer.resize(claim)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.BlockImage{{Name: "image1", PoolName: "mypool", Size: 2147483648}}, resized)
+
+	// the capacity of the volume and the claim are updated
+	pv, err = clientset.CoreV1().PersistentVolumes().Get("pv1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	capacity := pv.Spec.Capacity[v1.ResourceStorage]
+	assert.Equal(t, "2Gi", capacity.String())
+	claim, err = clientset.CoreV1().PersistentVolumeClaims("app").Get("claim1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	capacity = claim.Status.Capacity[v1.ResourceStorage]
+	assert.Equal(t, "2Gi", capacity.String())
+
+	// volumes of other provisioners are ignored
+	pv.Annotations[provisionedByAnnotation] = "kubernetes.io/rbd"
+	clientset.CoreV1().PersistentVolumes().Update(pv)
+	claim.Spec.Resources.Requests[v1.ResourceStorage] = resource.MustParse("3Gi")
+	err = resizer.resize(claim)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(resized))
+}
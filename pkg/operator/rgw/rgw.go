@@ -44,18 +44,24 @@ type Cluster struct {
 	context   *clusterd.Context
 	Namespace string
 	placement k8sutil.Placement
+	resources v1.ResourceRequirements
+	network   k8sutil.Network
 	Version   string
 	Replicas  int32
+	Port      int32
 }
 
 // New creates an instance of an rgw manager
-func New(context *clusterd.Context, namespace, version string, placement k8sutil.Placement) *Cluster {
+func New(context *clusterd.Context, namespace, version string, placement k8sutil.Placement, resources v1.ResourceRequirements, network k8sutil.Network) *Cluster {
 	return &Cluster{
 		context:   context,
 		Namespace: namespace,
 		placement: placement,
+		resources: resources,
+		network:   network,
 		Version:   version,
 		Replicas:  2,
+		Port:      cephrgw.RGWPort,
 	}
 }
 
@@ -89,6 +95,44 @@ func (c *Cluster) Start() error {
 	return nil
 }
 
+// Update the rgw service and deployment with the current settings if the object store was started
+func (c *Cluster) Update() error {
+	_, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Get(appName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Infof("rgw is not running in namespace %s", c.Namespace)
+			return nil
+		}
+		return fmt.Errorf("failed to get rgw deployment. %+v", err)
+	}
+
+	if err := c.updateService(); err != nil {
+		return fmt.Errorf("failed to update rgw service. %+v", err)
+	}
+
+	if _, err := c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Update(c.makeDeployment()); err != nil {
+		return fmt.Errorf("failed to update rgw deployment. %+v", err)
+	}
+	logger.Infof("rgw deployment updated")
+	return nil
+}
+
+// Delete the rgw deployment, service and keyring
+func (c *Cluster) Delete() error {
+	logger.Infof("deleting rgw in namespace %s", c.Namespace)
+	if err := k8sutil.DeleteResource("rgw deployment", appName, c.context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Delete); err != nil {
+		return err
+	}
+	if err := k8sutil.DeleteResource("rgw service", appName, c.context.Clientset.CoreV1().Services(c.Namespace).Delete); err != nil {
+		return err
+	}
+	if err := k8sutil.DeleteResource("rgw secret", appName, c.context.Clientset.CoreV1().Secrets(c.Namespace).Delete); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (c *Cluster) createKeyring() error {
 	_, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(appName, metav1.GetOptions{})
 	if err == nil {
@@ -137,6 +181,7 @@ func (c *Cluster) makeDeployment() *extensions.Deployment {
 		},
 	}
 	c.placement.ApplyToPodSpec(&podSpec)
+	c.network.ApplyToPodSpec(&podSpec)
 
 	podTemplateSpec := v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
@@ -158,23 +203,25 @@ func (c *Cluster) rgwContainer() v1.Container {
 		Args: []string{
 			"rgw",
 			fmt.Sprintf("--config-dir=%s", k8sutil.DataDir),
-			fmt.Sprintf("--rgw-port=%d", cephrgw.RGWPort),
+			fmt.Sprintf("--rgw-port=%d", c.Port),
 			fmt.Sprintf("--rgw-host=%s", cephrgw.DNSName),
 		},
-		Name:  appName,
-		Image: k8sutil.MakeRookImage(c.Version),
+		Name:      appName,
+		Image:     k8sutil.MakeRookImage(c.Version),
+		Resources: c.resources,
 		VolumeMounts: []v1.VolumeMount{
 			{Name: k8sutil.DataDirVolume, MountPath: k8sutil.DataDir},
 			k8sutil.ConfigOverrideMount(),
 		},
-		Env: []v1.EnvVar{
+		Env: append([]v1.EnvVar{
 			{Name: "ROOKD_RGW_KEYRING", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: appName}, Key: keyringName}}},
 			opmon.ClusterNameEnvVar(c.Namespace),
 			opmon.EndpointEnvVar(),
 			opmon.SecretEnvVar(),
 			opmon.AdminSecretEnvVar(),
 			k8sutil.ConfigOverrideEnvVar(),
-		},
+			k8sutil.CephConfigEnvVar(),
+		}, c.network.EnvVars()...),
 	}
 }
 
@@ -187,14 +234,7 @@ func (c *Cluster) startService() error {
 			Labels:    labels,
 		},
 		Spec: v1.ServiceSpec{
-			Ports: []v1.ServicePort{
-				{
-					Name:       appName,
-					Port:       cephrgw.RGWPort,
-					TargetPort: intstr.FromInt(int(cephrgw.RGWPort)),
-					Protocol:   v1.ProtocolTCP,
-				},
-			},
+			Ports:    c.servicePorts(),
 			Selector: labels,
 		},
 	}
@@ -208,10 +248,40 @@ func (c *Cluster) startService() error {
 		return nil
 	}
 
-	logger.Infof("RGW service running at %s:%d", s.Spec.ClusterIP, cephrgw.RGWPort)
+	logger.Infof("RGW service running at %s:%d", s.Spec.ClusterIP, c.Port)
 	return nil
 }
 
+// updateService sets the port of the rgw service if the port in the settings has changed
+func (c *Cluster) updateService() error {
+	s, err := c.context.Clientset.CoreV1().Services(c.Namespace).Get(appName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return c.startService()
+		}
+		return err
+	}
+	if len(s.Spec.Ports) == 1 && s.Spec.Ports[0].Port == c.Port {
+		return nil
+	}
+
+	logger.Infof("updating the rgw service port to %d", c.Port)
+	s.Spec.Ports = c.servicePorts()
+	_, err = c.context.Clientset.CoreV1().Services(c.Namespace).Update(s)
+	return err
+}
+
+func (c *Cluster) servicePorts() []v1.ServicePort {
+	return []v1.ServicePort{
+		{
+			Name:       appName,
+			Port:       c.Port,
+			TargetPort: intstr.FromInt(int(c.Port)),
+			Protocol:   v1.ProtocolTCP,
+		},
+	}
+}
+
 func (c *Cluster) getLabels() map[string]string {
 	return map[string]string{
 		k8sutil.AppAttr:     appName,
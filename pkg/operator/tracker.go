@@ -36,6 +36,8 @@ func newTPRTracker() *tprTracker {
 }
 
 func (t *tprTracker) add(name, version string) {
+	t.Lock()
+	defer t.Unlock()
 	t.clusterRVs[name] = version
 	if _, ok := t.stopChMap[name]; !ok {
 		t.stopChMap[name] = make(chan struct{})
@@ -43,16 +45,43 @@ func (t *tprTracker) add(name, version string) {
 }
 
 func (t *tprTracker) remove(name string) {
-	delete(t.clusterRVs, name)
-
 	t.Lock()
 	defer t.Unlock()
+	delete(t.clusterRVs, name)
 	if stopCh, ok := t.stopChMap[name]; ok {
 		close(stopCh)
 	}
 	delete(t.stopChMap, name)
 }
 
+// stopCh returns the channel that is closed when the cluster stops being tracked. The channel of a cluster that is
+// not tracked is already closed.
+func (t *tprTracker) stopCh(name string) <-chan struct{} {
+	t.RLock()
+	defer t.RUnlock()
+	if stopCh, ok := t.stopChMap[name]; ok {
+		return stopCh
+	}
+	stopCh := make(chan struct{})
+	close(stopCh)
+	return stopCh
+}
+
+// version returns the resource version of the tracked cluster
+func (t *tprTracker) version(name string) (string, bool) {
+	t.RLock()
+	defer t.RUnlock()
+	rv, ok := t.clusterRVs[name]
+	return rv, ok
+}
+
+// count returns the number of tracked clusters
+func (t *tprTracker) count() int {
+	t.RLock()
+	defer t.RUnlock()
+	return len(t.clusterRVs)
+}
+
 func (t *tprTracker) stop() {
 	t.Lock()
 	defer t.Unlock()
@@ -0,0 +1,410 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operator to manage Kubernetes storage.
+package operator
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/kit"
+	rookclient "github.com/rook/rook/pkg/rook/client"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwatch "k8s.io/apimachinery/pkg/watch"
+)
+
+type volumeSnapshotInitiator struct {
+	context *clusterd.Context
+}
+
+// volumeSnapshotManager takes the rbd snapshots of the volumes of a cluster. The volume snapshots are created in the
+// namespaces of the claims, so the snapshots in all namespaces are watched and the snapshots of volumes in other
+// clusters are ignored.
+type volumeSnapshotManager struct {
+	namespace  string
+	context    *clusterd.Context
+	rclient    rookclient.RookRestClient
+	clusterMgr *clusterManager
+	deleted    pausedDeletes
+}
+
+type volumeSnapshotEvent struct {
+	Type   kwatch.EventType
+	Object *cluster.VolumeSnapshot
+}
+
+func newVolumeSnapshotInitiator(context *clusterd.Context) *volumeSnapshotInitiator {
+	return &volumeSnapshotInitiator{context: context}
+}
+
+func (s *volumeSnapshotInitiator) Create(clusterMgr *clusterManager, namespace string) (resourceManager, error) {
+	rclient, err := clusterMgr.getRookClient(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api client for volume snapshot tpr for cluster in namespace %s. %+v", namespace, err)
+	}
+	return &volumeSnapshotManager{context: s.context, namespace: namespace, rclient: rclient, clusterMgr: clusterMgr}, nil
+}
+
+func (s *volumeSnapshotInitiator) Resource() kit.CustomResource {
+	return cluster.VolumeSnapshotResource
+}
+
+// Run the tpr manager until the caller signals with an EndWatch()
+func (s *volumeSnapshotManager) Manage() {
+	for {
+
+		// load and initialize the volume snapshots
+		watchVersion, err := s.Load()
+		if err != nil {
+			logger.Errorf("cannot load volume snapshot tpr for cluster %s. %+v. retrying...", s.namespace, err)
+		} else {
+			// watch for added/updated/deleted volume snapshots in all namespaces
+			watcher := kit.NewWatcher(s.context.KubeContext, cluster.VolumeSnapshotResource, "", watchVersion, s.handleVolumeSnapshotEvent, nil)
+			if err := watcher.Watch(); err != nil {
+				logger.Errorf("failed to watch volume snapshot tpr for cluster %s. %+v. retrying...", s.namespace, err)
+			}
+		}
+
+		<-time.After(time.Second * time.Duration(s.context.RetryDelay))
+	}
+}
+
+func (s *volumeSnapshotManager) handleVolumeSnapshotEvent(event *kit.RawEvent) error {
+	snapshot := &volumeSnapshotEvent{
+		Type:   event.Type,
+		Object: &cluster.VolumeSnapshot{},
+	}
+	err := json.Unmarshal(event.Object, snapshot.Object)
+	if err != nil {
+		return fmt.Errorf("fail to unmarshal VolumeSnapshot from data (%s): %v", snapshot.Object, err)
+	}
+
+	if s.clusterMgr.isPaused(s.namespace) {
+		// the volume snapshots will be reconciled when the cluster is resumed
+		if event.Type == kwatch.Deleted {
+			// the deleted volume snapshots are not listed when the cluster is resumed, so the event is replayed then
+			s.deleted.add(event)
+		}
+		logger.Infof("deferring %s event for volume snapshot %s while the cluster in namespace %s is paused", event.Type, snapshot.Object.Name, s.namespace)
+		return nil
+	}
+
+	switch event.Type {
+	case kwatch.Added, kwatch.Modified:
+		// saving the status generates a modified event, which is ignored once the snapshot is ready
+		s.takeSnapshot(snapshot.Object)
+
+	case kwatch.Deleted:
+		if err := s.deleteSnapshot(snapshot.Object); err != nil {
+			logger.Errorf("failed to delete volume snapshot %s in namespace %s. %+v", snapshot.Object.Name, snapshot.Object.Namespace, err)
+		}
+	}
+	return nil
+}
+
+func (s *volumeSnapshotManager) Load() (string, error) {
+	logger.Info("finding existing volume snapshots...")
+	snapshotList, err := s.getVolumeSnapshotList()
+	if err != nil {
+		return "", err
+	}
+
+	if s.clusterMgr.isPaused(s.namespace) {
+		logger.Infof("found %d volume snapshots. not checking them while the cluster in namespace %s is paused.", len(snapshotList.Items), s.namespace)
+		return snapshotList.Metadata.ResourceVersion, nil
+	}
+
+	// delete the volume snapshots that were deleted while the cluster was paused
+	s.deleted.replay(s.handleVolumeSnapshotEvent)
+
+	logger.Infof("found %d volume snapshots. ensuring the snapshots of cluster %s are taken.", len(snapshotList.Items), s.namespace)
+	for i := range snapshotList.Items {
+		s.takeSnapshot(&snapshotList.Items[i])
+	}
+
+	return snapshotList.Metadata.ResourceVersion, nil
+}
+
+// takeSnapshot takes the rbd snapshot of the volume bound to the claim of the volume snapshot if the volume belongs to
+// the cluster. A snapshot that is ready is never taken again.
+func (s *volumeSnapshotManager) takeSnapshot(snapshot *cluster.VolumeSnapshot) {
+	if snapshot.Status.Ready {
+		return
+	}
+	if err := snapshot.Validate(); err != nil {
+		s.updateStatus(snapshot, cluster.VolumeSnapshotStatus{}, fmt.Errorf("invalid volume snapshot %s arguments. %+v", snapshot.Name, err))
+		return
+	}
+
+	cfg, image, err := getClaimVolume(s.context, snapshot.Namespace, snapshot.ClaimName)
+	if err != nil {
+		s.updateStatus(snapshot, cluster.VolumeSnapshotStatus{}, err)
+		return
+	}
+	if cfg.clusterNamespace != s.namespace {
+		return
+	}
+
+	status := cluster.VolumeSnapshotStatus{
+		ClusterNamespace: cfg.clusterNamespace,
+		PoolName:         cfg.pool,
+		ImageName:        image,
+		SnapshotName:     snapshot.SnapshotName(),
+	}
+	status.DeletionPolicy, err = s.getDeletionPolicy(snapshot.SnapshotClassName)
+	if err != nil {
+		s.updateStatus(snapshot, status, err)
+		return
+	}
+
+	logger.Infof("taking snapshot %s of volume %s/%s for volume snapshot %s in namespace %s", status.SnapshotName, cfg.pool, image,
+		snapshot.Name, snapshot.Namespace)
+	taken, err := createVolumeSnapshot(status.SnapshotName, image, cfg.pool, s.rclient)
+	if err != nil {
+		logger.Errorf("failed to take volume snapshot %s in namespace %s. %+v", snapshot.Name, snapshot.Namespace, err)
+		s.updateStatus(snapshot, status, err)
+		return
+	}
+
+	status.Ready = true
+	status.RestoreSize = resource.NewQuantity(int64(taken.Size), resource.BinarySI).String()
+	s.updateStatus(snapshot, status, nil)
+}
+
+// deleteSnapshot deletes the rbd snapshot of a deleted volume snapshot unless the snapshot class retains it. The policy
+// is read from the class again since the status can be changed by the users of the namespace. The snapshot is only
+// found in the volumes of the cluster, so the snapshots of other clusters are ignored.
+func (s *volumeSnapshotManager) deleteSnapshot(snapshot *cluster.VolumeSnapshot) error {
+	if err := snapshot.Validate(); err != nil {
+		return nil
+	}
+	policy, err := s.getDeletionPolicy(snapshot.SnapshotClassName)
+	if err != nil {
+		return fmt.Errorf("retaining the snapshot. %+v", err)
+	}
+	target, err := findClaimSnapshot(s.context, snapshot, s.namespace, s.rclient)
+	if err != nil || target == nil {
+		return err
+	}
+	return deleteVolumeSnapshot(snapshot, target, policy, s.rclient)
+}
+
+// getDeletionPolicy reads the deletion policy from the snapshot class in the namespace of the cluster
+func (s *volumeSnapshotManager) getDeletionPolicy(className string) (cluster.SnapshotDeletionPolicy, error) {
+	if className == "" {
+		return cluster.SnapshotDeletionDelete, nil
+	}
+
+	raw, err := kit.GetRawResource(s.context.Clientset, cluster.VolumeSnapshotClassResource, s.namespace, className)
+	if err != nil {
+		return "", fmt.Errorf("failed to get volume snapshot class %s. %+v", className, err)
+	}
+	var class cluster.VolumeSnapshotClass
+	if err := json.Unmarshal(raw, &class); err != nil {
+		return "", fmt.Errorf("failed to unmarshal volume snapshot class %s. %+v", className, err)
+	}
+	return class.Policy()
+}
+
+// updateStatus records in the snapshot resource whether the snapshot is ready. Saving the status generates a modified
+// event for the snapshot, so the status is only saved when it changes.
+func (s *volumeSnapshotManager) updateStatus(snapshot *cluster.VolumeSnapshot, status cluster.VolumeSnapshotStatus, err error) {
+	if err != nil {
+		status.Ready = false
+		status.Message = err.Error()
+	}
+	if snapshot.Status == status {
+		return
+	}
+
+	if err := kit.UpdateRawResourceStatus(s.context.Clientset, cluster.VolumeSnapshotResource, snapshot.Namespace, snapshot.Name, status); err != nil {
+		logger.Warningf("failed to save status of volume snapshot %s in namespace %s. %+v", snapshot.Name, snapshot.Namespace, err)
+	}
+}
+
+func (s *volumeSnapshotManager) getVolumeSnapshotList() (*cluster.VolumeSnapshotList, error) {
+	raw, err := kit.GetRawList(s.context.Clientset, cluster.VolumeSnapshotResource)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := &cluster.VolumeSnapshotList{}
+	if err := json.Unmarshal(raw, snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// getClaimVolume returns the settings and the image of the rook block volume bound to the claim
+func getClaimVolume(context *clusterd.Context, namespace, claimName string) (*provisionerConfig, string, error) {
+	claim, err := context.Clientset.CoreV1().PersistentVolumeClaims(namespace).Get(claimName, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get claim %s. %+v", claimName, err)
+	}
+	if claim.Status.Phase != v1.ClaimBound || claim.Spec.VolumeName == "" {
+		return nil, "", fmt.Errorf("claim %s is not bound to a volume", claimName)
+	}
+
+	pv, err := context.Clientset.CoreV1().PersistentVolumes().Get(claim.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get volume %s. %+v", claim.Spec.VolumeName, err)
+	}
+	if pv.Annotations[provisionedByAnnotation] != provisionerName || pv.Spec.PersistentVolumeSource.RBD == nil {
+		return nil, "", fmt.Errorf("volume %s of claim %s is not a rook block volume", pv.Name, claimName)
+	}
+
+	if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Namespace != namespace || pv.Spec.ClaimRef.Name != claimName {
+		return nil, "", fmt.Errorf("volume %s is not bound to claim %s in namespace %s", pv.Name, claimName, namespace)
+	}
+
+	cfg, err := parseVolumeAnnotations(pv)
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, pv.Spec.PersistentVolumeSource.RBD.RBDImage, nil
+}
+
+// findClaimSnapshot returns the rbd snapshot of a volume snapshot, or nil if the snapshot was not taken. The snapshot
+// is searched in the rook block volumes of the cluster that were bound to the claim of the volume snapshot in its
+// namespace, so the snapshots of the volumes of other namespaces cannot be found.
+func findClaimSnapshot(context *clusterd.Context, snapshot *cluster.VolumeSnapshot, clusterNamespace string,
+	rclient rookclient.RookRestClient) (*model.BlockSnapshot, error) {
+
+	volumes, err := context.Clientset.CoreV1().PersistentVolumes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes. %+v", err)
+	}
+	for i := range volumes.Items {
+		pv := &volumes.Items[i]
+		claim := pv.Spec.ClaimRef
+		if claim == nil || claim.Namespace != snapshot.Namespace || claim.Name != snapshot.ClaimName {
+			continue
+		}
+		if pv.Annotations[provisionedByAnnotation] != provisionerName || pv.Spec.PersistentVolumeSource.RBD == nil {
+			continue
+		}
+		cfg, err := parseVolumeAnnotations(pv)
+		if err != nil || cfg.clusterNamespace != clusterNamespace {
+			continue
+		}
+
+		target := model.BlockSnapshot{Name: snapshot.SnapshotName(), ImageName: pv.Spec.PersistentVolumeSource.RBD.RBDImage, PoolName: cfg.pool}
+		found, err := findBlockSnapshot(target, rclient)
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, nil
+}
+
+// createVolumeSnapshot takes the rbd snapshot of the image if it does not exist yet. The snapshot is protected so that
+// volumes can be cloned from it.
+func createVolumeSnapshot(name, image, pool string, rclient rookclient.RookRestClient) (*model.BlockSnapshot, error) {
+	snapshot := model.BlockSnapshot{Name: name, ImageName: image, PoolName: pool}
+	existing, err := findBlockSnapshot(snapshot, rclient)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	if _, err := rclient.CreateBlockSnapshot(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot %s of image %s/%s. %+v", name, pool, image, err)
+	}
+	if _, err := rclient.ProtectBlockSnapshot(snapshot); err != nil {
+		// do not leave behind a snapshot that cannot be cloned
+		if _, deleteErr := rclient.DeleteBlockSnapshot(snapshot); deleteErr != nil {
+			logger.Warningf("failed to delete snapshot %s of image %s/%s after it could not be protected. %+v", name, pool, image, deleteErr)
+		}
+		return nil, fmt.Errorf("failed to protect snapshot %s of image %s/%s. %+v", name, pool, image, err)
+	}
+
+	created, err := findBlockSnapshot(snapshot, rclient)
+	if err != nil {
+		return nil, err
+	}
+	if created == nil {
+		return nil, fmt.Errorf("snapshot %s of image %s/%s not found after it was created", name, pool, image)
+	}
+	return created, nil
+}
+
+// deleteVolumeSnapshot unprotects and deletes the rbd snapshot of a volume snapshot unless the snapshot class retains
+// it. The snapshot cannot be deleted while volumes cloned from it are not flattened.
+func deleteVolumeSnapshot(snapshot *cluster.VolumeSnapshot, target *model.BlockSnapshot, policy cluster.SnapshotDeletionPolicy,
+	rclient rookclient.RookRestClient) error {
+
+	if policy == cluster.SnapshotDeletionRetain {
+		logger.Infof("retaining snapshot %s of image %s/%s of deleted volume snapshot %s in namespace %s", target.Name, target.PoolName,
+			target.ImageName, snapshot.Name, snapshot.Namespace)
+		return nil
+	}
+
+	logger.Infof("deleting snapshot %s of image %s/%s of volume snapshot %s in namespace %s", target.Name, target.PoolName, target.ImageName,
+		snapshot.Name, snapshot.Namespace)
+	if _, err := rclient.UnprotectBlockSnapshot(*target); err != nil {
+		return fmt.Errorf("failed to unprotect snapshot %s of image %s/%s. flatten the volumes cloned from it first. %+v", target.Name,
+			target.PoolName, target.ImageName, err)
+	}
+	if _, err := rclient.DeleteBlockSnapshot(*target); err != nil {
+		return fmt.Errorf("failed to delete snapshot %s of image %s/%s. %+v", target.Name, target.PoolName, target.ImageName, err)
+	}
+	return nil
+}
+
+// findBlockSnapshot returns the snapshot of the image with the same name or nil if the image has no such snapshot
+func findBlockSnapshot(snapshot model.BlockSnapshot, rclient rookclient.RookRestClient) (*model.BlockSnapshot, error) {
+	snapshots, err := rclient.GetBlockSnapshots(snapshot.PoolName, snapshot.ImageName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the snapshots of image %s/%s. %+v", snapshot.PoolName, snapshot.ImageName, err)
+	}
+	for i := range snapshots {
+		if snapshots[i].Name == snapshot.Name {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// volumeSnapshotSource returns the rbd snapshot that a claim in the cluster can be provisioned from. The snapshot is
+// found from the volume of the claim of the volume snapshot, not from the status of the volume snapshot.
+func volumeSnapshotSource(context *clusterd.Context, snapshot *cluster.VolumeSnapshot, clusterNamespace string,
+	rclient rookclient.RookRestClient) (*model.BlockSnapshot, error) {
+
+	if !snapshot.Status.Ready {
+		return nil, fmt.Errorf("volume snapshot %s is not ready", snapshot.Name)
+	}
+	if err := snapshot.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid volume snapshot %s. %+v", snapshot.Name, err)
+	}
+	source, err := findClaimSnapshot(context, snapshot, clusterNamespace, rclient)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, fmt.Errorf("the snapshot of volume snapshot %s was not found in the volumes of claim %s in cluster %s", snapshot.Name,
+			snapshot.ClaimName, clusterNamespace)
+	}
+	return source, nil
+}
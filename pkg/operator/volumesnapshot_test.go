@@ -0,0 +1,219 @@
+/*
+Copyright 2017 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package operator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/model"
+	"github.com/rook/rook/pkg/operator/cluster"
+	"github.com/rook/rook/pkg/operator/kit"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/rook/rook/pkg/rook/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetClaimVolume(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}
+	class, _ := parseClassParameters(map[string]string{"pool": "testPool", "clusterNamespace": "mycluster"})
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv1", Annotations: class.annotations()},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeSource: v1.PersistentVolumeSource{RBD: &v1.RBDVolumeSource{RBDImage: "image1", RBDPool: "testPool"}},
+		},
+	}
+	pv.Annotations[provisionedByAnnotation] = provisionerName
+	clientset.CoreV1().PersistentVolumes().Create(pv)
+	claim := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim1", Namespace: "app"},
+		Spec:       v1.PersistentVolumeClaimSpec{VolumeName: "pv1"},
+	}
+	clientset.CoreV1().PersistentVolumeClaims("app").Create(claim)
+
+	// the claim must be bound
+	_, _, err := getClaimVolume(context, "app", "claim1")
+	assert.NotNil(t, err)
+
+	claim.Status.Phase = v1.ClaimBound
+	clientset.CoreV1().PersistentVolumeClaims("app").Update(claim)
+
+	// the volume must be bound to the claim
+	_, _, err = getClaimVolume(context, "app", "claim1")
+	assert.NotNil(t, err)
+	pv.Spec.ClaimRef = &v1.ObjectReference{Name: "claim1", Namespace: "other"}
+	clientset.CoreV1().PersistentVolumes().Update(pv)
+	_, _, err = getClaimVolume(context, "app", "claim1")
+	assert.NotNil(t, err)
+
+	pv.Spec.ClaimRef.Namespace = "app"
+	clientset.CoreV1().PersistentVolumes().Update(pv)
+	cfg, image, err := getClaimVolume(context, "app", "claim1")
+	assert.Nil(t, err)
+	assert.Equal(t, "image1", image)
+	assert.Equal(t, "testPool", cfg.pool)
+	assert.Equal(t, "mycluster", cfg.clusterNamespace)
+
+	// only the volumes of the rook block provisioner can be snapshotted
+	pv.Annotations[provisionedByAnnotation] = "kubernetes.io/rbd"
+	clientset.CoreV1().PersistentVolumes().Update(pv)
+	_, _, err = getClaimVolume(context, "app", "claim1")
+	assert.NotNil(t, err)
+
+	_, _, err = getClaimVolume(context, "app", "claim2")
+	assert.NotNil(t, err)
+}
+
+func TestCreateVolumeSnapshot(t *testing.T) {
+	snapshots := []model.BlockSnapshot{}
+	protected := []string{}
+	protectErr := error(nil)
+	c := &test.MockRookRestClient{
+		MockGetBlockSnapshots: func(poolName, imageName string) ([]model.BlockSnapshot, error) {
+			return snapshots, nil
+		},
+		MockCreateBlockSnapshot: func(snapshot model.BlockSnapshot) (string, error) {
+			snapshot.Size = 1024 * 1024 * 1024
+			snapshots = append(snapshots, snapshot)
+			return "", nil
+		},
+		MockProtectBlockSnapshot: func(snapshot model.BlockSnapshot) (string, error) {
+			protected = append(protected, snapshot.Name)
+			return "", protectErr
+		},
+		MockDeleteBlockSnapshot: func(snapshot model.BlockSnapshot) (string, error) {
+			snapshots = []model.BlockSnapshot{}
+			return "", nil
+		},
+	}
+
+	// the snapshot is taken and protected
+	snapshot, err := createVolumeSnapshot("k8s-snapshot-snap1", "image1", "testPool", c)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BlockSnapshot{Name: "k8s-snapshot-snap1", ImageName: "image1", PoolName: "testPool", Size: 1024 * 1024 * 1024}, *snapshot)
+	assert.Equal(t, []string{"k8s-snapshot-snap1"}, protected)
+
+	// an existing snapshot is not taken again
+	_, err = createVolumeSnapshot("k8s-snapshot-snap1", "image1", "testPool", c)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(snapshots))
+	assert.Equal(t, 1, len(protected))
+
+	// the snapshot is removed if it cannot be protected
+	protectErr = fmt.Errorf("mock failure")
+	_, err = createVolumeSnapshot("k8s-snapshot-snap2", "image1", "testPool", c)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(snapshots))
+}
+
+func TestDeleteVolumeSnapshot(t *testing.T) {
+	unprotected := []string{}
+	deleted := []string{}
+	c := &test.MockRookRestClient{
+		MockUnprotectBlockSnapshot: func(snapshot model.BlockSnapshot) (string, error) {
+			unprotected = append(unprotected, snapshot.Name)
+			return "", nil
+		},
+		MockDeleteBlockSnapshot: func(snapshot model.BlockSnapshot) (string, error) {
+			deleted = append(deleted, snapshot.Name)
+			return "", nil
+		},
+	}
+	snapshot := &cluster.VolumeSnapshot{ObjectMeta: v1.ObjectMeta{Name: "snap1", Namespace: "app", UID: "uid1"}}
+	target := &model.BlockSnapshot{Name: "k8s-snapshot-snap1-uid1", ImageName: "image1", PoolName: "testPool"}
+
+	// the snapshot is retained by the class
+	err := deleteVolumeSnapshot(snapshot, target, cluster.SnapshotDeletionRetain, c)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deleted))
+
+	// the snapshot is unprotected and deleted
+	err = deleteVolumeSnapshot(snapshot, target, cluster.SnapshotDeletionDelete, c)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"k8s-snapshot-snap1-uid1"}, unprotected)
+	assert.Equal(t, []string{"k8s-snapshot-snap1-uid1"}, deleted)
+}
+
+func TestFindClaimSnapshot(t *testing.T) {
+	clientset := testop.New(3)
+	context := &clusterd.Context{KubeContext: kit.KubeContext{Clientset: clientset}}
+	c := &test.MockRookRestClient{
+		MockGetBlockSnapshots: func(poolName, imageName string) ([]model.BlockSnapshot, error) {
+			if poolName == "testPool" && imageName == "image1" {
+				return []model.BlockSnapshot{{Name: "k8s-snapshot-snap1-uid1", ImageName: imageName, PoolName: poolName}}, nil
+			}
+			return []model.BlockSnapshot{}, nil
+		},
+	}
+	newVolume := func(name, image, claimNamespace string) {
+		class, _ := parseClassParameters(map[string]string{"pool": "testPool"})
+		pv := &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: class.annotations()},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{RBD: &v1.RBDVolumeSource{RBDImage: image, RBDPool: "testPool"}},
+				ClaimRef:               &v1.ObjectReference{Name: "claim1", Namespace: claimNamespace},
+			},
+		}
+		pv.Annotations[provisionedByAnnotation] = provisionerName
+		clientset.CoreV1().PersistentVolumes().Create(pv)
+	}
+	snapshot := &cluster.VolumeSnapshot{
+		ObjectMeta:         v1.ObjectMeta{Name: "snap1", Namespace: "app", UID: "uid1"},
+		VolumeSnapshotSpec: cluster.VolumeSnapshotSpec{ClaimName: "claim1"},
+		// the status is not trusted
+		Status: cluster.VolumeSnapshotStatus{Ready: true, PoolName: "otherPool", ImageName: "other", SnapshotName: "othersnap"},
+	}
+
+	// the image of the volume of the claim in another namespace is not searched
+	newVolume("pv1", "image1", "other")
+	found, err := findClaimSnapshot(context, snapshot, "rook", c)
+	assert.Nil(t, err)
+	assert.Nil(t, found)
+	_, err = volumeSnapshotSource(context, snapshot, "rook", c)
+	assert.NotNil(t, err)
+
+	// the snapshot is found in the volume of the claim
+	clientset.CoreV1().PersistentVolumes().Delete("pv1", &metav1.DeleteOptions{})
+	newVolume("pv2", "image2", "app")
+	newVolume("pv3", "image1", "app")
+	found, err = findClaimSnapshot(context, snapshot, "rook", c)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BlockSnapshot{Name: "k8s-snapshot-snap1-uid1", ImageName: "image1", PoolName: "testPool"}, *found)
+	source, err := volumeSnapshotSource(context, snapshot, "rook", c)
+	assert.Nil(t, err)
+	assert.Equal(t, *found, *source)
+
+	// the volumes of other clusters are ignored
+	found, err = findClaimSnapshot(context, snapshot, "other", c)
+	assert.Nil(t, err)
+	assert.Nil(t, found)
+
+	// a volume snapshot created again with the same name does not find the old snapshot
+	snapshot.UID = "uid2"
+	found, err = findClaimSnapshot(context, snapshot, "rook", c)
+	assert.Nil(t, err)
+	assert.Nil(t, found)
+
+	// the snapshot must be ready
+	snapshot.UID = "uid1"
+	snapshot.Status.Ready = false
+	_, err = volumeSnapshotSource(context, snapshot, "rook", c)
+	assert.NotNil(t, err)
+}
@@ -19,12 +19,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/url"
+	"path"
 
 	"github.com/rook/rook/pkg/model"
 )
 
 const (
-	imageQueryName = "image"
+	imageQueryName    = "image"
+	snapshotQueryName = "snapshots"
 )
 
 func (c *RookNetworkRestClient) GetBlockImages() ([]model.BlockImage, error) {
@@ -56,6 +58,26 @@ func (c *RookNetworkRestClient) CreateBlockImage(newImage model.BlockImage) (str
 	return string(resp), nil
 }
 
+// ResizeBlockImage sets the size of the image. Shrinking the image fails unless forced.
+func (c *RookNetworkRestClient) ResizeBlockImage(image model.BlockImage, force bool) (string, error) {
+	body, err := json.Marshal(image)
+	if err != nil {
+		return "", err
+	}
+
+	query := imageQueryName
+	if force {
+		query += "?force=true"
+	}
+
+	resp, err := c.DoPut(query, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
 func (c *RookNetworkRestClient) DeleteBlockImage(image model.BlockImage) (string, error) {
 	baseURL, err := url.Parse(imageQueryName)
 	if err != nil {
@@ -75,3 +97,111 @@ func (c *RookNetworkRestClient) DeleteBlockImage(image model.BlockImage) (string
 
 	return string(resp), nil
 }
+
+// GetBlockSnapshots lists the snapshots of an image
+func (c *RookNetworkRestClient) GetBlockSnapshots(poolName, imageName string) ([]model.BlockSnapshot, error) {
+	body, err := c.DoGet(snapshotsQuery(poolName, imageName))
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []model.BlockSnapshot
+	err = json.Unmarshal(body, &snapshots)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+func (c *RookNetworkRestClient) CreateBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.DoPost(snapshotsQuery(snapshot.PoolName, snapshot.ImageName), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func (c *RookNetworkRestClient) DeleteBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	resp, err := c.DoDelete(path.Join(snapshotsQuery(snapshot.PoolName, snapshot.ImageName), snapshot.Name))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+// RollbackBlockSnapshot reverts the image of the snapshot to its content
+func (c *RookNetworkRestClient) RollbackBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	return c.postSnapshotAction(snapshot, "rollback")
+}
+
+func (c *RookNetworkRestClient) ProtectBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	return c.postSnapshotAction(snapshot, "protect")
+}
+
+func (c *RookNetworkRestClient) UnprotectBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	return c.postSnapshotAction(snapshot, "unprotect")
+}
+
+// CloneBlockSnapshot creates a copy-on-write clone of a protected snapshot
+func (c *RookNetworkRestClient) CloneBlockSnapshot(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error) {
+	body, err := json.Marshal(clone)
+	if err != nil {
+		return "", err
+	}
+
+	query := path.Join(snapshotsQuery(snapshot.PoolName, snapshot.ImageName), snapshot.Name, "clone")
+	resp, err := c.DoPost(query, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+// GetBlockSnapshotChildren lists the images cloned from a snapshot
+func (c *RookNetworkRestClient) GetBlockSnapshotChildren(snapshot model.BlockSnapshot) ([]model.BlockImage, error) {
+	body, err := c.DoGet(path.Join(snapshotsQuery(snapshot.PoolName, snapshot.ImageName), snapshot.Name, "children"))
+	if err != nil {
+		return nil, err
+	}
+
+	var children []model.BlockImage
+	err = json.Unmarshal(body, &children)
+	if err != nil {
+		return nil, err
+	}
+
+	return children, nil
+}
+
+// FlattenBlockImage removes the dependency of a cloned image on its parent snapshot
+func (c *RookNetworkRestClient) FlattenBlockImage(image model.BlockImage) (string, error) {
+	resp, err := c.DoPost(path.Join(imageQueryName, image.PoolName, image.Name, "flatten"), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func (c *RookNetworkRestClient) postSnapshotAction(snapshot model.BlockSnapshot, action string) (string, error) {
+	query := path.Join(snapshotsQuery(snapshot.PoolName, snapshot.ImageName), snapshot.Name, action)
+	resp, err := c.DoPost(query, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func snapshotsQuery(poolName, imageName string) string {
+	return path.Join(imageQueryName, poolName, imageName, snapshotQueryName)
+}
@@ -16,17 +16,20 @@ limitations under the License.
 package client
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"path"
 
 	"github.com/rook/rook/pkg/model"
 )
 
 const (
-	clientQueryName = "client"
+	clientUsersQueryName = "users"
+	clientQueryName      = "client"
 	successStatuses
 )
 
@@ -35,18 +38,35 @@ type RookRestClient interface {
 	GetNodes() ([]model.Node, error)
 	GetPools() ([]model.Pool, error)
 	CreatePool(pool model.Pool) (string, error)
+	UpdatePool(pool model.Pool) (string, error)
+	DeletePool(name string) (string, error)
 	GetBlockImages() ([]model.BlockImage, error)
 	CreateBlockImage(image model.BlockImage) (string, error)
 	DeleteBlockImage(image model.BlockImage) (string, error)
+	ResizeBlockImage(image model.BlockImage, force bool) (string, error)
+	GetBlockSnapshots(poolName, imageName string) ([]model.BlockSnapshot, error)
+	CreateBlockSnapshot(snapshot model.BlockSnapshot) (string, error)
+	DeleteBlockSnapshot(snapshot model.BlockSnapshot) (string, error)
+	RollbackBlockSnapshot(snapshot model.BlockSnapshot) (string, error)
+	ProtectBlockSnapshot(snapshot model.BlockSnapshot) (string, error)
+	UnprotectBlockSnapshot(snapshot model.BlockSnapshot) (string, error)
+	CloneBlockSnapshot(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error)
+	GetBlockSnapshotChildren(snapshot model.BlockSnapshot) ([]model.BlockImage, error)
+	FlattenBlockImage(image model.BlockImage) (string, error)
 	GetClientAccessInfo() (model.ClientAccessInfo, error)
+	CreateClientUser(user model.ClientUser) (*model.ClientUser, error)
+	DeleteClientUser(name string) error
 	GetFilesystems() ([]model.Filesystem, error)
 	CreateFilesystem(model.FilesystemRequest) (string, error)
 	DeleteFilesystem(model.FilesystemRequest) (string, error)
+	CreateFilesystemVolume(model.FilesystemVolume) (*model.FilesystemVolume, error)
+	DeleteFilesystemVolume(fsName, name string) error
 	GetStatusDetails() (model.StatusDetails, error)
 	CreateObjectStore() (string, error)
 	GetObjectStoreConnectionInfo() (*model.ObjectStoreConnectInfo, error)
 	ListBuckets() ([]model.ObjectBucket, error)
 	GetBucket(string) (*model.ObjectBucket, error)
+	CreateBucket(model.ObjectBucketRequest) error
 	DeleteBucket(string, bool) error
 	ListObjectUsers() ([]model.ObjectUser, error)
 	GetObjectUser(string) (*model.ObjectUser, error)
@@ -169,3 +189,29 @@ func (c *RookNetworkRestClient) GetClientAccessInfo() (model.ClientAccessInfo, e
 
 	return clientAccessInfo, nil
 }
+
+// CreateClientUser creates a user that can only access the block images of a pool and returns the user with its key
+func (c *RookNetworkRestClient) CreateClientUser(user model.ClientUser) (*model.ClientUser, error) {
+	body, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.DoPost(path.Join(clientQueryName, clientUsersQueryName), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var created model.ClientUser
+	err = json.Unmarshal(resp, &created)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (c *RookNetworkRestClient) DeleteClientUser(name string) error {
+	_, err := c.DoDelete(path.Join(clientQueryName, clientUsersQueryName, name))
+	return err
+}
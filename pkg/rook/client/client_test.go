@@ -30,6 +30,8 @@ const (
 	SuccessGetNodesContent                     = `[{"nodeID": "node1","publicIp": "1.2.3.100","privateIp": "10.0.0.100","storage": 100},{"nodeID": "node2","ipAddr": "10.0.0.101","storage": 200}]`
 	SuccessGetPoolsContent                     = "[{\"poolName\":\"rbd\",\"poolNum\":0,\"type\":0,\"replicationConfig\":{\"size\":1},\"erasureCodedConfig\":{\"dataChunkCount\":0,\"codingChunkCount\":0,\"algorithm\":\"\"}},{\"poolName\":\"ecPool1\",\"poolNum\":1,\"type\":1,\"replicationConfig\":{\"size\":0},\"erasureCodedConfig\":{\"dataChunkCount\":2,\"codingChunkCount\":1,\"algorithm\":\"jerasure::reed_sol_van\"}}]"
 	SuccessCreatePoolContent                   = `pool 'ecPool1' created`
+	SuccessUpdatePoolContent                   = `succeeded updating pool rbd`
+	SuccessDeletePoolContent                   = `succeeded deleting pool rbd`
 	SuccessGetBlockImagesContent               = `[{"imageName":"myimage1","poolName":"rbd","size":10485760,"device":"","mountPoint":""},{"imageName":"myimage2","poolName":"rbd2","size":10485761,"device":"","mountPoint":""}]`
 	SuccessCreateBlockImageContent             = `succeeded created image myimage3`
 	SuccessDeleteBlockImageContent             = `succeeded deleting image myimage3`
@@ -130,6 +132,30 @@ func TestCreatePool(t *testing.T) {
 	assert.Equal(t, "pool 'ecPool1' created\n", createPoolResponse)
 }
 
+func TestUpdatePool(t *testing.T) {
+	mockServer := NewMockHttpServer(200, SuccessUpdatePoolContent)
+	defer mockServer.Close()
+	mockHttpClient := NewMockHttpClient(mockServer.URL)
+	client := NewRookNetworkRestClient(mockServer.URL, mockHttpClient)
+
+	pool := model.Pool{Name: "rbd", Type: model.Replicated}
+	pool.ReplicationConfig.Size = 3
+	response, err := client.UpdatePool(pool)
+	assert.Nil(t, err)
+	assert.Equal(t, SuccessUpdatePoolContent+"\n", response)
+}
+
+func TestDeletePool(t *testing.T) {
+	mockServer := NewMockHttpServer(200, SuccessDeletePoolContent)
+	defer mockServer.Close()
+	mockHttpClient := NewMockHttpClient(mockServer.URL)
+	client := NewRookNetworkRestClient(mockServer.URL, mockHttpClient)
+
+	response, err := client.DeletePool("rbd")
+	assert.Nil(t, err)
+	assert.Equal(t, SuccessDeletePoolContent+"\n", response)
+}
+
 func TestGetBlockImages(t *testing.T) {
 	mockServer := NewMockHttpServer(200, SuccessGetBlockImagesContent)
 	defer mockServer.Close()
@@ -290,6 +316,22 @@ func TestCreatePoolFailure(t *testing.T) {
 	ClientFailureHelperWithVerification(t, clientFunc, verifyFunc)
 }
 
+func TestUpdatePoolFailure(t *testing.T) {
+	clientFunc := func(client RookRestClient) (interface{}, error) {
+		return client.UpdatePool(model.Pool{Name: "pool1"})
+	}
+	verifyFunc := getStringVerifyFunc(t)
+	ClientFailureHelperWithVerification(t, clientFunc, verifyFunc)
+}
+
+func TestDeletePoolFailure(t *testing.T) {
+	clientFunc := func(client RookRestClient) (interface{}, error) {
+		return client.DeletePool("pool1")
+	}
+	verifyFunc := getStringVerifyFunc(t)
+	ClientFailureHelperWithVerification(t, clientFunc, verifyFunc)
+}
+
 func TestGetBlockImagesFailure(t *testing.T) {
 	ClientFailureHelper(t, func(client RookRestClient) (interface{}, error) { return client.GetBlockImages() })
 }
@@ -19,12 +19,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/url"
+	"path"
 
 	"github.com/rook/rook/pkg/model"
 )
 
 const (
 	filesystemQueryName = "filesystem"
+	volumesQueryName    = "volumes"
 )
 
 func (c *RookNetworkRestClient) GetFilesystems() ([]model.Filesystem, error) {
@@ -73,3 +75,30 @@ func (c *RookNetworkRestClient) DeleteFilesystem(deleteFilesystem model.Filesyst
 
 	return string(resp), nil
 }
+
+// CreateFilesystemVolume creates a directory with a quota in the file system and a user restricted to the directory
+func (c *RookNetworkRestClient) CreateFilesystemVolume(volume model.FilesystemVolume) (*model.FilesystemVolume, error) {
+	body, err := json.Marshal(volume)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.DoPost(path.Join(filesystemQueryName, volume.Filesystem, volumesQueryName), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var created model.FilesystemVolume
+	err = json.Unmarshal(resp, &created)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// DeleteFilesystemVolume removes the directory and the user of the volume
+func (c *RookNetworkRestClient) DeleteFilesystemVolume(fsName, name string) error {
+	_, err := c.DoDelete(path.Join(filesystemQueryName, fsName, volumesQueryName, name))
+	return err
+}
@@ -88,6 +88,20 @@ func (c *RookNetworkRestClient) GetBucket(bucketName string) (*model.ObjectBucke
 	return &bucket, nil
 }
 
+func (c *RookNetworkRestClient) CreateBucket(bucket model.ObjectBucketRequest) error {
+	body, err := json.Marshal(bucket)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %+v", err)
+	}
+
+	_, err = c.DoPost(path.Join(objectStoreQueryName, bucketsQueryName), bytes.NewReader(body))
+	if err != nil && !IsHttpStatusCode(err, http.StatusCreated) {
+		return err
+	}
+
+	return nil
+}
+
 func (c *RookNetworkRestClient) DeleteBucket(bucketName string, purge bool) error {
 	query := path.Join(objectStoreQueryName, bucketsQueryName, bucketName)
 	if purge {
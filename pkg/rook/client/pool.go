@@ -18,6 +18,7 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"path"
 
 	"github.com/rook/rook/pkg/model"
 )
@@ -54,3 +55,26 @@ func (c *RookNetworkRestClient) CreatePool(newPool model.Pool) (string, error) {
 
 	return string(resp), nil
 }
+
+func (c *RookNetworkRestClient) UpdatePool(pool model.Pool) (string, error) {
+	body, err := json.Marshal(pool)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.DoPut(path.Join(poolQueryName, pool.Name), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
+
+func (c *RookNetworkRestClient) DeletePool(name string) (string, error) {
+	resp, err := c.DoDelete(path.Join(poolQueryName, name))
+	if err != nil {
+		return "", err
+	}
+
+	return string(resp), nil
+}
@@ -33,19 +33,36 @@ type MockRookRestClient struct {
 	MockGetNodes                     func() ([]model.Node, error)
 	MockGetPools                     func() ([]model.Pool, error)
 	MockCreatePool                   func(pool model.Pool) (string, error)
+	MockUpdatePool                   func(pool model.Pool) (string, error)
+	MockDeletePool                   func(name string) (string, error)
 	MockGetBlockImages               func() ([]model.BlockImage, error)
 	MockCreateBlockImage             func(image model.BlockImage) (string, error)
 	MockDeleteBlockImage             func(image model.BlockImage) (string, error)
+	MockResizeBlockImage             func(image model.BlockImage, force bool) (string, error)
+	MockGetBlockSnapshots            func(poolName, imageName string) ([]model.BlockSnapshot, error)
+	MockCreateBlockSnapshot          func(snapshot model.BlockSnapshot) (string, error)
+	MockDeleteBlockSnapshot          func(snapshot model.BlockSnapshot) (string, error)
+	MockRollbackBlockSnapshot        func(snapshot model.BlockSnapshot) (string, error)
+	MockProtectBlockSnapshot         func(snapshot model.BlockSnapshot) (string, error)
+	MockUnprotectBlockSnapshot       func(snapshot model.BlockSnapshot) (string, error)
+	MockCloneBlockSnapshot           func(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error)
+	MockGetBlockSnapshotChildren     func(snapshot model.BlockSnapshot) ([]model.BlockImage, error)
+	MockFlattenBlockImage            func(image model.BlockImage) (string, error)
 	MockGetClientAccessInfo          func() (model.ClientAccessInfo, error)
+	MockCreateClientUser             func(model.ClientUser) (*model.ClientUser, error)
+	MockDeleteClientUser             func(name string) error
 	MockGetFilesystems               func() ([]model.Filesystem, error)
 	MockCreateFilesystem             func(model.FilesystemRequest) (string, error)
 	MockDeleteFilesystem             func(model.FilesystemRequest) (string, error)
+	MockCreateFilesystemVolume       func(model.FilesystemVolume) (*model.FilesystemVolume, error)
+	MockDeleteFilesystemVolume       func(fsName, name string) error
 	MockGetStatusDetails             func() (model.StatusDetails, error)
 	MockCreateObjectStore            func() (string, error)
 	MockGetObjectStoreConnectionInfo func() (*model.ObjectStoreConnectInfo, error)
 	MockCreateObjectUser             func(model.ObjectUser) (*model.ObjectUser, error)
 	MockListBuckets                  func() ([]model.ObjectBucket, error)
 	MockGetBucket                    func(string) (*model.ObjectBucket, error)
+	MockCreateBucket                 func(model.ObjectBucketRequest) error
 	MockDeleteBucket                 func(string, bool) error
 	MockListObjectUsers              func() ([]model.ObjectUser, error)
 	MockGetObjectUser                func(string) (*model.ObjectUser, error)
@@ -77,6 +94,22 @@ func (m *MockRookRestClient) CreatePool(pool model.Pool) (string, error) {
 	return "", nil
 }
 
+func (m *MockRookRestClient) UpdatePool(pool model.Pool) (string, error) {
+	if m.MockUpdatePool != nil {
+		return m.MockUpdatePool(pool)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) DeletePool(name string) (string, error) {
+	if m.MockDeletePool != nil {
+		return m.MockDeletePool(name)
+	}
+
+	return "", nil
+}
+
 func (m *MockRookRestClient) GetBlockImages() ([]model.BlockImage, error) {
 	if m.MockGetBlockImages != nil {
 		return m.MockGetBlockImages()
@@ -93,6 +126,14 @@ func (m *MockRookRestClient) CreateBlockImage(image model.BlockImage) (string, e
 	return "", nil
 }
 
+func (m *MockRookRestClient) ResizeBlockImage(image model.BlockImage, force bool) (string, error) {
+	if m.MockResizeBlockImage != nil {
+		return m.MockResizeBlockImage(image, force)
+	}
+
+	return "", nil
+}
+
 func (m *MockRookRestClient) DeleteBlockImage(image model.BlockImage) (string, error) {
 	if m.MockDeleteBlockImage != nil {
 		return m.MockDeleteBlockImage(image)
@@ -101,6 +142,78 @@ func (m *MockRookRestClient) DeleteBlockImage(image model.BlockImage) (string, e
 	return "", nil
 }
 
+func (m *MockRookRestClient) GetBlockSnapshots(poolName, imageName string) ([]model.BlockSnapshot, error) {
+	if m.MockGetBlockSnapshots != nil {
+		return m.MockGetBlockSnapshots(poolName, imageName)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) CreateBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockCreateBlockSnapshot != nil {
+		return m.MockCreateBlockSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) DeleteBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockDeleteBlockSnapshot != nil {
+		return m.MockDeleteBlockSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) RollbackBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockRollbackBlockSnapshot != nil {
+		return m.MockRollbackBlockSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) ProtectBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockProtectBlockSnapshot != nil {
+		return m.MockProtectBlockSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) UnprotectBlockSnapshot(snapshot model.BlockSnapshot) (string, error) {
+	if m.MockUnprotectBlockSnapshot != nil {
+		return m.MockUnprotectBlockSnapshot(snapshot)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) CloneBlockSnapshot(snapshot model.BlockSnapshot, clone model.BlockImage) (string, error) {
+	if m.MockCloneBlockSnapshot != nil {
+		return m.MockCloneBlockSnapshot(snapshot, clone)
+	}
+
+	return "", nil
+}
+
+func (m *MockRookRestClient) GetBlockSnapshotChildren(snapshot model.BlockSnapshot) ([]model.BlockImage, error) {
+	if m.MockGetBlockSnapshotChildren != nil {
+		return m.MockGetBlockSnapshotChildren(snapshot)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) FlattenBlockImage(image model.BlockImage) (string, error) {
+	if m.MockFlattenBlockImage != nil {
+		return m.MockFlattenBlockImage(image)
+	}
+
+	return "", nil
+}
+
 func (m *MockRookRestClient) GetClientAccessInfo() (model.ClientAccessInfo, error) {
 	if m.MockGetClientAccessInfo != nil {
 		return m.MockGetClientAccessInfo()
@@ -109,6 +222,22 @@ func (m *MockRookRestClient) GetClientAccessInfo() (model.ClientAccessInfo, erro
 	return model.ClientAccessInfo{}, nil
 }
 
+func (m *MockRookRestClient) CreateClientUser(user model.ClientUser) (*model.ClientUser, error) {
+	if m.MockCreateClientUser != nil {
+		return m.MockCreateClientUser(user)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) DeleteClientUser(name string) error {
+	if m.MockDeleteClientUser != nil {
+		return m.MockDeleteClientUser(name)
+	}
+
+	return nil
+}
+
 func (m *MockRookRestClient) GetFilesystems() ([]model.Filesystem, error) {
 	if m.MockGetFilesystems != nil {
 		return m.MockGetFilesystems()
@@ -133,6 +262,22 @@ func (m *MockRookRestClient) DeleteFilesystem(fsr model.FilesystemRequest) (stri
 	return "", nil
 }
 
+func (m *MockRookRestClient) CreateFilesystemVolume(volume model.FilesystemVolume) (*model.FilesystemVolume, error) {
+	if m.MockCreateFilesystemVolume != nil {
+		return m.MockCreateFilesystemVolume(volume)
+	}
+
+	return nil, nil
+}
+
+func (m *MockRookRestClient) DeleteFilesystemVolume(fsName, name string) error {
+	if m.MockDeleteFilesystemVolume != nil {
+		return m.MockDeleteFilesystemVolume(fsName, name)
+	}
+
+	return nil
+}
+
 func (m *MockRookRestClient) GetStatusDetails() (model.StatusDetails, error) {
 	if m.MockGetStatusDetails != nil {
 		return m.MockGetStatusDetails()
@@ -177,6 +322,14 @@ func (m *MockRookRestClient) GetBucket(s string) (*model.ObjectBucket, error) {
 	return nil, nil
 }
 
+func (m *MockRookRestClient) CreateBucket(b model.ObjectBucketRequest) error {
+	if m.MockCreateBucket != nil {
+		return m.MockCreateBucket(b)
+	}
+
+	return nil
+}
+
 func (m *MockRookRestClient) DeleteBucket(s string, b bool) error {
 	if m.MockDeleteBucket != nil {
 		return m.MockDeleteBucket(s, b)